@@ -0,0 +1,306 @@
+package main
+
+import (
+	"cbs/api"
+	"cbs/api/characters"
+	"cbs/dtos"
+	"cbs/models"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// adminUsage describes the operator commands
+const adminUsage = `usage: <command> <subcommand> [flags]
+
+commands:
+  user create -email EMAIL -name NAME [-password PASSWORD]
+  user reset-password -user ID|EMAIL [-password PASSWORD]
+  user list [-q QUERY]
+  user disable -user ID|EMAIL
+  user enable -user ID|EMAIL
+  user universes -user ID|EMAIL
+  universe transfer -universe ID -user ID|EMAIL
+  images reconcile [-min-age DURATION] [-dry-run]
+  sessions clear [-user ID|EMAIL]
+  jobs dead [-limit N]
+  jobs retry -job ID
+
+Passwords are generated and printed when -password is omitted.`
+
+// adminCommands represents the commands handled by runAdmin
+var adminCommands = []string{"user", "universe", "images", "sessions", "jobs"}
+
+// isAdminCommand reports whether a command is an operator command
+func isAdminCommand(command string) bool {
+	for _, c := range adminCommands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// runAdmin runs an operator command using the API services
+func runAdmin(services *api.Services, args []string) error {
+	if len(args) < 2 {
+		return errors.New(adminUsage)
+	}
+	fs := flag.NewFlagSet(strings.Join(args[:2], " "), flag.ContinueOnError)
+	email := fs.String("email", "", "Email address of the new user")
+	name := fs.String("name", "", "Display name of the new user")
+	password := fs.String("password", "", "Password to set (generated when omitted)")
+	userRef := fs.String("user", "", "ID or email address of the user")
+	universeID := fs.String("universe", "", "ID of the universe")
+	query := fs.String("q", "", "Text to search email addresses and display names for")
+	minAge := fs.Duration("min-age", characters.OrphanMinAge, "Minimum age of orphaned files to delete")
+	dryRun := fs.Bool("dry-run", false, "List the orphaned files and missing files without deleting anything")
+	limit := fs.Int("limit", 50, "Maximum number of jobs to list")
+	jobID := fs.String("job", "", "ID of the job")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+
+	switch strings.Join(args[:2], " ") {
+	case "user create":
+		if *email == "" || *name == "" {
+			return errors.New("user create requires -email and -name")
+		}
+		pw, err := passwordOrGenerate(*password)
+		if err != nil {
+			return err
+		}
+		payload := dtos.ReqCreateUser{DisplayName: *name, Email: *email, Password: pw}
+		valError, err := api.ValidateDTO(payload)
+		if err != nil {
+			return err
+		}
+		if valError != nil {
+			messages := make([]string, len(valError.Issues))
+			for i, issue := range valError.Issues {
+				messages[i] = issue.Message
+			}
+			return errors.New(strings.Join(messages, "; "))
+		}
+		user := services.User.New(payload)
+		if err := services.User.Create(user); err != nil {
+			return err
+		}
+		fmt.Printf("Created user %s (%s)\n", user.ID, user.Email)
+		printGeneratedPassword(*password, pw)
+	case "user reset-password":
+		user, err := findUser(services, *userRef)
+		if err != nil {
+			return err
+		}
+		pw, err := passwordOrGenerate(*password)
+		if err != nil {
+			return err
+		}
+		if err := user.SetPassword(pw); err != nil {
+			return err
+		}
+		if err := services.User.Update(user); err != nil {
+			return err
+		}
+		cleared, err := services.Auth.ClearSessions(user)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reset the password of %s and cleared %d sessions\n", user.Email, cleared)
+		printGeneratedPassword(*password, pw)
+	case "user list":
+		users, err := services.User.Search(*query)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tEMAIL\tDISPLAY NAME\tDISABLED")
+		for _, u := range *users {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", u.ID, u.Email, u.DisplayName, u.Disabled)
+		}
+		return w.Flush()
+	case "user disable", "user enable":
+		user, err := findUser(services, *userRef)
+		if err != nil {
+			return err
+		}
+		disabled := args[1] == "disable"
+		if err := services.User.SetDisabled(user, disabled); err != nil {
+			return err
+		}
+		if !disabled {
+			fmt.Printf("Enabled %s\n", user.Email)
+			return nil
+		}
+		cleared, err := services.Auth.ClearSessions(user)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Disabled %s and cleared %d sessions\n", user.Email, cleared)
+	case "user universes":
+		user, err := findUser(services, *userRef)
+		if err != nil {
+			return err
+		}
+		universes, err := services.Universe.FindFromUser(user)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tROLE")
+		for _, u := range *universes {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", u.ID, u.Name, roleName(u.Role))
+		}
+		return w.Flush()
+	case "universe transfer":
+		if *universeID == "" {
+			return errors.New("universe transfer requires -universe")
+		}
+		universe, err := services.Universe.FindByID(*universeID)
+		if err != nil {
+			return fmt.Errorf("universe %s not found", *universeID)
+		}
+		user, err := findUser(services, *userRef)
+		if err != nil {
+			return err
+		}
+		if err := services.Universe.TransferOwnership(universe, user); err != nil {
+			return err
+		}
+		fmt.Printf("Transferred ownership of %s to %s\n", universe.Name, user.Email)
+	case "images reconcile":
+		reconciliation, err := services.Character.ReconcileImages(*minAge, *dryRun)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROBLEM\tFILE\tCHARACTER\tIMAGE")
+		for _, key := range reconciliation.OrphanedFiles {
+			fmt.Fprintf(w, "orphaned\t%s\t\t\n", key)
+		}
+		for _, r := range reconciliation.MissingFiles {
+			fmt.Fprintf(w, "missing\t%s\t%s\t%s\n", r.Path, r.CharacterID, r.Key)
+		}
+		for _, r := range reconciliation.UnsizedFiles {
+			fmt.Fprintf(w, "unsized\t%s\t%s\t%s\n", r.Path, r.CharacterID, r.Key)
+		}
+		for _, key := range reconciliation.ExpiredUploads {
+			fmt.Fprintf(w, "expired\t%s\t\t\n", key)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		orphaned, missing := len(reconciliation.OrphanedFiles), len(reconciliation.MissingFiles)
+		unsized, expired := len(reconciliation.UnsizedFiles), len(reconciliation.ExpiredUploads)
+		if *dryRun {
+			fmt.Printf("%d orphaned files and %d records of missing files would be deleted\n", orphaned, missing)
+			fmt.Printf("%d records of unsized files would be sized\n", unsized)
+			fmt.Printf("%d expired uploads would be deleted\n", expired)
+		} else {
+			fmt.Printf("Deleted %d orphaned files and %d records of missing files\n", orphaned, missing)
+			fmt.Printf("Sized %d records of unsized files\n", unsized)
+			fmt.Printf("Deleted %d expired uploads\n", expired)
+		}
+	case "sessions clear":
+		var user *models.User
+		if *userRef != "" {
+			u, err := findUser(services, *userRef)
+			if err != nil {
+				return err
+			}
+			user = u
+		}
+		cleared, err := services.Auth.ClearSessions(user)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Cleared %d sessions\n", cleared)
+	case "jobs dead":
+		jobs, err := services.Job.FindDead(*limit)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTYPE\tUNIVERSE\tATTEMPTS\tFAILED\tERROR")
+		for _, j := range *jobs {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%d\t%s\t%s\n",
+				j.ID,
+				j.Type,
+				j.UniverseID,
+				j.Attempts,
+				j.UpdatedAt.Format(time.RFC3339),
+				j.Error,
+			)
+		}
+		return w.Flush()
+	case "jobs retry":
+		if *jobID == "" {
+			return errors.New("jobs retry requires -job")
+		}
+		job, err := services.Job.Retry(*jobID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Queued job %s (%s) to run again\n", job.ID, job.Type)
+	default:
+		return errors.New(adminUsage)
+	}
+	return nil
+}
+
+// findUser finds a user by their ID, or by their email address when ref contains an "@"
+func findUser(services *api.Services, ref string) (*models.User, error) {
+	if ref == "" {
+		return nil, errors.New("a user must be given with -user")
+	}
+	var user *models.User
+	var err error
+	if strings.Contains(ref, "@") {
+		user, err = services.User.FindByEmail(ref)
+	} else {
+		user, err = services.User.FindByID(ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("user %s not found", ref)
+	}
+	return user, nil
+}
+
+// passwordOrGenerate returns the given password, or a random one if it is empty
+func passwordOrGenerate(password string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// printGeneratedPassword prints a password if it was generated rather than given
+func printGeneratedPassword(given string, password string) {
+	if given == "" {
+		fmt.Printf("Generated password: %s\n", password)
+	}
+}
+
+// roleName returns a readable name for a collaborator role
+func roleName(role models.CollaboratorRole) string {
+	switch role {
+	case models.CollaboratorOwner:
+		return "owner"
+	case models.CollaboratorAdmin:
+		return "admin"
+	}
+	return "member"
+}
@@ -2,8 +2,12 @@ package api
 
 import (
 	"cbs/models"
+	"cbs/repositories"
 	"cbs/services"
+	"log/slog"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-redis/redis"
@@ -12,18 +16,26 @@ import (
 	"gopkg.in/Masterminds/squirrel.v1"
 )
 
-// Service represents a resource service
+// Service represents a resource service, reading and writing its records through the repositories
 type Service struct {
-	Providers *Providers
-	Config    *Config
+	Providers    *Providers
+	Repositories *repositories.Repositories
+	Config       *Config
 }
 
 // Services represents a group of resource services
 type Services struct {
-	Auth      services.Auth
-	User      services.User
-	Universe  services.Universe
-	Character services.Character
+	Auth         services.Auth
+	User         services.User
+	Universe     services.Universe
+	Character    services.Character
+	Event        services.Event
+	Webhook      services.Webhook
+	Audit        services.Audit
+	Share        services.Share
+	Job          services.Job
+	Relationship services.Relationship
+	Comment      services.Comment
 }
 
 // Providers represents a collection of external connections
@@ -34,6 +46,7 @@ type Providers struct {
 	Storage    *Storage
 	ShortID    *shortid.Shortid
 	SQLBuilder *squirrel.StatementBuilderType
+	Logger     *slog.Logger
 }
 
 // Middlewares represents a collection of API-specific middlewares
@@ -42,6 +55,8 @@ type Middlewares struct {
 	Collaborator func(models.CollaboratorRole) func(http.Handler) http.Handler
 	Universe     func(http.Handler) http.Handler
 	Character    func(http.Handler) http.Handler
+	Webhook      func(http.Handler) http.Handler
+	Share        func(http.Handler) http.Handler
 }
 
 // Config represents API settings loaded from a YAML configuration file
@@ -57,6 +72,63 @@ type Config struct {
 	S3AccessSecret     string   `yaml:"s3_access_secret"`
 	S3Bucket           string   `yaml:"s3_bucket"`
 	ModelIDSeed        uint64   `yaml:"model_id_seed"`
+
+	// LogLevel represents the minimum level of logged messages (debug, info, warn or error)
+	LogLevel string `yaml:"log_level"`
+
+	// LogFormat represents the format of logged messages (json or text)
+	LogFormat string `yaml:"log_format"`
+
+	// AdminPort represents a separate port for the health, build information and metrics endpoints. Health and
+	// build information are served on the API port when it is not set, but metrics only are with PublicMetrics
+	AdminPort int `yaml:"admin_port"`
+
+	// PublicMetrics represents whether metrics are served on the API port when no admin port is set. They are
+	// kept off the public port by default since they reveal routes, traffic and session counts
+	PublicMetrics bool `yaml:"public_metrics"`
+
+	// MigrateOnStart represents whether pending database migrations are applied when the server starts. Databases
+	// created before migrations were tracked must be adopted with "migrate baseline" first
+	MigrateOnStart bool `yaml:"migrate_on_start"`
+
+	// ImportAsyncThreshold represents the number of rows above which character imports run in the background
+	ImportAsyncThreshold int `yaml:"import_async_threshold"`
+
+	// ReadHeaderTimeout, ReadTimeout, WriteTimeout and IdleTimeout represent the timeouts of the HTTP servers
+	// (e.g. "30s"). Event streams are exempt from the write timeout
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+
+	// ShutdownTimeout represents how long in-flight requests and background work are given to finish when
+	// the server receives SIGINT or SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// JobWorkers represents the number of background jobs this server runs at once. Jobs queued by any server are
+	// run by whichever server claims them first, so it may be 0 on servers that should only answer requests
+	JobWorkers int `yaml:"job_workers"`
+
+	// ImageReconcileInterval represents how often the stored image files and the records of character images are
+	// reconciled in the background (e.g. "24h"), or 0, the default, to only reconcile them through the admin command
+	ImageReconcileInterval time.Duration `yaml:"image_reconcile_interval"`
+
+	// ImageReconcileDelete represents whether background reconciliations delete orphaned files and the records of
+	// missing files. They only report what they would delete when it is not set
+	ImageReconcileDelete bool `yaml:"image_reconcile_delete"`
+
+	// MaxUniversesPerUser, MaxCharactersPerUniverse, MaxCollaboratorsPerUniverse, MaxGuideGroups, MaxGuideFields
+	// and MaxImageBytesPerUniverse represent the quotas of users and universes, where 0 is unlimited. Guide
+	// fields are counted across every group of a guide, and universes against the user that owns them
+	MaxUniversesPerUser         int `yaml:"max_universes_per_user"`
+	MaxCharactersPerUniverse    int `yaml:"max_characters_per_universe"`
+	MaxCollaboratorsPerUniverse int `yaml:"max_collaborators_per_universe"`
+	MaxGuideGroups              int `yaml:"max_guide_groups"`
+	MaxGuideFields              int `yaml:"max_guide_fields"`
+	MaxImageBytesPerUniverse    int `yaml:"max_image_bytes_per_universe"`
+
+	// unknownKeys represents the keys of the configuration file that were ignored
+	unknownKeys []string
 }
 
 // Server represents an API server with a loaded configuration and set of providers
@@ -66,10 +138,17 @@ type Server struct {
 	Config      *Config
 	Services    *Services
 	Middlewares *Middlewares
+
+	stopping   chan struct{}
+	stopOnce   sync.Once
+	background sync.WaitGroup
 }
 
 // NewServer creates a new API server
 func NewServer(config Config, providers *Providers, services *Services) *Server {
+	if providers.Logger == nil {
+		providers.Logger = slog.Default()
+	}
 	return &Server{
 		Mux:       chi.NewMux(),
 		Config:    &config,
@@ -80,6 +159,35 @@ func NewServer(config Config, providers *Providers, services *Services) *Server
 			Collaborator: MwCollaborator(services),
 			Universe:     MwUniverse(services),
 			Character:    MwCharacter(services),
+			Webhook:      MwWebhook(services),
+			Share:        MwShare(services),
 		},
+		stopping: make(chan struct{}),
+	}
+}
+
+// PublishEvent publishes a universe event to its streams and webhooks. Failures are logged rather than returned
+// since the change the event describes has already been made
+func (s *Server) PublishEvent(event *models.UniverseEvent) {
+	logger := s.Providers.Logger.With("type", event.Type, "universe", event.UniverseID)
+	if err := s.Services.Event.Publish(event); err != nil {
+		logger.Error("Failed to publish universe event", "error", err)
+	}
+	if err := s.Services.Webhook.Dispatch(event); err != nil {
+		logger.Error("Failed to dispatch universe event", "error", err)
+	}
+}
+
+// RecordAudit appends an action performed by a user to a universe's audit log. Failures are logged rather
+// than returned since the action has already been performed
+func (s *Server) RecordAudit(actor *models.User, event *models.AuditEvent) {
+	event.ActorID = &actor.ID
+	if err := s.Services.Audit.Record(event); err != nil {
+		s.Providers.Logger.Error(
+			"Failed to record audit event",
+			"action", event.Action,
+			"universe", event.UniverseID,
+			"error", err,
+		)
 	}
 }
@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"cbs/api/openapi"
+	"cbs/dtos"
+	"cbs/models"
+	"net/http"
+)
+
+// Operations describes the routes of the "audit" resource for the OpenAPI document
+var Operations = []openapi.Operation{
+	{
+		Method:      http.MethodGet,
+		Path:        "/",
+		Summary:     "List the audit log of a universe",
+		Description: "Every filter is optional, and events matching all of them are listed most recent first",
+		Session:     true,
+		Role:        openapi.RoleAdmin,
+		Parameters: []openapi.Parameter{
+			{Name: "p", In: "query", Description: "Zero-based page number", Schema: openapi.IntegerSchema()},
+			{Name: "actor", In: "query", Description: "ID of the user who performed the action"},
+			{Name: "action", In: "query", Description: "Action performed, such as character.create"},
+			{
+				Name:        "targetType",
+				In:          "query",
+				Description: "Type of the resource the action was performed on",
+				Schema: openapi.StringSchema(
+					string(models.AuditTargetCharacter),
+					string(models.AuditTargetUniverse),
+					string(models.AuditTargetCollaborator),
+					string(models.AuditTargetShare),
+					string(models.AuditTargetRelationship),
+				),
+			},
+			{Name: "target", In: "query", Description: "ID of the resource the action was performed on"},
+			{Name: "since", In: "query", Description: "Only list events from this RFC 3339 timestamp"},
+			{Name: "until", In: "query", Description: "Only list events until this RFC 3339 timestamp"},
+		},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "A page of audit events",
+			Content:     []openapi.Content{{Body: dtos.ResGetAuditEvents{}}},
+		}},
+	},
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// Router represents a router for the "audit" resource
+type Router api.Router
+
+// NewRouter creates a new router assigned to the "audit" resource
+func NewRouter(server *api.Server) *Router {
+	router := &Router{
+		Mux:    chi.NewMux(),
+		Server: server}
+	router.Use(
+		server.Middlewares.UserSession,
+		server.Middlewares.Universe,
+		server.Middlewares.Collaborator(models.CollaboratorAdmin),
+	)
+	router.Get("/", api.Handler(router.GetAuditEvents).ServeHTTP)
+	return router
+}
+
+// GetAuditEvents represents a route that retrieves a filtered page of a universe's audit log
+func (m *Router) GetAuditEvents(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	ctx, err := readAuditQuery(r)
+	if err != nil {
+		return err
+	}
+	events, total, err := m.Services.Audit.FindByUniverse(universe, ctx)
+	if err != nil {
+		return api.ErrInternal("Failed to get audit log")
+	}
+	api.SendResponse(w, dtos.ResGetAuditEvents{Events: events, Page: ctx.Page, Total: total}, http.StatusOK)
+	return nil
+}
+
+// readAuditQuery extracts an audit log query context from the URL parameters
+func readAuditQuery(r *http.Request) (dtos.AuditQuery, error) {
+	q := r.URL.Query()
+
+	// Extract the page from the URL parameters
+	page, err := strconv.Atoi(q.Get("p"))
+	if err != nil || page < 0 {
+		page = 0
+	}
+
+	ctx := dtos.AuditQuery{
+		Page:       page,
+		ActorID:    q.Get("actor"),
+		Action:     models.AuditAction(q.Get("action")),
+		TargetType: models.AuditTargetType(q.Get("targetType")),
+		TargetID:   q.Get("target"),
+	}
+
+	// Extract the time range from the URL parameters
+	for param, dst := range map[string]**time.Time{"since": &ctx.Since, "until": &ctx.Until} {
+		if q.Get(param) == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, q.Get(param))
+		if err != nil {
+			return ctx, api.ErrBadBody(fmt.Sprintf("'%s' must be an RFC 3339 timestamp", param))
+		}
+		*dst = &t
+	}
+	return ctx, nil
+}
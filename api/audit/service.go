@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"encoding/json"
+	"reflect"
+)
+
+// PageLimit represents the number of audit events returned per page
+const PageLimit = 50
+
+// Service represents a service implementation for the "audit" resource
+type Service api.Service
+
+// Record appends an event to a universe's audit log. When both a before and after summary are given,
+// only the properties that changed are kept
+func (s *Service) Record(event *models.AuditEvent) error {
+	before, err := normalizeSummary(event.Before)
+	if err != nil {
+		return err
+	}
+	after, err := normalizeSummary(event.After)
+	if err != nil {
+		return err
+	}
+	if before != nil && after != nil {
+		before, after = diffSummaries(before, after)
+	}
+	event.ID = s.Providers.ShortID.MustGenerate()
+	event.Before = before
+	event.After = after
+	return s.Repositories.Audit.Create(event)
+}
+
+// FindByUniverse returns a page of a universe's audit log, most recent first, along with the total
+// number of events matching the query
+func (s *Service) FindByUniverse(
+	universe *models.Universe,
+	ctx dtos.AuditQuery,
+) (*[]models.AuditEvent, int, error) {
+	events, count, err := s.Repositories.Audit.FindByUniverse(universe.ID, ctx, PageLimit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &events, count, nil
+}
+
+// normalizeSummary converts a summary's values into their JSON representation so that they can be compared
+// with values read back from the database
+func normalizeSummary(summary models.AuditSummary) (models.AuditSummary, error) {
+	if summary == nil {
+		return nil, nil
+	}
+	serialized, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+	var normalized models.AuditSummary
+	if err := json.Unmarshal(serialized, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// diffSummaries reduces a pair of summaries to the properties that differ between them
+func diffSummaries(before models.AuditSummary, after models.AuditSummary) (models.AuditSummary, models.AuditSummary) {
+	b := make(models.AuditSummary)
+	a := make(models.AuditSummary)
+	for k, v := range before {
+		if av, ok := after[k]; !ok || !reflect.DeepEqual(v, av) {
+			b[k] = v
+		}
+	}
+	for k, v := range after {
+		if bv, ok := before[k]; !ok || !reflect.DeepEqual(v, bv) {
+			a[k] = v
+		}
+	}
+	return b, a
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"cbs/models"
+	"reflect"
+	"testing"
+)
+
+func TestDiffSummaries(t *testing.T) {
+	before := &models.Character{
+		Name: "Mira",
+		Meta: &models.CharacterMeta{Name: &models.CharacterMetaName{FirstName: "Mira"}},
+		Fields: &models.CharacterFields{Groups: map[string]*models.CharacterFieldGroup{
+			"General": {Fields: map[string]*models.CharacterField{
+				"Age":     {Value: float64(32)},
+				"Aliases": {Value: []interface{}{"Mi"}},
+			}},
+		}},
+	}
+	after := &models.Character{
+		Name: "Mira",
+		Meta: &models.CharacterMeta{Name: &models.CharacterMetaName{FirstName: "Mira"}, Hidden: true},
+		Fields: &models.CharacterFields{Groups: map[string]*models.CharacterFieldGroup{
+			"General": {Fields: map[string]*models.CharacterField{
+				"Age":     {Value: float64(33)},
+				"Aliases": {Value: []interface{}{"Mi"}},
+			}},
+		}},
+	}
+	b, err := normalizeSummary(before.AuditSummary())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := normalizeSummary(after.AuditSummary())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotb, gota := diffSummaries(b, a)
+	wantb := models.AuditSummary{"fields.General.Age": float64(32), "meta.hidden": false}
+	wanta := models.AuditSummary{"fields.General.Age": float64(33), "meta.hidden": true}
+	if !reflect.DeepEqual(gotb, wantb) {
+		t.Errorf("got before %v; want %v", gotb, wantb)
+	}
+	if !reflect.DeepEqual(gota, wanta) {
+		t.Errorf("got after %v; want %v", gota, wanta)
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"cbs/api/openapi"
+	"cbs/dtos"
+	"net/http"
+)
+
+// Operations describes the routes of the "auth" resource for the OpenAPI document
+var Operations = []openapi.Operation{
+	{
+		Method:  http.MethodPost,
+		Path:    "/login",
+		Summary: "Log into a user session",
+		Body:    []openapi.Content{{Body: dtos.ReqLogIn{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The logged in user. The session cookie is set",
+			Content:     []openapi.Content{{Body: dtos.ResGetUser{}}},
+		}},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/me",
+		Summary: "Get the user of the session",
+		Session: true,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The user of the session",
+			Content:     []openapi.Content{{Body: dtos.ResGetUser{}}},
+		}},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/me/collaborations",
+		Summary: "List the universes the user of the session collaborates in",
+		Session: true,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The universes of the user",
+			Content:     []openapi.Content{{Body: dtos.ResGetUniverses{}}},
+		}},
+	},
+	{
+		Method:    http.MethodGet,
+		Path:      "/logout",
+		Summary:   "Log out of the session",
+		Session:   true,
+		Responses: []openapi.Response{{Status: http.StatusNoContent, Description: "The session cookie is cleared"}},
+	},
+}
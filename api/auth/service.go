@@ -3,8 +3,6 @@ package auth
 import (
 	"cbs/api"
 	"cbs/models"
-	"encoding/json"
-	"fmt"
 	"net/http"
 	"time"
 
@@ -21,33 +19,26 @@ func genSessionKey() string {
 
 // Authenticate returns a User if the passed credentials are valid
 func (s *Service) Authenticate(email, password string) (*models.User, error) {
-	var user models.User
-	if err := s.Providers.DB.Get(&user, "SELECT password_hash FROM users WHERE email = $1", email); err != nil {
+	user, err := s.Repositories.User.FindCredentials(email)
+	if err != nil {
 		return nil, err
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		return nil, api.ErrBadAuth("")
 	}
-	user = models.User{}
-	if err := s.Providers.DB.Get(
-		&user,
-		"SELECT id, display_name, email FROM users WHERE email = $1",
-		email,
-	); err != nil {
-		return nil, err
+	if user.Disabled {
+		return nil, api.ErrBadAuth("This account has been disabled")
 	}
-	return &user, nil
+	return &models.User{ID: user.ID, DisplayName: user.DisplayName, Email: user.Email}, nil
 }
 
 // Login creates a new session between the request and the user
 func (s *Service) Login(user *models.User, w http.ResponseWriter) error {
 	sesskey := genSessionKey()
-	serialized, err := json.Marshal(user)
-	if err != nil {
+	maxage := s.Config.SessionAge()
+	if err := s.Repositories.Session.Create(sesskey, user, maxage); err != nil {
 		return err
 	}
-	maxage, _ := time.ParseDuration(s.Config.MaxSessionAge)
-	s.Providers.Redis.Set(fmt.Sprintf("session:%v", sesskey), serialized, maxage)
 	http.SetCookie(w, &http.Cookie{
 		Name:   "user_session",
 		Value:  sesskey,
@@ -66,24 +57,32 @@ func (s *Service) Logout(w http.ResponseWriter) error {
 	return nil
 }
 
-// User returns the User associated with the request's session
+// ClearSessions destroys every session belonging to a user, or every session of every user when user is nil.
+// The number of destroyed sessions is returned
+func (s *Service) ClearSessions(user *models.User) (int, error) {
+	if user == nil {
+		return s.Repositories.Session.Clear("")
+	}
+	return s.Repositories.Session.Clear(user.ID)
+}
+
+// User returns the User associated with the request's session, refreshing the session. The user is read again
+// so that the sessions of disabled users are rejected
 func (s *Service) User(req *http.Request) (*models.User, error) {
-	var user models.User
 	sesskey, err := req.Cookie("user_session")
 	if err != nil {
 		return nil, err
 	}
-	serialized, err := s.Providers.Redis.Get(fmt.Sprintf("session:%v", sesskey.Value)).Result()
+	cached, err := s.Repositories.Session.Find(sesskey.Value, s.Config.SessionAge())
 	if err != nil {
 		return nil, err
 	}
-
-	// Refresh TTL
-	maxage, _ := time.ParseDuration(s.Config.MaxSessionAge)
-	_ = s.Providers.Redis.Expire(fmt.Sprintf("session:%v", sesskey.Value), maxage)
-
-	if err := json.Unmarshal([]byte(serialized), &user); err != nil {
+	user, err := s.Repositories.User.FindByID(cached.ID)
+	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	if user.Disabled {
+		return nil, api.ErrBadAuth("This account has been disabled")
+	}
+	return user, nil
 }
@@ -0,0 +1,180 @@
+package characters
+
+import (
+	"bufio"
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ExportContentTypes maps every export format to the content type it is served with
+var ExportContentTypes = map[dtos.CharacterExportFormat]string{
+	dtos.CharacterExportFormatCSV:      "text/csv; charset=utf-8",
+	dtos.CharacterExportFormatJSON:     "application/json",
+	dtos.CharacterExportFormatMarkdown: "text/markdown; charset=utf-8",
+}
+
+// Export writes a collection of characters to w in the requested format
+func (s *Service) Export(
+	universe *models.Universe,
+	characters []models.Character,
+	format dtos.CharacterExportFormat,
+	w io.Writer,
+) error {
+	switch format {
+	case dtos.CharacterExportFormatCSV:
+		return exportCSV(universe, characters, w)
+	case dtos.CharacterExportFormatJSON:
+		return json.NewEncoder(w).Encode(characters)
+	case dtos.CharacterExportFormatMarkdown:
+		return exportMarkdown(universe, characters, w)
+	}
+	return api.ErrBadBody(fmt.Sprintf("Unsupported export format '%s'", format))
+}
+
+// QueueExport queues the characters of a universe matching a query to be exported in the background, with the
+// visibility of the querying collaborator. The export is downloaded as the result of the job
+func (s *Service) QueueExport(
+	universe *models.Universe,
+	ctx dtos.CharacterQuery,
+	format dtos.CharacterExportFormat,
+	creator *models.User,
+) (*models.Job, error) {
+	if _, ok := ExportContentTypes[format]; !ok {
+		return nil, api.ErrBadBody(fmt.Sprintf("Unsupported export format '%s'", format))
+	}
+	job, err := models.NewJob(
+		s.Providers.ShortID.MustGenerate(),
+		models.JobCharactersExport,
+		universe.ID,
+		creator.ID,
+		exportJob{Format: format, Query: ctx},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return job, api.EnqueueJob(s.Repositories, job)
+}
+
+// exportCSV writes characters as CSV rows, using the same "Group.Field" columns accepted by imports
+func exportCSV(universe *models.Universe, characters []models.Character, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := []string{"name", "tag"}
+	for _, group := range *universe.Guide.Groups {
+		for _, field := range *group.Fields {
+			header = append(header, fmt.Sprintf("%s.%s", group.Name, field.Name))
+		}
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, character := range characters {
+		record := []string{character.Name, character.Tag}
+		for _, group := range *universe.Guide.Groups {
+			for _, field := range *group.Fields {
+				record = append(record, formatExportValue(findField(&character, group.Name, field.Name), importListSeparator))
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportMarkdown writes characters as Markdown documents with guide groups as headings. Description
+// fields are written as-is since they may already contain Markdown
+func exportMarkdown(universe *models.Universe, characters []models.Character, w io.Writer) error {
+	buff := bufio.NewWriter(w)
+	for i, character := range characters {
+		if i > 0 {
+			fmt.Fprint(buff, "\n---\n\n")
+		}
+		title := character.Name
+		if title == "" {
+			title = "Unnamed character"
+		}
+		if character.Tag != "" {
+			title = fmt.Sprintf("%s (%s)", title, character.Tag)
+		}
+		fmt.Fprintf(buff, "# %s\n", title)
+		for _, group := range *universe.Guide.Groups {
+			var lines []string
+			for _, field := range *group.Fields {
+				cField := findField(&character, group.Name, field.Name)
+				if cField == nil || cField.Value == nil {
+					continue
+				}
+				switch field.Type {
+				case models.GuideFieldDescription:
+					lines = append(lines, fmt.Sprintf("**%s**\n\n%s\n", field.Name, formatExportValue(cField, "")))
+				case models.GuideFieldList:
+					items := make([]string, 0)
+					for _, v := range toExportList(cField.Value) {
+						items = append(items, fmt.Sprintf("- %s", v))
+					}
+					lines = append(lines, fmt.Sprintf("**%s**\n\n%s\n", field.Name, strings.Join(items, "\n")))
+				default:
+					lines = append(lines, fmt.Sprintf("**%s:** %s\n", field.Name, formatExportValue(cField, ", ")))
+				}
+			}
+			if len(lines) == 0 {
+				continue
+			}
+			fmt.Fprintf(buff, "\n## %s\n\n%s", group.Name, strings.Join(lines, "\n"))
+		}
+	}
+	return buff.Flush()
+}
+
+// findField returns a character's field by group and field name, or nil if it is absent or hidden
+func findField(character *models.Character, group string, field string) *models.CharacterField {
+	if character.Fields == nil {
+		return nil
+	}
+	cGroup, ok := character.Fields.Groups[group]
+	if !ok || cGroup == nil {
+		return nil
+	}
+	return cGroup.Fields[field]
+}
+
+// formatExportValue converts a character field value into text, joining list values with sep
+func formatExportValue(field *models.CharacterField, sep string) string {
+	if field == nil || field.Value == nil {
+		return ""
+	}
+	switch v := field.Value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}, []string:
+		return strings.Join(toExportList(v), sep)
+	}
+	return fmt.Sprintf("%v", field.Value)
+}
+
+// toExportList converts a list field value into a slice of strings
+func toExportList(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		l := make([]string, 0, len(v))
+		for _, e := range v {
+			l = append(l, fmt.Sprintf("%v", e))
+		}
+		return l
+	}
+	return []string{fmt.Sprintf("%v", value)}
+}
@@ -0,0 +1,44 @@
+package characters
+
+import (
+	"bytes"
+	"cbs/models"
+	"testing"
+)
+
+func TestExportCSV(t *testing.T) {
+	characters := []models.Character{
+		{
+			Name: "Mira",
+			Tag:  "Rogue",
+			Fields: &models.CharacterFields{
+				Groups: map[string]*models.CharacterFieldGroup{
+					"General": {
+						Fields: map[string]*models.CharacterField{
+							"Age":     {Value: float64(32), Type: models.GuideFieldNumber},
+							"Aliases": {Value: []interface{}{"Mi", "M"}, Type: models.GuideFieldList},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "Hidden",
+			Fields: &models.CharacterFields{
+				Groups: map[string]*models.CharacterFieldGroup{
+					"General": {Fields: nil, Hidden: true},
+				},
+			},
+		},
+	}
+	want := "name,tag,General.Age,General.Alive,General.Aliases,General.Classes\n" +
+		"Mira,Rogue,32,,Mi;M,\n" +
+		"Hidden,,,,,\n"
+	var buff bytes.Buffer
+	if err := exportCSV(testImportUniverse, characters, &buff); err != nil {
+		t.Fatalf("got error %v; want nil", err)
+	}
+	if buff.String() != want {
+		t.Errorf("got export %q; want %q", buff.String(), want)
+	}
+}
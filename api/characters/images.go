@@ -0,0 +1,118 @@
+package characters
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/api/jobs"
+	"cbs/models"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/disintegration/imaging"
+
+	// Registers the WebP decoder with image.Decode, which imaging relies on
+	_ "golang.org/x/image/webp"
+)
+
+// ImageWidths represents the widths in pixels of the square variants character images are stored in. Images are
+// never enlarged, so variants wider than an upload keep its size
+var ImageWidths = []int{64, 256, 1024}
+
+// MaxImagePixels represents the largest number of pixels an uploaded image may have. Images are decoded into
+// memory at 4 bytes per pixel however small their file is, so larger ones are rejected before they are decoded
+const MaxImagePixels = 40 * 1000 * 1000
+
+// OrphanMinAge represents how old a stored file must be before it is reconciled as an orphan in the background,
+// since newer files may belong to a character still being saved
+const OrphanMinAge = time.Hour
+
+// UploadRetention represents how long uploaded images are kept when they could not be processed. It outlasts the
+// dead-letter queue so that failed image jobs can be retried for as long as they are kept
+const UploadRetention = jobs.DeadRetention + 24*time.Hour
+
+// imageFormats represents the formats character images are accepted in by detected content type, along with the
+// format their variants are encoded in. WebP cannot be encoded, so it is kept lossless only when it is transparent
+var imageFormats = map[string]imaging.Format{
+	"image/jpeg": imaging.JPEG,
+	"image/png":  imaging.PNG,
+	"image/gif":  imaging.PNG,
+	"image/webp": imaging.JPEG,
+}
+
+// formatContentTypes represents the content types of the formats variants are encoded in
+var formatContentTypes = map[imaging.Format]string{
+	imaging.JPEG: "image/jpeg",
+	imaging.PNG:  "image/png",
+}
+
+// opaque returns whether every pixel of an image is opaque
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// imageFormat returns the format the variants of an uploaded image are encoded in, rejecting images whose detected
+// content type is not accepted
+func imageFormat(data []byte) (imaging.Format, error) {
+	format, ok := imageFormats[http.DetectContentType(data)]
+	if !ok {
+		return 0, api.ErrBadBody("Image must be a JPEG, PNG, GIF or WebP file")
+	}
+	return format, nil
+}
+
+// processImage decodes an uploaded image, turns it upright according to its EXIF orientation and encodes a
+// variant of it for each of ImageWidths. Re-encoding leaves out the metadata of the upload
+func processImage(file io.Reader) ([]models.ImageVariant, error) {
+	defer api.ObserveDuration(api.ImageOptimizationDuration, time.Now())
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	format, err := imageFormat(data)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, api.ErrBadBody("Image could not be decoded")
+	}
+	if int64(config.Width)*int64(config.Height) > MaxImagePixels {
+		return nil, api.ErrBadBody(fmt.Sprintf("Image must be at most %d megapixels", MaxImagePixels/1000000))
+	}
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, api.ErrBadBody("Image could not be decoded")
+	}
+	if format == imaging.JPEG && !opaque(img) {
+		format = imaging.PNG
+	}
+
+	// Crop to the largest square the image fits, which no variant is wider than
+	size := img.Bounds().Dx()
+	if img.Bounds().Dy() < size {
+		size = img.Bounds().Dy()
+	}
+	variants := make([]models.ImageVariant, len(ImageWidths))
+	for i, width := range ImageWidths {
+		if width > size {
+			width = size
+		}
+		buff := new(bytes.Buffer)
+		if err := imaging.Encode(buff, imaging.Thumbnail(img, width, width, imaging.CatmullRom), format); err != nil {
+			return nil, err
+		}
+		variants[i] = models.ImageVariant{
+			Width:       ImageWidths[i],
+			ContentType: formatContentTypes[format],
+			Data:        buff.Bytes(),
+		}
+	}
+	return variants, nil
+}
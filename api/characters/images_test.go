@@ -0,0 +1,164 @@
+package characters
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage returns an image of the given dimensions whose left half is red and right half is blue, with the
+// given alpha
+func testImage(width, height int, alpha uint8) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			c := color.NRGBA{R: 255, A: alpha}
+			if x >= width/2 {
+				c = color.NRGBA{B: 255, A: alpha}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// withOrientation inserts an EXIF segment with the given orientation tag into an encoded JPEG
+func withOrientation(data []byte, orientation byte) []byte {
+	exif := []byte{
+		0xFF, 0xE1, 0x00, 0x22, 'E', 'x', 'i', 'f', 0x00, 0x00,
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08,
+		0x00, 0x01, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, orientation, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	return append(append(append([]byte{}, data[:2]...), exif...), data[2:]...)
+}
+
+func TestProcessImage(t *testing.T) {
+	encode := func(encoder func(*bytes.Buffer) error) []byte {
+		buff := new(bytes.Buffer)
+		if err := encoder(buff); err != nil {
+			t.Fatal(err)
+		}
+		return buff.Bytes()
+	}
+	photo := encode(func(b *bytes.Buffer) error { return jpeg.Encode(b, testImage(400, 300, 255), nil) })
+
+	// A small GIF declaring a 65535x65535 screen, which would take 16GB to decode
+	huge := encode(func(b *bytes.Buffer) error { return gif.Encode(b, testImage(32, 32, 255), nil) })
+	copy(huge[6:10], []byte{0xFF, 0xFF, 0xFF, 0xFF})
+
+	tests := []struct {
+		name       string
+		file       []byte
+		wantType   string
+		wantWidths []int
+		wantErr    bool
+	}{
+		{
+			name:       "jpeg",
+			file:       photo,
+			wantType:   "image/jpeg",
+			wantWidths: []int{64, 256, 300},
+		},
+		{
+			name: "transparent png",
+			file: encode(func(b *bytes.Buffer) error {
+				return png.Encode(b, testImage(100, 100, 128))
+			}),
+			wantType:   "image/png",
+			wantWidths: []int{64, 100, 100},
+		},
+		{
+			name: "opaque png",
+			file: encode(func(b *bytes.Buffer) error {
+				return png.Encode(b, testImage(100, 100, 255))
+			}),
+			wantType:   "image/png",
+			wantWidths: []int{64, 100, 100},
+		},
+		{
+			name: "gif",
+			file: encode(func(b *bytes.Buffer) error {
+				return gif.Encode(b, testImage(32, 32, 255), nil)
+			}),
+			wantType:   "image/png",
+			wantWidths: []int{32, 32, 32},
+		},
+		{
+			name:     "exif orientation",
+			file:     withOrientation(photo, 6),
+			wantType: "image/jpeg",
+			// The image is turned 300 pixels wide and 400 tall before it is cropped
+			wantWidths: []int{64, 256, 300},
+		},
+		{name: "text", file: []byte("not an image"), wantErr: true},
+		{name: "truncated", file: photo[:len(photo)/8], wantErr: true},
+		{name: "too many pixels", file: huge, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, err := processImage(bytes.NewReader(tt.file))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v; want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(variants) != len(ImageWidths) {
+				t.Fatalf("got %d variants; want %d", len(variants), len(ImageWidths))
+			}
+			for i, variant := range variants {
+				if variant.Width != ImageWidths[i] {
+					t.Errorf("got variant width %d; want %d", variant.Width, ImageWidths[i])
+				}
+				if variant.ContentType != tt.wantType {
+					t.Errorf("got content type %s; want %s", variant.ContentType, tt.wantType)
+				}
+				if bytes.Contains(variant.Data, []byte("Exif")) {
+					t.Errorf("got variant %d with EXIF metadata; want it stripped", variant.Width)
+				}
+				config, _, err := image.DecodeConfig(bytes.NewReader(variant.Data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if config.Width != tt.wantWidths[i] || config.Height != tt.wantWidths[i] {
+					t.Errorf(
+						"got variant %d of %dx%d; want %dx%[4]d",
+						variant.Width,
+						config.Width,
+						config.Height,
+						tt.wantWidths[i],
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessImage_Orientation(t *testing.T) {
+	buff := new(bytes.Buffer)
+	if err := jpeg.Encode(buff, testImage(40, 40, 255), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Orientation 6 is turned clockwise, which brings the red left half to the top
+	variants, err := processImage(bytes.NewReader(withOrientation(buff.Bytes(), 6)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(variants[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	top, bottom := img.At(20, 5), img.At(20, 35)
+	if r, _, b, _ := top.RGBA(); r < b {
+		t.Errorf("got top pixel %v; want red", top)
+	}
+	if r, _, b, _ := bottom.RGBA(); b < r {
+		t.Errorf("got bottom pixel %v; want blue", bottom)
+	}
+}
@@ -0,0 +1,371 @@
+package characters
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"cbs/repositories"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxImportSize represents the maximum allowed size for character import files
+const MaxImportSize = 20 * 1024 * 1024
+
+// DefaultImportAsyncThreshold represents the number of rows above which imports
+// are processed in the background when no threshold is configured
+const DefaultImportAsyncThreshold = 100
+
+// ImportTTL represents how long the progress of a background import is kept for polling
+const ImportTTL = 24 * time.Hour
+
+// importSaveInterval represents the number of rows processed between progress saves of background imports
+const importSaveInterval = 25
+
+// importListSeparator separates the elements of list and multiple option fields inside a CSV cell
+const importListSeparator = ";"
+
+// importProperties represents the CSV columns that map to character properties rather than guide fields
+var importProperties = []string{
+	"name",
+	"tag",
+	"hidden",
+	"nameHidden",
+	"firstName",
+	"middleName",
+	"lastName",
+	"nickname",
+	"preferredName",
+}
+
+// importColumn represents what a single CSV column maps to
+type importColumn struct {
+	property string
+	group    string
+	field    models.UniverseGuideField
+}
+
+// NewImport creates a new character import
+func (s *Service) NewImport(
+	universe *models.Universe,
+	owner *models.User,
+	opts dtos.CharacterImportOptions,
+) *models.CharacterImport {
+	now := time.Now()
+	return &models.CharacterImport{
+		ID:         s.Providers.ShortID.MustGenerate(),
+		UniverseID: universe.ID,
+		OwnerID:    owner.ID,
+		Status:     models.CharacterImportPending,
+		Atomic:     opts.Atomic,
+		DryRun:     opts.DryRun,
+		Errors:     make([]models.CharacterImportError, 0),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// ParseImport reads a collection of character creation requests from an import file
+func (s *Service) ParseImport(
+	universe *models.Universe,
+	format dtos.CharacterImportFormat,
+	file io.Reader,
+) ([]dtos.ReqCreateCharacter, error) {
+	switch format {
+	case dtos.CharacterImportFormatCSV:
+		return parseImportCSV(universe, file)
+	case dtos.CharacterImportFormatJSON:
+		rows := make([]dtos.ReqCreateCharacter, 0)
+		if err := api.ReadBody(file, &rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+	return nil, api.ErrBadBody(fmt.Sprintf("Unsupported import format '%s'", format))
+}
+
+// parseImportCSV reads character creation requests from a CSV file whose header row names either
+// character properties (e.g. "name") or guide fields in the form of "Group.Field"
+func parseImportCSV(universe *models.Universe, file io.Reader) ([]dtos.ReqCreateCharacter, error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, api.ErrBadBody("Import file is empty")
+	}
+	if err != nil {
+		return nil, api.ErrBadBody("Failed to parse CSV header")
+	}
+
+	// Resolve every column to a character property or a guide field
+	guide := make(map[string]importColumn)
+	for _, group := range *universe.Guide.Groups {
+		for _, field := range *group.Fields {
+			guide[fmt.Sprintf("%s.%s", group.Name, field.Name)] = importColumn{group: group.Name, field: field}
+		}
+	}
+	columns := make([]importColumn, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if strInSlice(name, importProperties) {
+			columns[i] = importColumn{property: name}
+			continue
+		}
+		column, ok := guide[name]
+		if !ok {
+			return nil, api.ErrBadBody(
+				fmt.Sprintf("Column '%s' does not match a character property or guide field", name),
+			)
+		}
+		columns[i] = column
+	}
+
+	rows := make([]dtos.ReqCreateCharacter, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, api.ErrBadBody(fmt.Sprintf("Failed to parse CSV row %d", len(rows)+1))
+		}
+		row := dtos.ReqCreateCharacter{
+			Fields: &dtos.ReqCharacterFields{Groups: make(map[string]dtos.ReqCharacterGroup)},
+			Meta:   &dtos.ReqCharacterMeta{},
+		}
+		for i, cell := range record {
+			cell = strings.TrimSpace(cell)
+			column := columns[i]
+			if column.property != "" {
+				if err := setImportProperty(&row, column.property, cell); err != nil {
+					return nil, api.ErrBadBody(fmt.Sprintf("Row %d: %s", len(rows)+1, err.Error()))
+				}
+				continue
+			}
+
+			// Empty cells leave the field unset so that required fields are reported by validation
+			if cell == "" {
+				continue
+			}
+			group, ok := row.Fields.Groups[column.group]
+			if !ok {
+				group = dtos.ReqCharacterGroup{Fields: make(map[string]dtos.ReqCharacterField)}
+				row.Fields.Groups[column.group] = group
+			}
+			group.Fields[column.field.Name] = dtos.ReqCharacterField{
+				Value: parseImportValue(column.field, cell),
+				Type:  column.field.Type,
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// setImportProperty assigns a CSV cell to a character property of a character creation request
+func setImportProperty(row *dtos.ReqCreateCharacter, property string, cell string) error {
+	switch property {
+	case "name":
+		row.Name = cell
+	case "tag":
+		row.Tag = cell
+	case "hidden", "nameHidden":
+		if cell == "" {
+			return nil
+		}
+		v, err := strconv.ParseBool(cell)
+		if err != nil {
+			return fmt.Errorf("column '%s' must be a boolean", property)
+		}
+		if property == "hidden" {
+			row.Meta.Hidden = v
+		} else {
+			row.Meta.NameHidden = v
+		}
+	case "firstName":
+		row.Meta.Name.FirstName = cell
+	case "middleName":
+		row.Meta.Name.MiddleName = cell
+	case "lastName":
+		row.Meta.Name.LastName = cell
+	case "nickname":
+		row.Meta.Name.Nickname = cell
+	case "preferredName":
+		row.Meta.Name.PreferredName = cell
+	}
+	return nil
+}
+
+// parseImportValue converts a CSV cell into the value type expected by a guide field. Cells that
+// cannot be converted are passed through as strings so that validation reports them against their row
+func parseImportValue(field models.UniverseGuideField, cell string) interface{} {
+	switch field.Type {
+	case models.GuideFieldNumber, models.GuideFieldProgress:
+		if v, err := strconv.ParseFloat(cell, 64); err == nil {
+			return v
+		}
+	case models.GuideFieldToggle:
+		if v, err := strconv.ParseBool(cell); err == nil {
+			return v
+		}
+	case models.GuideFieldList:
+		return splitImportList(cell)
+	case models.GuideFieldOptions:
+		if meta, ok := field.Meta.(models.UniverseGuideMetaOptions); ok && meta.Multiple {
+			return splitImportList(cell)
+		}
+	}
+	return cell
+}
+
+// splitImportList splits a CSV cell into the generic list representation produced by JSON decoding
+func splitImportList(cell string) []interface{} {
+	l := make([]interface{}, 0)
+	for _, v := range strings.Split(cell, importListSeparator) {
+		l = append(l, strings.TrimSpace(v))
+	}
+	return l
+}
+
+// addImportError records a failed row on a character import
+func addImportError(job *models.CharacterImport, row int, name string, err error) {
+	rowErr := models.CharacterImportError{Row: row, Name: name, Message: "Failed to create character"}
+	if apierr, ok := err.(api.Error); ok {
+		rowErr.Message = apierr.Message
+		rowErr.Issues = apierr.Issues
+	}
+	job.Errors = append(job.Errors, rowErr)
+	job.Failed++
+}
+
+// prepareImportRow converts and validates a single import row into a character
+func (s *Service) prepareImportRow(universe *models.Universe, row dtos.ReqCreateCharacter) (*models.Character, error) {
+	valError, err := api.ValidateDTO(&row)
+	if err != nil {
+		return nil, err
+	}
+	if valError != nil {
+		return nil, *valError
+	}
+	character := s.New(row)
+	if err := s.Validate(character, universe); err != nil {
+		return nil, err
+	}
+	return character, nil
+}
+
+// Import validates and creates characters from parsed import rows, recording progress and row errors on the
+// import. Atomic imports only create characters when every row is valid, and dry runs never create characters
+func (s *Service) Import(
+	job *models.CharacterImport,
+	universe *models.Universe,
+	owner *models.User,
+	rows []dtos.ReqCreateCharacter,
+) error {
+	job.Status = models.CharacterImportProcessing
+	job.Total = len(rows)
+	if job.Async {
+		if err := s.SaveImport(job); err != nil {
+			return err
+		}
+	}
+
+	// Validate every row, creating characters straight away when the import is best-effort
+	characters := make([]*models.Character, 0, len(rows))
+	for i, row := range rows {
+		character, err := s.prepareImportRow(universe, row)
+		if err != nil {
+			addImportError(job, i+1, row.Name, err)
+		} else if job.DryRun || job.Atomic {
+			characters = append(characters, character)
+		} else if _, err := s.Create(universe, character, owner); err != nil {
+			addImportError(job, i+1, row.Name, err)
+		} else {
+			job.Created++
+		}
+		job.Processed++
+		if job.Async && job.Processed%importSaveInterval == 0 {
+			if err := s.SaveImport(job); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch {
+	case job.DryRun:
+		job.Preview = characters
+	case job.Atomic && job.Failed == 0:
+		if err := s.createAll(job, universe, owner, characters); err != nil {
+			job.Status = models.CharacterImportFailed
+			s.finishImport(job)
+			return err
+		}
+	}
+
+	job.Status = models.CharacterImportCompleted
+	if job.Atomic && job.Failed > 0 {
+		job.Status = models.CharacterImportFailed
+	}
+	return s.finishImport(job)
+}
+
+// createAll creates every character of an atomic import at once. When they would exceed the character quota of
+// the universe, none are created and the first row over the quota is reported
+func (s *Service) createAll(
+	job *models.CharacterImport,
+	universe *models.Universe,
+	owner *models.User,
+	characters []*models.Character,
+) error {
+	_, err := s.Repositories.Character.Create(universe.ID, owner.ID, s.Config.MaxCharactersPerUniverse, characters...)
+	if err == repositories.ErrQuota {
+		used, err := s.Repositories.Character.CountByUniverse(universe.ID)
+		if err != nil {
+			return err
+		}
+		first := s.Config.MaxCharactersPerUniverse - used
+		if first < 0 {
+			first = 0
+		} else if first >= len(characters) {
+			first = len(characters) - 1
+		}
+		addImportError(job, first+1, characters[first].Name, s.errCharacterQuota())
+		return nil
+	}
+	if batchErr, ok := err.(*repositories.BatchError); ok {
+		addImportError(job, batchErr.Index+1, characters[batchErr.Index].Name, batchErr.Err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	job.Created = len(characters)
+	return nil
+}
+
+// finishImport stamps a character import and stores its final state if it is being polled
+func (s *Service) finishImport(job *models.CharacterImport) error {
+	job.UpdatedAt = time.Now()
+	if !job.Async {
+		return nil
+	}
+	return s.SaveImport(job)
+}
+
+// SaveImport stores the progress of a character import for polling
+func (s *Service) SaveImport(job *models.CharacterImport) error {
+	return s.Repositories.Import.Save(job, ImportTTL)
+}
+
+// FindImportByID returns the progress of a character import by its ID
+func (s *Service) FindImportByID(universe *models.Universe, id string) (*models.CharacterImport, error) {
+	job, err := s.Repositories.Import.FindByID(universe.ID, id)
+	if err == repositories.ErrNotFound {
+		return nil, api.ErrNotFound("Import not found")
+	}
+	return job, err
+}
@@ -0,0 +1,101 @@
+package characters
+
+import (
+	"cbs/api"
+	"cbs/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testImportUniverse = &models.Universe{
+	Guide: &models.UniverseGuide{
+		Groups: &[]models.UniverseGuideGroup{
+			{
+				Name: "General",
+				Fields: &[]models.UniverseGuideField{
+					{Name: "Age", Type: models.GuideFieldNumber, Meta: models.UniverseGuideMetaNumber{}},
+					{Name: "Alive", Type: models.GuideFieldToggle, Meta: models.UniverseGuideMetaToggle{}},
+					{Name: "Aliases", Type: models.GuideFieldList, Meta: models.UniverseGuideMetaList{}},
+					{
+						Name: "Classes",
+						Type: models.GuideFieldOptions,
+						Meta: models.UniverseGuideMetaOptions{Multiple: true, Options: []string{"Mage", "Rogue"}},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestParseImportCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    map[string]interface{}
+		wantrow string
+		wanterr bool
+	}{
+		{
+			name: "typed fields",
+			csv: "name,hidden,General.Age,General.Alive,General.Aliases,General.Classes\n" +
+				"Mira,true,32,false,Mi; M,Mage;Rogue",
+			wantrow: "Mira",
+			want: map[string]interface{}{
+				"Age":     float64(32),
+				"Alive":   false,
+				"Aliases": []interface{}{"Mi", "M"},
+				"Classes": []interface{}{"Mage", "Rogue"},
+			},
+		},
+		{
+			name:    "empty cells are omitted",
+			csv:     "name,General.Age\nMira,",
+			wantrow: "Mira",
+			want:    map[string]interface{}{},
+		},
+		{
+			name:    "unconvertible values pass through",
+			csv:     "name,General.Age\nMira,old",
+			wantrow: "Mira",
+			want:    map[string]interface{}{"Age": "old"},
+		},
+		{
+			name:    "unknown column",
+			csv:     "name,General.Height\nMira,170",
+			wanterr: true,
+		},
+		{
+			name:    "invalid boolean property",
+			csv:     "name,hidden\nMira,maybe",
+			wanterr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseImportCSV(testImportUniverse, strings.NewReader(tt.csv))
+			if tt.wanterr {
+				if _, ok := err.(api.Error); !ok {
+					t.Fatalf("got error %v; want api error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v; want nil", err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("got %d rows; want 1", len(rows))
+			}
+			if rows[0].Name != tt.wantrow {
+				t.Errorf("got name %v; want %v", rows[0].Name, tt.wantrow)
+			}
+			got := make(map[string]interface{})
+			for k, v := range rows[0].Fields.Groups["General"].Fields {
+				got[k] = v.Value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got fields %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package characters
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"cbs/repositories"
+	"cbs/services"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// imageJob represents the payload of a job assigning an uploaded image to a character. The image is stored under
+// the Upload key, except for jobs queued before uploads were stored, which carry it as Data
+type imageJob struct {
+	CharacterID string `json:"characterId"`
+	Key         string `json:"key"`
+	Upload      string `json:"upload,omitempty"`
+	Data        []byte `json:"data,omitempty"`
+}
+
+// exportJob represents the payload of a job exporting the characters of a universe
+type exportJob struct {
+	Format dtos.CharacterExportFormat `json:"format"`
+	Query  dtos.CharacterQuery        `json:"query"`
+}
+
+// JobHandlers returns the handlers of the background jobs of the "characters" resource by job type
+func JobHandlers(server *api.Server) map[models.JobType]services.JobHandler {
+	return map[models.JobType]services.JobHandler{
+		models.JobImageProcess:     processImageJob(server),
+		models.JobFilesDelete:      deleteFilesJob(server),
+		models.JobCharactersExport: exportCharactersJob(server),
+		models.JobImagesReconcile:  reconcileImagesJob(server),
+	}
+}
+
+// readPayload deserializes the payload of a job, which is not worth retrying when it is invalid
+func readPayload(job *models.Job, payload interface{}) error {
+	if err := json.Unmarshal(job.Payload, payload); err != nil {
+		return api.ErrBadBody(fmt.Sprintf("Invalid payload for job of type '%s'", job.Type))
+	}
+	return nil
+}
+
+// processImageJob assigns an uploaded image to a character, then records and publishes the change on behalf of
+// the user who uploaded it. Images of characters deleted since they were uploaded are dropped
+func processImageJob(server *api.Server) services.JobHandler {
+	return func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		var payload imageJob
+		if err := readPayload(job, &payload); err != nil {
+			return nil, err
+		}
+		character, err := server.Services.Character.FindByID(payload.CharacterID)
+		if err == repositories.ErrNotFound {
+			if payload.Upload != "" {
+				return nil, server.Services.Character.DeleteFiles([]string{payload.Upload})
+			}
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if payload.Upload != "" {
+			err = server.Services.Character.SetUploadedImage(character, payload.Key, payload.Upload)
+		} else {
+			err = server.Services.Character.SetImage(character, payload.Key, bytes.NewReader(payload.Data))
+		}
+		if err != nil {
+			return nil, err
+		}
+		server.RecordAudit(&models.User{ID: job.CreatorID}, &models.AuditEvent{
+			UniverseID: character.UniverseID,
+			Action:     models.AuditImageSet,
+			TargetType: models.AuditTargetCharacter,
+			TargetID:   character.ID,
+			After:      models.AuditSummary{"key": payload.Key},
+		})
+		if updated, err := server.Services.Character.FindByID(character.ID); err == nil {
+			server.PublishEvent(&models.UniverseEvent{
+				Type:       models.EventCharacterUpdated,
+				UniverseID: updated.UniverseID,
+				ActorID:    job.CreatorID,
+				Character:  updated,
+			})
+		}
+		return nil, nil
+	}
+}
+
+// deleteFilesJob deletes the stored files of deleted images
+func deleteFilesJob(server *api.Server) services.JobHandler {
+	return func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		var payload models.JobFiles
+		if err := readPayload(job, &payload); err != nil {
+			return nil, err
+		}
+		return nil, server.Services.Character.DeleteFiles(payload.Keys)
+	}
+}
+
+// reconcileImagesJob reconciles the stored image files with the records of character images, logging what it
+// deleted and sized. Nothing is changed unless deletion is enabled in the configuration
+func reconcileImagesJob(server *api.Server) services.JobHandler {
+	return func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		dryRun := !server.Config.ImageReconcileDelete
+		reconciliation, err := server.Services.Character.ReconcileImages(OrphanMinAge, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		server.Providers.Logger.Info(
+			"Reconciled images",
+			"dry_run", dryRun,
+			"orphaned_files", len(reconciliation.OrphanedFiles),
+			"missing_files", len(reconciliation.MissingFiles),
+			"unsized_files", len(reconciliation.UnsizedFiles),
+			"expired_uploads", len(reconciliation.ExpiredUploads),
+		)
+		return nil, nil
+	}
+}
+
+// exportCharactersJob exports the characters of a universe matching a query, with the visibility of the
+// collaborator who requested the export
+func exportCharactersJob(server *api.Server) services.JobHandler {
+	return func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		var payload exportJob
+		if err := readPayload(job, &payload); err != nil {
+			return nil, err
+		}
+		universe, err := server.Services.Universe.FindByID(job.UniverseID)
+		if err == repositories.ErrNotFound {
+			return nil, api.ErrNotFound("Universe not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		characters, err := server.Services.Character.FindAllByUniverse(universe, payload.Query)
+		if err != nil {
+			return nil, err
+		}
+		var buff bytes.Buffer
+		if err := server.Services.Character.Export(universe, *characters, payload.Format, &buff); err != nil {
+			return nil, err
+		}
+		return &models.JobResult{
+			ContentType: ExportContentTypes[payload.Format],
+			Filename:    fmt.Sprintf("%s.%s", universe.ID, payload.Format),
+			Data:        buff.Bytes(),
+		}, nil
+	}
+}
@@ -0,0 +1,254 @@
+package characters
+
+import (
+	"cbs/api/openapi"
+	"cbs/dtos"
+	"cbs/models"
+	"net/http"
+)
+
+// queryParameters represents the parameters that filter and sort character lists and exports
+var queryParameters = []openapi.Parameter{
+	{Name: "p", In: "query", Description: "Zero-based page number", Schema: openapi.IntegerSchema()},
+	{Name: "q", In: "query", Description: "Search query matched against character names"},
+	{
+		Name:        "s",
+		In:          "query",
+		Description: "Sorting order, defaulting to nominal",
+		Schema: openapi.StringSchema(
+			string(dtos.CharacterQuerySortNominal),
+			string(dtos.CharacterQuerySortLexicographical),
+		),
+	},
+	{
+		Name:        "hidden",
+		In:          "query",
+		Description: "Whether hidden characters are included, defaulting to true",
+		Schema:      openapi.BooleanSchema(),
+	},
+}
+
+// exportParameter represents the format parameter of exports
+var exportParameter = openapi.Parameter{
+	Name:        "format",
+	In:          "query",
+	Description: "File format of the export, defaulting to json",
+	Schema: openapi.StringSchema(
+		string(dtos.CharacterExportFormatCSV),
+		string(dtos.CharacterExportFormatJSON),
+		string(dtos.CharacterExportFormatMarkdown),
+	),
+}
+
+// exportResponse represents the file download of exports
+var exportResponse = openapi.Response{
+	Status:      http.StatusOK,
+	Description: "The export as a file download",
+	Headers:     []string{"Content-Disposition"},
+	Content: []openapi.Content{
+		{MediaType: "text/csv"},
+		{MediaType: "application/json", Body: []models.Character{}},
+		{MediaType: "text/markdown"},
+	},
+}
+
+// Operations describes the routes of the "characters" resource for the OpenAPI document
+var Operations = []openapi.Operation{
+	{
+		Method:     http.MethodGet,
+		Path:       "/",
+		Summary:    "List the characters of a universe",
+		Session:    true,
+		Role:       openapi.RoleMember,
+		Parameters: queryParameters,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "A page of characters",
+			Content:     []openapi.Content{{Body: dtos.ResGetCharacters{}}},
+		}},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/",
+		Summary: "Create a character",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Body: []openapi.Content{{
+			MediaType: "multipart/form-data",
+			Form: []openapi.FormPart{
+				{Name: "data", Required: true, Body: dtos.ReqCreateCharacter{}},
+				{Name: "avatar"},
+			},
+		}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusCreated,
+			Description: "The created character. An uploaded avatar is processed by the job named in X-Job-ID",
+			Headers:     []string{"X-Job-ID"},
+			Content:     []openapi.Content{{Body: dtos.ResGetCharacter{}}},
+		}},
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/",
+		Summary: "Delete every character of a universe",
+		Session: true,
+		Role:    openapi.RoleOwner,
+		Responses: []openapi.Response{{
+			Status:      http.StatusNoContent,
+			Description: "The characters were deleted",
+		}},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/import",
+		Summary:     "Import characters from a CSV or JSON file",
+		Description: "Large imports are processed in the background and answered with 202 Accepted",
+		Session:     true,
+		Role:        openapi.RoleMember,
+		Parameters: []openapi.Parameter{
+			{
+				Name:        "format",
+				In:          "query",
+				Description: "File format of the import, detected from the file name when not given",
+				Schema: openapi.StringSchema(
+					string(dtos.CharacterImportFormatCSV),
+					string(dtos.CharacterImportFormatJSON),
+				),
+			},
+			{
+				Name:        "mode",
+				In:          "query",
+				Description: "Whether one invalid row rejects the whole import, defaulting to atomic",
+				Schema:      openapi.StringSchema("atomic", "partial"),
+			},
+			{
+				Name:        "dry",
+				In:          "query",
+				Description: "Whether the import is only validated",
+				Schema:      openapi.BooleanSchema(),
+			},
+		},
+		Body: []openapi.Content{{
+			MediaType: "multipart/form-data",
+			Form:      []openapi.FormPart{{Name: "file", Required: true}},
+		}},
+		Responses: []openapi.Response{
+			{
+				Status:      http.StatusOK,
+				Description: "The results of the import",
+				Content:     []openapi.Content{{Body: dtos.ResGetCharacterImport{}}},
+			},
+			{
+				Status:      http.StatusAccepted,
+				Description: "The import was started in the background",
+				Content:     []openapi.Content{{Body: dtos.ResGetCharacterImport{}}},
+			},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/import/{importID}",
+		Summary: "Get the progress and results of a background import",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The import",
+			Content:     []openapi.Content{{Body: dtos.ResGetCharacterImport{}}},
+		}},
+	},
+	{
+		Method:     http.MethodGet,
+		Path:       "/export",
+		Summary:    "Export the characters of a universe",
+		Session:    true,
+		Role:       openapi.RoleMember,
+		Parameters: append([]openapi.Parameter{exportParameter}, queryParameters...),
+		Responses:  []openapi.Response{exportResponse},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/export",
+		Summary:     "Export the characters of a universe in the background",
+		Description: "The export is downloaded from the result of the job once it has succeeded",
+		Session:     true,
+		Role:        openapi.RoleMember,
+		Parameters:  append([]openapi.Parameter{exportParameter}, queryParameters...),
+		Responses: []openapi.Response{{
+			Status:      http.StatusAccepted,
+			Description: "The queued export job",
+			Headers:     []string{"X-Job-ID"},
+			Content:     []openapi.Content{{Body: dtos.ResGetJob{}}},
+		}},
+	},
+	{
+		Method:     http.MethodGet,
+		Path:       "/{characterID}",
+		Summary:    "Get a character",
+		Session:    true,
+		Role:       openapi.RoleMember,
+		Parameters: []openapi.Parameter{openapi.IfNoneMatch},
+		Responses: []openapi.Response{
+			{
+				Status:      http.StatusOK,
+				Description: "The character",
+				Headers:     []string{"ETag"},
+				Content:     []openapi.Content{{Body: dtos.ResGetCharacter{}}},
+			},
+			{Status: http.StatusNotModified, Description: "The cached copy is current"},
+		},
+	},
+	{
+		Method:     http.MethodGet,
+		Path:       "/{characterID}/export",
+		Summary:    "Export a character",
+		Session:    true,
+		Role:       openapi.RoleMember,
+		Parameters: []openapi.Parameter{exportParameter},
+		Responses:  []openapi.Response{exportResponse},
+	},
+	{
+		Method:  http.MethodPatch,
+		Path:    "/{characterID}",
+		Summary: "Edit a character",
+		Description: "A multipart form replaces the character, while merge and JSON patch documents change only " +
+			"the fields they name. Members can only edit their own characters",
+		Session:    true,
+		Role:       openapi.RoleMember,
+		Parameters: []openapi.Parameter{openapi.IfMatch},
+		Body: []openapi.Content{
+			{
+				MediaType: "multipart/form-data",
+				Form: []openapi.FormPart{
+					{Name: "data", Required: true, Body: dtos.ReqCreateCharacter{}},
+					{Name: "avatar"},
+				},
+			},
+			{MediaType: string(dtos.CharacterPatchMerge), Body: map[string]interface{}{}},
+			{MediaType: string(dtos.CharacterPatchJSON), Body: []map[string]interface{}{}},
+		},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The edited character. An uploaded avatar is processed by the job named in X-Job-ID",
+			Headers:     []string{"ETag", "X-Job-ID"},
+			Content:     []openapi.Content{{Body: dtos.ResGetCharacter{}}},
+		}},
+	},
+	{
+		Method:     http.MethodDelete,
+		Path:       "/{characterID}",
+		Summary:    "Delete a character",
+		Session:    true,
+		Role:       openapi.RoleMember,
+		Parameters: []openapi.Parameter{openapi.IfMatch},
+		Responses:  []openapi.Response{{Status: http.StatusNoContent, Description: "The character was deleted"}},
+	},
+	{
+		Method:    http.MethodDelete,
+		Path:      "/{characterID}/avatar",
+		Summary:   "Delete the avatar of a character",
+		Session:   true,
+		Role:      openapi.RoleMember,
+		Responses: []openapi.Response{{Status: http.StatusNoContent, Description: "The avatar was deleted"}},
+	},
+}
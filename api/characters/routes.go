@@ -1,11 +1,15 @@
 package characters
 
 import (
+	"bytes"
 	"cbs/api"
 	"cbs/dtos"
 	"cbs/models"
 	"fmt"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +35,10 @@ func NewRouter(server *api.Server) *Router {
 	)
 	router.Get("/", api.Handler(router.GetCharacters).ServeHTTP)
 	router.Post("/", api.Handler(router.CreateCharacter).ServeHTTP)
+	router.Post("/import", api.Handler(router.ImportCharacters).ServeHTTP)
+	router.Get("/import/{importID}", api.Handler(router.GetImport).ServeHTTP)
+	router.Get("/export", api.Handler(router.ExportCharacters).ServeHTTP)
+	router.Post("/export", api.Handler(router.QueueExport).ServeHTTP)
 	router.With(server.Middlewares.Collaborator(models.CollaboratorOwner)).Delete(
 		"/",
 		api.Handler(router.DeleteCharacters).ServeHTTP,
@@ -38,6 +46,7 @@ func NewRouter(server *api.Server) *Router {
 	router.Route("/{characterID}", func(r chi.Router) {
 		r.Use(server.Middlewares.Character)
 		r.Get("/", api.Handler(router.GetCharacter).ServeHTTP)
+		r.Get("/export", api.Handler(router.ExportCharacter).ServeHTTP)
 		r.Patch("/", api.Handler(router.EditCharacter).ServeHTTP)
 		r.Delete("/", api.Handler(router.DeleteCharacter).ServeHTTP)
 		r.Delete("/avatar", api.Handler(router.DeleteAvatar).ServeHTTP)
@@ -66,11 +75,10 @@ func (m *Router) CreateCharacter(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	avatar, _, err := r.FormFile("avatar")
-	if err == nil {
-		if err := m.Services.Character.SetImage(saved, "avatar", avatar); err != nil {
-			return err
-		}
+	m.auditCharacter(r, universe, models.AuditCharacterCreate, saved.ID, nil, saved.AuditSummary())
+
+	if err := m.queueAvatar(w, r, saved); err != nil {
+		return err
 	}
 
 	images, err := m.Services.Character.FindCharacterImages(saved.ID)
@@ -79,15 +87,142 @@ func (m *Router) CreateCharacter(w http.ResponseWriter, r *http.Request) error {
 	}
 	saved.Images = images
 
+	m.PublishEvent(&models.UniverseEvent{
+		Type:       models.EventCharacterCreated,
+		UniverseID: universe.ID,
+		ActorID:    user.ID,
+		Character:  saved,
+	})
 	api.SendResponse(w, dtos.ResGetCharacter{Character: saved}, http.StatusCreated)
 	return nil
 }
 
+// ImportCharacters represents a route that creates a collection of characters from a CSV or JSON file
+func (m *Router) ImportCharacters(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+
+	// Limits the request size to MaxImportSize
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImportSize)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return api.ErrBadBody("An import file must be provided")
+	}
+	defer file.Close()
+
+	// Extract the file format from the URL parameters, falling back to the file extension
+	format := dtos.CharacterImportFormat(strings.ToLower(r.URL.Query().Get("format")))
+	if format == "" {
+		format = dtos.CharacterImportFormat(strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), "."))
+	}
+
+	// Extract whether the import should be all-or-nothing or best-effort from the URL parameters
+	atomic := r.URL.Query().Get("mode") != "partial"
+
+	// Extract whether the import should only be previewed from the URL parameters
+	dryRun, err := strconv.ParseBool(r.URL.Query().Get("dry"))
+	if err != nil {
+		dryRun = false
+	}
+
+	rows, err := m.Services.Character.ParseImport(universe, format, file)
+	if err != nil {
+		return err
+	}
+	job := m.Services.Character.NewImport(
+		universe,
+		user,
+		dtos.CharacterImportOptions{Atomic: atomic, DryRun: dryRun},
+	)
+
+	// Large imports are processed in the background and polled through GetImport
+	threshold := m.Config.ImportAsyncThreshold
+	if threshold <= 0 {
+		threshold = DefaultImportAsyncThreshold
+	}
+	if len(rows) > threshold {
+		job.Async = true
+		if err := m.Services.Character.SaveImport(job); err != nil {
+			return api.ErrInternal("Failed to start import")
+		}
+		api.SendResponse(w, dtos.ResGetCharacterImport{CharacterImport: job}, http.StatusAccepted)
+		logger := api.Logger(r)
+		m.Go(func() {
+			if err := m.Services.Character.Import(job, universe, user, rows); err != nil {
+				logger.Error("Character import failed", "import", job.ID, "error", err)
+			}
+			m.reportImport(job, user)
+		})
+		return nil
+	}
+
+	if err := m.Services.Character.Import(job, universe, user, rows); err != nil {
+		return err
+	}
+	m.reportImport(job, user)
+	api.SendResponse(w, dtos.ResGetCharacterImport{CharacterImport: job}, http.StatusOK)
+	return nil
+}
+
+// reportImport records a character import that created characters in the audit log and publishes a summary
+// of it. Row errors and previews are left out of the event since they may name characters that members are
+// not allowed to see
+func (m *Router) reportImport(job *models.CharacterImport, user *models.User) {
+	if job.DryRun || job.Created == 0 {
+		return
+	}
+	m.RecordAudit(user, &models.AuditEvent{
+		UniverseID: job.UniverseID,
+		Action:     models.AuditCharactersImport,
+		TargetType: models.AuditTargetUniverse,
+		TargetID:   job.UniverseID,
+		After:      models.AuditSummary{"import": job.ID, "created": job.Created, "failed": job.Failed},
+	})
+	summary := *job
+	summary.Errors = nil
+	summary.Preview = nil
+	m.PublishEvent(&models.UniverseEvent{
+		Type:       models.EventCharactersImported,
+		UniverseID: job.UniverseID,
+		ActorID:    user.ID,
+		Import:     &summary,
+	})
+}
+
+// GetImport represents a route that retrieves the progress of a character import
+func (m *Router) GetImport(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	job, err := m.Services.Character.FindImportByID(universe, chi.URLParam(r, "importID"))
+	if err != nil {
+		return err
+	}
+	if collaborator.Role == models.CollaboratorMember && collaborator.UserID != job.OwnerID {
+		return api.ErrBadAuth("You do not have permission to view this import")
+	}
+	api.SendResponse(w, dtos.ResGetCharacterImport{CharacterImport: job}, http.StatusOK)
+	return nil
+}
+
 // GetCharacters represents a route that retrieves a collection of characters pertaining to a universe
 func (m *Router) GetCharacters(w http.ResponseWriter, r *http.Request) error {
 	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
 	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
 
+	// Create the database query context
+	ctx := ReadCharacterQuery(r, collaborator)
+
+	characters, total, err := m.Services.Character.FindByUniverse(universe, ctx)
+	if err != nil {
+		return err
+	}
+	api.SendResponse(w, dtos.ResGetCharacters{Characters: characters, Page: ctx.Page, Total: total}, http.StatusOK)
+	return nil
+}
+
+// ReadCharacterQuery extracts a character query context from the URL parameters
+func ReadCharacterQuery(r *http.Request, collaborator *models.Collaborator) dtos.CharacterQuery {
 	// Extract the page from the URL parameters
 	upage := r.URL.Query().Get("p")
 	page, err := strconv.Atoi(upage)
@@ -107,30 +242,109 @@ func (m *Router) GetCharacters(w http.ResponseWriter, r *http.Request) error {
 
 	// Extract whether hidden characters should be included from the URL parameters
 	uAllowHidden := r.URL.Query().Get("hidden")
-	fmt.Println(uAllowHidden)
 	allowHidden, err := strconv.ParseBool(uAllowHidden)
 	if err != nil {
 		allowHidden = true
 	}
 
-	// Create the database query context
-	ctx := dtos.CharacterQuery{Collaborator: collaborator, Page: page, Query: query, Sort: sort,
+	return dtos.CharacterQuery{Collaborator: collaborator, Page: page, Query: query, Sort: sort,
 		IncludeHidden: allowHidden}
+}
 
-	characters, total, err := m.Services.Character.FindByUniverse(universe, ctx)
+// canViewCharacter reports whether a collaborator is allowed to view a character
+func canViewCharacter(collaborator *models.Collaborator, character *models.Character) bool {
+	return collaborator.CanViewCharacter(character.Meta.Hidden, character.Owner.ID)
+}
+
+// readExportFormat returns the export format of a request, defaulting to JSON
+func readExportFormat(r *http.Request) dtos.CharacterExportFormat {
+	format := dtos.CharacterExportFormat(strings.ToLower(r.URL.Query().Get("format")))
+	if format == "" {
+		return dtos.CharacterExportFormatJSON
+	}
+	return format
+}
+
+// sendExport writes an export of characters to the ResponseWriter as a file download
+func (m *Router) sendExport(
+	w http.ResponseWriter,
+	r *http.Request,
+	universe *models.Universe,
+	characters []models.Character,
+	filename string,
+) error {
+	format := readExportFormat(r)
+	contentType, ok := ExportContentTypes[format]
+	if !ok {
+		return api.ErrBadBody(fmt.Sprintf("Unsupported export format '%s'", format))
+	}
+	var buff bytes.Buffer
+	if err := m.Services.Character.Export(universe, characters, format, &buff); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", filename, format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buff.Bytes())
+	return nil
+}
+
+// ExportCharacters represents a route that exports a filtered collection of characters pertaining to a universe
+func (m *Router) ExportCharacters(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	characters, err := m.Services.Character.FindAllByUniverse(universe, ReadCharacterQuery(r, collaborator))
 	if err != nil {
 		return err
 	}
-	api.SendResponse(w, dtos.ResGetCharacters{Characters: characters, Page: page, Total: total}, http.StatusOK)
+	return m.sendExport(w, r, universe, *characters, universe.ID)
+}
+
+// QueueExport represents a route that queues a filtered collection of characters pertaining to a universe to be
+// exported in the background, for universes too large to export within a request
+func (m *Router) QueueExport(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	job, err := m.Services.Character.QueueExport(universe, ReadCharacterQuery(r, collaborator), readExportFormat(r), user)
+	if err != nil {
+		return err
+	}
+	job.Payload = nil
+	w.Header().Set(api.JobHeader, job.ID)
+	api.SendResponse(w, dtos.ResGetJob{Job: job}, http.StatusAccepted)
 	return nil
 }
 
+// ExportCharacter represents a route that exports a single character pertaining to a universe
+func (m *Router) ExportCharacter(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	character, _ := r.Context().Value(api.CharacterContextKey).(*models.Character)
+	if !canViewCharacter(collaborator, character) {
+		return api.ErrBadAuth("You do not have permission to view this character")
+	}
+	return m.sendExport(w, r, universe, []models.Character{*character}, character.ID)
+}
+
 // DeleteCharacters represents a route that deletes all characters from a universe
 func (m *Router) DeleteCharacters(w http.ResponseWriter, r *http.Request) error {
 	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
 	if err := m.Services.Character.DeleteAll(universe); err != nil {
 		return err
 	}
+	m.RecordAudit(user, &models.AuditEvent{
+		UniverseID: universe.ID,
+		Action:     models.AuditCharactersDeleteAll,
+		TargetType: models.AuditTargetUniverse,
+		TargetID:   universe.ID,
+	})
+	m.PublishEvent(&models.UniverseEvent{
+		Type:       models.EventCharactersCleared,
+		UniverseID: universe.ID,
+		ActorID:    user.ID,
+	})
 	w.WriteHeader(http.StatusNoContent)
 	w.Write([]byte(""))
 	return nil
@@ -140,10 +354,13 @@ func (m *Router) DeleteCharacters(w http.ResponseWriter, r *http.Request) error
 func (m *Router) GetCharacter(w http.ResponseWriter, r *http.Request) error {
 	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
 	character, _ := r.Context().Value(api.CharacterContextKey).(*models.Character)
-	if character.Meta.Hidden &&
-		collaborator.Role == models.CollaboratorMember && collaborator.UserID != character.Owner.ID {
+	if !canViewCharacter(collaborator, character) {
 		return api.ErrBadAuth("You do not have permission to view this character")
 	}
+	if api.CheckIfNoneMatch(w, r, character.ETag()) {
+		return nil
+	}
+	api.SetETag(w, character.ETag())
 	api.SendResponse(w, dtos.ResGetCharacter{Character: character}, http.StatusOK)
 	return nil
 }
@@ -153,10 +370,22 @@ func (m *Router) EditCharacter(w http.ResponseWriter, r *http.Request) error {
 	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
 	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
 	merged, _ := r.Context().Value(api.CharacterContextKey).(*models.Character)
-	merged.Fields = nil
 	if collaborator.Role == models.CollaboratorMember && collaborator.UserID != merged.Owner.ID {
 		return api.ErrBadAuth("You do not have permission to edit this character")
 	}
+	if err := api.CheckIfMatch(r, merged.ETag()); err != nil {
+		return err
+	}
+	before := merged.AuditSummary()
+
+	// Partial updates are sent as patch documents rather than as a multipart form
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch patchType := dtos.CharacterPatchType(mediaType); patchType {
+	case dtos.CharacterPatchMerge, dtos.CharacterPatchJSON:
+		return m.patchCharacter(w, r, universe, merged, patchType, before)
+	}
+
+	merged.Fields = nil
 	forbidden := struct {
 		ID         string
 		UniverseID string
@@ -176,13 +405,6 @@ func (m *Router) EditCharacter(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	avatar, _, err := r.FormFile("avatar")
-	if err == nil {
-		if err := m.Services.Character.SetImage(merged, "avatar", avatar); err != nil {
-			return err
-		}
-	}
-
 	if merged.ID != forbidden.ID || merged.UniverseID != forbidden.UniverseID {
 		return api.ErrBadBody("ID cannot be changed")
 	}
@@ -198,21 +420,134 @@ func (m *Router) EditCharacter(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	updated.Owner = merged.Owner
+	if err := m.queueAvatar(w, r, updated); err != nil {
+		return err
+	}
+	m.auditCharacter(r, universe, models.AuditCharacterUpdate, updated.ID, before, updated.AuditSummary())
+	m.publishUpdate(r, universe, updated)
+	api.SetETag(w, updated.ETag())
+	api.SendResponse(w, dtos.ResGetCharacter{Character: updated}, http.StatusOK)
+	return nil
+}
+
+// patchCharacter applies a patch document from the request body to a character, then validates and saves it
+func (m *Router) patchCharacter(
+	w http.ResponseWriter,
+	r *http.Request,
+	universe *models.Universe,
+	character *models.Character,
+	patchType dtos.CharacterPatchType,
+	before models.AuditSummary,
+) error {
+	// Limits the request size to MaxRequestSize
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestSize)
+
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return api.ErrBadBody("Failed to read patch")
+	}
+	patched, err := m.Services.Character.Patch(character, patchType, patch)
+	if err != nil {
+		return err
+	}
+	if patched.ID != character.ID || patched.UniverseID != character.UniverseID {
+		return api.ErrBadBody("ID cannot be changed")
+	}
+	if !patched.CreatedAt.Equal(character.CreatedAt) || !patched.UpdatedAt.Equal(character.UpdatedAt) {
+		return api.ErrBadBody("Timestamps cannot be changed")
+	}
+	valError, err := api.ValidateDTO(patched)
+	if err != nil {
+		return err
+	}
+	if valError != nil {
+		return *valError
+	}
+	if err := m.Services.Character.Validate(patched, universe); err != nil {
+		return err
+	}
+	updated, err := m.Services.Character.Update(patched)
+	if err != nil {
+		return err
+	}
+
+	updated.Owner = character.Owner
+	m.auditCharacter(r, universe, models.AuditCharacterUpdate, updated.ID, before, updated.AuditSummary())
+	m.publishUpdate(r, universe, updated)
+	api.SetETag(w, updated.ETag())
 	api.SendResponse(w, dtos.ResGetCharacter{Character: updated}, http.StatusOK)
 	return nil
 }
 
+// auditCharacter records an action performed on a character in the audit log
+func (m *Router) auditCharacter(
+	r *http.Request,
+	universe *models.Universe,
+	action models.AuditAction,
+	characterID string,
+	before models.AuditSummary,
+	after models.AuditSummary,
+) {
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	m.RecordAudit(user, &models.AuditEvent{
+		UniverseID: universe.ID,
+		Action:     action,
+		TargetType: models.AuditTargetCharacter,
+		TargetID:   characterID,
+		Before:     before,
+		After:      after,
+	})
+}
+
+// queueAvatar queues the avatar uploaded with a multipart form, if any, to be assigned to a character in the
+// background, naming the job in JobHeader
+func (m *Router) queueAvatar(w http.ResponseWriter, r *http.Request, character *models.Character) error {
+	avatar, _, err := r.FormFile("avatar")
+	if err != nil {
+		return nil
+	}
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	job, err := m.Services.Character.QueueImage(character, "avatar", avatar, user)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(api.JobHeader, job.ID)
+	return nil
+}
+
+// publishUpdate publishes the new state of an edited character
+func (m *Router) publishUpdate(r *http.Request, universe *models.Universe, character *models.Character) {
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	m.PublishEvent(&models.UniverseEvent{
+		Type:       models.EventCharacterUpdated,
+		UniverseID: universe.ID,
+		ActorID:    user.ID,
+		Character:  character,
+	})
+}
+
 // DeleteCharacter deletes a character
 func (m *Router) DeleteCharacter(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
 	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
 	character, _ := r.Context().Value(api.CharacterContextKey).(*models.Character)
 	if collaborator.Role == models.CollaboratorMember && collaborator.UserID != character.Owner.ID {
 		return api.ErrBadAuth("You do not have permission to delete this character")
 	}
+	if err := api.CheckIfMatch(r, character.ETag()); err != nil {
+		return err
+	}
 	if err := m.Services.Character.Delete(character); err != nil {
 		return err
 	}
-	m.Services.Character.DeleteImage(character, "avatar")
+	m.auditCharacter(r, universe, models.AuditCharacterDelete, character.ID, character.AuditSummary(), nil)
+	m.PublishEvent(&models.UniverseEvent{
+		Type:       models.EventCharacterDeleted,
+		UniverseID: universe.ID,
+		ActorID:    user.ID,
+		Character:  character,
+	})
 	w.WriteHeader(http.StatusNoContent)
 	w.Write([]byte(""))
 	return nil
@@ -220,10 +555,12 @@ func (m *Router) DeleteCharacter(w http.ResponseWriter, r *http.Request) error {
 
 // DeleteAvatar deletes the avatar assigned to a character
 func (m *Router) DeleteAvatar(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
 	character, _ := r.Context().Value(api.CharacterContextKey).(*models.Character)
 	if err := m.Services.Character.DeleteImage(character, "avatar"); err != nil {
 		return err
 	}
+	m.auditCharacter(r, universe, models.AuditImageDelete, character.ID, models.AuditSummary{"key": "avatar"}, nil)
 	w.WriteHeader(http.StatusNoContent)
 	w.Write([]byte(""))
 	return nil
@@ -5,21 +5,20 @@ import (
 	"cbs/api"
 	"cbs/dtos"
 	"cbs/models"
+	"cbs/repositories"
+	"encoding/json"
 	"fmt"
-	"image"
-	"image/jpeg"
 	"io"
+	"io/ioutil"
 	"math"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/disintegration/imaging"
+	jsonpatch "github.com/evanphx/json-patch"
 )
 
-// AvatarSize represents the width and height dimensions for character avatars
-const AvatarSize = 512
-
 // Service represents a service implementation for the "characters" resource
 type Service api.Service
 
@@ -69,26 +68,13 @@ func (s *Service) New(data dtos.ReqCreateCharacter) *models.Character {
 
 // FindCharacterImages returns images associated with a character
 func (s *Service) FindCharacterImages(id string) (models.CharacterImages, error) {
-	images := make(models.CharacterImages)
-
-	rows, err := s.Providers.DB.Queryx(`SELECT key, url FROM character_images WHERE character_id=$1`, id)
-	if err != nil {
-		return nil, err
-	}
-	for rows.Next() {
-		var key, url string
-		if err := rows.Scan(&key, &url); err != nil {
-			return nil, err
-		}
-		images[key] = url
-	}
-	return images, nil
+	return s.Repositories.Image.FindByCharacter(id)
 }
 
 // FindByID returns a character by their ID
 func (s *Service) FindByID(id string) (*models.Character, error) {
-	var character models.Character
-	if err := s.Providers.DB.Get(&character, QueryFindByID, id); err != nil {
+	character, err := s.Repositories.Character.FindByID(id)
+	if err != nil {
 		return nil, err
 	}
 	images, err := s.FindCharacterImages(id)
@@ -97,7 +83,7 @@ func (s *Service) FindByID(id string) (*models.Character, error) {
 	}
 	character.Images = images
 
-	return &character, nil
+	return character, nil
 }
 
 // FindByUniverse returns a collection of character references associated with a universe
@@ -105,228 +91,42 @@ func (s *Service) FindByUniverse(
 	universe *models.Universe,
 	ctx dtos.CharacterQuery,
 ) (*[]models.CharacterReference, int, error) {
-	var (
-		count      = 0
-		characters = make([]models.CharacterReference, 0)
-		query      = ""
-		/* query      = `SELECT id, name, tag, owner_id, created_at, updated_at, character_images.url AS avatar_url,
-		meta->'hidden' AS hidden FROM characters`
-		cquery = "SELECT count(*) FROM characters"*/
-	)
-
-	// Normalize the search query
-	if ctx.Query == "" {
-		query = "%"
-	} else {
-		query = fmt.Sprintf("%%%v%%", strings.Replace(ctx.Query, " ", "%", -1))
-	}
-
-	// Create the search query
-	gensql := s.Providers.SQLBuilder.Select(`id, name, tag, owner_id, created_at, updated_at, character_images.url
-	AS avatar_url, (meta->>'hidden')::boolean AS hidden, CASE WHEN meta->>'nameHidden' IS NULL THEN false ELSE
-	(meta->>'nameHidden')::boolean END AS name_hidden, meta->'name' AS parsed_name`).From(`characters`).LeftJoin(`
-	character_images ON character_images.character_id = characters.id`).Where(`universe_id = ? AND name ILIKE ?`,
-		universe.ID, query)
-
-	// Factor whether all characters should be included into the query
-	if ctx.Collaborator.Role != models.CollaboratorMember {
-		// Factor whether hidden characters should be included or not
-		if !ctx.IncludeHidden {
-			gensql = gensql.Where(`(meta->>'hidden')::boolean IS FALSE`)
-		}
-	} else {
-		// Factor whether hidden characters should be included or not
-		if !ctx.IncludeHidden {
-			gensql = gensql.Where(`((meta->>'hidden')::boolean IS FALSE OR (owner_id=? AND (meta->>'hidden')::boolean
-			IS FALSE))`, ctx.Collaborator.UserID)
-		} else {
-			gensql = gensql.Where(`((meta->>'hidden')::boolean IS FALSE OR owner_id=?)`, ctx.Collaborator.UserID)
-		}
-	}
-
-	// Factor whether characters should be sorted nominally or lexicographically
-	if ctx.Sort == dtos.CharacterQuerySortLexicographical {
-		gensql = gensql.OrderBy(`meta->'name'->>'lastName' = '' OR meta->'name'->>'firstName' = '' OR (meta->>
-		'nameHidden')::boolean IS TRUE, CASE WHEN meta->'name'->>'preferredName' != '' THEN meta->'name'->>
-		'preferredName' ELSE meta->'name'->>'lastName' END, meta->'name'->>'lastName', meta->'name'->>'firstName'`)
-	} else {
-		gensql = gensql.OrderBy(`(meta->>'nameHidden')::boolean IS TRUE, name`)
-	}
-
-	// Apply the rest of the statements
-	gensql = gensql.Limit(uint64(s.Config.CharacterPageLimit)).Offset(uint64(ctx.Page * s.Config.CharacterPageLimit))
-
-	// Convert to SQL statement
-	querysql, queryargs, err := gensql.ToSql()
+	characters, count, err := s.Repositories.Character.FindByUniverse(universe.ID, ctx, s.Config.CharacterPageLimit)
 	if err != nil {
 		return nil, 0, err
 	}
-
-	// Create the count query
-	gensql = s.Providers.SQLBuilder.Select(`COUNT(*)`).From(`characters`).Where(`universe_id = ? AND name ILIKE ?`,
-		universe.ID, query)
-
-	// Factor whether all characters should be included in the query
-	if ctx.Collaborator.Role != models.CollaboratorMember {
-		// Factor whether hidden characters should be included or not
-		if !ctx.IncludeHidden {
-			gensql = gensql.Where(`(meta->>'hidden')::boolean IS FALSE`)
-		}
-	} else {
-		// Factor whether hidden charactesr should be included or not
-		if !ctx.IncludeHidden {
-			gensql = gensql.Where(`((meta->>'hidden')::boolean IS FALSE OR (owner_id=? AND (meta->>'hidden')::boolean
-			IS FALSE))`, ctx.Collaborator.UserID)
-		} else {
-			gensql = gensql.Where(`((meta->>'hidden')::boolean IS FALSE OR owner_id=?)`, ctx.Collaborator.UserID)
-		}
-	}
-
-	// Convert to SQL statement
-	countsql, countargs, err := gensql.ToSql()
-	if err != nil {
-		return nil, 0, err
-	}
-
-	// Run the queries
-	if err := s.Providers.DB.Select(&characters, querysql, queryargs...); err != nil {
-		return nil, 0, err
-	}
-	if err := s.Providers.DB.Get(&count, countsql, countargs...); err != nil {
-		return nil, 0, err
-	}
-
 	for i, c := range characters {
 		if ctx.Collaborator.Role == models.CollaboratorMember && c.OwnerID != ctx.Collaborator.UserID {
 			characters[i].HideHiddenFields()
 		}
 	}
-
 	return &characters, count, nil
-	/*if ctx.Collaborator.Role != models.CollaboratorMember {
-		var err error
-		// Use admin database query
-		if ctx.Sort == dtos.CharacterQuerySortLexicographical {
-			// Use admin lexicographical database query
-
-		} else {
-			// Use admin nominal database query
-			q, args, _ := s.Providers.SQLBuilder.Select(`id, name, tag, owner_id, created_at, updated_at,
-			character_images.url AS avatar_url, meta->'hidden' AS hidden, CASE WHEN meta->'nameHidden' IS NULL THEN false ELSE (meta->>'nameHidden')::boolean END AS name_hidden, meta->'name' AS parsed_name`).
-				From(`characters`).LeftJoin(`character_images ON character_images.character_id = characters.id`).
-				Where(`universe_id = ? AND name ILIKE ?`, universe.ID, query).
-				OrderBy(`CASE WHEN (meta->>'nameHidden')::boolean=true THEN NULL ELSE name END, name`).Limit(
-				uint64(s.Config.CharacterPageLimit)).Offset(uint64(ctx.Page)).ToSql()
-			err = s.Providers.DB.Select(
-				&characters,
-				q,
-				args...,
-			)
-		}
-
-		if err != nil {
-			return nil, 0, err
-		}
-
-		// Use admin database count query
-		if err := s.Providers.DB.Get(
-			&count,
-			QueryFindByUniverseAllCount,
-			universe.ID,
-			query,
-		); err != nil {
-			return nil, 0, err
-		}
-	} else {
-		var err error
-		// Use public database query
-		if ctx.Sort == dtos.CharacterQuerySortLexicographical {
-			// Use public lexicographical database query
-			err = s.Providers.DB.Select(
-				&characters,
-				QueryFindByUniversePublicLex,
-				universe.ID,
-				ctx.Collaborator.UserID,
-				query,
-				s.Config.CharacterPageLimit,
-				ctx.Page*s.Config.CharacterPageLimit,
-			)
-		} else {
-			// Use public nominal database query
-			err = s.Providers.DB.Select(
-				&characters,
-				QueryFindByUniversePublicNom,
-				universe.ID,
-				ctx.Collaborator.UserID,
-				query,
-				s.Config.CharacterPageLimit,
-				ctx.Page*s.Config.CharacterPageLimit,
-			)
-		}
-
-		if err != nil {
-			return nil, 0, err
-		}
+}
 
-		// Use public database count query
-		if err := s.Providers.DB.Get(
-			&count,
-			QueryFindByUniversePublicCount,
-			universe.ID,
-			query,
-			ctx.Collaborator.UserID,
-		); err != nil {
-			return nil, 0, err
-		}
+// FindAllByUniverse returns every character associated with a universe that matches the query, with their
+// hidden fields obscured according to the querying collaborator
+func (s *Service) FindAllByUniverse(
+	universe *models.Universe,
+	ctx dtos.CharacterQuery,
+) (*[]models.Character, error) {
+	characters, err := s.Repositories.Character.FindAllByUniverse(universe.ID, ctx)
+	if err != nil {
+		return nil, err
 	}
-
-	return &characters, count, nil*/
-	/*if ctx.Collaborator.Role != models.CollaboratorMember {
-		qs := "LEFT JOIN character_images ON character_images.character_id = characters.id WHERE universe_id = $1"
-		query = fmt.Sprintf("%v %v AND name ILIKE $2 ORDER BY name LIMIT $3 OFFSET $4", query, qs)
-		cquery = fmt.Sprintf("%v %v AND name ILIKE $2", cquery, qs)
-
-		// Retrieve characters
-		if err := s.Providers.DB.Select(
-			&characters,
-			query,
-			universe.ID,
-			ctx.Query,
-			s.Config.CharacterPageLimit,
-			ctx.Page*s.Config.CharacterPageLimit,
-		); err != nil {
-			return nil, 0, err
-		}
-
-		// Retrieve total count
-		if err := s.Providers.DB.Get(&count, cquery, universe.ID, ctx.Query); err != nil {
-			return nil, 0, err
-		}
-	} else {
-		qs := `LEFT JOIN character_images ON character_images.character_id = characters.id WHERE universe_id = $1 AND
-		(meta->'hidden'='false' OR owner_id=$2)`
-		query = fmt.Sprintf("%v %v AND name ILIKE $3 ORDER BY name LIMIT $4 OFFSET $5", query, qs)
-		cquery = fmt.Sprintf("%v %v AND name ILIKE $3", cquery, qs)
-
-		// Retrieve characters
-		if err := s.Providers.DB.Select(
-			&characters,
-			query,
-			universe.ID,
-			ctx.Collaborator.UserID,
-			ctx.Query,
-			s.Config.CharacterPageLimit,
-			ctx.Page*s.Config.CharacterPageLimit,
-		); err != nil {
-			return nil, 0, err
+	images, err := s.Repositories.Image.FindByUniverse(universe.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range characters {
+		characters[i].Images = images[c.ID]
+		if characters[i].Images == nil {
+			characters[i].Images = make(models.CharacterImages)
 		}
-
-		// Retrieve total count
-		if err := s.Providers.DB.Get(&count, cquery, universe.ID, ctx.Collaborator.UserID, ctx.Query); err != nil {
-			return nil, 0, err
+		if ctx.Collaborator.Role == models.CollaboratorMember && c.OwnerID != ctx.Collaborator.UserID {
+			characters[i].HideHiddenFields()
 		}
 	}
-	return &characters, count, nil*/
+	return &characters, nil
 }
 
 // Search returns a selection of characters according to a specified search query
@@ -335,379 +135,186 @@ func (s *Service) Search(
 	squery string,
 	ctx dtos.CharacterQuery,
 ) (*[]models.CharacterReference, int, error) {
-	squery = strings.Replace(squery, " ", "%", -1)
-	var (
-		references []models.CharacterReference
-		count      = 0
-		query      = `SELECT id, name, tag, owner_id, created_at, updated_at, character_images.url AS avatar_url
-		FROM characters WHERE name ILIKE $1 AND universe_id = $2`
-		cquery = `SELECT count(*) FROM characters WHERE name ILIKE $1 AND universe_id = $2`
-	)
-	if ctx.Collaborator.Role != models.CollaboratorMember {
-		query = fmt.Sprintf("%v ORDER BY name LIMIT $3 OFFSET $4 ", query)
-
-		// Retrieve characters
-		if err := s.Providers.DB.Select(
-			&references,
-			query,
-			squery,
-			universe.ID,
-			s.Config.CharacterPageLimit,
-			ctx.Page*s.Config.CharacterPageLimit,
-		); err != nil {
-			return nil, 0, err
-		}
-	} else {
-		query = fmt.Sprintf("%v AND (meta->'hidden'='false' OR owner_id=$3) ORDER BY name LIMIT $4 OFFSET $5", query)
-
-		// Retrieve characters
-		if err := s.Providers.DB.Select(
-			&references,
-			query,
-			squery,
-			universe.ID,
-			ctx.Collaborator.UserID,
-			s.Config.CharacterPageLimit,
-			ctx.Page*s.Config.CharacterPageLimit,
-		); err != nil {
-			return nil, 0, err
-		}
-	}
-	if err := s.Providers.DB.Get(&count, cquery, squery, universe.ID); err != nil {
-		return nil, 0, err
-	}
+	ctx.Query = squery
+	return s.FindByUniverse(universe, ctx)
+}
 
-	return &references, count, nil
+// errCharacterQuota returns the error reported when characters would exceed the character quota of a universe
+func (s *Service) errCharacterQuota() error {
+	return api.ErrQuota(fmt.Sprintf("Universes can hold up to %d characters", s.Config.MaxCharactersPerUniverse))
 }
 
-// Create saves a new character to the database
+// Create saves a new character to the database, within the character quota of its universe
 func (s *Service) Create(
 	universe *models.Universe,
 	character *models.Character,
 	owner *models.User,
 ) (*models.Character, error) {
-	var c models.Character
-	if err := s.Providers.DB.Get(
-		&c,
-		`INSERT INTO characters (id, universe_id, owner_id, name, tag, fields, meta) VALUES
-		($1, $2, $3, $4, $5, $6, $7) RETURNING id, universe_id, name, tag, fields, meta`,
-		character.ID,
+	created, err := s.Repositories.Character.Create(
 		universe.ID,
 		owner.ID,
-		character.Name,
-		character.Tag,
-		character.Fields,
-		character.Meta,
-	); err != nil {
+		s.Config.MaxCharactersPerUniverse,
+		character,
+	)
+	if err == repositories.ErrQuota {
+		return nil, s.errCharacterQuota()
+	}
+	if batchErr, ok := err.(*repositories.BatchError); ok {
+		return nil, batchErr.Err
+	}
+	if err != nil {
 		return nil, err
 	}
+	c := created[0]
 	c.Owner = owner
 	return &c, nil
 }
 
+// CopyAll copies every character of a universe into another, hidden characters included, under a new owner.
+// Images are not copied. Nothing is copied when the characters would exceed the character quota of the target.
+// The number of copied characters is returned
+func (s *Service) CopyAll(source *models.Universe, target *models.Universe, owner *models.User) (int, error) {
+	characters, err := s.Repositories.Character.FindAllByUniverse(source.ID, dtos.CharacterQuery{
+		Collaborator:  &models.Collaborator{Role: models.CollaboratorOwner},
+		IncludeHidden: true,
+	})
+	if err != nil || len(characters) == 0 {
+		return 0, err
+	}
+	copies := make([]*models.Character, len(characters))
+	for i := range characters {
+		characters[i].ID = s.Providers.ShortID.MustGenerate()
+		copies[i] = &characters[i]
+	}
+	_, err = s.Repositories.Character.Create(target.ID, owner.ID, s.Config.MaxCharactersPerUniverse, copies...)
+	if err == repositories.ErrQuota {
+		return 0, s.errCharacterQuota()
+	}
+	if batchErr, ok := err.(*repositories.BatchError); ok {
+		return 0, batchErr.Err
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(copies), nil
+}
+
 // Update updates an existing character in the database
 func (s *Service) Update(character *models.Character) (*models.Character, error) {
-	var c models.Character
-	character.UpdatedAt = time.Now()
-	rows, err := s.Providers.DB.NamedQuery(
-		`UPDATE characters SET name = :name, tag = :tag, fields = :fields, meta = :meta,
-		updated_at = :updated_at WHERE id = :id RETURNING id, name, tag, fields, meta, updated_at, created_at`,
-		character,
-	)
+	c, err := s.Repositories.Character.Update(character)
+	if err == repositories.ErrStale {
+		return nil, api.ErrPrecondition("Character was modified since it was last retrieved")
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		if err := rows.StructScan(&c); err != nil {
-			return nil, err
-		}
-	}
 	images, err := s.FindCharacterImages(character.ID)
 	if err != nil {
 		return nil, err
 	}
 	c.Images = images
+	return c, nil
+}
+
+// Patch applies a JSON Merge Patch or JSON Patch document to the JSON representation of a character,
+// returning the patched character without saving it
+func (s *Service) Patch(
+	character *models.Character,
+	patchType dtos.CharacterPatchType,
+	patch []byte,
+) (*models.Character, error) {
+	original, err := json.Marshal(character)
+	if err != nil {
+		return nil, err
+	}
+	var patched []byte
+	switch patchType {
+	case dtos.CharacterPatchMerge:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case dtos.CharacterPatchJSON:
+		var operations jsonpatch.Patch
+		operations, err = jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, api.ErrBadBody("Failed to parse patch")
+		}
+		patched, err = operations.Apply(original)
+	default:
+		return nil, api.ErrBadBody(fmt.Sprintf("Unsupported patch type '%s'", patchType))
+	}
+	if err != nil {
+		return nil, api.ErrBadBody(fmt.Sprintf("Failed to apply patch: %v", err))
+	}
+	var c models.Character
+	if err := json.Unmarshal(patched, &c); err != nil {
+		return nil, api.ErrBadBody("Patched character is malformed")
+	}
 	return &c, nil
 }
 
-// Validate validates a character according to a universe's guide, and fixes auto-fixable
-// errors if possible and specified
+// newIssue creates a validation issue for a character field
+func newIssue(
+	path string,
+	rule models.ValidationRule,
+	expected interface{},
+	format string,
+	a ...interface{},
+) models.ValidationIssue {
+	return models.ValidationIssue{Path: path, Rule: rule, Expected: expected, Message: fmt.Sprintf(format, a...)}
+}
+
+// validationRange describes the inclusive bounds of a range validation rule
+func validationRange(min interface{}, max interface{}) map[string]interface{} {
+	return map[string]interface{}{"min": min, "max": max}
+}
+
+// Validate validates a character according to a universe's guide, collecting every failed rule,
+// and fixes auto-fixable errors if possible and specified
 func (s *Service) Validate(character *models.Character, universe *models.Universe) error {
+	issues := make([]models.ValidationIssue, 0)
 	for _, group := range *universe.Guide.Groups {
-		if cGroup, ok := character.Fields.Groups[group.Name]; ok {
-			for _, field := range *group.Fields {
-				if cField, ok := cGroup.Fields[field.Name]; ok {
-					if cField.Type != field.Type {
-						return api.ErrBadBody(
-							fmt.Sprintf(
-								"Field '%s' in group '%s' must specify type '%s'",
-								field.Name,
-								group.Name,
-								field.Type,
-							),
-						)
-					}
-					switch field.Type {
-					case models.GuideFieldText:
-						v, ok := cField.Value.(string)
-						if !ok {
-							return api.ErrBadBody(
-								fmt.Sprintf("Field '%s' in group '%s' must be a string", field.Name, group.Name),
-							)
-						}
-						meta, _ := field.Meta.(models.UniverseGuideMetaText)
-						if ok, _ := regexp.Match(meta.Pattern, []byte(v)); !ok {
-							return api.ErrBadBody(
-								fmt.Sprintf(
-									"Field '%s' in group '%s' must match pattern %s",
-									field.Name,
-									group.Name,
-									meta.Pattern,
-								),
-							)
-						}
-						if len(v) < meta.MinLength || len(v) > meta.MaxLength {
-							return api.ErrBadBody(
-								fmt.Sprintf(
-									"Field '%s' in group '%s' must be in range of %d and %d",
-									field.Name,
-									group.Name,
-									meta.MinLength,
-									meta.MaxLength,
-								),
-							)
-						}
-						cField.Value = strings.TrimSpace(v)
-					case models.GuideFieldDescription:
-						v, ok := cField.Value.(string)
-						if !ok {
-							return api.ErrBadBody(
-								fmt.Sprintf("Field '%s' in group '%s' must be a string", field.Name, group.Name),
-							)
-						}
-						meta, _ := field.Meta.(models.UniverseGuideMetaDescription)
-						if len(v) < meta.MinLength || len(v) > meta.MaxLength {
-							return api.ErrBadBody(
-								fmt.Sprintf(
-									"Field '%s' in group '%s' must be in range of %d and %d",
-									field.Name,
-									group.Name,
-									meta.MinLength,
-									meta.MaxLength,
-								),
-							)
-						}
-						cField.Value = strings.TrimSpace(v)
-					case models.GuideFieldNumber:
-						meta, _ := field.Meta.(models.UniverseGuideMetaNumber)
-						if meta.Float {
-							v, ok := cField.Value.(float64)
-							if !ok {
-								return api.ErrBadBody(
-									fmt.Sprintf("Field '%s' in group '%s' must be a float", field.Name, group.Name),
-								)
-							}
-							if v < meta.Min || v > meta.Max {
-								return api.ErrBadBody(
-									fmt.Sprintf(
-										"Field '%s' in group '%s' must be in range of %f and %f",
-										field.Name,
-										group.Name,
-										meta.Min,
-										meta.Max,
-									),
-								)
-							}
-							if meta.Tick != 0 && math.Mod(float64(v), float64(meta.Tick)) != 0 {
-								return api.ErrBadBody(
-									fmt.Sprintf(
-										"Field '%s' in group '%s' must be divisible by %f",
-										field.Name,
-										group.Name,
-										meta.Tick,
-									),
-								)
-							}
-						} else {
-							v, ok := cField.Value.(float64)
-							if !ok || (ok && v != float64(int64(v))) {
-								return api.ErrBadBody(
-									fmt.Sprintf("Field '%s' in group '%s' must be an integer", field.Name, group.Name),
-								)
-							}
-							if int(v) < int(meta.Min) || int(v) > int(meta.Max) {
-								return api.ErrBadBody(
-									fmt.Sprintf(
-										"Field '%s' in group '%s' must be in range of %d and %d",
-										field.Name,
-										group.Name,
-										int(meta.Min),
-										int(meta.Max),
-									),
-								)
-							}
-							if meta.Tick != 0 && math.Mod(v, meta.Tick) != 0 {
-								return api.ErrBadBody(
-									fmt.Sprintf(
-										"Field '%s' in group '%s' must be divisible by %d",
-										field.Name,
-										group.Name,
-										int(meta.Tick),
-									),
-								)
-							}
-						}
-					case models.GuideFieldProgress:
-						v, ok := cField.Value.(float64)
-						fmt.Printf("%v - %T", cField.Value, cField.Value)
-						if !ok {
-							return api.ErrBadBody(
-								fmt.Sprintf("Field '%s' in group '%s' must be a float", field.Name, group.Name),
-							)
-						}
-						meta, _ := field.Meta.(models.UniverseGuideMetaProgress)
-						if v < float64(meta.Min) || v > float64(meta.Max) {
-							return api.ErrBadBody(
-								fmt.Sprintf(
-									"Field '%s' in group '%s' must be in range of %f and %f",
-									field.Name,
-									group.Name,
-									meta.Min,
-									meta.Max,
-								),
-							)
-						}
-						if meta.Tick != 0 && math.Mod(v, meta.Tick) != 0 {
-							return api.ErrBadBody(
-								fmt.Sprintf(
-									"Field '%s' in group '%s' must be divisible by %f",
-									field.Name,
-									group.Name,
-									meta.Tick,
-								),
-							)
-						}
-					case models.GuideFieldOptions:
-						meta, _ := field.Meta.(models.UniverseGuideMetaOptions)
-						if meta.Multiple {
-							v, ok := cField.Value.([]interface{})
-							if !ok {
-								return api.ErrBadBody(
-									fmt.Sprintf(
-										"Field '%s' in group '%s' must be a string list",
-										field.Name,
-										group.Name,
-									),
-								)
-							}
-							l := make([]string, 0)
-							for _, v2 := range v {
-								v2, ok := v2.(string)
-								if !ok {
-									return api.ErrBadBody(
-										fmt.Sprintf(
-											"Field '%s' in group '%s' must be a string list",
-											field.Name,
-											group.Name,
-										),
-									)
-								}
-								if !strInSlice(v2, meta.Options) {
-									return api.ErrBadBody(
-										fmt.Sprintf(
-											"Field '%s' in group '%s' must all be one of %v",
-											field.Name,
-											group.Name,
-											meta.Options,
-										),
-									)
-								}
-								l = append(l, strings.TrimSpace(v2))
-							}
-							cField.Value = l
-						} else {
-							v, ok := cField.Value.(string)
-							if !ok {
-								return api.ErrBadBody(
-									fmt.Sprintf("Field '%s' in group '%s' must be a string", field.Name, group.Name),
-								)
-							}
-							cField.Value = strings.TrimSpace(v)
-							if !strInSlice(v, meta.Options) {
-								return api.ErrBadBody(
-									fmt.Sprintf(
-										"Field '%s' in group '%s' must be one of %v",
-										field.Name,
-										group.Name,
-										meta.Options,
-									),
-								)
-							}
-						}
-					case models.GuideFieldList:
-						v, ok := cField.Value.([]interface{})
-						if !ok {
-							return api.ErrBadBody(
-								fmt.Sprintf("Field '%s' in group '%s' must be a string list", field.Name, group.Name),
-							)
-						}
-						l := make([]string, 0)
-						for _, v2 := range v {
-							vs, ok := v2.(string)
-							if !ok {
-								return api.ErrBadBody(
-									fmt.Sprintf(
-										"Field '%s' in group '%s' must be a string list",
-										field.Name,
-										group.Name,
-									),
-								)
-							}
-							if strings.TrimSpace(vs) == "" {
-								return api.ErrBadBody(
-									fmt.Sprintf(
-										"Field '%s' in group '%s' may not contain empty strings",
-										field.Name,
-										group.Name,
-									),
-								)
-							}
-							l = append(l, strings.TrimSpace(vs))
-						}
-						cField.Value = l
-						meta, _ := field.Meta.(models.UniverseGuideMetaList)
-						if len(v) < meta.MinElements || len(v) > meta.MaxElements {
-							return api.ErrBadBody(
-								fmt.Sprintf(
-									"Field '%s' in group '%s' must contain number of elements in range of %d and %d",
-									field.Name,
-									group.Name,
-									meta.MinElements,
-									meta.MaxElements,
-								),
-							)
-						}
-					}
-				} else {
-					if field.Required {
-						return api.ErrBadBody(
-							fmt.Sprintf("Field '%s' in group '%s' is required", field.Name, group.Name),
-						)
-					}
-				}
-			}
-		} else {
+		gPath := fmt.Sprintf("fields.groups.%s", group.Name)
+		cGroup, ok := character.Fields.Groups[group.Name]
+		if !ok || cGroup == nil {
 			if group.Required {
-				return api.ErrBadBody(
-					fmt.Sprintf("Group '%s' is required", group.Name),
-				)
+				issues = append(issues, newIssue(
+					gPath,
+					models.ValidationRuleRequired,
+					nil,
+					"Group '%s' is required",
+					group.Name,
+				))
+			}
+			continue
+		}
+		for _, field := range *group.Fields {
+			path := fmt.Sprintf("%s.fields.%s", gPath, field.Name)
+			cField, ok := cGroup.Fields[field.Name]
+			if !ok || cField == nil {
+				if field.Required {
+					issues = append(issues, newIssue(
+						path,
+						models.ValidationRuleRequired,
+						nil,
+						"Field '%s' in group '%s' is required",
+						field.Name,
+						group.Name,
+					))
+				}
+				continue
 			}
+			issues = append(issues, validateField(path, group.Name, field, cField)...)
 		}
 	}
 
-	// Ensure no forbidden groups or fields are sent over
-	for i, group := range character.Fields.Groups {
+	// Ensure no forbidden groups or fields are sent over. Names are sorted so that issues are reported in the same
+	// order every time
+	groupNames := make([]string, 0, len(character.Fields.Groups))
+	for name := range character.Fields.Groups {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+	for _, i := range groupNames {
+		group := character.Fields.Groups[i]
 		var hasGroup *models.UniverseGuideGroup
 		for _, uGroup := range *universe.Guide.Groups {
 			if uGroup.Name == i {
@@ -715,107 +322,480 @@ func (s *Service) Validate(character *models.Character, universe *models.Univers
 				break
 			}
 		}
-		if hasGroup != nil {
-			for k := range group.Fields {
-				var hasField bool
-				for _, uField := range *hasGroup.Fields {
-					if uField.Name == k {
-						hasField = true
-					}
+		gPath := fmt.Sprintf("fields.groups.%s", i)
+		if hasGroup == nil {
+			issues = append(issues, newIssue(
+				gPath,
+				models.ValidationRuleUnknown,
+				nil,
+				"Guide does not document provided group '%s'",
+				i,
+			))
+			continue
+		}
+		if group == nil {
+			continue
+		}
+		fieldNames := make([]string, 0, len(group.Fields))
+		for name := range group.Fields {
+			fieldNames = append(fieldNames, name)
+		}
+		sort.Strings(fieldNames)
+		for _, k := range fieldNames {
+			var hasField bool
+			for _, uField := range *hasGroup.Fields {
+				if uField.Name == k {
+					hasField = true
+				}
+			}
+			if !hasField {
+				issues = append(issues, newIssue(
+					fmt.Sprintf("%s.fields.%s", gPath, k),
+					models.ValidationRuleUnknown,
+					nil,
+					"Guide does not document provided field '%s' in group '%s'",
+					k,
+					hasGroup.Name,
+				))
+			}
+		}
+	}
+
+	if len(issues) > 0 {
+		return api.ErrValidation(issues)
+	}
+	return nil
+}
+
+// validateField validates a single character field according to its guide field, returning every failed
+// rule, and fixes auto-fixable errors (e.g. surrounding whitespace) in place
+func validateField(
+	path string,
+	groupName string,
+	field models.UniverseGuideField,
+	cField *models.CharacterField,
+) []models.ValidationIssue {
+	issues := make([]models.ValidationIssue, 0)
+	if cField.Type != field.Type {
+		return append(issues, newIssue(
+			path,
+			models.ValidationRuleType,
+			field.Type,
+			"Field '%s' in group '%s' must specify type '%s'",
+			field.Name,
+			groupName,
+			field.Type,
+		))
+	}
+	typeIssue := func(expected string, article string) []models.ValidationIssue {
+		return append(issues, newIssue(
+			path,
+			models.ValidationRuleType,
+			expected,
+			"Field '%s' in group '%s' must be %s %s",
+			field.Name,
+			groupName,
+			article,
+			expected,
+		))
+	}
+	switch field.Type {
+	case models.GuideFieldText:
+		v, ok := cField.Value.(string)
+		if !ok {
+			return typeIssue("string", "a")
+		}
+		meta, _ := field.Meta.(models.UniverseGuideMetaText)
+		if ok, _ := regexp.Match(meta.Pattern, []byte(v)); !ok {
+			issues = append(issues, newIssue(
+				path,
+				models.ValidationRulePattern,
+				meta.Pattern,
+				"Field '%s' in group '%s' must match pattern %s",
+				field.Name,
+				groupName,
+				meta.Pattern,
+			))
+		}
+		if len(v) < meta.MinLength || len(v) > meta.MaxLength {
+			issues = append(issues, newIssue(
+				path,
+				models.ValidationRuleRange,
+				validationRange(meta.MinLength, meta.MaxLength),
+				"Field '%s' in group '%s' must be in range of %d and %d",
+				field.Name,
+				groupName,
+				meta.MinLength,
+				meta.MaxLength,
+			))
+		}
+		cField.Value = strings.TrimSpace(v)
+	case models.GuideFieldDescription:
+		v, ok := cField.Value.(string)
+		if !ok {
+			return typeIssue("string", "a")
+		}
+		meta, _ := field.Meta.(models.UniverseGuideMetaDescription)
+		if len(v) < meta.MinLength || len(v) > meta.MaxLength {
+			issues = append(issues, newIssue(
+				path,
+				models.ValidationRuleRange,
+				validationRange(meta.MinLength, meta.MaxLength),
+				"Field '%s' in group '%s' must be in range of %d and %d",
+				field.Name,
+				groupName,
+				meta.MinLength,
+				meta.MaxLength,
+			))
+		}
+		cField.Value = strings.TrimSpace(v)
+	case models.GuideFieldNumber:
+		meta, _ := field.Meta.(models.UniverseGuideMetaNumber)
+		if meta.Float {
+			v, ok := cField.Value.(float64)
+			if !ok {
+				return typeIssue("float", "a")
+			}
+			if v < meta.Min || v > meta.Max {
+				issues = append(issues, newIssue(
+					path,
+					models.ValidationRuleRange,
+					validationRange(meta.Min, meta.Max),
+					"Field '%s' in group '%s' must be in range of %f and %f",
+					field.Name,
+					groupName,
+					meta.Min,
+					meta.Max,
+				))
+			}
+			if meta.Tick != 0 && math.Mod(float64(v), float64(meta.Tick)) != 0 {
+				issues = append(issues, newIssue(
+					path,
+					models.ValidationRuleStep,
+					meta.Tick,
+					"Field '%s' in group '%s' must be divisible by %f",
+					field.Name,
+					groupName,
+					meta.Tick,
+				))
+			}
+		} else {
+			v, ok := cField.Value.(float64)
+			if !ok || (ok && v != float64(int64(v))) {
+				return typeIssue("integer", "an")
+			}
+			if int(v) < int(meta.Min) || int(v) > int(meta.Max) {
+				issues = append(issues, newIssue(
+					path,
+					models.ValidationRuleRange,
+					validationRange(int(meta.Min), int(meta.Max)),
+					"Field '%s' in group '%s' must be in range of %d and %d",
+					field.Name,
+					groupName,
+					int(meta.Min),
+					int(meta.Max),
+				))
+			}
+			if meta.Tick != 0 && math.Mod(v, meta.Tick) != 0 {
+				issues = append(issues, newIssue(
+					path,
+					models.ValidationRuleStep,
+					int(meta.Tick),
+					"Field '%s' in group '%s' must be divisible by %d",
+					field.Name,
+					groupName,
+					int(meta.Tick),
+				))
+			}
+		}
+	case models.GuideFieldToggle:
+		if _, ok := cField.Value.(bool); !ok {
+			return typeIssue("boolean", "a")
+		}
+	case models.GuideFieldProgress:
+		v, ok := cField.Value.(float64)
+		if !ok {
+			return typeIssue("float", "a")
+		}
+		meta, _ := field.Meta.(models.UniverseGuideMetaProgress)
+		if v < float64(meta.Min) || v > float64(meta.Max) {
+			issues = append(issues, newIssue(
+				path,
+				models.ValidationRuleRange,
+				validationRange(meta.Min, meta.Max),
+				"Field '%s' in group '%s' must be in range of %f and %f",
+				field.Name,
+				groupName,
+				meta.Min,
+				meta.Max,
+			))
+		}
+		if meta.Tick != 0 && math.Mod(v, meta.Tick) != 0 {
+			issues = append(issues, newIssue(
+				path,
+				models.ValidationRuleStep,
+				meta.Tick,
+				"Field '%s' in group '%s' must be divisible by %f",
+				field.Name,
+				groupName,
+				meta.Tick,
+			))
+		}
+	case models.GuideFieldOptions:
+		meta, _ := field.Meta.(models.UniverseGuideMetaOptions)
+		if meta.Multiple {
+			v, ok := cField.Value.([]interface{})
+			if !ok {
+				return typeIssue("string list", "a")
+			}
+			l := make([]string, 0)
+			for _, v2 := range v {
+				v2, ok := v2.(string)
+				if !ok {
+					return typeIssue("string list", "a")
 				}
-				if !hasField {
-					return api.ErrBadBody(
-						fmt.Sprintf("Guide does not document provided field '%s' in group '%s'", k, hasGroup.Name),
-					)
+				l = append(l, strings.TrimSpace(v2))
+			}
+			for _, v2 := range l {
+				if !strInSlice(v2, meta.Options) {
+					issues = append(issues, newIssue(
+						path,
+						models.ValidationRuleOptions,
+						meta.Options,
+						"Field '%s' in group '%s' must all be one of %v",
+						field.Name,
+						groupName,
+						meta.Options,
+					))
+					break
 				}
 			}
+			cField.Value = l
 		} else {
-			return api.ErrBadBody(
-				fmt.Sprintf("Guide does not document provided group '%s'", i),
-			)
+			v, ok := cField.Value.(string)
+			if !ok {
+				return typeIssue("string", "a")
+			}
+			cField.Value = strings.TrimSpace(v)
+			if !strInSlice(v, meta.Options) {
+				issues = append(issues, newIssue(
+					path,
+					models.ValidationRuleOptions,
+					meta.Options,
+					"Field '%s' in group '%s' must be one of %v",
+					field.Name,
+					groupName,
+					meta.Options,
+				))
+			}
+		}
+	case models.GuideFieldList:
+		v, ok := cField.Value.([]interface{})
+		if !ok {
+			return typeIssue("string list", "a")
+		}
+		l := make([]string, 0)
+		for _, v2 := range v {
+			vs, ok := v2.(string)
+			if !ok {
+				return typeIssue("string list", "a")
+			}
+			if strings.TrimSpace(vs) == "" {
+				issues = append(issues, newIssue(
+					path,
+					models.ValidationRuleRequired,
+					nil,
+					"Field '%s' in group '%s' may not contain empty strings",
+					field.Name,
+					groupName,
+				))
+				break
+			}
+			l = append(l, strings.TrimSpace(vs))
+		}
+		cField.Value = l
+		meta, _ := field.Meta.(models.UniverseGuideMetaList)
+		if len(v) < meta.MinElements || len(v) > meta.MaxElements {
+			issues = append(issues, newIssue(
+				path,
+				models.ValidationRuleRange,
+				validationRange(meta.MinElements, meta.MaxElements),
+				"Field '%s' in group '%s' must contain number of elements in range of %d and %d",
+				field.Name,
+				groupName,
+				meta.MinElements,
+				meta.MaxElements,
+			))
 		}
 	}
-	return nil
+	return issues
 }
 
-// Delete deletes a character
+// Delete deletes a character, queueing the files of their images to be deleted in the background
 func (s *Service) Delete(character *models.Character) error {
-	if _, err := s.Providers.DB.Exec(`DELETE FROM characters WHERE id=$1`, character.ID); err != nil {
+	keys, err := s.Repositories.Image.FindFiles(character.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.Repositories.Character.Delete(character.ID); err != nil {
 		return err
 	}
+	api.EnqueueFileDeletion(s.Providers, s.Repositories, character.UniverseID, keys)
 	return nil
 }
 
-// SetImage assigns an image to a character
-func (s *Service) SetImage(character *models.Character, key string, image io.Reader) error {
-	path := fmt.Sprintf("%s_%s", character.ID, key)
+// QueueImage checks that an uploaded image is in an accepted format, stores it until it is processed and queues it
+// to be assigned to a character in the background through SetUploadedImage
+func (s *Service) QueueImage(
+	character *models.Character,
+	key string,
+	image io.Reader,
+	creator *models.User,
+) (*models.Job, error) {
+	data, err := ioutil.ReadAll(image)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := imageFormat(data); err != nil {
+		return nil, err
+	}
+	id := s.Providers.ShortID.MustGenerate()
+	upload := repositories.UploadPrefix + id
+	if err := s.Repositories.Image.SaveUpload(upload, data); err != nil {
+		return nil, err
+	}
+	job, err := models.NewJob(
+		id,
+		models.JobImageProcess,
+		character.UniverseID,
+		creator.ID,
+		imageJob{CharacterID: character.ID, Key: key, Upload: upload},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return job, api.EnqueueJob(s.Repositories, job)
+}
 
-	optimized, err := s.optimizeImage(image)
+// SetUploadedImage assigns an image stored by QueueImage to a character through SetImage, then removes the upload
+func (s *Service) SetUploadedImage(character *models.Character, key string, upload string) error {
+	data, err := s.Repositories.Image.FindUpload(upload)
+	if err == repositories.ErrNotFound {
+		return api.ErrBadBody("The uploaded image is no longer stored")
+	}
 	if err != nil {
 		return err
 	}
+	if err := s.SetImage(character, key, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	return s.Repositories.Image.DeleteFile(upload)
+}
 
-	url, err := s.Providers.Storage.Upload(optimized, path)
+// SetImage assigns an image to a character, storing a variant of it for each of ImageWidths within the image
+// quota of its universe. The variants of the image it replaces, if any, no longer count against the quota
+func (s *Service) SetImage(character *models.Character, key string, image io.Reader) error {
+	variants, err := processImage(image)
 	if err != nil {
 		return err
 	}
-	if _, err := s.Providers.DB.Exec(
-		`INSERT INTO character_images (character_id, key, url) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
-		character.ID,
-		key,
-		url,
+	added := 0
+	for _, variant := range variants {
+		added += len(variant.Data)
+	}
+	used, err := s.Repositories.Image.SizeByUniverse(character.UniverseID)
+	if err != nil {
+		return err
+	}
+	replaced, err := s.Repositories.Image.Size(character.ID, key)
+	if err != nil {
+		return err
+	}
+	if err := api.CheckQuota(
+		int(used-replaced),
+		added,
+		s.Config.MaxImageBytesPerUniverse,
+		fmt.Sprintf("Universes can store up to %d bytes of images", s.Config.MaxImageBytesPerUniverse),
 	); err != nil {
 		return err
 	}
-	return nil
+	return s.Repositories.Image.Save(character.ID, key, variants)
 }
 
 // DeleteImage removes an image associated with a character
 func (s *Service) DeleteImage(character *models.Character, key string) error {
-	path := fmt.Sprintf("%v_%v", character.ID, key)
-	if err := s.Providers.Storage.Delete(path); err != nil {
-		return err
+	return s.Repositories.Image.Delete(character.ID, key)
+}
+
+// ReconcileImages brings the stored image files and the records of character images back in sync, deleting the
+// records of files that are no longer stored and the files that no longer belong to a character, such as those
+// left behind by failed deletions. Files newer than minAge are kept since they may belong to a character still
+// being saved. Records saved before image sizes were recorded are given the size of their files, so they count
+// towards image quotas, and uploads older than UploadRetention are deleted. Nothing is changed when dryRun is set,
+// and the reconciliation reports what was or would be
+func (s *Service) ReconcileImages(minAge time.Duration, dryRun bool) (*models.ImageReconciliation, error) {
+	missing, err := s.Repositories.Image.FindMissing()
+	if err != nil {
+		return nil, err
 	}
-	if _, err := s.Providers.DB.Exec(
-		`DELETE FROM character_images WHERE character_id=$1 AND key=$2`,
-		character.ID,
-		key,
-	); err != nil {
-		return err
+	orphaned, err := s.Repositories.Image.FindOrphaned(minAge)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	unsized, err := s.Repositories.Image.FindUnsized()
+	if err != nil {
+		return nil, err
+	}
+	expired, err := s.Repositories.Image.FindUploads(UploadRetention)
+	if err != nil {
+		return nil, err
+	}
+	reconciliation := &models.ImageReconciliation{
+		OrphanedFiles:  orphaned,
+		MissingFiles:   missing,
+		UnsizedFiles:   unsized,
+		ExpiredUploads: expired,
+		DryRun:         dryRun,
+	}
+	if dryRun {
+		return reconciliation, nil
+	}
+	for _, record := range missing {
+		if err := s.Repositories.Image.DeleteRecord(record); err != nil {
+			return nil, err
+		}
+	}
+	for _, record := range unsized {
+		if err := s.Repositories.Image.SetSize(record); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.DeleteFiles(append(orphaned, expired...)); err != nil {
+		return nil, err
+	}
+	return reconciliation, nil
 }
 
-// DeleteAll deletes all characters from a specified universe
+// DeleteAll deletes all characters from a specified universe, queueing the files of their images to be deleted
+// in the background
 func (s *Service) DeleteAll(universe *models.Universe) error {
-	var ids []models.Character
-	if err := s.Providers.DB.Select(
-		&ids,
-		`SELECT id FROM characters WHERE universe_id = $1`,
-		universe.ID,
-	); err != nil {
+	keys, err := s.Repositories.Image.FindFilesByUniverse(universe.ID)
+	if err != nil {
 		return err
 	}
-	for _, id := range ids {
-		s.DeleteImage(&id, "avatar")
-	}
-	if _, err := s.Providers.DB.Exec(`DELETE FROM characters WHERE universe_id = $1`, universe.ID); err != nil {
+	if err := s.Repositories.Character.DeleteByUniverse(universe.ID); err != nil {
 		return err
 	}
+	api.EnqueueFileDeletion(s.Providers, s.Repositories, universe.ID, keys)
 	return nil
 }
 
-func (s *Service) optimizeImage(file io.Reader) (io.Reader, error) {
-	buff := new(bytes.Buffer)
-	img, _, err := image.Decode(file)
-	if err != nil {
-		return nil, err
-	}
-
-	dstImg := imaging.Thumbnail(img, AvatarSize, AvatarSize, imaging.CatmullRom)
-	if err := jpeg.Encode(buff, dstImg, nil); err != nil {
-		return nil, err
+// DeleteFiles deletes stored image files by their keys. Files that no longer exist are not an error
+func (s *Service) DeleteFiles(keys []string) error {
+	for _, key := range keys {
+		if err := s.Repositories.Image.DeleteFile(key); err != nil {
+			return err
+		}
 	}
-
-	return bytes.NewReader(buff.Bytes()), nil
+	return nil
 }
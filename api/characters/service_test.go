@@ -0,0 +1,161 @@
+package characters
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"strings"
+	"testing"
+)
+
+func TestService_Validate(t *testing.T) {
+	universe := &models.Universe{
+		Guide: &models.UniverseGuide{
+			Groups: &[]models.UniverseGuideGroup{
+				{
+					Name: "General",
+					Fields: &[]models.UniverseGuideField{
+						{
+							Name:     "Biography",
+							Type:     models.GuideFieldDescription,
+							Required: true,
+							Meta:     models.UniverseGuideMetaDescription{MinLength: 1, MaxLength: 10},
+						},
+						{
+							Name: "Age",
+							Type: models.GuideFieldNumber,
+							Meta: models.UniverseGuideMetaNumber{Min: 0, Max: 100},
+						},
+						{
+							Name: "Class",
+							Type: models.GuideFieldOptions,
+							Meta: models.UniverseGuideMetaOptions{Options: []string{"Mage", "Rogue"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	character := &models.Character{
+		Fields: &models.CharacterFields{
+			Groups: map[string]*models.CharacterFieldGroup{
+				"General": {
+					Fields: map[string]*models.CharacterField{
+						"Age":    {Value: float64(200), Type: models.GuideFieldNumber},
+						"Class":  {Value: "Bard", Type: models.GuideFieldOptions},
+						"Height": {Value: float64(170), Type: models.GuideFieldNumber},
+						"Build":  {Value: "Lean", Type: models.GuideFieldOptions},
+					},
+				},
+				"Skills":     {},
+				"Appearance": {},
+			},
+		},
+	}
+	want := map[string]models.ValidationRule{
+		"fields.groups.General.fields.Biography": models.ValidationRuleRequired,
+		"fields.groups.General.fields.Age":       models.ValidationRuleRange,
+		"fields.groups.General.fields.Class":     models.ValidationRuleOptions,
+		"fields.groups.General.fields.Height":    models.ValidationRuleUnknown,
+		"fields.groups.General.fields.Build":     models.ValidationRuleUnknown,
+		"fields.groups.Appearance":               models.ValidationRuleUnknown,
+		"fields.groups.Skills":                   models.ValidationRuleUnknown,
+	}
+	err := (&Service{}).Validate(character, universe)
+	apierr, ok := err.(api.Error)
+	if !ok {
+		t.Fatalf("got error %v; want api error", err)
+	}
+	got := make(map[string]models.ValidationRule)
+	unknown := make([]string, 0)
+	for _, issue := range apierr.Issues {
+		got[issue.Path] = issue.Rule
+		if issue.Rule == models.ValidationRuleUnknown {
+			unknown = append(unknown, issue.Path)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d issues; want %d", len(got), len(want))
+	}
+	for path, rule := range want {
+		if got[path] != rule {
+			t.Errorf("got rule %q for %v; want %q", got[path], path, rule)
+		}
+	}
+
+	// Unknown groups and fields are reported in order of their names
+	wantUnknown := []string{
+		"fields.groups.Appearance",
+		"fields.groups.General.fields.Build",
+		"fields.groups.General.fields.Height",
+		"fields.groups.Skills",
+	}
+	if strings.Join(unknown, ",") != strings.Join(wantUnknown, ",") {
+		t.Errorf("got unknown issues %v; want %v", unknown, wantUnknown)
+	}
+}
+
+func TestService_Patch(t *testing.T) {
+	tests := []struct {
+		name      string
+		patchType dtos.CharacterPatchType
+		patch     string
+		want      interface{}
+		wanterr   bool
+	}{
+		{
+			name:      "merge patch",
+			patchType: dtos.CharacterPatchMerge,
+			patch:     `{"fields":{"groups":{"General":{"fields":{"Health":{"value":40}}}}}}`,
+			want:      float64(40),
+		},
+		{
+			name:      "json patch",
+			patchType: dtos.CharacterPatchJSON,
+			patch:     `[{"op":"replace","path":"/fields/groups/General/fields/Health/value","value":25}]`,
+			want:      float64(25),
+		},
+		{
+			name:      "failed json patch test",
+			patchType: dtos.CharacterPatchJSON,
+			patch:     `[{"op":"test","path":"/name","value":"Someone else"}]`,
+			wanterr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			character := &models.Character{
+				ID:   "character",
+				Name: "Mira",
+				Fields: &models.CharacterFields{
+					Groups: map[string]*models.CharacterFieldGroup{
+						"General": {
+							Fields: map[string]*models.CharacterField{
+								"Health":    {Value: float64(80), Type: models.GuideFieldProgress},
+								"Biography": {Value: "A rogue", Type: models.GuideFieldDescription},
+							},
+						},
+					},
+				},
+				Meta: &models.CharacterMeta{},
+			}
+			patched, err := (&Service{}).Patch(character, tt.patchType, []byte(tt.patch))
+			if tt.wanterr {
+				if _, ok := err.(api.Error); !ok {
+					t.Fatalf("got error %v; want api error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v; want nil", err)
+			}
+			fields := patched.Fields.Groups["General"].Fields
+			if fields["Health"].Value != tt.want {
+				t.Errorf("got health %v; want %v", fields["Health"].Value, tt.want)
+			}
+			if fields["Biography"].Value != "A rogue" || patched.Name != "Mira" {
+				t.Errorf("patch modified untouched values")
+			}
+		})
+	}
+}
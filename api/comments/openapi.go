@@ -0,0 +1,95 @@
+package comments
+
+import (
+	"cbs/api/openapi"
+	"cbs/dtos"
+	"net/http"
+)
+
+// Operations describes the routes of the "comments" resource for the OpenAPI document
+var Operations = []openapi.Operation{
+	{
+		Method:  http.MethodGet,
+		Path:    "/",
+		Summary: "List the comment threads of a character",
+		Description: "Comments on a hidden character are only visible to those who can view the character. Replies " +
+			"are listed with the thread they belong to",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Parameters: []openapi.Parameter{{
+			Name:        "resolved",
+			In:          "query",
+			Description: "Only list resolved threads when true, or open threads when false",
+			Schema:      openapi.BooleanSchema(),
+		}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The threads, oldest first",
+			Content:     []openapi.Content{{Body: dtos.ResGetComments{}}},
+		}},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/",
+		Summary: "Comment on a character",
+		Description: "Comments with a parent reply to its thread. Collaborators are mentioned with \"@name\" in " +
+			"the body, matching their display names, and must be able to view the character. The IDs of the " +
+			"mentioned collaborators are sent along with the comment.created event",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Body:    []openapi.Content{{Body: dtos.ReqCreateComment{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusCreated,
+			Description: "The created comment",
+			Content:     []openapi.Content{{Body: dtos.ResGetComment{}}},
+		}},
+	},
+	{
+		Method:      http.MethodPatch,
+		Path:        "/{commentID}",
+		Summary:     "Edit the body of a comment",
+		Description: "Members can only edit their own comments. Mentions are read again from the new body",
+		Session:     true,
+		Role:        openapi.RoleMember,
+		Body:        []openapi.Content{{Body: dtos.ReqEditComment{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The edited comment",
+			Content:     []openapi.Content{{Body: dtos.ResGetComment{}}},
+		}},
+	},
+	{
+		Method:      http.MethodDelete,
+		Path:        "/{commentID}",
+		Summary:     "Delete a comment",
+		Description: "Members can only delete their own comments. Deleting a thread deletes its replies",
+		Session:     true,
+		Role:        openapi.RoleMember,
+		Responses:   []openapi.Response{{Status: http.StatusNoContent, Description: "The comment was deleted"}},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/{commentID}/resolve",
+		Summary:     "Resolve a thread",
+		Description: "Only the comment starting a thread can be resolved",
+		Session:     true,
+		Role:        openapi.RoleMember,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The resolved comment",
+			Content:     []openapi.Content{{Body: dtos.ResGetComment{}}},
+		}},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/{commentID}/unresolve",
+		Summary: "Reopen a resolved thread",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The reopened comment",
+			Content:     []openapi.Content{{Body: dtos.ResGetComment{}}},
+		}},
+	},
+}
@@ -0,0 +1,195 @@
+package comments
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// Router represents a router for the "comments" resource
+type Router api.Router
+
+// NewRouter creates a new router assigned to the "comments" resource, through which collaborators discuss a
+// character in threads
+func NewRouter(server *api.Server) *Router {
+	router := &Router{
+		Mux:    chi.NewMux(),
+		Server: server}
+	router.Use(
+		server.Middlewares.UserSession,
+		server.Middlewares.Universe,
+		server.Middlewares.Collaborator(models.CollaboratorMember),
+		server.Middlewares.Character,
+		viewable,
+	)
+	router.Get("/", api.Handler(router.GetComments).ServeHTTP)
+	router.Post("/", api.Handler(router.CreateComment).ServeHTTP)
+	router.Patch("/{commentID}", api.Handler(router.EditComment).ServeHTTP)
+	router.Delete("/{commentID}", api.Handler(router.DeleteComment).ServeHTTP)
+	router.Post("/{commentID}/resolve", api.Handler(router.ResolveComment).ServeHTTP)
+	router.Post("/{commentID}/unresolve", api.Handler(router.UnresolveComment).ServeHTTP)
+	return router
+}
+
+// viewable rejects requests for characters of other universes and for characters the collaborator cannot view,
+// following the checks of the characters router
+func viewable(next http.Handler) http.Handler {
+	return api.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+		collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+		character, _ := r.Context().Value(api.CharacterContextKey).(*models.Character)
+		if character.UniverseID != universe.ID {
+			return api.ErrNotFound("Character not found")
+		}
+		if !collaborator.CanViewCharacter(character.Meta.Hidden, character.Owner.ID) {
+			return api.ErrBadAuth("You do not have permission to view this character")
+		}
+		next.ServeHTTP(w, r)
+		return nil
+	})
+}
+
+// canEdit reports whether a collaborator is allowed to edit and delete a comment. Members can only edit their own
+// comments
+func canEdit(collaborator *models.Collaborator, comment *models.Comment) bool {
+	return collaborator.Role != models.CollaboratorMember || collaborator.UserID == comment.Author.ID
+}
+
+// findComment returns the comment of a request
+func (m *Router) findComment(r *http.Request) (*models.Comment, error) {
+	character, _ := r.Context().Value(api.CharacterContextKey).(*models.Character)
+	return m.Services.Comment.FindByID(character, chi.URLParam(r, "commentID"))
+}
+
+// GetComments represents a route that returns the comment threads of a character. Only resolved or only open
+// threads are returned when the "resolved" parameter is set
+func (m *Router) GetComments(w http.ResponseWriter, r *http.Request) error {
+	character, _ := r.Context().Value(api.CharacterContextKey).(*models.Character)
+	var resolved *bool
+	if value := r.URL.Query().Get("resolved"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return api.ErrBadBody("Resolved must be true or false")
+		}
+		resolved = &parsed
+	}
+	threads, err := m.Services.Comment.FindByCharacter(character, resolved)
+	if err != nil {
+		return err
+	}
+	api.SendResponse(w, dtos.ResGetComments{Threads: threads}, http.StatusOK)
+	return nil
+}
+
+// CreateComment represents a route that comments on a character, either starting a thread or replying to one
+func (m *Router) CreateComment(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	character, _ := r.Context().Value(api.CharacterContextKey).(*models.Character)
+	var payload dtos.ReqCreateComment
+	if err := api.ReadAndValidateBody(r.Body, &payload); err != nil {
+		return err
+	}
+	comment, err := m.Services.Comment.New(universe, character, user, payload)
+	if err != nil {
+		return err
+	}
+	if err := m.Services.Comment.Create(comment); err != nil {
+		return err
+	}
+	m.publish(r, models.EventCommentCreated, comment)
+	api.SendResponse(w, dtos.ResGetComment{Comment: comment}, http.StatusCreated)
+	return nil
+}
+
+// publish publishes an event about a comment on the character of a request. Events carry the character so that
+// comments on hidden characters only reach those who can view them
+func (m *Router) publish(r *http.Request, eventType models.UniverseEventType, comment *models.Comment) {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	character, _ := r.Context().Value(api.CharacterContextKey).(*models.Character)
+	m.PublishEvent(&models.UniverseEvent{
+		Type:       eventType,
+		UniverseID: universe.ID,
+		ActorID:    user.ID,
+		Character:  character,
+		Comment:    comment,
+	})
+}
+
+// EditComment represents a route that changes the body of a comment, along with the collaborators it mentions
+func (m *Router) EditComment(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	character, _ := r.Context().Value(api.CharacterContextKey).(*models.Character)
+	comment, err := m.findComment(r)
+	if err != nil {
+		return err
+	}
+	if !canEdit(collaborator, comment) {
+		return api.ErrBadAuth("You do not have permission to edit this comment")
+	}
+	var payload dtos.ReqEditComment
+	if err := api.ReadAndValidateBody(r.Body, &payload); err != nil {
+		return err
+	}
+	if err := m.Services.Comment.Update(universe, character, comment, payload); err != nil {
+		return err
+	}
+	m.publish(r, models.EventCommentUpdated, comment)
+	api.SendResponse(w, dtos.ResGetComment{Comment: comment}, http.StatusOK)
+	return nil
+}
+
+// DeleteComment represents a route that removes a comment, along with its replies when it starts a thread
+func (m *Router) DeleteComment(w http.ResponseWriter, r *http.Request) error {
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	comment, err := m.findComment(r)
+	if err != nil {
+		return err
+	}
+	if !canEdit(collaborator, comment) {
+		return api.ErrBadAuth("You do not have permission to delete this comment")
+	}
+	if err := m.Services.Comment.Delete(comment); err != nil {
+		return err
+	}
+	m.publish(r, models.EventCommentDeleted, comment)
+	w.WriteHeader(http.StatusNoContent)
+	w.Write([]byte(""))
+	return nil
+}
+
+// ResolveComment represents a route that marks a thread as resolved. Anyone taking part in the discussion can
+// resolve it
+func (m *Router) ResolveComment(w http.ResponseWriter, r *http.Request) error {
+	return m.resolve(w, r, true)
+}
+
+// UnresolveComment represents a route that reopens a resolved thread
+func (m *Router) UnresolveComment(w http.ResponseWriter, r *http.Request) error {
+	return m.resolve(w, r, false)
+}
+
+// resolve resolves or reopens the thread of a request and responds with it
+func (m *Router) resolve(w http.ResponseWriter, r *http.Request, resolved bool) error {
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	comment, err := m.findComment(r)
+	if err != nil {
+		return err
+	}
+	if err := m.Services.Comment.Resolve(comment, user, resolved); err != nil {
+		return err
+	}
+	eventType := models.EventCommentUnresolved
+	if resolved {
+		eventType = models.EventCommentResolved
+	}
+	m.publish(r, eventType, comment)
+	api.SendResponse(w, dtos.ResGetComment{Comment: comment}, http.StatusOK)
+	return nil
+}
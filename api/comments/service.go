@@ -0,0 +1,165 @@
+package comments
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"cbs/repositories"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Service represents a service implementation for the "comments" resource
+type Service api.Service
+
+// mentionPattern matches the "@name" tokens of a comment body. Tokens must start the body or follow whitespace so
+// that email addresses are not taken for mentions
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@(\S+)`)
+
+// mentions resolves the "@name" tokens of a comment body to the IDs of the collaborators whose display names they
+// match, ignoring case and trailing punctuation. Tokens that do not name a collaborator are left as plain text, but
+// mentioning a collaborator who cannot view the character is an error so that nobody is pointed to a discussion
+// they cannot read
+func (s *Service) mentions(
+	universe *models.Universe,
+	character *models.Character,
+	body string,
+) (models.CommentMentions, error) {
+	mentions := models.CommentMentions{}
+	matches := mentionPattern.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		return mentions, nil
+	}
+	collaborators, err := s.Repositories.Collaborator.FindByUniverse(universe.ID)
+	if err != nil {
+		return nil, err
+	}
+	mentioned := make(map[string]bool)
+	for _, match := range matches {
+		name := strings.TrimRight(match[1], ".,;:!?)")
+		for _, collaborator := range collaborators {
+			collaborator.UserID = collaborator.User.ID
+			if mentioned[collaborator.UserID] || !strings.EqualFold(collaborator.User.DisplayName, name) {
+				continue
+			}
+			if !collaborator.CanViewCharacter(character.Meta.Hidden, character.Owner.ID) {
+				return nil, api.ErrBadBody(fmt.Sprintf("User '%s' cannot view this character", name))
+			}
+			mentioned[collaborator.UserID] = true
+			mentions = append(mentions, collaborator.UserID)
+		}
+	}
+	return mentions, nil
+}
+
+// New creates a new comment on a character. Replies to a reply are added to the thread of the comment it replies
+// to, so threads are a single level deep
+func (s *Service) New(
+	universe *models.Universe,
+	character *models.Character,
+	author *models.User,
+	data dtos.ReqCreateComment,
+) (*models.Comment, error) {
+	mentions, err := s.mentions(universe, character, data.Body)
+	if err != nil {
+		return nil, err
+	}
+	comment := &models.Comment{
+		ID:          s.Providers.ShortID.MustGenerate(),
+		UniverseID:  universe.ID,
+		CharacterID: character.ID,
+		Author:      author,
+		Body:        data.Body,
+		Mentions:    mentions,
+	}
+	if data.ParentID != "" {
+		parent, err := s.FindByID(character, data.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		comment.ParentID = &parent.ID
+		if parent.ParentID != nil {
+			comment.ParentID = parent.ParentID
+		}
+	}
+	return comment, nil
+}
+
+// FindByID returns a comment on a character by its ID
+func (s *Service) FindByID(character *models.Character, id string) (*models.Comment, error) {
+	comment, err := s.Repositories.Comment.FindByID(character.ID, id)
+	if err == repositories.ErrNotFound {
+		return nil, api.ErrNotFound("Comment not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// FindByCharacter returns the comment threads of a character, oldest first. Only resolved or only open threads
+// are returned when resolved is set
+func (s *Service) FindByCharacter(character *models.Character, resolved *bool) (*[]models.CommentThread, error) {
+	comments, err := s.Repositories.Comment.FindByCharacter(character.ID)
+	if err != nil {
+		return nil, err
+	}
+	threads := make([]models.CommentThread, 0)
+	positions := make(map[string]int)
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			if resolved == nil || comment.Resolved() == *resolved {
+				positions[comment.ID] = len(threads)
+				threads = append(threads, models.CommentThread{Comment: comment, Replies: make([]models.Comment, 0)})
+			}
+			continue
+		}
+		if i, ok := positions[*comment.ParentID]; ok {
+			threads[i].Replies = append(threads[i].Replies, comment)
+		}
+	}
+	return &threads, nil
+}
+
+// Create saves a new comment
+func (s *Service) Create(comment *models.Comment) error {
+	return s.Repositories.Comment.Create(comment)
+}
+
+// Update changes the body of a comment, along with the collaborators it mentions
+func (s *Service) Update(
+	universe *models.Universe,
+	character *models.Character,
+	comment *models.Comment,
+	data dtos.ReqEditComment,
+) error {
+	mentions, err := s.mentions(universe, character, data.Body)
+	if err != nil {
+		return err
+	}
+	comment.Body = data.Body
+	comment.Mentions = mentions
+	return s.Repositories.Comment.Update(comment)
+}
+
+// Resolve marks a thread as resolved by a user, or reopens it. Replies are resolved along with their thread
+func (s *Service) Resolve(comment *models.Comment, user *models.User, resolved bool) error {
+	if comment.ParentID != nil {
+		return api.ErrBadBody("Only the comment starting a thread can be resolved")
+	}
+	comment.ResolvedBy = nil
+	comment.ResolvedAt = nil
+	if resolved {
+		now := time.Now()
+		comment.ResolvedBy = &user.ID
+		comment.ResolvedAt = &now
+	}
+	return s.Repositories.Comment.Resolve(comment)
+}
+
+// Delete removes a comment. Removing the comment starting a thread removes its replies too
+func (s *Service) Delete(comment *models.Comment) error {
+	return s.Repositories.Comment.Delete(comment.ID)
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// EnvPrefix represents the prefix of the environment variables that override configuration keys. Every key can
+// be set through CBS_<KEY> (e.g. CBS_DATABASE_URL) or read from a file through CBS_<KEY>_FILE
+const EnvPrefix = "CBS_"
+
+// DefaultConfig returns the configuration used for every key that is not set
+func DefaultConfig() *Config {
+	return &Config{
+		Host:               "0.0.0.0",
+		Port:               8080,
+		DatabaseURL:        "postgres://localhost:5432/characterbase?sslmode=disable",
+		RedisURL:           "localhost:6379",
+		MaxSessionAge:      "720h",
+		CharacterPageLimit: 25,
+		LogLevel:           "info",
+		LogFormat:          "json",
+		ReadHeaderTimeout:  DefaultReadHeaderTimeout,
+		ReadTimeout:        DefaultReadTimeout,
+		WriteTimeout:       DefaultWriteTimeout,
+		IdleTimeout:        DefaultIdleTimeout,
+		ShutdownTimeout:    DefaultShutdownTimeout,
+
+		JobWorkers: 2,
+
+		MaxUniversesPerUser:         100,
+		MaxCharactersPerUniverse:    10000,
+		MaxCollaboratorsPerUniverse: 100,
+		MaxGuideGroups:              50,
+		MaxGuideFields:              500,
+		MaxImageBytesPerUniverse:    1 << 30,
+	}
+}
+
+// LoadConfig layers the YAML configuration file at path and the environment on top of the defaults, then
+// validates the result. The file is skipped when path is empty. Unknown keys in the file are ignored so that
+// existing files keep working as keys are removed, and are reported by UnknownKeys
+func LoadConfig(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	config := DefaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read configuration file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("failed to parse configuration file: %w", err)
+		}
+		if config.unknownKeys, err = unknownKeys(data); err != nil {
+			return nil, fmt.Errorf("failed to parse configuration file: %w", err)
+		}
+	}
+	if err := config.applyEnv(lookupEnv); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// UnknownKeys returns the keys of the configuration file that are not configuration keys, in the order they
+// appear in, such as misspelled keys or keys that were removed
+func (c *Config) UnknownKeys() []string {
+	return c.unknownKeys
+}
+
+// unknownKeys returns the top-level keys of a YAML configuration file that are not configuration keys
+func unknownKeys(data []byte) ([]string, error) {
+	var keys yaml.MapSlice
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("yaml"); key != "" {
+			known[key] = true
+		}
+	}
+	unknown := make([]string, 0)
+	for _, item := range keys {
+		if key := fmt.Sprint(item.Key); !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	return unknown, nil
+}
+
+// applyEnv overrides every key that is set in the environment
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("yaml")
+		if key == "" {
+			continue
+		}
+		name := EnvPrefix + strings.ToUpper(key)
+		value, ok := lookupEnv(name)
+		if path, fromFile := lookupEnv(name + "_FILE"); fromFile {
+			if ok {
+				return fmt.Errorf("%s and %s_FILE are both set", name, name)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s_FILE: %w", name, err)
+			}
+			value, ok = strings.TrimRight(string(data), "\r\n"), true
+		}
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(i), value); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setField parses an environment value into a configuration field
+func setField(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a duration", value)
+		}
+		field.SetInt(int64(d))
+	case string:
+		field.SetString(value)
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("'%s' is not an integer", value)
+		}
+		field.SetInt(int64(n))
+	case uint64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a positive integer", value)
+		}
+		field.SetUint(n)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a boolean", value)
+		}
+		field.SetBool(b)
+	case []string:
+		var values []string
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported type %v", field.Type())
+	}
+	return nil
+}
+
+// Validate checks every configuration key and reports all of the invalid ones at once
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	check(c.Port > 0 && c.Port <= 65535, "port must be between 1 and 65535 (got %d)", c.Port)
+	check(c.AdminPort >= 0 && c.AdminPort <= 65535, "admin_port must be between 0 and 65535 (got %d)", c.AdminPort)
+	check(c.AdminPort == 0 || c.AdminPort != c.Port, "admin_port must differ from port")
+	check(c.DatabaseURL != "", "database_url is required")
+	check(c.RedisURL != "", "redis_url is required")
+	if age, err := time.ParseDuration(c.MaxSessionAge); err != nil {
+		check(false, "max_session_age must be a duration such as \"720h\" (got '%s')", c.MaxSessionAge)
+	} else {
+		check(age > 0, "max_session_age must be positive")
+	}
+	check(c.CharacterPageLimit > 0, "character_page_limit must be positive (got %d)", c.CharacterPageLimit)
+	check(c.S3AccessKey != "", "s3_access_key is required")
+	check(c.S3AccessSecret != "", "s3_access_secret is required")
+	check(c.S3Bucket != "", "s3_bucket is required")
+	check(c.ModelIDSeed != 0, "model_id_seed is required")
+	if _, err := NewLogger(io.Discard, c.LogLevel, c.LogFormat); err != nil {
+		check(false, "%v", err)
+	}
+	check(c.ImportAsyncThreshold >= 0, "import_async_threshold must not be negative")
+	check(c.ReadHeaderTimeout >= 0, "read_header_timeout must not be negative")
+	check(c.ReadTimeout >= 0, "read_timeout must not be negative")
+	check(c.WriteTimeout >= 0, "write_timeout must not be negative")
+	check(c.IdleTimeout >= 0, "idle_timeout must not be negative")
+	check(c.ShutdownTimeout >= 0, "shutdown_timeout must not be negative")
+	check(c.JobWorkers >= 0, "job_workers must not be negative")
+	check(c.ImageReconcileInterval >= 0, "image_reconcile_interval must not be negative")
+	check(c.MaxUniversesPerUser >= 0, "max_universes_per_user must not be negative")
+	check(c.MaxCharactersPerUniverse >= 0, "max_characters_per_universe must not be negative")
+	check(c.MaxCollaboratorsPerUniverse >= 0, "max_collaborators_per_universe must not be negative")
+	check(c.MaxGuideGroups >= 0, "max_guide_groups must not be negative")
+	check(c.MaxGuideFields >= 0, "max_guide_fields must not be negative")
+	check(c.MaxImageBytesPerUniverse >= 0, "max_image_bytes_per_universe must not be negative")
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("invalid configuration:\n  - " + strings.Join(problems, "\n  - "))
+}
+
+// SessionAge returns how long sessions last without activity
+func (c *Config) SessionAge() time.Duration {
+	age, _ := time.ParseDuration(c.MaxSessionAge)
+	return age
+}
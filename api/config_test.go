@@ -0,0 +1,127 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(file, []byte("port: 9000\ns3_bucket: from-file\nmodel_id_seed: 7\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secret, []byte("from-secret-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	required := map[string]string{"CBS_S3_ACCESS_KEY": "key", "CBS_S3_ACCESS_SECRET": "secret"}
+
+	tests := []struct {
+		name  string
+		path  string
+		env   map[string]string
+		check func(c *Config) bool
+		err   string
+	}{
+		{
+			name:  "file over defaults",
+			path:  file,
+			env:   required,
+			check: func(c *Config) bool { return c.Port == 9000 && c.S3Bucket == "from-file" && c.CharacterPageLimit == 25 },
+		},
+		{
+			name: "environment over file",
+			path: file,
+			env: map[string]string{
+				"CBS_S3_ACCESS_KEY": "key", "CBS_S3_ACCESS_SECRET_FILE": secret, "CBS_PORT": "9100",
+				"CBS_ALLOWED_ORIGINS": "https://a.example, https://b.example", "CBS_READ_TIMEOUT": "5s",
+			},
+			check: func(c *Config) bool {
+				return c.Port == 9100 && c.S3AccessSecret == "from-secret-file" && c.ReadTimeout == 5*time.Second &&
+					reflect.DeepEqual(c.AllowedOrigins, []string{"https://a.example", "https://b.example"})
+			},
+		},
+		{
+			name: "environment only",
+			env: map[string]string{
+				"CBS_S3_ACCESS_KEY": "key", "CBS_S3_ACCESS_SECRET": "secret", "CBS_S3_BUCKET": "bucket",
+				"CBS_MODEL_ID_SEED": "1",
+			},
+			check: func(c *Config) bool { return c.Port == 8080 && c.SessionAge() == 720*time.Hour },
+		},
+		{name: "unparseable value", path: file, env: map[string]string{"CBS_PORT": "http"}, err: "invalid CBS_PORT"},
+		{
+			name: "value and file",
+			path: file,
+			env:  map[string]string{"CBS_S3_ACCESS_SECRET": "a", "CBS_S3_ACCESS_SECRET_FILE": secret},
+			err:  "are both set",
+		},
+		{name: "missing seed", env: required, err: "model_id_seed is required"},
+		{
+			name: "invalid values",
+			path: file,
+			env:  map[string]string{"CBS_PORT": "70000", "CBS_MAX_SESSION_AGE": "30 days"},
+			err:  "port must be between 1 and 65535",
+		},
+		{
+			name: "negative quota",
+			path: file,
+			env:  map[string]string{"CBS_MAX_GUIDE_FIELDS": "-1"},
+			err:  "max_guide_fields must not be negative",
+		},
+		{
+			name: "unparseable session age",
+			path: file,
+			env:  map[string]string{"CBS_MAX_SESSION_AGE": "30 days"},
+			err:  "max_session_age must be a duration",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookupEnv := func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}
+			config, err := LoadConfig(tt.path, lookupEnv)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v; want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(config) {
+				t.Errorf("unexpected configuration %+v", config)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_UnknownKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte("port: 9000\ns3_bucket: bucket\nmodel_id_seed: 7\nimage_bucket: old\nprot: 9100\n")
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"CBS_S3_ACCESS_KEY": "key", "CBS_S3_ACCESS_SECRET": "secret"}
+	config, err := LoadConfig(file, func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Port != 9000 {
+		t.Errorf("got port %v; want the known keys loaded", config.Port)
+	}
+	if want := []string{"image_bucket", "prot"}; !reflect.DeepEqual(config.UnknownKeys(), want) {
+		t.Errorf("got unknown keys %v; want %v", config.UnknownKeys(), want)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"cbs/models"
 	"fmt"
 	"net/http"
 )
@@ -20,13 +21,21 @@ var (
 
 	// ErrCodeInternal describes an internal server error
 	ErrCodeInternal ErrorCode = "INTERNALSERV"
+
+	// ErrCodePrecondition describes a resource that was modified since the client last retrieved it
+	ErrCodePrecondition ErrorCode = "PRECONDITION"
+
+	// ErrCodeQuota describes an action that would exceed a quota of a user or universe
+	ErrCodeQuota ErrorCode = "QUOTA"
 )
 
 // Error represents an API response error
 type Error struct {
-	Code    ErrorCode `json:"error"`
-	Message string    `json:"message"`
-	Status  int       `json:"-"`
+	Code      ErrorCode                `json:"error"`
+	Message   string                   `json:"message"`
+	Issues    []models.ValidationIssue `json:"issues,omitempty"`
+	RequestID string                   `json:"requestId,omitempty"`
+	Status    int                      `json:"-"`
 }
 
 func (e Error) Error() string {
@@ -35,7 +44,7 @@ func (e Error) Error() string {
 
 // NewError creates a new API response error
 func NewError(code ErrorCode, message string, status int) Error {
-	return Error{code, message, status}
+	return Error{Code: code, Message: message, Status: status}
 }
 
 // ErrNotFound generates a Not Found API error
@@ -54,6 +63,19 @@ func ErrBadBody(message string) Error {
 	return NewError(ErrCodeBadBody, "Bad request body", http.StatusBadRequest)
 }
 
+// ErrValidation generates a Bad Request API error listing every failed validation rule
+func ErrValidation(issues []models.ValidationIssue) Error {
+	message := "Validation failed"
+	if len(issues) == 1 {
+		message = issues[0].Message
+	} else if len(issues) > 1 {
+		message = fmt.Sprintf("%d fields failed validation", len(issues))
+	}
+	err := NewError(ErrCodeBadBody, message, http.StatusBadRequest)
+	err.Issues = issues
+	return err
+}
+
 // ErrInternal generates an Internal Server API error
 func ErrInternal(message string) Error {
 	if message != "" {
@@ -69,3 +91,32 @@ func ErrBadAuth(message string) Error {
 	}
 	return NewError(ErrCodeBadAuth, "Authentication failed", http.StatusUnauthorized)
 }
+
+// ErrPrecondition generates a Precondition Failed API error
+func ErrPrecondition(message string) Error {
+	if message != "" {
+		return NewError(ErrCodePrecondition, message, http.StatusPreconditionFailed)
+	}
+	return NewError(
+		ErrCodePrecondition,
+		"Resource was modified since it was last retrieved",
+		http.StatusPreconditionFailed,
+	)
+}
+
+// ErrQuota generates a Forbidden API error for an action that would exceed a quota
+func ErrQuota(message string) Error {
+	if message != "" {
+		return NewError(ErrCodeQuota, message, http.StatusForbidden)
+	}
+	return NewError(ErrCodeQuota, "Quota exceeded", http.StatusForbidden)
+}
+
+// CheckQuota returns a quota error with the given message when adding to the used amount of a resource would
+// exceed its limit. A limit of 0 is unlimited
+func CheckQuota(used int, added int, limit int, message string) error {
+	if limit > 0 && used+added > limit {
+		return ErrQuota(message)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// matchesETag reports whether an If-Match or If-None-Match header value matches an entity tag. Weak comparison
+// lets "W/" prefixed tags match their strong counterparts, while strong comparison only matches strong tags that
+// are identical (RFC 7232 section 2.3.2)
+func matchesETag(header string, etag string, weak bool) bool {
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		} else if strings.HasPrefix(candidate, "W/") || strings.HasPrefix(etag, "W/") {
+			continue
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetETag assigns an entity tag to the response
+func SetETag(w http.ResponseWriter, etag string) {
+	w.Header().Set("ETag", etag)
+}
+
+// CheckIfMatch returns a Precondition Failed API error if the request carries an
+// If-Match header that does not strongly match the current entity tag of the resource
+func CheckIfMatch(r *http.Request, etag string) error {
+	header := r.Header.Get("If-Match")
+	if header != "" && !matchesETag(header, etag, false) {
+		return ErrPrecondition("")
+	}
+	return nil
+}
+
+// CheckIfNoneMatch sends a Not Modified response and returns true if the request carries an
+// If-None-Match header that weakly matches the current entity tag of the resource
+func CheckIfNoneMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !matchesETag(header, etag, true) {
+		return false
+	}
+	SetETag(w, etag)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		wanterr bool
+	}{
+		{name: "no header", header: "", wanterr: false},
+		{name: "matching", header: `"universe-2"`, wanterr: false},
+		{name: "weak matching", header: `W/"universe-2"`, wanterr: true},
+		{name: "list matching", header: `"universe-1", "universe-2"`, wanterr: false},
+		{name: "wildcard", header: "*", wanterr: false},
+		{name: "stale", header: `"universe-1"`, wanterr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			err := CheckIfMatch(r, `"universe-2"`)
+			if !tt.wanterr && err != nil {
+				t.Fatalf("got error %v; want nil", err)
+			}
+			if tt.wanterr {
+				apierr, ok := err.(Error)
+				if !ok || apierr.Status != http.StatusPreconditionFailed {
+					t.Fatalf("got error %v; want precondition failed", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckIfNoneMatch(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("If-None-Match", `"universe-2"`)
+	rr := httptest.NewRecorder()
+	if !CheckIfNoneMatch(rr, r, `"universe-2"`) {
+		t.Fatalf("got modified; want not modified")
+	}
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("got response status %v; want %v", rr.Code, http.StatusNotModified)
+	}
+	if CheckIfNoneMatch(httptest.NewRecorder(), r, `"universe-3"`) {
+		t.Errorf("got not modified; want modified")
+	}
+	r.Header.Set("If-None-Match", `W/"universe-2"`)
+	if !CheckIfNoneMatch(httptest.NewRecorder(), r, `"universe-2"`) {
+		t.Errorf("got modified for a weak tag; want not modified")
+	}
+}
@@ -0,0 +1,26 @@
+package events
+
+import (
+	"cbs/api/openapi"
+	"cbs/models"
+	"net/http"
+)
+
+// Operations describes the routes of the "events" resource for the OpenAPI document
+var Operations = []openapi.Operation{
+	{
+		Method:  http.MethodGet,
+		Path:    "/",
+		Summary: "Stream the events of a universe",
+		Description: "Events are sent as Server-Sent Events whose data is the event, named after its type. Events " +
+			"about hidden characters are only sent to those who can view them. The stream ends when the universe is " +
+			"deleted or the recipient is removed from it, and is kept open with a heartbeat comment every 30 seconds",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The event stream",
+			Content:     []openapi.Content{{MediaType: "text/event-stream", Body: models.UniverseEvent{}}},
+		}},
+	},
+}
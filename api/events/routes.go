@@ -0,0 +1,110 @@
+package events
+
+import (
+	"cbs/api"
+	"cbs/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// HeartbeatInterval represents how often a comment is written to idle event streams to keep them open
+const HeartbeatInterval = 30 * time.Second
+
+// Router represents a router for the "events" resource
+type Router api.Router
+
+// NewRouter creates a new router assigned to the "events" resource
+func NewRouter(server *api.Server) *Router {
+	router := &Router{
+		Mux:    chi.NewMux(),
+		Server: server}
+	router.Use(
+		server.Middlewares.UserSession,
+		server.Middlewares.Universe,
+		server.Middlewares.Collaborator(models.CollaboratorMember),
+	)
+	router.Get("/", api.Handler(router.StreamEvents).ServeHTTP)
+	return router
+}
+
+// StreamEvents represents a route that streams the events of a universe as Server-Sent Events
+func (m *Router) StreamEvents(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return api.ErrInternal("Event streaming is not supported")
+	}
+	events, unsubscribe, err := m.Services.Event.Subscribe(universe)
+	if err != nil {
+		return api.ErrInternal("Failed to subscribe to universe events")
+	}
+	defer unsubscribe()
+
+	// The recipient's role may change while the stream is open, so a copy is kept up to date
+	recipient := *collaborator
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	// Streams stay open far longer than the server write timeout allows
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(HeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-m.Stopping():
+			// Clients reconnect to another instance on their own
+			return nil
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			event = m.Services.Event.Filter(event, &recipient)
+			if event == nil {
+				continue
+			}
+			if err := writeEvent(w, event); err != nil {
+				api.Logger(r).Warn("Failed to write universe event", "event", event.ID, "error", err)
+				return nil
+			}
+			flusher.Flush()
+
+			// Streams end once the recipient loses access to the universe
+			if event.Type == models.EventUniverseDeleted {
+				return nil
+			}
+			if event.Collaborator != nil && event.Collaborator.UserID == recipient.UserID {
+				switch event.Type {
+				case models.EventCollaboratorRemoved:
+					return nil
+				case models.EventCollaboratorUpdated:
+					recipient.Role = event.Collaborator.Role
+				}
+			}
+		}
+	}
+}
+
+// writeEvent writes a universe event to the ResponseWriter in the Server-Sent Events format
+func writeEvent(w http.ResponseWriter, event *models.UniverseEvent) error {
+	serialized, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, serialized)
+	return err
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"cbs/api"
+	"cbs/models"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Service represents the service layer for universe events
+type Service api.Service
+
+func eventChannel(universeID string) string {
+	return fmt.Sprintf("events:universe:%v", universeID)
+}
+
+// Publish broadcasts a universe event to every subscriber of the universe across all server instances
+func (s *Service) Publish(event *models.UniverseEvent) error {
+	if event.ID == "" {
+		event.ID = s.Providers.ShortID.MustGenerate()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	serialized, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.Providers.Redis.Publish(eventChannel(event.UniverseID), serialized).Err()
+}
+
+// Subscribe listens to the events of a universe. Events are delivered on the returned channel
+// until the returned function is called to close the subscription
+func (s *Service) Subscribe(universe *models.Universe) (<-chan *models.UniverseEvent, func() error, error) {
+	pubsub := s.Providers.Redis.Subscribe(eventChannel(universe.ID))
+
+	// Wait for the subscription to be confirmed so that no events are missed once the stream starts
+	if _, err := pubsub.Receive(); err != nil {
+		pubsub.Close()
+		return nil, nil, err
+	}
+
+	// The relay stops once unsubscribed, even while it waits for the reader to take an event
+	events := make(chan *models.UniverseEvent)
+	done := make(chan struct{})
+	go func() {
+		defer close(events)
+		for msg := range pubsub.Channel() {
+			var event models.UniverseEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				s.Providers.Logger.Error("Failed to decode universe event", "universe", universe.ID, "error", err)
+				continue
+			}
+			select {
+			case events <- &event:
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	unsubscribe := func() error {
+		once.Do(func() { close(done) })
+		return pubsub.Close()
+	}
+	return events, unsubscribe, nil
+}
+
+// Filter prepares an event for a single recipient. Members never receive hidden characters or the values of
+// hidden fields of characters they do not own, and nil is returned when the recipient must not see the event
+func (s *Service) Filter(event *models.UniverseEvent, collaborator *models.Collaborator) *models.UniverseEvent {
+	character := event.Character
+	if character == nil || collaborator.Role != models.CollaboratorMember {
+		return event
+	}
+	ownerID := character.OwnerID
+	if character.Owner != nil {
+		ownerID = character.Owner.ID
+	}
+	if ownerID == collaborator.UserID {
+		return event
+	}
+	if character.Meta != nil && character.Meta.Hidden {
+		// Characters that become hidden disappear from the member's view, so updates are reported as deletions
+		switch event.Type {
+		case models.EventCharacterUpdated, models.EventCharacterDeleted:
+			event.Type = models.EventCharacterDeleted
+			event.Character = &models.Character{ID: character.ID}
+			return event
+		}
+		return nil
+	}
+	if character.Meta != nil && character.Fields != nil {
+		character.HideHiddenFields()
+	}
+	return event
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"cbs/models"
+	"testing"
+)
+
+func testEventCharacter(hidden bool) *models.Character {
+	return &models.Character{
+		ID:    "c1",
+		Name:  "Mira",
+		Owner: &models.User{ID: "owner"},
+		Meta:  &models.CharacterMeta{Hidden: hidden},
+		Fields: &models.CharacterFields{Groups: map[string]*models.CharacterFieldGroup{
+			"General": {Fields: map[string]*models.CharacterField{
+				"Secret": {Value: "treasure", Hidden: true},
+			}},
+		}},
+	}
+}
+
+func TestService_Filter(t *testing.T) {
+	s := &Service{}
+	member := &models.Collaborator{UserID: "member", Role: models.CollaboratorMember}
+	tests := []struct {
+		name       string
+		event      *models.UniverseEvent
+		recipient  *models.Collaborator
+		wantnil    bool
+		wanttype   models.UniverseEventType
+		wantsecret interface{}
+	}{
+		{
+			name:       "admins receive hidden values",
+			event:      &models.UniverseEvent{Type: models.EventCharacterCreated, Character: testEventCharacter(false)},
+			recipient:  &models.Collaborator{UserID: "admin", Role: models.CollaboratorAdmin},
+			wanttype:   models.EventCharacterCreated,
+			wantsecret: "treasure",
+		},
+		{
+			name:       "owners receive hidden values",
+			event:      &models.UniverseEvent{Type: models.EventCharacterUpdated, Character: testEventCharacter(true)},
+			recipient:  &models.Collaborator{UserID: "owner", Role: models.CollaboratorMember},
+			wanttype:   models.EventCharacterUpdated,
+			wantsecret: "treasure",
+		},
+		{
+			name:      "members receive visible characters without hidden values",
+			event:     &models.UniverseEvent{Type: models.EventCharacterUpdated, Character: testEventCharacter(false)},
+			recipient: member,
+			wanttype:  models.EventCharacterUpdated,
+		},
+		{
+			name:      "members do not receive hidden characters",
+			event:     &models.UniverseEvent{Type: models.EventCharacterCreated, Character: testEventCharacter(true)},
+			recipient: member,
+			wantnil:   true,
+		},
+		{
+			name:      "hidden character updates are reported to members as deletions",
+			event:     &models.UniverseEvent{Type: models.EventCharacterUpdated, Character: testEventCharacter(true)},
+			recipient: member,
+			wanttype:  models.EventCharacterDeleted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.Filter(tt.event, tt.recipient)
+			if tt.wantnil {
+				if got != nil {
+					t.Fatalf("got event %v; want nil", got.Type)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("got nil; want event")
+			}
+			if got.Type != tt.wanttype {
+				t.Errorf("got type %v; want %v", got.Type, tt.wanttype)
+			}
+			if got.Type == models.EventCharacterDeleted {
+				if got.Character.ID != "c1" || got.Character.Name != "" || got.Character.Fields != nil {
+					t.Errorf("got character %+v; want only its ID", got.Character)
+				}
+				return
+			}
+			secret := got.Character.Fields.Groups["General"].Fields["Secret"].Value
+			if secret != tt.wantsecret {
+				t.Errorf("got secret %v; want %v", secret, tt.wantsecret)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package health
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"context"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// CheckTimeout represents how long each dependency has to respond to a readiness check
+const CheckTimeout = 2 * time.Second
+
+// Build metadata, set at build time with
+// -ldflags "-X cbs/api/health.Version=... -X cbs/api/health.Commit=... -X cbs/api/health.BuildTime=..."
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Paths represents every path served by the health router, for mounting it next to other root routers
+var Paths = []string{"/healthz", "/readyz", "/version"}
+
+// Router represents a router for the health and build information endpoints
+type Router api.Router
+
+// NewRouter creates a new router for the health and build information endpoints. None of its routes
+// require authentication
+func NewRouter(server *api.Server) *Router {
+	router := &Router{
+		Mux:    chi.NewMux(),
+		Server: server,
+	}
+	router.Get("/healthz", api.Handler(router.GetHealth).ServeHTTP)
+	router.Get("/readyz", api.Handler(router.GetReadiness).ServeHTTP)
+	router.Get("/version", api.Handler(router.GetVersion).ServeHTTP)
+	return router
+}
+
+// GetHealth represents a route that reports that the process is alive
+func (m *Router) GetHealth(w http.ResponseWriter, r *http.Request) error {
+	api.SendResponse(w, dtos.ResGetHealth{Status: "ok"}, http.StatusOK)
+	return nil
+}
+
+// GetReadiness represents a route that reports whether the database, Redis and storage are reachable.
+// It responds with 503 Service Unavailable when any of them is not, or once the server is shutting down so that
+// no new traffic is sent to it. Failed checks are logged rather than sent, since their errors may describe the
+// infrastructure
+func (m *Router) GetReadiness(w http.ResponseWriter, r *http.Request) error {
+	select {
+	case <-m.Stopping():
+		res := dtos.ResGetReadiness{Status: "stopping", Checks: make(map[string]dtos.DependencyStatus)}
+		api.SendResponse(w, res, http.StatusServiceUnavailable)
+		return nil
+	default:
+	}
+
+	checks := map[string]func(ctx context.Context) error{
+		"database": func(ctx context.Context) error {
+			return m.Providers.DB.PingContext(ctx)
+		},
+		"redis": func(ctx context.Context) error {
+			return m.Providers.Redis.Ping().Err()
+		},
+	}
+	if m.Providers.Storage != nil {
+		checks["storage"] = m.Providers.Storage.Ping
+	}
+
+	res := dtos.ResGetReadiness{Status: "ok", Checks: make(map[string]dtos.DependencyStatus)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) error) {
+			defer wg.Done()
+			status, err := runCheck(r.Context(), check)
+			if err != nil {
+				api.Logger(r).Warn("Readiness check failed", "dependency", name, "error", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			res.Checks[name] = status
+			if err != nil {
+				res.Status = "unavailable"
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	if res.Status != "ok" {
+		api.SendResponse(w, res, http.StatusServiceUnavailable)
+		return nil
+	}
+	api.SendResponse(w, res, http.StatusOK)
+	return nil
+}
+
+// GetVersion represents a route that returns the build metadata of the running binary
+func (m *Router) GetVersion(w http.ResponseWriter, r *http.Request) error {
+	res := dtos.ResGetVersion{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	// Fall back to the version control information embedded by the Go toolchain
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && res.Commit == "":
+				res.Commit = setting.Value
+			case setting.Key == "vcs.time" && res.BuildTime == "":
+				res.BuildTime = setting.Value
+			}
+		}
+	}
+	api.SendResponse(w, res, http.StatusOK)
+	return nil
+}
+
+// runCheck runs a dependency check, failing it if it does not complete within CheckTimeout. The error of a failed
+// check is returned alongside its status
+func runCheck(parent context.Context, check func(ctx context.Context) error) (dtos.DependencyStatus, error) {
+	ctx, cancel := context.WithTimeout(parent, CheckTimeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	status := dtos.DependencyStatus{Status: "ok", Latency: time.Since(start).String()}
+	if err != nil {
+		status.Status = "unavailable"
+	}
+	return status, err
+}
@@ -0,0 +1,61 @@
+package health
+
+import (
+	"cbs/api"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   func(ctx context.Context) error
+		wanterr bool
+	}{
+		{name: "reachable", check: func(ctx context.Context) error { return nil }},
+		{name: "failing", check: func(ctx context.Context) error { return errors.New("refused") }, wanterr: true},
+		{
+			name: "timed out",
+			check: func(ctx context.Context) error {
+				time.Sleep(CheckTimeout + time.Second)
+				return nil
+			},
+			wanterr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runCheck(context.Background(), tt.check)
+			if (err != nil) != tt.wanterr || (got.Status == "ok") == tt.wanterr {
+				t.Errorf("got status %v (error: %v); want error %v", got.Status, err, tt.wanterr)
+			}
+		})
+	}
+}
+
+func TestGetHealth(t *testing.T) {
+	rr := httptest.NewRecorder()
+	if err := (&Router{}).GetHealth(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %v; want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestGetReadiness_Stopping(t *testing.T) {
+	server := api.NewServer(api.Config{}, &api.Providers{}, &api.Services{})
+	server.Stop()
+	rr := httptest.NewRecorder()
+	router := &Router{Server: server}
+	if err := router.GetReadiness(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %v while stopping; want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -30,6 +30,18 @@ const (
 
 	// CharacterContextKey represents a context key for accessing the character from the request context
 	CharacterContextKey
+
+	// WebhookContextKey represents a context key for accessing the webhook from the request context
+	WebhookContextKey
+
+	// ShareContextKey represents a context key for accessing the share link from the request context
+	ShareContextKey
+
+	// RequestIDContextKey represents a context key for accessing the request ID from the request context
+	RequestIDContextKey
+
+	// LoggerContextKey represents a context key for accessing the request logger from the request context
+	LoggerContextKey
 )
 
 // Router represents a router with access to the database
@@ -41,16 +53,22 @@ type Router struct {
 // Handler represents a generic HTTP handler with improved error-handling support
 type Handler func(w http.ResponseWriter, r *http.Request) error
 
+// ServeHTTP runs the handler and sends any returned error. Errors that are not API errors are logged and
+// replaced with a generic internal error, so clients can only correlate them through the request ID
 func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	err := h(w, r)
-	if err != nil {
-		apierr, ok := err.(Error)
-		if !ok {
-			SendError(w, ErrInternal(err.Error()))
-		} else {
-			SendError(w, apierr)
-		}
+	if err == nil {
+		return
+	}
+	apierr, ok := err.(Error)
+	if !ok {
+		Logger(r).Error("Request failed", "error", err)
+		apierr = ErrInternal("")
+	} else if apierr.Status >= http.StatusInternalServerError {
+		Logger(r).Error("Request failed", "error", apierr.Message, "code", apierr.Code)
 	}
+	apierr.RequestID = RequestID(r)
+	SendError(w, apierr)
 }
 
 func addAPIHeaders(w http.ResponseWriter, status int) {
@@ -69,23 +87,95 @@ func ValidatorJSONPlugin(fld reflect.StructField) string {
 	return name
 }
 
-// GetValidationError returns a simplified error
-// from go-validator validation results
-func GetValidationError(err error) (string, error) {
-	valError, ok := err.(validator.ValidationErrors)
-	fmt.Printf("%T\n", err)
+// validationRules maps go-validator tags onto machine-readable validation rules
+var validationRules = map[string]models.ValidationRule{
+	"required":   models.ValidationRuleRequired,
+	"min":        models.ValidationRuleRange,
+	"max":        models.ValidationRuleRange,
+	"len":        models.ValidationRuleRange,
+	"gt":         models.ValidationRuleRange,
+	"gte":        models.ValidationRuleRange,
+	"lt":         models.ValidationRuleRange,
+	"lte":        models.ValidationRuleRange,
+	"gtcsfield":  models.ValidationRuleRange,
+	"gtecsfield": models.ValidationRuleRange,
+	"ltcsfield":  models.ValidationRuleRange,
+	"ltecsfield": models.ValidationRuleRange,
+	"oneof":      models.ValidationRuleOptions,
+	"email":      models.ValidationRulePattern,
+	"url":        models.ValidationRulePattern,
+}
+
+// validationComparisons describes go-validator range tags for error message generation
+var validationComparisons = map[string]string{
+	"min":        "at least",
+	"max":        "at most",
+	"len":        "exactly",
+	"gt":         "greater than",
+	"gte":        "at least",
+	"lt":         "less than",
+	"lte":        "at most",
+	"gtcsfield":  "greater than",
+	"gtecsfield": "at least",
+	"ltcsfield":  "less than",
+	"ltecsfield": "at most",
+}
+
+// validationPath converts a go-validator namespace (e.g. "ReqCreateCharacter.fields.groups[General]")
+// into a dot-separated path relative to the validated document (e.g. "fields.groups.General")
+func validationPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		namespace = namespace[i+1:]
+	}
+	namespace = strings.Replace(namespace, "[", ".", -1)
+	return strings.Replace(namespace, "]", "", -1)
+}
+
+// validationMessage generates a human-readable message for a failed go-validator rule
+func validationMessage(fe validator.FieldError) string {
+	switch tag := fe.Tag(); validationRules[tag] {
+	case models.ValidationRuleRequired:
+		return fmt.Sprintf("%v is required", fe.Field())
+	case models.ValidationRuleOptions:
+		return fmt.Sprintf("%v must be one of [%v]", fe.Field(), fe.Param())
+	case models.ValidationRulePattern:
+		return fmt.Sprintf("%v must be a valid %v", fe.Field(), tag)
+	case models.ValidationRuleRange:
+		return fmt.Sprintf("%v must be %v %v", fe.Field(), validationComparisons[tag], fe.Param())
+	}
+	return fmt.Sprintf("%v failed validation", fe.Field())
+}
+
+// GetValidationIssues returns every failed rule from go-validator validation results
+func GetValidationIssues(err error) ([]models.ValidationIssue, error) {
+	valErrors, ok := err.(validator.ValidationErrors)
 	if !ok {
-		return "", errors.New("failed to validate")
+		return nil, errors.New("failed to validate")
 	}
-	if len(valError) > 0 {
-		fmt.Println(valError[0])
-		return fmt.Sprintf("%v failed validation", valError[0].Field()), nil
+	issues := make([]models.ValidationIssue, 0, len(valErrors))
+	for _, fe := range valErrors {
+		// Tags without a rule are reported as unknown so that clients only ever see documented rules
+		rule, ok := validationRules[fe.Tag()]
+		if !ok {
+			rule = models.ValidationRuleUnknown
+		}
+		var expected interface{}
+		if fe.Param() != "" {
+			expected = fe.Param()
+		}
+		issues = append(issues, models.ValidationIssue{
+			Path:     validationPath(fe.Namespace()),
+			Rule:     rule,
+			Expected: expected,
+			Message:  validationMessage(fe),
+		})
 	}
-	return "", nil
+	return issues, nil
 }
 
 // SendError sends a failed API response to the ResponseWriter
 func SendError(w http.ResponseWriter, err Error) {
+	ErrorsTotal.WithLabelValues(string(err.Code)).Inc()
 	SendResponse(w, err, err.Status)
 }
 
@@ -125,12 +215,12 @@ func ValidateDTO(body interface{}) (*Error, error) {
 
 	err := v.Struct(body)
 	if err != nil {
-		verr, err := GetValidationError(err)
+		issues, err := GetValidationIssues(err)
 		if err != nil {
 			return nil, err
 		}
-		if verr != "" {
-			err := ErrBadBody(verr)
+		if len(issues) > 0 {
+			err := ErrValidation(issues)
 			return &err, nil
 		}
 	}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"cbs/models"
+	"reflect"
+	"testing"
+)
+
+type testValidationDTO struct {
+	Name   string                     `json:"name" validate:"required"`
+	Email  string                     `json:"email" validate:"required,email"`
+	Role   int                        `json:"role" validate:"oneof=0 1"`
+	Color  string                     `json:"color" validate:"hexcolor"`
+	Groups map[string]testValidationG `json:"groups" validate:"dive"`
+}
+
+type testValidationG struct {
+	Size int `json:"size" validate:"max=3"`
+}
+
+func TestValidateDTO(t *testing.T) {
+	body := testValidationDTO{
+		Email:  "invalid",
+		Role:   2,
+		Color:  "blue",
+		Groups: map[string]testValidationG{"General": {Size: 4}},
+	}
+	want := []models.ValidationIssue{
+		{Path: "name", Rule: models.ValidationRuleRequired, Message: "name is required"},
+		{Path: "email", Rule: models.ValidationRulePattern, Message: "email must be a valid email"},
+		{Path: "role", Rule: models.ValidationRuleOptions, Expected: "0 1", Message: "role must be one of [0 1]"},
+		{Path: "color", Rule: models.ValidationRuleUnknown, Message: "color failed validation"},
+		{Path: "groups.General.size", Rule: models.ValidationRuleRange, Expected: "3", Message: "size must be at most 3"},
+	}
+	valError, err := ValidateDTO(body)
+	if err != nil {
+		t.Fatalf("got error %v; want nil", err)
+	}
+	if valError == nil {
+		t.Fatalf("got no validation error; want %d issues", len(want))
+	}
+	if valError.Code != ErrCodeBadBody {
+		t.Errorf("got error code %v; want %v", valError.Code, ErrCodeBadBody)
+	}
+	if !reflect.DeepEqual(valError.Issues, want) {
+		t.Errorf("got issues %+v; want %+v", valError.Issues, want)
+	}
+}
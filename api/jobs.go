@@ -0,0 +1,44 @@
+package api
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"time"
+)
+
+// JobHeader represents the header naming the background job a request started, which is followed through the
+// jobs of its universe
+const JobHeader = "X-Job-ID"
+
+// EnqueueJob stores a pending job and queues it to run at its RunAt time, or immediately when it is not set
+func EnqueueJob(repos *repositories.Repositories, job *models.Job) error {
+	if job.RunAt == nil {
+		now := time.Now()
+		job.RunAt = &now
+	}
+	if err := repos.Job.Save(job, 0); err != nil {
+		return err
+	}
+	return repos.Job.Schedule(job.ID, *job.RunAt)
+}
+
+// EnqueueFileDeletion queues the stored files of deleted images to be deleted in the background. Failures are
+// logged rather than returned since files left behind are purged later as orphans
+func EnqueueFileDeletion(providers *Providers, repos *repositories.Repositories, universeID string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	job, err := models.NewJob(
+		providers.ShortID.MustGenerate(),
+		models.JobFilesDelete,
+		universeID,
+		"",
+		models.JobFiles{Keys: keys},
+	)
+	if err == nil {
+		err = EnqueueJob(repos, job)
+	}
+	if err != nil {
+		providers.Logger.Error("Failed to queue the deletion of image files", "universe", universeID, "error", err)
+	}
+}
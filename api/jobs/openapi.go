@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"cbs/api/openapi"
+	"cbs/dtos"
+	"net/http"
+)
+
+// Operations describes the routes of the "jobs" resource for the OpenAPI document
+var Operations = []openapi.Operation{
+	{
+		Method:  http.MethodGet,
+		Path:    "/{jobID}",
+		Summary: "Get the status of a background job",
+		Description: "Jobs are started by routes such as avatar uploads and asynchronous exports. Members can only " +
+			"follow the jobs they started. Finished jobs are kept for a day",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The job",
+			Content:     []openapi.Content{{Body: dtos.ResGetJob{}}},
+		}},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/{jobID}/result",
+		Summary:     "Download the file produced by a background job",
+		Description: "Only succeeded jobs with a result, such as exports, have a file to download",
+		Session:     true,
+		Role:        openapi.RoleMember,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The file as a download",
+			Headers:     []string{"Content-Disposition"},
+		}},
+	},
+}
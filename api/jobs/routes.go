@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// Router represents a router for the "jobs" resource
+type Router api.Router
+
+// NewRouter creates a new router assigned to the "jobs" resource, through which collaborators follow the
+// background jobs of a universe
+func NewRouter(server *api.Server) *Router {
+	router := &Router{
+		Mux:    chi.NewMux(),
+		Server: server}
+	router.Use(
+		server.Middlewares.UserSession,
+		server.Middlewares.Universe,
+		server.Middlewares.Collaborator(models.CollaboratorMember),
+	)
+	router.Get("/{jobID}", api.Handler(router.GetJob).ServeHTTP)
+	router.Get("/{jobID}/result", api.Handler(router.GetJobResult).ServeHTTP)
+	return router
+}
+
+// findJob returns the job of a request. Members can only follow the jobs they started
+func (m *Router) findJob(r *http.Request) (*models.Job, error) {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	job, err := m.Services.Job.FindByID(universe, chi.URLParam(r, "jobID"))
+	if err != nil {
+		return nil, err
+	}
+	if collaborator.Role == models.CollaboratorMember && job.CreatorID != collaborator.UserID {
+		return nil, api.ErrNotFound("Job not found")
+	}
+	job.Payload = nil
+	return job, nil
+}
+
+// GetJob represents a route that returns the status of a background job
+func (m *Router) GetJob(w http.ResponseWriter, r *http.Request) error {
+	job, err := m.findJob(r)
+	if err != nil {
+		return err
+	}
+	api.SendResponse(w, dtos.ResGetJob{Job: job}, http.StatusOK)
+	return nil
+}
+
+// GetJobResult represents a route that downloads the file produced by a background job, such as an export
+func (m *Router) GetJobResult(w http.ResponseWriter, r *http.Request) error {
+	job, err := m.findJob(r)
+	if err != nil {
+		return err
+	}
+	if !job.HasResult {
+		return api.ErrNotFound("Job result not found")
+	}
+	result, err := m.Services.Job.FindResult(job)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", result.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", result.Filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(result.Data)
+	return nil
+}
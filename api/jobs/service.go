@@ -0,0 +1,273 @@
+package jobs
+
+import (
+	"cbs/api"
+	"cbs/models"
+	"cbs/repositories"
+	"cbs/services"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// MaxAttempts represents the number of times a job is run before it is moved to the dead-letter queue
+const MaxAttempts = 5
+
+// Backoff represents the delay before the first retry of a failed job, doubled for every retry after
+const Backoff = 5 * time.Second
+
+// Lease represents how long a job is claimed by a worker. The lease is renewed every LeaseRenewal while the job
+// runs, so that only the jobs of workers that stopped are claimed again by another worker
+const (
+	Lease        = time.Minute
+	LeaseRenewal = Lease / 3
+)
+
+// PollInterval represents how often the queue is checked for due jobs
+const PollInterval = time.Second
+
+// Retention represents how long finished jobs and the files they produced are kept for inspection, and
+// DeadRetention how long dead jobs are kept in the dead-letter queue
+const (
+	Retention     = 24 * time.Hour
+	DeadRetention = 7 * 24 * time.Hour
+)
+
+// Service represents a service implementation for the "jobs" resource
+type Service api.Service
+
+// Enqueue stores a pending job and queues it to run
+func (s *Service) Enqueue(job *models.Job) error {
+	return api.EnqueueJob(s.Repositories, job)
+}
+
+// FindByID returns a job of a universe by its ID
+func (s *Service) FindByID(universe *models.Universe, id string) (*models.Job, error) {
+	job, err := s.Repositories.Job.FindByID(id)
+	if err == repositories.ErrNotFound || (err == nil && job.UniverseID != universe.ID) {
+		return nil, api.ErrNotFound("Job not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// FindResult returns the file produced by a job
+func (s *Service) FindResult(job *models.Job) (*models.JobResult, error) {
+	result, err := s.Repositories.Job.FindResult(job.ID)
+	if err == repositories.ErrNotFound {
+		return nil, api.ErrNotFound("Job result not found")
+	}
+	return result, err
+}
+
+// FindDead returns up to limit jobs of the dead-letter queue, most recently failed first
+func (s *Service) FindDead(limit int) (*[]models.Job, error) {
+	jobs, err := s.Repositories.Job.FindDead(limit)
+	if err != nil {
+		return nil, err
+	}
+	return &jobs, nil
+}
+
+// Retry takes a dead job out of the dead-letter queue and queues it to run again with a fresh set of attempts
+func (s *Service) Retry(id string) (*models.Job, error) {
+	job, err := s.Repositories.Job.FindByID(id)
+	if err == repositories.ErrNotFound {
+		return nil, api.ErrNotFound("Job not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.JobDead {
+		return nil, api.ErrBadBody("Only dead jobs can be retried")
+	}
+	now := time.Now()
+	job.Status = models.JobPending
+	job.Attempts = 0
+	job.Error = ""
+	job.RunAt = &now
+	job.UpdatedAt = now
+	if err := s.Repositories.Job.Unbury(job.ID); err != nil {
+		return nil, err
+	}
+	return job, api.EnqueueJob(s.Repositories, job)
+}
+
+// Work runs due jobs until ctx is cancelled. Any number of workers may run on any number of server instances
+// since every job is claimed by exactly one of them at a time
+func (s *Service) Work(ctx context.Context, handlers map[models.JobType]services.JobHandler) {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.RunDue(ctx, handlers)
+		}
+	}
+}
+
+// Recur queues a job of a type once every interval until ctx is cancelled, starting immediately. Jobs are named
+// after the interval they are queued in, so that a job queued by several servers runs once per interval
+func (s *Service) Recur(ctx context.Context, jobType models.JobType, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.enqueueOnce(jobType, time.Now().Truncate(interval)); err != nil {
+			s.Providers.Logger.Error("Failed to queue recurring job", "type", jobType, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueueOnce queues the job of a type for the interval starting at a given time, unless it was already queued
+func (s *Service) enqueueOnce(jobType models.JobType, start time.Time) error {
+	id := fmt.Sprintf("%s-%d", jobType, start.Unix())
+	if _, err := s.Repositories.Job.FindByID(id); err != repositories.ErrNotFound {
+		return err
+	}
+	job, err := models.NewJob(id, jobType, "", "", nil)
+	if err != nil {
+		return err
+	}
+	return s.Enqueue(job)
+}
+
+// RunDue claims and runs due jobs one at a time until none are left or ctx is cancelled, returning how many
+// were run
+func (s *Service) RunDue(ctx context.Context, handlers map[models.JobType]services.JobHandler) int {
+	run := 0
+	for ctx.Err() == nil {
+		ids, err := s.Repositories.Job.Claim(time.Now(), Lease, 1)
+		if err != nil {
+			s.Providers.Logger.Error("Failed to read the job queue", "error", err)
+			return run
+		}
+		if len(ids) == 0 {
+			return run
+		}
+		if err := s.attempt(ctx, ids[0], handlers); err != nil {
+			s.Providers.Logger.Error("Failed to process job", "job", ids[0], "error", err)
+		}
+		run++
+	}
+	return run
+}
+
+// attempt runs a claimed job, scheduling a retry with exponential backoff when it fails and burying it once it
+// has failed every attempt
+func (s *Service) attempt(ctx context.Context, id string, handlers map[models.JobType]services.JobHandler) error {
+	job, err := s.Repositories.Job.FindByID(id)
+	if err == repositories.ErrNotFound {
+		return s.Repositories.Job.Complete(id)
+	}
+	if err != nil {
+		return err
+	}
+	job.Status = models.JobRunning
+	job.Attempts++
+	job.UpdatedAt = time.Now()
+	if err := s.Repositories.Job.Save(job, 0); err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		s.heartbeat(job.ID, LeaseRenewal, stop)
+	}()
+	result, err := run(ctx, job, handlers[job.Type])
+	close(stop)
+	<-renewed
+
+	now := time.Now()
+	job.UpdatedAt = now
+	job.RunAt = nil
+	switch {
+	case err == nil:
+		job.Status = models.JobSucceeded
+		job.Error = ""
+		if result != nil {
+			if err := s.Repositories.Job.SaveResult(job.ID, result, Retention); err != nil {
+				return err
+			}
+			job.HasResult = true
+		}
+		if err := s.Repositories.Job.Save(job, Retention); err != nil {
+			return err
+		}
+		return s.Repositories.Job.Complete(job.ID)
+	case job.Attempts >= MaxAttempts || permanent(err):
+		s.Providers.Logger.Warn("Job failed and was moved to the dead-letter queue", "job", job.ID, "error", err)
+		job.Status = models.JobDead
+		job.Error = err.Error()
+		if err := s.Repositories.Job.Save(job, DeadRetention); err != nil {
+			return err
+		}
+		return s.Repositories.Job.Bury(job.ID, now)
+	default:
+		next := now.Add(backoff(job.Attempts))
+		job.Status = models.JobPending
+		job.Error = err.Error()
+		job.RunAt = &next
+		if err := s.Repositories.Job.Save(job, 0); err != nil {
+			return err
+		}
+		return s.Repositories.Job.Schedule(job.ID, next)
+	}
+}
+
+// heartbeat renews the lease of a running job every interval until stop is closed
+func (s *Service) heartbeat(id string, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := s.Repositories.Job.Renew(id, time.Now().Add(Lease)); err != nil {
+				s.Providers.Logger.Warn("Failed to renew job lease", "job", id, "error", err)
+			}
+		}
+	}
+}
+
+// run calls the handler of a job, reporting a panic as an error and a missing handler as a permanent failure
+func run(
+	ctx context.Context,
+	job *models.Job,
+	handler services.JobHandler,
+) (result *models.JobResult, err error) {
+	if handler == nil {
+		return nil, api.ErrBadBody(fmt.Sprintf("No handler for jobs of type '%s'", job.Type))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, api.ErrInternal(fmt.Sprintf("Job panicked: %v", r))
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// permanent reports whether a job failed in a way retrying cannot fix, such as an invalid payload or an exceeded
+// quota
+func permanent(err error) bool {
+	var apiErr api.Error
+	return errors.As(err, &apiErr) && apiErr.Status < http.StatusInternalServerError
+}
+
+// backoff returns the delay before the next attempt of a job that has failed the given number of times
+func backoff(attempts int) time.Duration {
+	return Backoff * time.Duration(1<<uint(attempts-1))
+}
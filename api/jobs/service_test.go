@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"cbs/api"
+	"cbs/models"
+	"cbs/repositories/memory"
+	"cbs/services"
+	"context"
+	"io/ioutil"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/teris-io/shortid"
+)
+
+// newService returns a jobs service backed by the in-memory repositories
+func newService(t *testing.T) *Service {
+	sid, err := shortid.New(0, shortid.DefaultABC, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	providers := &api.Providers{ShortID: sid, Logger: slog.New(slog.NewTextHandler(ioutil.Discard, nil))}
+	return &Service{Providers: providers, Repositories: memory.New(), Config: api.DefaultConfig()}
+}
+
+// enqueue queues a job of type "test" that has already failed the given number of times
+func enqueue(t *testing.T, s *Service, attempts int) *models.Job {
+	job, err := models.NewJob(s.Providers.ShortID.MustGenerate(), "test", "u1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Attempts = attempts
+	if err := s.Enqueue(job); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestRunDue(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempts   int
+		handler    services.JobHandler
+		wantStatus models.JobStatus
+		wantResult bool
+		wantDead   int
+	}{
+		{
+			name: "succeeded",
+			handler: func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+				return nil, nil
+			},
+			wantStatus: models.JobSucceeded,
+		},
+		{
+			name: "succeeded with result",
+			handler: func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+				return &models.JobResult{ContentType: "text/plain", Filename: "a.txt", Data: []byte("a")}, nil
+			},
+			wantStatus: models.JobSucceeded,
+			wantResult: true,
+		},
+		{
+			name: "failed",
+			handler: func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+				return nil, api.ErrInternal("Storage unavailable")
+			},
+			wantStatus: models.JobPending,
+		},
+		{
+			name: "panicked",
+			handler: func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+				panic("nil map")
+			},
+			wantStatus: models.JobPending,
+		},
+		{
+			name:     "failed every attempt",
+			attempts: MaxAttempts - 1,
+			handler: func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+				return nil, api.ErrInternal("Storage unavailable")
+			},
+			wantStatus: models.JobDead,
+			wantDead:   1,
+		},
+		{
+			name: "failed permanently",
+			handler: func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+				return nil, api.ErrBadBody("Image could not be decoded")
+			},
+			wantStatus: models.JobDead,
+			wantDead:   1,
+		},
+		{name: "no handler", wantStatus: models.JobDead, wantDead: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService(t)
+			job := enqueue(t, s, tt.attempts)
+			handlers := map[models.JobType]services.JobHandler{}
+			if tt.handler != nil {
+				handlers["test"] = tt.handler
+			}
+
+			// A failed job is not due again until its backoff has passed
+			if run := s.RunDue(context.Background(), handlers); run != 1 {
+				t.Fatalf("got %d jobs run; want 1", run)
+			}
+			if run := s.RunDue(context.Background(), handlers); run != 0 {
+				t.Errorf("got %d jobs run again; want 0", run)
+			}
+
+			got, err := s.Repositories.Job.FindByID(job.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("got status %s; want %s", got.Status, tt.wantStatus)
+			}
+			if got.Attempts != tt.attempts+1 {
+				t.Errorf("got %d attempts; want %d", got.Attempts, tt.attempts+1)
+			}
+			if got.HasResult != tt.wantResult {
+				t.Errorf("got result %v; want %v", got.HasResult, tt.wantResult)
+			}
+			if tt.wantStatus == models.JobPending && (got.RunAt == nil || got.RunAt.Before(time.Now())) {
+				t.Errorf("got retry at %v; want a retry after the backoff", got.RunAt)
+			}
+			dead, err := s.FindDead(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*dead) != tt.wantDead {
+				t.Errorf("got %d dead jobs; want %d", len(*dead), tt.wantDead)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	s := newService(t)
+	job := enqueue(t, s, 0)
+	if _, err := s.Retry(job.ID); err == nil {
+		t.Error("got pending job retried; want error")
+	}
+
+	fail := map[models.JobType]services.JobHandler{
+		"test": func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+			return nil, api.ErrBadBody("Invalid payload")
+		},
+	}
+	s.RunDue(context.Background(), fail)
+	retried, err := s.Retry(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried.Status != models.JobPending || retried.Attempts != 0 || retried.Error != "" {
+		t.Errorf("got retried job %+v; want a pending job with no attempts", retried)
+	}
+	if dead, _ := s.FindDead(10); len(*dead) != 0 {
+		t.Errorf("got %d dead jobs; want 0", len(*dead))
+	}
+
+	succeed := map[models.JobType]services.JobHandler{
+		"test": func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+			return nil, nil
+		},
+	}
+	if run := s.RunDue(context.Background(), succeed); run != 1 {
+		t.Errorf("got %d jobs run; want 1", run)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: Backoff, 2: 2 * Backoff, 4: 8 * Backoff} {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v; want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestEnqueueOnce(t *testing.T) {
+	s := newService(t)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{start, start, start.Add(time.Hour)} {
+		if err := s.enqueueOnce("test", at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The same interval is only queued once, even though its first job is still pending
+	handlers := map[models.JobType]services.JobHandler{
+		"test": func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+			return nil, nil
+		},
+	}
+	if run := s.RunDue(context.Background(), handlers); run != 2 {
+		t.Errorf("got %d jobs run; want 2", run)
+	}
+	if err := s.enqueueOnce("test", start); err != nil {
+		t.Fatal(err)
+	}
+	if run := s.RunDue(context.Background(), handlers); run != 0 {
+		t.Errorf("got %d jobs run for an interval that already ran; want 0", run)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	s := newService(t)
+	job := enqueue(t, s, 0)
+	if ids, err := s.Repositories.Job.Claim(time.Now(), 20*time.Millisecond, 1); err != nil || len(ids) != 1 {
+		t.Fatalf("got claimed jobs %v (%v); want %v", ids, err, job.ID)
+	}
+
+	// A running job keeps its lease for as long as it runs
+	stop := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		s.heartbeat(job.ID, 5*time.Millisecond, stop)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+	<-renewed
+	if ids, err := s.Repositories.Job.Claim(time.Now(), Lease, 1); err != nil || len(ids) != 0 {
+		t.Errorf("got claimed jobs %v (%v) while the job was running; want none", ids, err)
+	}
+
+	// A finished job is not put back in the queue by a late renewal
+	if err := s.Repositories.Job.Complete(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Repositories.Job.Renew(job.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if ids, err := s.Repositories.Job.Claim(time.Now().Add(time.Hour), Lease, 1); err != nil || len(ids) != 0 {
+		t.Errorf("got claimed jobs %v (%v) after the job finished; want none", ids, err)
+	}
+}
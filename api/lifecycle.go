@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Default timeouts used when they are not set in the configuration
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = time.Minute
+	DefaultWriteTimeout      = 90 * time.Second
+	DefaultIdleTimeout       = 2 * time.Minute
+	DefaultShutdownTimeout   = 30 * time.Second
+)
+
+// durationOr returns d, or fallback when d is not set
+func durationOr(d time.Duration, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// ShutdownGracePeriod returns how long in-flight requests and background work are given to finish on shutdown
+func (c *Config) ShutdownGracePeriod() time.Duration {
+	return durationOr(c.ShutdownTimeout, DefaultShutdownTimeout)
+}
+
+// NewHTTPServer creates an HTTP server for a handler with the timeouts of the configuration
+func NewHTTPServer(config *Config, address string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: durationOr(config.ReadHeaderTimeout, DefaultReadHeaderTimeout),
+		ReadTimeout:       durationOr(config.ReadTimeout, DefaultReadTimeout),
+		WriteTimeout:      durationOr(config.WriteTimeout, DefaultWriteTimeout),
+		IdleTimeout:       durationOr(config.IdleTimeout, DefaultIdleTimeout),
+	}
+}
+
+// Go runs fn in the background. Shutdown waits for it to return through Wait
+func (s *Server) Go(fn func()) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn()
+	}()
+}
+
+// Stop signals long-lived requests such as event streams to end so the server can shut down. It is safe to call
+// more than once
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopping)
+	})
+}
+
+// Stopping returns a channel that is closed once the server starts shutting down
+func (s *Server) Stopping() <-chan struct{} {
+	return s.stopping
+}
+
+// Wait blocks until all background work started with Go has returned or the context is done
+func (s *Server) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
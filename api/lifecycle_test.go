@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestServerShutdown(t *testing.T) {
+	server := NewServer(Config{}, &Providers{}, &Services{})
+	release := make(chan struct{})
+	server.Go(func() {
+		<-release
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := server.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got %v while background work was running; want %v", err, context.DeadlineExceeded)
+	}
+
+	server.Stop()
+	server.Stop()
+	select {
+	case <-server.Stopping():
+	default:
+		t.Fatal("stopping channel was not closed")
+	}
+
+	close(release)
+	if err := server.Wait(context.Background()); err != nil {
+		t.Fatalf("got %v after background work returned; want nil", err)
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/segmentio/ksuid"
+)
+
+// RequestIDHeader represents the header a request ID is read from and echoed in
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern matches request IDs accepted from clients and proxies
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// NewLogger creates a leveled structured logger. Level is one of "debug", "info", "warn" or "error" and
+// format is either "json" or "text", defaulting to "info" and "json" respectively
+func NewLogger(w io.Writer, level string, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("unknown log level '%s'", level)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "", "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format '%s'", format)
+}
+
+// MwRequestID assigns every request an ID, reusing the one sent by the client or a proxy when it is well-formed,
+// and echoes it in the response headers
+func MwRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = ksuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), RequestIDContextKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// MwRequestLogger generates a middleware closure that gives every request a logger tagged with its request ID
+// and logs every completed request
+func MwRequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			reqLogger := logger.With("requestId", RequestID(r))
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ctx := context.WithValue(r.Context(), LoggerContextKey, reqLogger)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+			reqLogger.Info(
+				"Request handled",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", ww.Status(),
+				"bytes", ww.BytesWritten(),
+				"duration", time.Since(start),
+			)
+		})
+	}
+}
+
+// RequestID returns the ID assigned to the request, or an empty string if it was not assigned one
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value(RequestIDContextKey).(string)
+	return id
+}
+
+// Logger returns the logger assigned to the request, falling back to the default logger
+func Logger(r *http.Request) *slog.Logger {
+	if logger, ok := r.Context().Value(LoggerContextKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestErrorCorrelation(t *testing.T) {
+	var logs bytes.Buffer
+	logger, err := NewLogger(&logs, "info", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := MwRequestID(MwRequestLogger(logger)(Handler(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("pq: connection refused")
+	})))
+
+	tests := []struct {
+		name   string
+		header string
+		reuse  bool
+	}{
+		{"generated", "", false},
+		{"forwarded", "edge-1234.abc", true},
+		{"malformed", "bad id\nwith newline", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, tt.header)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if id == "" || (tt.reuse && id != tt.header) || (!tt.reuse && id == tt.header) {
+				t.Fatalf("got request ID %q for header %q", id, tt.header)
+			}
+			var body Error
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.RequestID != id || body.Code != ErrCodeInternal {
+				t.Errorf("got error %+v; want an internal error with request ID %q", body, id)
+			}
+			if strings.Contains(w.Body.String(), "connection refused") {
+				t.Error("internal error details were sent to the client")
+			}
+			if !strings.Contains(logs.String(), "pq: connection refused") || !strings.Contains(logs.String(), id) {
+				t.Errorf("logs do not correlate the error with the request: %s", logs.String())
+			}
+		})
+	}
+}
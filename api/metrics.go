@@ -0,0 +1,187 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-redis/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsNamespace prefixes every metric exposed by the API
+const metricsNamespace = "cbs"
+
+// SessionCountInterval represents how long the count of active sessions is reused before sessions are counted
+// again, since counting them scans every session key
+const SessionCountInterval = time.Minute
+
+var (
+	// RequestsTotal counts handled requests by method, route pattern and status
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "Number of handled HTTP requests.",
+	}, []string{"method", "route", "status"})
+
+	// RequestDuration observes request latency by method, route pattern and status
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// ErrorsTotal counts API error responses by error code
+	ErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_errors_total",
+		Help:      "Number of API error responses.",
+	}, []string{"code"})
+
+	// RedisCommandDuration observes Redis command latency by command name
+	RedisCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "redis_command_duration_seconds",
+		Help:      "Time taken to run Redis commands.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command"})
+
+	// StorageDuration observes storage operation latency by operation
+	StorageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Time taken by file storage operations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// ImageOptimizationDuration observes the time taken to resize and re-encode uploaded images
+	ImageOptimizationDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "image_optimization_duration_seconds",
+		Help:      "Time taken to optimize uploaded images.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// SessionCountErrors counts the times active sessions could not be counted
+	SessionCountErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "session_count_errors_total",
+		Help:      "Number of failed attempts to count active sessions.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		RequestsTotal,
+		RequestDuration,
+		ErrorsTotal,
+		RedisCommandDuration,
+		StorageDuration,
+		ImageOptimizationDuration,
+		SessionCountErrors,
+	)
+}
+
+// RegisterProviderMetrics exposes the database pool statistics and active session count, and starts timing
+// Redis commands. Sessions are counted at most once per SessionCountInterval
+func RegisterProviderMetrics(providers *Providers) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(providers.DB.DB, "postgres"))
+	sessions := &sessionCount{count: func() (int, error) { return countSessions(providers.Redis) }}
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "active_sessions",
+		Help:      "Number of user sessions that have not expired.",
+	}, func() float64 {
+		return float64(sessions.Get(time.Now()))
+	}))
+	providers.Redis.WrapProcess(func(next func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			start := time.Now()
+			err := next(cmd)
+			RedisCommandDuration.WithLabelValues(cmd.Name()).Observe(time.Since(start).Seconds())
+			return err
+		}
+	})
+}
+
+// sessionCount caches a count of sessions so that scrapes do not each scan every session key
+type sessionCount struct {
+	sync.Mutex
+	count     func() (int, error)
+	value     int
+	countedAt time.Time
+}
+
+// Get returns the cached count of sessions, counting them again when the count is older than
+// SessionCountInterval. Failed counts are recorded in SessionCountErrors and retried on the next call, while
+// the last successful count is returned
+func (c *sessionCount) Get(now time.Time) int {
+	c.Lock()
+	defer c.Unlock()
+	if !c.countedAt.IsZero() && now.Sub(c.countedAt) < SessionCountInterval {
+		return c.value
+	}
+	value, err := c.count()
+	if err != nil {
+		SessionCountErrors.Inc()
+		return c.value
+	}
+	c.value = value
+	c.countedAt = now
+	return c.value
+}
+
+// countSessions counts the sessions stored in Redis
+func countSessions(client *redis.Client) (int, error) {
+	count := 0
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(cursor, "session:*", 1000).Result()
+		if err != nil {
+			return 0, err
+		}
+		count += len(keys)
+		if next == 0 {
+			return count, nil
+		}
+		cursor = next
+	}
+}
+
+// MetricsHandler serves every registered metric in the Prometheus exposition format
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// MwMetrics records the count and duration of every request, labelled by the route pattern it matched
+func MwMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		// Patterns rather than paths are used as labels so that IDs do not create a series per resource
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := prometheus.Labels{"method": r.Method, "route": route, "status": strconv.Itoa(status)}
+		RequestsTotal.With(labels).Inc()
+		RequestDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveDuration records the time elapsed since start on a histogram
+func ObserveDuration(observer prometheus.Observer, start time.Time) {
+	observer.Observe(time.Since(start).Seconds())
+}
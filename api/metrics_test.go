@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMwMetrics(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(MwMetrics)
+	router.Get("/universes/{universeID}", Handler(func(w http.ResponseWriter, r *http.Request) error {
+		return ErrNotFound("")
+	}).ServeHTTP)
+
+	labels := prometheus.Labels{"method": "GET", "route": "/universes/{universeID}", "status": "404"}
+	before := testutil.ToFloat64(RequestsTotal.With(labels))
+	errorsBefore := testutil.ToFloat64(ErrorsTotal.WithLabelValues(string(ErrCodeNotFound)))
+	for _, id := range []string{"a", "b"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/universes/"+id, nil))
+	}
+	if got := testutil.ToFloat64(RequestsTotal.With(labels)) - before; got != 2 {
+		t.Errorf("got %v requests for the route pattern; want 2", got)
+	}
+	if got := testutil.ToFloat64(ErrorsTotal.WithLabelValues(string(ErrCodeNotFound))) - errorsBefore; got != 2 {
+		t.Errorf("got %v not found errors; want 2", got)
+	}
+}
+
+func TestSessionCount(t *testing.T) {
+	counts := []int{-1, 3, -1, 5}
+	calls := 0
+	sessions := &sessionCount{count: func() (int, error) {
+		calls++
+		if counts[calls-1] < 0 {
+			return 0, errors.New("scan failed")
+		}
+		return counts[calls-1], nil
+	}}
+	errorsBefore := testutil.ToFloat64(SessionCountErrors)
+	start := time.Now()
+	steps := []struct {
+		at   time.Duration
+		want int
+	}{
+		{at: 0, want: 0},
+		{at: time.Second, want: 3},
+		{at: SessionCountInterval, want: 3},
+		{at: SessionCountInterval + time.Second, want: 3},
+		{at: SessionCountInterval + 2*time.Second, want: 5},
+	}
+	for _, step := range steps {
+		if got := sessions.Get(start.Add(step.at)); got != step.want {
+			t.Errorf("got %d sessions after %v; want %d", got, step.at, step.want)
+		}
+	}
+	if calls != 4 {
+		t.Errorf("got %d counts; want 4", calls)
+	}
+	if got := testutil.ToFloat64(SessionCountErrors) - errorsBefore; got != 2 {
+		t.Errorf("got %v count errors; want 2", got)
+	}
+}
@@ -91,3 +91,40 @@ func MwCharacter(services *Services) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// MwWebhook generates a middleware closure that stores a Webhook of the request universe in the request context
+func MwWebhook(services *Services) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Handler(func(w http.ResponseWriter, r *http.Request) error {
+			universe, _ := r.Context().Value(UniverseContextKey).(*models.Universe)
+			webhook, err := services.Webhook.FindByID(universe, chi.URLParam(r, "webhookID"))
+			if err != nil {
+				return err
+			}
+			ctx := context.WithValue(r.Context(), WebhookContextKey, webhook)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return nil
+		})
+	}
+}
+
+// MwShare generates a middleware closure that stores the share link of the request token and its universe in the
+// request context. Unknown and expired tokens are rejected alike
+func MwShare(services *Services) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Handler(func(w http.ResponseWriter, r *http.Request) error {
+			share, err := services.Share.FindByToken(chi.URLParam(r, "token"))
+			if err != nil {
+				return err
+			}
+			universe, err := services.Universe.FindByID(share.UniverseID)
+			if err != nil {
+				return ErrNotFound("Share not found")
+			}
+			ctx := context.WithValue(r.Context(), ShareContextKey, share)
+			ctx = context.WithValue(ctx, UniverseContextKey, universe)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return nil
+		})
+	}
+}
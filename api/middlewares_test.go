@@ -40,6 +40,9 @@ func (m *AuthMock) Login(*models.User, http.ResponseWriter) error {
 func (m *AuthMock) Logout(http.ResponseWriter) error {
 	return nil
 }
+func (m *AuthMock) ClearSessions(*models.User) (int, error) {
+	return 0, nil
+}
 
 func TestMwUserSession(t *testing.T) {
 	services := &Services{Auth: &AuthMock{Config: nil, Providers: nil}}
@@ -0,0 +1,357 @@
+package openapi
+
+import (
+	"cbs/api"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// Version represents the version of the OpenAPI specification that documents are generated for
+const Version = "3.0.3"
+
+// sessionScheme represents the name of the security scheme of session cookies
+const sessionScheme = "session"
+
+// Minimum collaborator roles of operations
+const (
+	RoleMember = "member"
+	RoleAdmin  = "admin"
+	RoleOwner  = "owner"
+)
+
+// Conditional request headers of resources with entity tags
+var (
+	IfMatch = Parameter{
+		Name:        "If-Match",
+		In:          "header",
+		Description: "Entity tag the resource must still have for the change to apply",
+	}
+	IfNoneMatch = Parameter{
+		Name:        "If-None-Match",
+		In:          "header",
+		Description: "Entity tag of a cached copy, answered with 304 Not Modified while it is current",
+	}
+)
+
+// pathParamPattern matches the URL parameters of chi route patterns
+var pathParamPattern = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// Operation describes a route of a router for the OpenAPI document
+type Operation struct {
+	// Method and Path identify the route, with Path relative to the router it is registered on
+	Method string
+	Path   string
+
+	Summary     string
+	Description string
+
+	// Session represents whether the route requires a user session
+	Session bool
+
+	// Role represents the minimum collaborator role required in the universe of the route, if any
+	Role string
+
+	// Parameters represents the query and header parameters. Path parameters are read from the route pattern
+	Parameters []Parameter
+
+	// Body represents the accepted request bodies, one per media type
+	Body []Content
+
+	Responses []Response
+}
+
+// Parameter describes a query or header parameter of an operation
+type Parameter struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Schema      *Schema
+}
+
+// Content describes a request or response body of a media type
+type Content struct {
+	// MediaType defaults to application/json
+	MediaType string
+
+	// Body represents a value of the Go type of the body, or nil for bodies without a schema such as files
+	Body interface{}
+
+	// Form represents the parts of a multipart/form-data body
+	Form []FormPart
+}
+
+// FormPart describes a part of a multipart/form-data body
+type FormPart struct {
+	Name     string
+	Required bool
+
+	// Body represents a value of the Go type of a JSON part, or nil for a file
+	Body interface{}
+}
+
+// Response describes a successful response of an operation. Error responses are documented for every operation
+type Response struct {
+	Status      int
+	Description string
+	Headers     []string
+	Content     []Content
+}
+
+// Mount describes a router mounted on the API and the operations of its routes
+type Mount struct {
+	Prefix     string
+	Tag        string
+	Router     chi.Routes
+	Operations []Operation
+}
+
+// Info represents the metadata of an OpenAPI document
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Document represents an OpenAPI document
+type Document struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       Info                                   `json:"info"`
+	Paths      map[string]map[string]*operationObject `json:"paths"`
+	Components components                             `json:"components"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in"`
+	Name string `json:"name"`
+}
+
+type operationObject struct {
+	Tags        []string                   `json:"tags,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Parameters  []parameterObject          `json:"parameters,omitempty"`
+	RequestBody *requestBodyObject         `json:"requestBody,omitempty"`
+	Responses   map[string]*responseObject `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+	Role        string                     `json:"x-collaborator-role,omitempty"`
+}
+
+type parameterObject struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBodyObject struct {
+	Required bool                        `json:"required"`
+	Content  map[string]*mediaTypeObject `json:"content"`
+}
+
+type responseObject struct {
+	Description string                      `json:"description"`
+	Headers     map[string]*headerObject    `json:"headers,omitempty"`
+	Content     map[string]*mediaTypeObject `json:"content,omitempty"`
+}
+
+type headerObject struct {
+	Schema *Schema `json:"schema"`
+}
+
+type mediaTypeObject struct {
+	Schema   *Schema                    `json:"schema,omitempty"`
+	Encoding map[string]*encodingObject `json:"encoding,omitempty"`
+}
+
+type encodingObject struct {
+	ContentType string `json:"contentType"`
+}
+
+// Build generates an OpenAPI document from the routes of mounted routers. Every route must be described by an
+// operation and every operation must match a route, so the document cannot drift from the routers
+func Build(info Info, mounts ...Mount) (*Document, error) {
+	b := newSchemaBuilder()
+	errorSchema := b.schemaOf(api.Error{})
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]*operationObject{},
+		Components: components{
+			Schemas: b.schemas,
+			SecuritySchemes: map[string]securityScheme{
+				sessionScheme: {Type: "apiKey", In: "cookie", Name: "user_session"},
+			},
+		},
+	}
+	var problems []string
+	for _, mount := range mounts {
+		operations := make(map[string]Operation, len(mount.Operations))
+		for _, op := range mount.Operations {
+			operations[op.Method+" "+op.Path] = op
+		}
+		err := chi.Walk(mount.Router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			route = normalizePath(route)
+			key := method + " " + route
+			op, ok := operations[key]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s %s is not documented", method, joinPath(mount.Prefix, route)))
+				return nil
+			}
+			delete(operations, key)
+			path := joinPath(mount.Prefix, route)
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = map[string]*operationObject{}
+			}
+			doc.Paths[path][strings.ToLower(method)] = b.operation(mount.Tag, path, op, errorSchema)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, op := range operations {
+			problems = append(problems, fmt.Sprintf(
+				"%s %s is documented but not routed",
+				op.Method,
+				joinPath(mount.Prefix, op.Path),
+			))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, errors.New("OpenAPI document does not match the routes:\n  - " + strings.Join(problems, "\n  - "))
+	}
+	return doc, nil
+}
+
+// normalizePath removes the trailing slash chi reports for the index route of subrouters
+func normalizePath(route string) string {
+	if len(route) > 1 {
+		return strings.TrimSuffix(route, "/")
+	}
+	return route
+}
+
+// joinPath joins the prefix of a mounted router with the path of one of its routes
+func joinPath(prefix string, route string) string {
+	if route == "/" && prefix != "" {
+		return prefix
+	}
+	return prefix + route
+}
+
+// operation generates the operation object of a documented route
+func (b *schemaBuilder) operation(tag string, path string, op Operation, errorSchema *Schema) *operationObject {
+	o := &operationObject{
+		Summary:     op.Summary,
+		Description: op.Description,
+		Role:        op.Role,
+		Responses: map[string]*responseObject{
+			"default": {
+				Description: "Error",
+				Content:     map[string]*mediaTypeObject{"application/json": {Schema: errorSchema}},
+			},
+		},
+	}
+	if tag != "" {
+		o.Tags = []string{tag}
+	}
+	if op.Session {
+		o.Security = []map[string][]string{{sessionScheme: {}}}
+	}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		o.Parameters = append(o.Parameters, parameterObject{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   StringSchema(),
+		})
+	}
+	for _, p := range op.Parameters {
+		schema := p.Schema
+		if schema == nil {
+			schema = StringSchema()
+		}
+		o.Parameters = append(o.Parameters, parameterObject{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required,
+			Schema:      schema,
+		})
+	}
+	if len(op.Body) > 0 {
+		o.RequestBody = &requestBodyObject{Required: true, Content: b.content(op.Body)}
+	}
+	for _, r := range op.Responses {
+		res := &responseObject{Description: r.Description, Content: b.content(r.Content)}
+		if len(res.Content) == 0 {
+			res.Content = nil
+		}
+		for _, h := range r.Headers {
+			if res.Headers == nil {
+				res.Headers = map[string]*headerObject{}
+			}
+			res.Headers[h] = &headerObject{Schema: StringSchema()}
+		}
+		o.Responses[strconv.Itoa(r.Status)] = res
+	}
+	return o
+}
+
+// content generates the media type objects of request or response bodies
+func (b *schemaBuilder) content(contents []Content) map[string]*mediaTypeObject {
+	media := make(map[string]*mediaTypeObject, len(contents))
+	for _, c := range contents {
+		mediaType := c.MediaType
+		if mediaType == "" {
+			mediaType = "application/json"
+		}
+		if len(c.Form) == 0 {
+			media[mediaType] = &mediaTypeObject{Schema: b.schemaOf(c.Body)}
+			continue
+		}
+		form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		encoding := map[string]*encodingObject{}
+		for _, part := range c.Form {
+			if part.Body == nil {
+				form.Properties[part.Name] = &Schema{Type: "string", Format: "binary"}
+			} else {
+				form.Properties[part.Name] = b.schemaOf(part.Body)
+				encoding[part.Name] = &encodingObject{ContentType: "application/json"}
+			}
+			if part.Required {
+				form.Required = append(form.Required, part.Name)
+			}
+		}
+		media[mediaType] = &mediaTypeObject{Schema: form, Encoding: encoding}
+	}
+	return media
+}
+
+// Handler creates a handler that serves a document as JSON
+func Handler(doc *Document) (http.Handler, error) {
+	serialized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(serialized)
+	}), nil
+}
@@ -0,0 +1,57 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+func TestBuildDetectsDrift(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	router := chi.NewRouter()
+	router.Get("/", noop)
+	router.Route("/{itemID}", func(r chi.Router) {
+		r.Get("/", noop)
+	})
+	get := func(path string) Operation {
+		return Operation{Method: http.MethodGet, Path: path}
+	}
+
+	tests := []struct {
+		name       string
+		operations []Operation
+		problems   []string
+	}{
+		{"documented", []Operation{get("/"), get("/{itemID}")}, nil},
+		{"undocumented route", []Operation{get("/")}, []string{"GET /items/{itemID} is not documented"}},
+		{
+			"stale operation",
+			[]Operation{get("/"), get("/{itemID}"), get("/{itemID}/parts")},
+			[]string{"GET /items/{itemID}/parts is documented but not routed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Build(Info{}, Mount{Prefix: "/items", Router: router, Operations: tt.operations})
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := doc.Paths["/items/{itemID}"]["get"]; !ok {
+					t.Errorf("got paths %v; want /items/{itemID} to be documented", doc.Paths)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("got no error; want the drift to be reported")
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("got error %q; want it to contain %q", err, problem)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Schema represents an OpenAPI schema object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+}
+
+// StringSchema creates a schema for a string, optionally restricted to a set of values
+func StringSchema(enum ...string) *Schema {
+	s := &Schema{Type: "string"}
+	for _, v := range enum {
+		s.Enum = append(s.Enum, v)
+	}
+	return s
+}
+
+// IntegerSchema creates a schema for an integer
+func IntegerSchema() *Schema {
+	return &Schema{Type: "integer"}
+}
+
+// BooleanSchema creates a schema for a boolean
+func BooleanSchema() *Schema {
+	return &Schema{Type: "boolean"}
+}
+
+// schemaBuilder generates schemas from Go types, collecting every struct type as a reusable component
+type schemaBuilder struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+// schemaOf returns the schema of the type of a value, or nil when v is nil
+func (b *schemaBuilder) schemaOf(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+	return b.schema(reflect.TypeOf(v))
+}
+
+// schema returns the schema of a type as encoding/json marshals it
+func (b *schemaBuilder) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		return b.ref(t)
+	}
+	// Interfaces accept any value
+	return &Schema{}
+}
+
+// ref registers a named struct type as a component and returns a reference to it
+func (b *schemaBuilder) ref(t reflect.Type) *Schema {
+	name, ok := b.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := b.schemas[name]; taken {
+			// Types of different packages may share a name
+			pkg := pkgName(t)
+			name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+		b.names[t] = name
+		// The placeholder is registered first so recursive types terminate
+		b.schemas[name] = &Schema{}
+		*b.schemas[name] = *b.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// pkgName returns the last element of the import path of a type
+func pkgName(t reflect.Type) string {
+	path := t.PkgPath()
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// object generates the schema of a struct type
+func (b *schemaBuilder) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	b.addFields(s, t, false)
+	if len(s.Properties) == 0 {
+		s.Properties = nil
+	}
+	return s
+}
+
+// addFields adds the JSON properties of a struct type to an object schema. Fields of embedded structs are
+// flattened the same way encoding/json does, without replacing the fields of the outer struct
+func (b *schemaBuilder) addFields(s *Schema, t reflect.Type, embedded bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts := f.Tag.Get("json"), ""
+		if j := strings.Index(name, ","); j >= 0 {
+			name, opts = name[:j], name[j+1:]
+		}
+		if name == "-" && opts == "" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			b.addFields(s, ft, true)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, exists := s.Properties[name]; exists && embedded {
+			continue
+		}
+		prop := b.schema(f.Type)
+		if applyValidation(prop, ft, f.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+}
+
+// applyValidation describes go-validator rules of a field in its schema and returns whether the field is
+// required. Rules after "dive" apply to elements and cross-field rules cannot be described, so both are skipped
+func applyValidation(s *Schema, t reflect.Type, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		switch name {
+		case "dive":
+			return required
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				if s.Type == "integer" {
+					n, err := strconv.Atoi(v)
+					if err == nil {
+						s.Enum = append(s.Enum, n)
+						continue
+					}
+				}
+				s.Enum = append(s.Enum, v)
+			}
+		case "min", "gte":
+			setBound(s, t, param, true, false)
+		case "max", "lte":
+			setBound(s, t, param, false, false)
+		case "gt":
+			setBound(s, t, param, true, true)
+		case "lt":
+			setBound(s, t, param, false, true)
+		case "len":
+			setBound(s, t, param, true, false)
+			setBound(s, t, param, false, false)
+		}
+	}
+	return required
+}
+
+// setBound sets a length, size or value bound depending on the kind of the field, as go-validator does
+func setBound(s *Schema, t reflect.Type, param string, lower bool, exclusive bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	count := int(n)
+	if exclusive && lower {
+		count++
+	} else if exclusive {
+		count--
+	}
+	switch t.Kind() {
+	case reflect.String:
+		if lower {
+			s.MinLength = &count
+		} else {
+			s.MaxLength = &count
+		}
+	case reflect.Slice, reflect.Array:
+		if lower {
+			s.MinItems = &count
+		} else {
+			s.MaxItems = &count
+		}
+	case reflect.Map, reflect.Struct, reflect.Interface, reflect.Bool:
+	default:
+		if lower {
+			s.Minimum, s.ExclusiveMinimum = &n, exclusive
+		} else {
+			s.Maximum, s.ExclusiveMaximum = &n, exclusive
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package relationships
+
+import (
+	"cbs/api/openapi"
+	"cbs/dtos"
+	"net/http"
+)
+
+// Operations describes the routes of the "relationships" resource for the OpenAPI document
+var Operations = []openapi.Operation{
+	{
+		Method:  http.MethodGet,
+		Path:    "/",
+		Summary: "List the relationships of a universe",
+		Description: "Relationships involving a character the collaborator cannot view are left out, the same way " +
+			"hidden characters are left out of the characters list",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Parameters: []openapi.Parameter{
+			{Name: "character", In: "query", Description: "Only list the relationships of this character"},
+		},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The relationships, oldest first",
+			Content:     []openapi.Content{{Body: dtos.ResGetRelationships{}}},
+		}},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/",
+		Summary: "Relate two characters of a universe",
+		Description: "The type must be one of the relationship types in the universe settings, and two characters " +
+			"can only have one relationship of each type. Members can only relate their own characters to characters " +
+			"they can view",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Body:    []openapi.Content{{Body: dtos.ReqCreateRelationship{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusCreated,
+			Description: "The created relationship",
+			Content:     []openapi.Content{{Body: dtos.ResGetRelationship{}}},
+		}},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/graph/{characterID}",
+		Summary: "Get the neighbourhood of a character as a graph",
+		Description: "Relationships are followed in both directions. Characters the collaborator cannot view are " +
+			"left out along with their relationships, so nothing is reached through them",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Parameters: []openapi.Parameter{{
+			Name:        "depth",
+			In:          "query",
+			Description: "Number of hops away from the character to reach, from 1 to 3 and 1 by default",
+			Schema:      openapi.IntegerSchema(),
+		}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The characters reached and the relationships between them",
+			Content:     []openapi.Content{{Body: dtos.ResGetRelationshipGraph{}}},
+		}},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/{relationshipID}",
+		Summary: "Get a relationship",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The relationship",
+			Content:     []openapi.Content{{Body: dtos.ResGetRelationship{}}},
+		}},
+	},
+	{
+		Method:      http.MethodPatch,
+		Path:        "/{relationshipID}",
+		Summary:     "Edit the type, direction and description of a relationship",
+		Description: "Members can only edit the relationships pointing from their own characters",
+		Session:     true,
+		Role:        openapi.RoleMember,
+		Body:        []openapi.Content{{Body: dtos.ReqEditRelationship{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The edited relationship",
+			Content:     []openapi.Content{{Body: dtos.ResGetRelationship{}}},
+		}},
+	},
+	{
+		Method:      http.MethodDelete,
+		Path:        "/{relationshipID}",
+		Summary:     "Delete a relationship",
+		Description: "Members can only delete the relationships pointing from their own characters",
+		Session:     true,
+		Role:        openapi.RoleMember,
+		Responses:   []openapi.Response{{Status: http.StatusNoContent, Description: "The relationship was deleted"}},
+	},
+}
@@ -0,0 +1,227 @@
+package relationships
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"cbs/repositories"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// Router represents a router for the "relationships" resource
+type Router api.Router
+
+// NewRouter creates a new router assigned to the "relationships" resource, through which collaborators relate
+// the characters of a universe and explore how they are connected
+func NewRouter(server *api.Server) *Router {
+	router := &Router{
+		Mux:    chi.NewMux(),
+		Server: server}
+	router.Use(
+		server.Middlewares.UserSession,
+		server.Middlewares.Universe,
+		server.Middlewares.Collaborator(models.CollaboratorMember),
+	)
+	router.Get("/", api.Handler(router.GetRelationships).ServeHTTP)
+	router.Post("/", api.Handler(router.CreateRelationship).ServeHTTP)
+	router.Get("/graph/{characterID}", api.Handler(router.GetGraph).ServeHTTP)
+	router.Get("/{relationshipID}", api.Handler(router.GetRelationship).ServeHTTP)
+	router.Patch("/{relationshipID}", api.Handler(router.EditRelationship).ServeHTTP)
+	router.Delete("/{relationshipID}", api.Handler(router.DeleteRelationship).ServeHTTP)
+	return router
+}
+
+// findCharacter returns a character of a universe by its ID, reporting characters of other universes as not found
+func (m *Router) findCharacter(universe *models.Universe, id string) (*models.Character, error) {
+	character, err := m.Services.Character.FindByID(id)
+	if err == repositories.ErrNotFound || (err == nil && character.UniverseID != universe.ID) {
+		return nil, api.ErrNotFound("Character not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return character, nil
+}
+
+// findRelationship returns the relationship of a request along with the character it points from. Members do
+// not find relationships involving a character they cannot view
+func (m *Router) findRelationship(r *http.Request) (*models.Relationship, *models.Character, error) {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	relationship, err := m.Services.Relationship.FindByID(universe, chi.URLParam(r, "relationshipID"))
+	if err != nil {
+		return nil, nil, err
+	}
+	source, err := m.findCharacter(universe, relationship.SourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := m.findCharacter(universe, relationship.TargetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !collaborator.CanViewCharacter(source.Meta.Hidden, source.Owner.ID) ||
+		!collaborator.CanViewCharacter(target.Meta.Hidden, target.Owner.ID) {
+		return nil, nil, api.ErrNotFound("Relationship not found")
+	}
+	return relationship, source, nil
+}
+
+// canEdit reports whether a collaborator is allowed to edit the relationships pointing from a character. Members
+// can only edit those of their own characters
+func canEdit(collaborator *models.Collaborator, source *models.Character) bool {
+	return collaborator.Role != models.CollaboratorMember || collaborator.UserID == source.Owner.ID
+}
+
+// GetRelationships represents a route that returns the relationships of a universe, or those of a single
+// character when the "character" parameter is set
+func (m *Router) GetRelationships(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	relationships, err := m.Services.Relationship.FindByUniverse(
+		universe,
+		collaborator,
+		r.URL.Query().Get("character"),
+	)
+	if err != nil {
+		return err
+	}
+	api.SendResponse(w, dtos.ResGetRelationships{Relationships: relationships}, http.StatusOK)
+	return nil
+}
+
+// CreateRelationship represents a route that relates two characters. Members can only relate their own
+// characters to characters they can view
+func (m *Router) CreateRelationship(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	var payload dtos.ReqCreateRelationship
+	if err := api.ReadAndValidateBody(r.Body, &payload); err != nil {
+		return err
+	}
+	source, err := m.findCharacter(universe, payload.SourceID)
+	if err != nil {
+		return err
+	}
+	target, err := m.findCharacter(universe, payload.TargetID)
+	if err != nil {
+		return err
+	}
+	if !canEdit(collaborator, source) || !collaborator.CanViewCharacter(target.Meta.Hidden, target.Owner.ID) {
+		return api.ErrBadAuth("You do not have permission to relate these characters")
+	}
+	relationship, err := m.Services.Relationship.New(universe, payload)
+	if err != nil {
+		return err
+	}
+	if err := m.Services.Relationship.Create(relationship); err != nil {
+		return err
+	}
+	m.RecordAudit(user, &models.AuditEvent{
+		UniverseID: universe.ID,
+		Action:     models.AuditRelationshipCreate,
+		TargetType: models.AuditTargetRelationship,
+		TargetID:   relationship.ID,
+		After:      relationship.AuditSummary(),
+	})
+	api.SendResponse(w, dtos.ResGetRelationship{Relationship: relationship}, http.StatusCreated)
+	return nil
+}
+
+// GetRelationship represents a route that returns a relationship
+func (m *Router) GetRelationship(w http.ResponseWriter, r *http.Request) error {
+	relationship, _, err := m.findRelationship(r)
+	if err != nil {
+		return err
+	}
+	api.SendResponse(w, dtos.ResGetRelationship{Relationship: relationship}, http.StatusOK)
+	return nil
+}
+
+// EditRelationship represents a route that changes the type, direction and description of a relationship
+func (m *Router) EditRelationship(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	relationship, source, err := m.findRelationship(r)
+	if err != nil {
+		return err
+	}
+	if !canEdit(collaborator, source) {
+		return api.ErrBadAuth("You do not have permission to edit this relationship")
+	}
+	var payload dtos.ReqEditRelationship
+	if err := api.ReadAndValidateBody(r.Body, &payload); err != nil {
+		return err
+	}
+	before := relationship.AuditSummary()
+	if err := m.Services.Relationship.Update(universe, relationship, payload); err != nil {
+		return err
+	}
+	m.RecordAudit(user, &models.AuditEvent{
+		UniverseID: universe.ID,
+		Action:     models.AuditRelationshipUpdate,
+		TargetType: models.AuditTargetRelationship,
+		TargetID:   relationship.ID,
+		Before:     before,
+		After:      relationship.AuditSummary(),
+	})
+	api.SendResponse(w, dtos.ResGetRelationship{Relationship: relationship}, http.StatusOK)
+	return nil
+}
+
+// DeleteRelationship represents a route that removes a relationship
+func (m *Router) DeleteRelationship(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	relationship, source, err := m.findRelationship(r)
+	if err != nil {
+		return err
+	}
+	if !canEdit(collaborator, source) {
+		return api.ErrBadAuth("You do not have permission to delete this relationship")
+	}
+	if err := m.Services.Relationship.Delete(relationship); err != nil {
+		return err
+	}
+	m.RecordAudit(user, &models.AuditEvent{
+		UniverseID: universe.ID,
+		Action:     models.AuditRelationshipDelete,
+		TargetType: models.AuditTargetRelationship,
+		TargetID:   relationship.ID,
+		Before:     relationship.AuditSummary(),
+	})
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// GetGraph represents a route that returns the neighbourhood of a character as nodes and edges, reaching as many
+// hops away as the "depth" parameter asks for, one by default
+func (m *Router) GetGraph(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	character, err := m.findCharacter(universe, chi.URLParam(r, "characterID"))
+	if err != nil {
+		return err
+	}
+	if !collaborator.CanViewCharacter(character.Meta.Hidden, character.Owner.ID) {
+		return api.ErrBadAuth("You do not have permission to view this character")
+	}
+	depth := 1
+	if d := r.URL.Query().Get("depth"); d != "" {
+		if depth, err = strconv.Atoi(d); err != nil {
+			return api.ErrBadBody("Depth must be a number")
+		}
+	}
+	graph, err := m.Services.Relationship.Graph(universe, collaborator, character, depth)
+	if err != nil {
+		return err
+	}
+	api.SendResponse(w, dtos.ResGetRelationshipGraph{RelationshipGraph: graph}, http.StatusOK)
+	return nil
+}
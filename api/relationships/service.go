@@ -0,0 +1,231 @@
+package relationships
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"cbs/repositories"
+	"fmt"
+)
+
+// MaxGraphDepth represents the largest number of hops the graph of a character reaches
+const MaxGraphDepth = 3
+
+// Service represents a service implementation for the "relationships" resource
+type Service api.Service
+
+// checkType returns an error when a relationship type is not one of the relationship types of a universe
+func checkType(universe *models.Universe, relationshipType string) error {
+	if universe.Settings == nil || !universe.Settings.HasRelationshipType(relationshipType) {
+		return api.ErrBadBody(fmt.Sprintf("Unknown relationship type '%s'", relationshipType))
+	}
+	return nil
+}
+
+// New creates a new relationship between two characters of a universe
+func (s *Service) New(universe *models.Universe, data dtos.ReqCreateRelationship) (*models.Relationship, error) {
+	if err := checkType(universe, data.Type); err != nil {
+		return nil, err
+	}
+	return &models.Relationship{
+		ID:            s.Providers.ShortID.MustGenerate(),
+		UniverseID:    universe.ID,
+		SourceID:      data.SourceID,
+		TargetID:      data.TargetID,
+		Type:          data.Type,
+		Bidirectional: data.Bidirectional,
+		Description:   data.Description,
+	}, nil
+}
+
+// FindByID returns a relationship of a universe by its ID
+func (s *Service) FindByID(universe *models.Universe, id string) (*models.Relationship, error) {
+	relationship, err := s.Repositories.Relationship.FindByID(universe.ID, id)
+	if err == repositories.ErrNotFound {
+		return nil, api.ErrNotFound("Relationship not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return relationship, nil
+}
+
+// FindByUniverse returns the relationships of a universe, or only those of a character when characterID is set.
+// Relationships involving a character the collaborator cannot view are left out
+func (s *Service) FindByUniverse(
+	universe *models.Universe,
+	collaborator *models.Collaborator,
+	characterID string,
+) (*[]models.Relationship, error) {
+	var characterIDs []string
+	if characterID != "" {
+		characterIDs = []string{characterID}
+	}
+	relationships, err := s.Repositories.Relationship.FindByCharacters(universe.ID, characterIDs)
+	if err != nil {
+		return nil, err
+	}
+	if collaborator.Role != models.CollaboratorMember {
+		return &relationships, nil
+	}
+
+	involved := make([]string, 0, 2*len(relationships))
+	for _, r := range relationships {
+		involved = append(involved, r.SourceID, r.TargetID)
+	}
+	references, err := s.visible(universe, collaborator, involved)
+	if err != nil {
+		return nil, err
+	}
+	visible := make(map[string]bool, len(references))
+	for _, reference := range references {
+		visible[reference.ID] = true
+	}
+	filtered := make([]models.Relationship, 0, len(relationships))
+	for _, r := range relationships {
+		if visible[r.SourceID] && visible[r.TargetID] {
+			filtered = append(filtered, r)
+		}
+	}
+	return &filtered, nil
+}
+
+// visible returns references to the characters among the given IDs that a collaborator can view, ordered by
+// name. Hidden names are obscured from members that do not own the character
+func (s *Service) visible(
+	universe *models.Universe,
+	collaborator *models.Collaborator,
+	ids []string,
+) ([]models.CharacterReference, error) {
+	references, err := s.Repositories.Character.FindReferences(universe.ID, ids)
+	if err != nil {
+		return nil, err
+	}
+	visible := make([]models.CharacterReference, 0, len(references))
+	for _, reference := range references {
+		if !collaborator.CanViewCharacter(reference.Hidden, reference.OwnerID) {
+			continue
+		}
+		if collaborator.Role == models.CollaboratorMember && reference.OwnerID != collaborator.UserID {
+			reference.HideHiddenFields()
+		}
+		visible = append(visible, reference)
+	}
+	return visible, nil
+}
+
+// Graph returns the neighbourhood of a character up to depth hops away, following relationships in both
+// directions. Characters the collaborator cannot view are left out along with their relationships, so nothing
+// is reached through them
+func (s *Service) Graph(
+	universe *models.Universe,
+	collaborator *models.Collaborator,
+	character *models.Character,
+	depth int,
+) (*models.RelationshipGraph, error) {
+	if depth < 1 || depth > MaxGraphDepth {
+		return nil, api.ErrBadBody(fmt.Sprintf("Depth must be between 1 and %d", MaxGraphDepth))
+	}
+	root, err := s.visible(universe, collaborator, []string{character.ID})
+	if err != nil {
+		return nil, err
+	}
+	if len(root) == 0 {
+		return nil, api.ErrNotFound("Character not found")
+	}
+	graph := &models.RelationshipGraph{Nodes: root, Edges: make([]models.Relationship, 0)}
+
+	// Every hop reads the relationships of the characters reached by the previous hop. Those of the characters
+	// reached by the last hop are read too, for the edges between characters already in the graph
+	seen := map[string]bool{character.ID: true}
+	nodes := map[string]bool{character.ID: true}
+	edges := make(map[string]bool)
+	frontier := []string{character.ID}
+	for hop := 0; len(frontier) > 0; hop++ {
+		relationships, err := s.Repositories.Relationship.FindByCharacters(universe.ID, frontier)
+		if err != nil {
+			return nil, err
+		}
+		frontier = nil
+		if hop < depth {
+			unseen := make([]string, 0)
+			for _, r := range relationships {
+				for _, id := range []string{r.SourceID, r.TargetID} {
+					if !seen[id] {
+						seen[id] = true
+						unseen = append(unseen, id)
+					}
+				}
+			}
+			reached, err := s.visible(universe, collaborator, unseen)
+			if err != nil {
+				return nil, err
+			}
+			for _, reference := range reached {
+				nodes[reference.ID] = true
+				graph.Nodes = append(graph.Nodes, reference)
+				frontier = append(frontier, reference.ID)
+			}
+		}
+		for _, r := range relationships {
+			if nodes[r.SourceID] && nodes[r.TargetID] && !edges[r.ID] {
+				edges[r.ID] = true
+				graph.Edges = append(graph.Edges, r)
+			}
+		}
+	}
+	return graph, nil
+}
+
+// checkDuplicate returns an error when two characters already have a relationship of a type, whichever way it
+// points
+func (s *Service) checkDuplicate(universeID string, sourceID string, targetID string, relationshipType string) error {
+	existing, err := s.Repositories.Relationship.FindByCharacters(universeID, []string{sourceID})
+	if err != nil {
+		return err
+	}
+	for _, r := range existing {
+		if r.Type == relationshipType && r.Connects(sourceID, targetID) {
+			return api.ErrBadBody(fmt.Sprintf("These characters already have a '%s' relationship", relationshipType))
+		}
+	}
+	return nil
+}
+
+// Create saves a new relationship. Two characters can only have one relationship of each type
+func (s *Service) Create(relationship *models.Relationship) error {
+	if err := s.checkDuplicate(
+		relationship.UniverseID,
+		relationship.SourceID,
+		relationship.TargetID,
+		relationship.Type,
+	); err != nil {
+		return err
+	}
+	return s.Repositories.Relationship.Create(relationship)
+}
+
+// Update changes the type, direction and description of a relationship
+func (s *Service) Update(
+	universe *models.Universe,
+	relationship *models.Relationship,
+	data dtos.ReqEditRelationship,
+) error {
+	if data.Type != relationship.Type {
+		if err := checkType(universe, data.Type); err != nil {
+			return err
+		}
+		if err := s.checkDuplicate(universe.ID, relationship.SourceID, relationship.TargetID, data.Type); err != nil {
+			return err
+		}
+	}
+	relationship.Type = data.Type
+	relationship.Bidirectional = data.Bidirectional
+	relationship.Description = data.Description
+	return s.Repositories.Relationship.Update(relationship)
+}
+
+// Delete removes a relationship
+func (s *Service) Delete(relationship *models.Relationship) error {
+	return s.Repositories.Relationship.Delete(relationship.ID)
+}
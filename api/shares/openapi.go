@@ -0,0 +1,104 @@
+package shares
+
+import (
+	"cbs/api/openapi"
+	"cbs/dtos"
+	"net/http"
+)
+
+// Operations describes the routes of the "shares" resource for the OpenAPI document
+var Operations = []openapi.Operation{
+	{
+		Method:  http.MethodGet,
+		Path:    "/",
+		Summary: "List the share links of a universe",
+		Description: "Admins and the owner see every share link, while members only see those they created. Each " +
+			"link is opened at /shared/{token}",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The share links",
+			Content:     []openapi.Content{{Body: dtos.ResGetShares{}}},
+		}},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/",
+		Summary: "Create a public share link to a universe or one of its characters",
+		Description: "Only the owner can share the whole universe. Characters can be shared by their owners and " +
+			"by admins. Hidden characters and fields are never exposed through a share link",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Body:    []openapi.Content{{Body: dtos.ReqCreateShare{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusCreated,
+			Description: "The created share link",
+			Content:     []openapi.Content{{Body: dtos.ResGetShare{}}},
+		}},
+	},
+	{
+		Method:      http.MethodDelete,
+		Path:        "/{shareID}",
+		Summary:     "Delete a share link, disabling its token",
+		Description: "Members can only delete the share links they created",
+		Session:     true,
+		Role:        openapi.RoleMember,
+		Responses:   []openapi.Response{{Status: http.StatusNoContent, Description: "The share link was deleted"}},
+	},
+}
+
+// PublicOperations describes the routes serving what share links expose for the OpenAPI document
+var PublicOperations = []openapi.Operation{
+	{
+		Method:      http.MethodGet,
+		Path:        "/",
+		Summary:     "Get what a share link exposes",
+		Description: "Unknown and expired tokens are not found. The character is included when only it is shared",
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The shared universe and character",
+			Content:     []openapi.Content{{Body: dtos.ResGetShared{}}},
+		}},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/characters",
+		Summary:     "List the characters of a shared universe",
+		Description: "Hidden characters are left out and hidden names are obscured",
+		Parameters: []openapi.Parameter{
+			{Name: "p", In: "query", Description: "Zero-based page number", Schema: openapi.IntegerSchema()},
+			{Name: "q", In: "query", Description: "Search query matched against character names"},
+			{
+				Name:        "s",
+				In:          "query",
+				Description: "Sorting order, defaulting to nominal",
+				Schema: openapi.StringSchema(
+					string(dtos.CharacterQuerySortNominal),
+					string(dtos.CharacterQuerySortLexicographical),
+				),
+			},
+		},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "A page of characters",
+			Content:     []openapi.Content{{Body: dtos.ResGetCharacters{}}},
+		}},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/characters/{characterID}",
+		Summary:     "Get a character through a share link",
+		Description: "Hidden fields are obscured, and hidden characters are not found",
+		Parameters:  []openapi.Parameter{openapi.IfNoneMatch},
+		Responses: []openapi.Response{
+			{
+				Status:      http.StatusOK,
+				Description: "The character",
+				Headers:     []string{"ETag"},
+				Content:     []openapi.Content{{Body: dtos.ResGetCharacter{}}},
+			},
+			{Status: http.StatusNotModified, Description: "The cached copy is current"},
+		},
+	},
+}
@@ -0,0 +1,212 @@
+package shares
+
+import (
+	"cbs/api"
+	"cbs/api/characters"
+	"cbs/dtos"
+	"cbs/models"
+	"cbs/repositories"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// Router represents a router for the "shares" resource
+type Router api.Router
+
+// NewRouter creates a new router assigned to the "shares" resource, through which collaborators manage the
+// share links of a universe
+func NewRouter(server *api.Server) *Router {
+	router := &Router{
+		Mux:    chi.NewMux(),
+		Server: server}
+	router.Use(
+		server.Middlewares.UserSession,
+		server.Middlewares.Universe,
+		server.Middlewares.Collaborator(models.CollaboratorMember),
+	)
+	router.Get("/", api.Handler(router.GetShares).ServeHTTP)
+	router.Post("/", api.Handler(router.CreateShare).ServeHTTP)
+	router.Delete("/{shareID}", api.Handler(router.DeleteShare).ServeHTTP)
+	return router
+}
+
+// NewPublicRouter creates a new router serving what share links expose, read-only and without a session
+func NewPublicRouter(server *api.Server) *Router {
+	router := &Router{
+		Mux:    chi.NewMux(),
+		Server: server}
+	router.Use(server.Middlewares.Share)
+	router.Get("/", api.Handler(router.GetShared).ServeHTTP)
+	router.Get("/characters", api.Handler(router.GetSharedCharacters).ServeHTTP)
+	router.Get("/characters/{characterID}", api.Handler(router.GetSharedCharacter).ServeHTTP)
+	return router
+}
+
+// GetShares represents a route that returns the share links of a universe. Members only see those they created
+func (m *Router) GetShares(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	var creator *models.User
+	if collaborator.Role == models.CollaboratorMember {
+		creator = user
+	}
+	shares, err := m.Services.Share.FindByUniverse(universe, creator)
+	if err != nil {
+		return api.ErrInternal("Failed to get shares")
+	}
+	api.SendResponse(w, dtos.ResGetShares{Shares: shares}, http.StatusOK)
+	return nil
+}
+
+// CreateShare represents a route that creates a share link. Only the owner can share the whole universe, while
+// characters can be shared by their owners and by admins
+func (m *Router) CreateShare(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	var payload dtos.ReqCreateShare
+	if err := api.ReadAndValidateBody(r.Body, &payload); err != nil {
+		return err
+	}
+	if payload.CharacterID == "" {
+		if collaborator.Role != models.CollaboratorOwner {
+			return api.ErrBadAuth("Only the owner can share the universe")
+		}
+	} else {
+		character, err := m.Services.Character.FindByID(payload.CharacterID)
+		if err == repositories.ErrNotFound || (err == nil && character.UniverseID != universe.ID) {
+			return api.ErrNotFound("Character not found")
+		}
+		if err != nil {
+			return err
+		}
+		if collaborator.Role == models.CollaboratorMember && character.Owner.ID != user.ID {
+			return api.ErrBadAuth("You do not have permission to share this character")
+		}
+	}
+	share, err := m.Services.Share.New(universe, user, payload)
+	if err != nil {
+		return err
+	}
+	if err := m.Services.Share.Create(share); err != nil {
+		return api.ErrInternal("Failed to create share")
+	}
+	m.RecordAudit(user, &models.AuditEvent{
+		UniverseID: universe.ID,
+		Action:     models.AuditShareCreate,
+		TargetType: models.AuditTargetShare,
+		TargetID:   share.ID,
+		After:      share.AuditSummary(),
+	})
+	api.SendResponse(w, dtos.ResGetShare{Share: share}, http.StatusCreated)
+	return nil
+}
+
+// DeleteShare represents a route that removes a share link. Members can only remove those they created
+func (m *Router) DeleteShare(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	collaborator, _ := r.Context().Value(api.CollaboratorContextKey).(*models.Collaborator)
+	share, err := m.Services.Share.FindByID(universe, chi.URLParam(r, "shareID"))
+	if err != nil {
+		return err
+	}
+	if collaborator.Role == models.CollaboratorMember && share.CreatorID != user.ID {
+		return api.ErrBadAuth("You do not have permission to delete this share")
+	}
+	if err := m.Services.Share.Delete(share); err != nil {
+		return err
+	}
+	m.RecordAudit(user, &models.AuditEvent{
+		UniverseID: universe.ID,
+		Action:     models.AuditShareDelete,
+		TargetType: models.AuditTargetShare,
+		TargetID:   share.ID,
+		Before:     share.AuditSummary(),
+	})
+	w.WriteHeader(http.StatusNoContent)
+	w.Write([]byte(""))
+	return nil
+}
+
+// anonymous returns the collaborator that share links are viewed as. It has member permissions without owning
+// any character, so hidden characters and fields are left out
+func anonymous(share *models.Share) *models.Collaborator {
+	return &models.Collaborator{UniverseID: share.UniverseID, Role: models.CollaboratorMember}
+}
+
+// findSharedCharacter returns a character exposed by a share link with its hidden fields obscured
+func (m *Router) findSharedCharacter(share *models.Share, id string) (*models.Character, error) {
+	if share.CharacterID != nil && *share.CharacterID != id {
+		return nil, api.ErrNotFound("Character not found")
+	}
+	character, err := m.Services.Character.FindByID(id)
+	if err == repositories.ErrNotFound || (err == nil && character.UniverseID != share.UniverseID) {
+		return nil, api.ErrNotFound("Character not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if character.Meta.Hidden {
+		return nil, api.ErrNotFound("Character not found")
+	}
+	character.HideHiddenFields()
+
+	// Owners are named without their email address
+	if character.Owner != nil {
+		character.Owner = &models.User{ID: character.Owner.ID, DisplayName: character.Owner.DisplayName}
+	}
+	return character, nil
+}
+
+// GetShared represents a route that returns the universe of a share link, along with the character when only a
+// character is shared
+func (m *Router) GetShared(w http.ResponseWriter, r *http.Request) error {
+	share, _ := r.Context().Value(api.ShareContextKey).(*models.Share)
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	res := dtos.ResGetShared{Universe: universe, ExpiresAt: share.ExpiresAt}
+	if share.CharacterID != nil {
+		character, err := m.findSharedCharacter(share, *share.CharacterID)
+		if err != nil {
+			return err
+		}
+		res.Character = character
+	}
+	api.SendResponse(w, res, http.StatusOK)
+	return nil
+}
+
+// GetSharedCharacters represents a route that retrieves the characters of a shared universe, leaving out hidden
+// characters
+func (m *Router) GetSharedCharacters(w http.ResponseWriter, r *http.Request) error {
+	share, _ := r.Context().Value(api.ShareContextKey).(*models.Share)
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	if share.CharacterID != nil {
+		return api.ErrNotFound("Only a single character is shared")
+	}
+	ctx := characters.ReadCharacterQuery(r, anonymous(share))
+	ctx.IncludeHidden = false
+	list, total, err := m.Services.Character.FindByUniverse(universe, ctx)
+	if err != nil {
+		return err
+	}
+	api.SendResponse(w, dtos.ResGetCharacters{Characters: list, Page: ctx.Page, Total: total}, http.StatusOK)
+	return nil
+}
+
+// GetSharedCharacter represents a route that retrieves a single character exposed by a share link
+func (m *Router) GetSharedCharacter(w http.ResponseWriter, r *http.Request) error {
+	share, _ := r.Context().Value(api.ShareContextKey).(*models.Share)
+	character, err := m.findSharedCharacter(share, chi.URLParam(r, "characterID"))
+	if err != nil {
+		return err
+	}
+	if api.CheckIfNoneMatch(w, r, character.ETag()) {
+		return nil
+	}
+	api.SetETag(w, character.ETag())
+	api.SendResponse(w, dtos.ResGetCharacter{Character: character}, http.StatusOK)
+	return nil
+}
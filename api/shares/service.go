@@ -0,0 +1,102 @@
+package shares
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"cbs/repositories"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+)
+
+// tokenSize represents the number of random bytes in a share token
+const tokenSize = 32
+
+// Service represents a service implementation for the "shares" resource
+type Service api.Service
+
+// newToken generates a random, URL-safe share token
+func newToken() (string, error) {
+	b := make([]byte, tokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// New creates a new share link to a universe, or to one of its characters, with a freshly generated token
+func (s *Service) New(
+	universe *models.Universe,
+	creator *models.User,
+	data dtos.ReqCreateShare,
+) (*models.Share, error) {
+	if data.ExpiresAt != nil && !data.ExpiresAt.After(time.Now()) {
+		return nil, api.ErrBadBody("Share links must expire in the future")
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	share := &models.Share{
+		ID:         s.Providers.ShortID.MustGenerate(),
+		Token:      token,
+		UniverseID: universe.ID,
+		CreatorID:  creator.ID,
+		ExpiresAt:  data.ExpiresAt,
+	}
+	if data.CharacterID != "" {
+		share.CharacterID = &data.CharacterID
+	}
+	return share, nil
+}
+
+// FindByUniverse returns the share links of a universe, or only those created by a user when one is given
+func (s *Service) FindByUniverse(universe *models.Universe, creator *models.User) (*[]models.Share, error) {
+	creatorID := ""
+	if creator != nil {
+		creatorID = creator.ID
+	}
+	shares, err := s.Repositories.Share.FindByUniverse(universe.ID, creatorID)
+	if err != nil {
+		return nil, err
+	}
+	return &shares, nil
+}
+
+// FindByID returns a share link of a universe by its ID
+func (s *Service) FindByID(universe *models.Universe, id string) (*models.Share, error) {
+	share, err := s.Repositories.Share.FindByID(universe.ID, id)
+	if err != nil {
+		if err == repositories.ErrNotFound {
+			return nil, api.ErrNotFound("Share not found")
+		}
+		return nil, err
+	}
+	return share, nil
+}
+
+// FindByToken returns the share link of a token. Expired links are reported as not found, like unknown tokens
+func (s *Service) FindByToken(token string) (*models.Share, error) {
+	share, err := s.Repositories.Share.FindByToken(token)
+	if err != nil {
+		if err == repositories.ErrNotFound {
+			return nil, api.ErrNotFound("Share not found")
+		}
+		return nil, err
+	}
+	if share.Expired(time.Now()) {
+		return nil, api.ErrNotFound("Share not found")
+	}
+	return share, nil
+}
+
+// Create stores a new share link
+func (s *Service) Create(share *models.Share) error {
+	return s.Repositories.Share.Create(share)
+}
+
+// Delete removes a share link, immediately disabling its token
+func (s *Service) Delete(share *models.Share) error {
+	return s.Repositories.Share.Delete(share.ID)
+}
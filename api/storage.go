@@ -1,12 +1,17 @@
 package api
 
 import (
+	"cbs/repositories"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/credentials"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -21,6 +26,13 @@ type StorageConfig struct {
 	Bucket       string
 }
 
+// StorageObject represents a file stored through the Storage interface
+type StorageObject struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
 // Storage represents an interface pointing to an external file host (AWS S3, in this case)
 type Storage struct {
 	AWS     *s3.S3
@@ -44,14 +56,16 @@ func NewStorage(config StorageConfig) (*Storage, error) {
 	}, nil
 }
 
-// Upload uploads a file to AWS S3 through the Storage interface, returning the resource URL
-func (s *Storage) Upload(file io.Reader, key string) (string, error) {
+// Upload uploads a file of the given content type to AWS S3 through the Storage interface, returning the resource
+// URL
+func (s *Storage) Upload(file io.Reader, key string, contentType string) (string, error) {
+	defer ObserveDuration(StorageDuration.WithLabelValues("upload"), time.Now())
 	uploader := s3manager.NewUploader(s.session)
 	_, err := uploader.Upload(&s3manager.UploadInput{
 		Bucket:      aws.String(s.config.Bucket),
 		Key:         aws.String(key),
 		Body:        file,
-		ContentType: aws.String("image/jpeg"),
+		ContentType: aws.String(contentType),
 	})
 	if err != nil {
 		return "", err
@@ -59,21 +73,60 @@ func (s *Storage) Upload(file io.Reader, key string) (string, error) {
 	return fmt.Sprintf("https://s3.%s.amazonaws.com/%s/%s", s.config.Region, s.config.Bucket, key), nil
 }
 
-// Delete removes a file from AWS S3 through the Storage interface
-func (s *Storage) Delete(key string) error {
-	_, err := s.AWS.DeleteObject(&s3.DeleteObjectInput{
+// Download reads a file from AWS S3 through the Storage interface, returning repositories.ErrNotFound when it is
+// not stored
+func (s *Storage) Download(key string) ([]byte, error) {
+	defer ObserveDuration(StorageDuration.WithLabelValues("download"), time.Now())
+	object, err := s.AWS.GetObject(&s3.GetObjectInput{
 		Bucket: aws.String(s.config.Bucket),
 		Key:    aws.String(key),
 	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, repositories.ErrNotFound
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = s.AWS.WaitUntilObjectNotExists(&s3.HeadObjectInput{
+	defer object.Body.Close()
+	return ioutil.ReadAll(object.Body)
+}
+
+// Delete removes a file from AWS S3 through the Storage interface. Deleting a file that does not exist succeeds,
+// so deletions can be retried
+func (s *Storage) Delete(key string) error {
+	defer ObserveDuration(StorageDuration.WithLabelValues("delete"), time.Now())
+	_, err := s.AWS.DeleteObject(&s3.DeleteObjectInput{
 		Bucket: aws.String(s.config.Bucket),
 		Key:    aws.String(key),
 	})
+	return err
+}
+
+// List returns the files stored through the Storage interface whose keys start with prefix
+func (s *Storage) List(prefix string) ([]StorageObject, error) {
+	defer ObserveDuration(StorageDuration.WithLabelValues("list"), time.Now())
+	objects := make([]StorageObject, 0)
+	err := s.AWS.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.config.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, last bool) bool {
+		for _, o := range page.Contents {
+			objects = append(objects, StorageObject{
+				Key:          aws.StringValue(o.Key),
+				Size:         aws.Int64Value(o.Size),
+				LastModified: aws.TimeValue(o.LastModified),
+			})
+		}
+		return true
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return objects, nil
+}
+
+// Ping checks that the storage bucket is reachable
+func (s *Storage) Ping(ctx context.Context) error {
+	_, err := s.AWS.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.config.Bucket)})
+	return err
 }
@@ -0,0 +1,156 @@
+package universes
+
+import (
+	"cbs/api/openapi"
+	"cbs/dtos"
+	"net/http"
+)
+
+// Operations describes the routes of the "universes" resource for the OpenAPI document
+var Operations = []openapi.Operation{
+	{
+		Method:  http.MethodPost,
+		Path:    "/",
+		Summary: "Create a universe owned by the user of the session",
+		Description: "The universe starts with the default guide and settings, those of a built-in template, or a " +
+			"copy of those of a source universe the user collaborates in. Admins of the source universe may " +
+			"copy its characters too, without their images",
+		Session: true,
+		Body:    []openapi.Content{{Body: dtos.ReqCreateUniverse{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusCreated,
+			Description: "The created universe",
+			Content:     []openapi.Content{{Body: dtos.ResGetUniverse{}}},
+		}},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/templates",
+		Summary: "List the built-in universe templates",
+		Session: true,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The templates",
+			Content:     []openapi.Content{{Body: dtos.ResGetUniverseTemplates{}}},
+		}},
+	},
+	{
+		Method:     http.MethodGet,
+		Path:       "/{universeID}",
+		Summary:    "Get a universe",
+		Session:    true,
+		Role:       openapi.RoleMember,
+		Parameters: []openapi.Parameter{openapi.IfNoneMatch},
+		Responses: []openapi.Response{
+			{
+				Status:      http.StatusOK,
+				Description: "The universe",
+				Headers:     []string{"ETag"},
+				Content:     []openapi.Content{{Body: dtos.ResGetUniverse{}}},
+			},
+			{Status: http.StatusNotModified, Description: "The cached copy is current"},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/{universeID}/usage",
+		Summary: "Get the usage of a universe's quotas",
+		Description: "Actions that would exceed a quota fail with the QUOTA error code. A limit of 0 is unlimited. " +
+			"Images uploaded before image sizes were recorded only count towards the image quota once images are " +
+			"reconciled",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The usage of each quota",
+			Content:     []openapi.Content{{Body: dtos.ResGetUniverseUsage{}}},
+		}},
+	},
+	{
+		Method:      http.MethodPatch,
+		Path:        "/{universeID}",
+		Summary:     "Edit a universe",
+		Description: "The body replaces the universe. The edit is rejected when its version no longer matches",
+		Session:     true,
+		Role:        openapi.RoleOwner,
+		Parameters:  []openapi.Parameter{openapi.IfMatch},
+		Body:        []openapi.Content{{Body: dtos.ReqEditUniverse{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The edited universe",
+			Headers:     []string{"ETag"},
+			Content:     []openapi.Content{{Body: dtos.ResGetUniverse{}}},
+		}},
+	},
+	{
+		Method:     http.MethodDelete,
+		Path:       "/{universeID}",
+		Summary:    "Delete a universe and all of its characters",
+		Session:    true,
+		Role:       openapi.RoleOwner,
+		Parameters: []openapi.Parameter{openapi.IfMatch},
+		Responses:  []openapi.Response{{Status: http.StatusNoContent, Description: "The universe was deleted"}},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/{universeID}/me",
+		Summary: "Get the collaborator of the user of the session",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The collaborator",
+			Content:     []openapi.Content{{Body: dtos.ResGetCollaborator{}}},
+		}},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/{universeID}/collaborators",
+		Summary: "List the collaborators of a universe",
+		Session: true,
+		Role:    openapi.RoleMember,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The collaborators",
+			Content:     []openapi.Content{{Body: dtos.ResGetCollaborators{}}},
+		}},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/{universeID}/collaborators",
+		Summary:     "Add a collaborator to a universe",
+		Description: "The user is found by their ID or, when no ID is given, by their email",
+		Session:     true,
+		Role:        openapi.RoleOwner,
+		Body:        []openapi.Content{{Body: dtos.ReqAddCollaborator{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The added collaborator",
+			Content:     []openapi.Content{{Body: dtos.ResGetCollaborator{}}},
+		}},
+	},
+	{
+		Method:  http.MethodPatch,
+		Path:    "/{universeID}/collaborators",
+		Summary: "Change the role of a collaborator",
+		Session: true,
+		Role:    openapi.RoleOwner,
+		Body:    []openapi.Content{{Body: dtos.ReqEditCollaborator{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The edited collaborator",
+			Content:     []openapi.Content{{Body: dtos.ResGetCollaborator{}}},
+		}},
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/{universeID}/collaborators",
+		Summary: "Remove a collaborator from a universe",
+		Session: true,
+		Role:    openapi.RoleOwner,
+		Parameters: []openapi.Parameter{
+			{Name: "id", In: "query", Description: "ID of the user to remove", Required: true},
+		},
+		Responses: []openapi.Response{{Status: http.StatusNoContent, Description: "The collaborator was removed"}},
+	},
+}
@@ -4,6 +4,7 @@ import (
 	"cbs/api"
 	"cbs/dtos"
 	"cbs/models"
+	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi"
@@ -28,6 +29,10 @@ func NewRouter(server *api.Server) *Router {
 			"/me",
 			api.Handler(router.GetMe).ServeHTTP,
 		)
+		sr.With(server.Middlewares.Collaborator(models.CollaboratorMember)).Get(
+			"/usage",
+			api.Handler(router.GetUsage).ServeHTTP,
+		)
 		sr.With(server.Middlewares.Collaborator(models.CollaboratorOwner)).Delete(
 			"/",
 			api.Handler(router.DeleteUniverse).ServeHTTP,
@@ -57,27 +62,116 @@ func NewRouter(server *api.Server) *Router {
 		"/",
 		api.Handler(router.CreateUniverse).ServeHTTP,
 	)
+	router.Get(
+		"/templates",
+		api.Handler(router.GetTemplates).ServeHTTP,
+	)
 	return router
 }
 
-// CreateUniverse represents a route that creates a new universe
+// CreateUniverse represents a route that creates a new universe, starting from the default guide, a built-in
+// template or a copy of another universe the user collaborates in
 func (m *Router) CreateUniverse(w http.ResponseWriter, r *http.Request) error {
 	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
 	var payload dtos.ReqCreateUniverse
 	if err := api.ReadAndValidateBody(r.Body, &payload); err != nil {
 		return err
 	}
-	universe := m.Services.Universe.New(payload)
+	if payload.Source != "" && payload.Template != "" {
+		return api.ErrBadBody("Only a source universe or a template may be provided")
+	}
+	if payload.IncludeCharacters && payload.Source == "" {
+		return api.ErrBadBody("Characters can only be included from a source universe")
+	}
+
+	var (
+		universe = m.Services.Universe.New(payload)
+		source   *models.Universe
+		summary  = models.AuditSummary{}
+	)
+	switch {
+	case payload.Template != "":
+		template, err := m.Services.Universe.FindTemplate(payload.Template)
+		if err != nil {
+			return api.ErrBadBody(fmt.Sprintf("Unknown template '%s'", payload.Template))
+		}
+		source = &models.Universe{Guide: template.Guide, Settings: template.Settings}
+		summary["template"] = template.Name
+	case payload.Source != "":
+		var err error
+		if source, err = m.findSource(user, payload); err != nil {
+			return err
+		}
+		summary["source"] = source.ID
+	}
+	if source != nil {
+		var err error
+		if universe, err = m.Services.Universe.Copy(payload, source); err != nil {
+			return err
+		}
+	}
 	if err := m.Services.Universe.Create(universe, user); err != nil {
+		if apierr, ok := err.(api.Error); ok {
+			return apierr
+		}
 		return api.ErrInternal("Failed to create universe")
 	}
+
+	// Copy the characters last, removing the universe again if they cannot all be copied
+	if payload.IncludeCharacters {
+		copied, err := m.Services.Character.CopyAll(source, universe, user)
+		if err != nil {
+			m.Services.Universe.Delete(universe)
+			if apierr, ok := err.(api.Error); ok {
+				return apierr
+			}
+			api.Logger(r).Error("Failed to copy characters", "source", source.ID, "error", err)
+			return api.ErrInternal("Failed to copy characters")
+		}
+		summary["characters"] = copied
+	}
+	summary["name"] = universe.Name
+	m.RecordAudit(user, &models.AuditEvent{
+		UniverseID: universe.ID,
+		Action:     models.AuditUniverseCreate,
+		TargetType: models.AuditTargetUniverse,
+		TargetID:   universe.ID,
+		After:      summary,
+	})
 	api.SendResponse(w, dtos.ResGetUniverse{Universe: universe}, http.StatusCreated)
 	return nil
 }
 
+// findSource returns the universe a new universe is copied from. The user must collaborate in it, and only
+// admins may copy its characters since members cannot see every character
+func (m *Router) findSource(user *models.User, payload dtos.ReqCreateUniverse) (*models.Universe, error) {
+	collaborator, err := m.Services.Universe.FindCollaboratorByID(payload.Source, user.ID)
+	if err != nil {
+		return nil, api.ErrNotFound("Source universe not found")
+	}
+	if payload.IncludeCharacters && collaborator.Role == models.CollaboratorMember {
+		return nil, api.ErrBadAuth("Only admins can copy the characters of a universe")
+	}
+	source, err := m.Services.Universe.FindByID(payload.Source)
+	if err != nil {
+		return nil, api.ErrNotFound("Source universe not found")
+	}
+	return source, nil
+}
+
+// GetTemplates represents a route that returns the built-in universe templates
+func (m *Router) GetTemplates(w http.ResponseWriter, r *http.Request) error {
+	api.SendResponse(w, dtos.ResGetUniverseTemplates{Templates: m.Services.Universe.Templates()}, http.StatusOK)
+	return nil
+}
+
 // GetUniverse represents a route that returns a universe based on its ID
 func (m *Router) GetUniverse(w http.ResponseWriter, r *http.Request) error {
 	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	if api.CheckIfNoneMatch(w, r, universe.ETag()) {
+		return nil
+	}
+	api.SetETag(w, universe.ETag())
 	api.SendResponse(w, dtos.ResGetUniverse{Universe: universe}, http.StatusOK)
 	return nil
 }
@@ -85,6 +179,12 @@ func (m *Router) GetUniverse(w http.ResponseWriter, r *http.Request) error {
 // EditUniverse represents a route that modifies a universe based on its ID
 func (m *Router) EditUniverse(w http.ResponseWriter, r *http.Request) error {
 	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	if err := api.CheckIfMatch(r, universe.ETag()); err != nil {
+		return err
+	}
+	before := universe.AuditSummary()
+	version := universe.Version
 	payload := dtos.ReqEditUniverse{Universe: universe}
 	if err := api.ReadAndValidateBody(r.Body, &payload); err != nil {
 		return err
@@ -92,12 +192,36 @@ func (m *Router) EditUniverse(w http.ResponseWriter, r *http.Request) error {
 	if payload.ID != universe.ID {
 		return api.ErrBadBody("ID cannot be changed")
 	}
+
+	// The body is decoded onto the loaded universe, so the update is guarded by the version it was loaded at
+	// rather than whichever version the body claims. A body sent for another version is stale
+	if payload.Version != version {
+		return api.ErrPrecondition("")
+	}
 	if err := universe.Guide.SetFieldMeta(); err != nil {
 		return api.ErrInternal("Failed to edit universe")
 	}
 	if err := m.Services.Universe.Update(payload.Universe, nil); err != nil {
+		if apierr, ok := err.(api.Error); ok {
+			return apierr
+		}
 		return api.ErrInternal("Failed to edit universe")
 	}
+	m.RecordAudit(user, &models.AuditEvent{
+		UniverseID: universe.ID,
+		Action:     models.AuditUniverseUpdate,
+		TargetType: models.AuditTargetUniverse,
+		TargetID:   universe.ID,
+		Before:     before,
+		After:      payload.Universe.AuditSummary(),
+	})
+	m.PublishEvent(&models.UniverseEvent{
+		Type:       models.EventGuideChanged,
+		UniverseID: universe.ID,
+		ActorID:    user.ID,
+		Universe:   payload.Universe,
+	})
+	api.SetETag(w, payload.Universe.ETag())
 	api.SendResponse(w, dtos.ResGetUniverse{Universe: payload.Universe}, http.StatusOK)
 	return nil
 }
@@ -109,6 +233,17 @@ func (m *Router) GetMe(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// GetUsage represents a route that returns how much of each of its quotas a universe uses
+func (m *Router) GetUsage(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	usage, err := m.Services.Universe.Usage(universe)
+	if err != nil {
+		return api.ErrInternal("Failed to get usage")
+	}
+	api.SendResponse(w, dtos.ResGetUniverseUsage{UniverseUsage: usage}, http.StatusOK)
+	return nil
+}
+
 // GetCollaborators represents a route that returns a list of collaborators associated with a universe
 func (m *Router) GetCollaborators(w http.ResponseWriter, r *http.Request) error {
 	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
@@ -152,6 +287,8 @@ func (m *Router) AddCollaborator(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
+	m.publishCollaborator(r, universe, models.EventCollaboratorAdded, collaborator)
+	m.auditCollaborator(r, universe, models.AuditCollaboratorAdd, collaborator.UserID, nil, collaborator.AuditSummary())
 	api.SendResponse(w, dtos.ResGetCollaborator{Collaborator: collaborator}, http.StatusOK)
 	return nil
 }
@@ -170,6 +307,7 @@ func (m *Router) EditCollaborator(w http.ResponseWriter, r *http.Request) error
 	if collaborator.Role == models.CollaboratorOwner {
 		return api.ErrBadBody("Cannot edit owner")
 	}
+	before := collaborator.AuditSummary()
 	collaborator.Role = payload.Role
 	collaborator, err = m.Services.Universe.UpdateCollaborator(universe, collaborator)
 	if err != nil {
@@ -180,6 +318,8 @@ func (m *Router) EditCollaborator(w http.ResponseWriter, r *http.Request) error
 		return err
 	}
 	collaborator.User = user
+	m.publishCollaborator(r, universe, models.EventCollaboratorUpdated, collaborator)
+	m.auditCollaborator(r, universe, models.AuditCollaboratorUpdate, payload.ID, before, collaborator.AuditSummary())
 	api.SendResponse(w, dtos.ResGetCollaborator{Collaborator: collaborator}, http.StatusOK)
 	return nil
 }
@@ -198,6 +338,8 @@ func (m *Router) RemoveCollaborator(w http.ResponseWriter, r *http.Request) erro
 	if err := m.Services.Universe.RemoveCollaborator(universe, collaborator); err != nil {
 		return err
 	}
+	m.publishCollaborator(r, universe, models.EventCollaboratorRemoved, collaborator)
+	m.auditCollaborator(r, universe, models.AuditCollaboratorRemove, collaborator.UserID, collaborator.AuditSummary(), nil)
 	w.WriteHeader(http.StatusNoContent)
 	w.Write([]byte(""))
 	return nil
@@ -206,10 +348,55 @@ func (m *Router) RemoveCollaborator(w http.ResponseWriter, r *http.Request) erro
 // DeleteUniverse represents a route that deletes an existing universe
 func (m *Router) DeleteUniverse(w http.ResponseWriter, r *http.Request) error {
 	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	if err := api.CheckIfMatch(r, universe.ETag()); err != nil {
+		return err
+	}
 	if err := m.Services.Universe.Delete(universe); err != nil {
 		return err
 	}
+	m.PublishEvent(&models.UniverseEvent{
+		Type:       models.EventUniverseDeleted,
+		UniverseID: universe.ID,
+		ActorID:    user.ID,
+	})
 	w.WriteHeader(http.StatusNoContent)
 	w.Write([]byte(""))
 	return nil
 }
+
+// publishCollaborator publishes a change to the collaborators of a universe
+func (m *Router) publishCollaborator(
+	r *http.Request,
+	universe *models.Universe,
+	eventType models.UniverseEventType,
+	collaborator *models.Collaborator,
+) {
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	m.PublishEvent(&models.UniverseEvent{
+		Type:         eventType,
+		UniverseID:   universe.ID,
+		ActorID:      user.ID,
+		Collaborator: collaborator,
+	})
+}
+
+// auditCollaborator records a change to the collaborators of a universe in its audit log
+func (m *Router) auditCollaborator(
+	r *http.Request,
+	universe *models.Universe,
+	action models.AuditAction,
+	userID string,
+	before models.AuditSummary,
+	after models.AuditSummary,
+) {
+	user, _ := r.Context().Value(api.UserContextKey).(*models.User)
+	m.RecordAudit(user, &models.AuditEvent{
+		UniverseID: universe.ID,
+		Action:     action,
+		TargetType: models.AuditTargetCollaborator,
+		TargetID:   userID,
+		Before:     before,
+		After:      after,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"cbs/api"
 	"cbs/dtos"
 	"cbs/models"
+	"cbs/repositories"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -36,8 +37,12 @@ var DefaultUniverseSettings = &models.UniverseSettings{
 	TitleField:                   "Name",
 	AllowAvatars:                 true,
 	AllowLexicographicalOrdering: false,
+	RelationshipTypes:            DefaultRelationshipTypes,
 }
 
+// DefaultRelationshipTypes represents the relationship types given to new universes and built-in templates
+var DefaultRelationshipTypes = []string{"family", "ally", "rival"}
+
 // DefaultUniverseGuideJSON represents the JSON marshalled version of DefaultUniverseGuide
 var DefaultUniverseGuideJSON []byte
 
@@ -72,13 +77,53 @@ func (s *Service) New(data dtos.ReqCreateUniverse) *models.Universe {
 	return universe
 }
 
+// Copy creates a new universe with a copy of the guide and settings of a source universe or template
+func (s *Service) Copy(data dtos.ReqCreateUniverse, source *models.Universe) (*models.Universe, error) {
+	universe := &models.Universe{
+		ID:          s.Providers.ShortID.MustGenerate(),
+		Name:        data.Name,
+		Description: data.Description,
+		Guide:       &models.UniverseGuide{},
+		Settings:    &models.UniverseSettings{},
+	}
+
+	// Round trip through the stored representation so the copy shares no memory with the source
+	guide, err := source.Guide.Value()
+	if err != nil {
+		return nil, err
+	}
+	if err := universe.Guide.Scan(guide); err != nil {
+		return nil, err
+	}
+	settings, err := source.Settings.Value()
+	if err != nil {
+		return nil, err
+	}
+	if err := universe.Settings.Scan(settings); err != nil {
+		return nil, err
+	}
+	return universe, nil
+}
+
+// Templates returns the built-in universe templates
+func (s *Service) Templates() []models.UniverseTemplate {
+	return Templates
+}
+
+// FindTemplate returns a built-in universe template by its name
+func (s *Service) FindTemplate(name string) (*models.UniverseTemplate, error) {
+	for i := range Templates {
+		if Templates[i].Name == name {
+			return &Templates[i], nil
+		}
+	}
+	return nil, api.ErrNotFound(fmt.Sprintf("Template '%s' not found", name))
+}
+
 // Find returns all Universes
 func (s *Service) Find() (*[]models.Universe, error) {
-	var universes []models.Universe
-	if err := s.Providers.DB.Select(
-		&universes,
-		"SELECT id, name, description, guide, settings FROM universes",
-	); err != nil {
+	universes, err := s.Repositories.Universe.FindAll()
+	if err != nil {
 		return nil, err
 	}
 	return &universes, nil
@@ -86,35 +131,27 @@ func (s *Service) Find() (*[]models.Universe, error) {
 
 // FindByID returns a Universe by their ID
 func (s *Service) FindByID(id string) (*models.Universe, error) {
-	var universe models.Universe
-	if err := s.Providers.DB.Get(
-		&universe,
-		"SELECT id, name, description, guide, settings FROM universes WHERE id = $1",
-		id,
-	); err != nil {
-		return nil, err
-	}
-	return &universe, nil
+	return s.Repositories.Universe.FindByID(id)
 }
 
-// Delete removes a universe from the database
+// Delete removes a universe from the database, queueing the files of its character images to be deleted in the
+// background
 func (s *Service) Delete(universe *models.Universe) error {
-	if _, err := s.Providers.DB.Exec(`DELETE FROM universes WHERE id = $1`, universe.ID); err != nil {
+	keys, err := s.Repositories.Image.FindFilesByUniverse(universe.ID)
+	if err != nil {
 		return err
 	}
+	if err := s.Repositories.Universe.Delete(universe.ID); err != nil {
+		return err
+	}
+	api.EnqueueFileDeletion(s.Providers, s.Repositories, universe.ID, keys)
 	return nil
 }
 
 // FindFromUser returns a selection of universe references the user is collaborating in
 func (s *Service) FindFromUser(user *models.User) (*[]models.UniverseReference, error) {
-	// Calling make initializes the slice to prevent JSON from marshalling empty results into "null"
-	universes := make([]models.UniverseReference, 0)
-	if err := s.Providers.DB.Select(
-		&universes,
-		`SELECT universes.id, universes.name, collaborators.role FROM collaborators JOIN universes
-		ON universes.id = collaborators.universe_id WHERE user_id = $1 ORDER BY universes.name`,
-		user.ID,
-	); err != nil {
+	universes, err := s.Repositories.Universe.FindByCollaborator(user.ID)
+	if err != nil {
 		return nil, err
 	}
 	return &universes, nil
@@ -122,136 +159,137 @@ func (s *Service) FindFromUser(user *models.User) (*[]models.UniverseReference,
 
 // FindCollaboratorByID returns a collaborator relation from a given universe ID and user
 func (s *Service) FindCollaboratorByID(universeid string, userid string) (*models.Collaborator, error) {
-	var collaborator models.Collaborator
-	if err := s.Providers.DB.Get(
-		&collaborator,
-		"SELECT universe_id, user_id, role FROM collaborators WHERE universe_id = $1 AND user_id = $2",
-		universeid,
-		userid); err != nil {
-		return nil, err
-	}
-	return &collaborator, nil
+	return s.Repositories.Collaborator.FindByID(universeid, userid)
 }
 
-// Create creates a new universe in the database
+// Create creates a new universe in the database, within the universe quota of its owner and the guide quotas
 func (s *Service) Create(universe *models.Universe, owner *models.User) error {
-	tx, err := s.Providers.DB.Beginx()
-	if err != nil {
+	if err := s.checkGuideQuota(universe); err != nil {
 		return err
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-	rows, err := tx.NamedQuery(
-		`INSERT INTO universes (id, name, description, guide, settings) VALUES (:id, 
-	:name, :description, :guide, :settings) RETURNING id, name, description, guide, settings`,
-		universe,
-	)
+	err := s.Repositories.Universe.Create(universe, owner.ID, s.Config.MaxUniversesPerUser)
+	if err == repositories.ErrQuota {
+		return s.errOwnedQuota()
+	}
+	return err
+}
+
+// checkOwnedQuota ensures a user can own one more universe within their universe quota
+func (s *Service) checkOwnedQuota(owner *models.User) error {
+	universes, err := s.Repositories.Universe.FindByCollaborator(owner.ID)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		if err := rows.StructScan(universe); err != nil {
-			return err
+	owned := 0
+	for _, u := range universes {
+		if u.Role == models.CollaboratorOwner {
+			owned++
 		}
 	}
-	_, err = tx.Exec(
-		`INSERT INTO collaborators (universe_id, user_id, role) VALUES ($1,$2,$3)`,
-		universe.ID,
-		owner.ID,
-		models.CollaboratorOwner,
-	)
-	if err != nil {
-		return err
-	}
-	if err := tx.Commit(); err != nil {
-		return err
+	if s.Config.MaxUniversesPerUser > 0 && owned >= s.Config.MaxUniversesPerUser {
+		return s.errOwnedQuota()
 	}
 	return nil
 }
 
-// Update updates an existing universe in the database
-func (s *Service) Update(universe *models.Universe, owner *models.User) error {
-	tx, err := s.Providers.DB.Beginx()
-	if err != nil {
+// errOwnedQuota returns the error reported when a user would exceed their universe quota
+func (s *Service) errOwnedQuota() error {
+	return api.ErrQuota(fmt.Sprintf("Users can own up to %d universes", s.Config.MaxUniversesPerUser))
+}
+
+// checkGuideQuota ensures the guide of a universe stays within the group and field quotas
+func (s *Service) checkGuideQuota(universe *models.Universe) error {
+	groups, fields := universe.GuideSize()
+	if err := api.CheckQuota(
+		0,
+		groups,
+		s.Config.MaxGuideGroups,
+		fmt.Sprintf("Guides can have up to %d groups", s.Config.MaxGuideGroups),
+	); err != nil {
 		return err
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-	rows, err := tx.NamedQuery(
-		`UPDATE universes SET name = :name, description = :description, guide = :guide,
-	settings = :settings WHERE id = :id RETURNING id, name, description, guide, settings`,
-		universe,
+	return api.CheckQuota(
+		0,
+		fields,
+		s.Config.MaxGuideFields,
+		fmt.Sprintf("Guides can have up to %d fields", s.Config.MaxGuideFields),
 	)
-	if err != nil {
+}
+
+// Update updates an existing universe in the database, replacing its owner when one is passed. The guide must stay
+// within the guide quotas
+func (s *Service) Update(universe *models.Universe, owner *models.User) error {
+	if err := s.checkGuideQuota(universe); err != nil {
 		return err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		if err := rows.StructScan(universe); err != nil {
-			return err
-		}
-	}
+	ownerID := ""
 	if owner != nil {
-		_, err = tx.Exec(`DELETE FROM collaborators WHERE universe_id = $1 AND role = 2`, universe.ID)
-		if err != nil {
-			return err
-		}
-		_, err = tx.Exec(
-			`INSERT INTO collaborators (universe_id, user_id, role) VALUES ($1,$2,$3)`,
-			universe.ID,
-			owner.ID,
-			models.CollaboratorOwner,
-		)
-		if err != nil {
-			return err
-		}
+		ownerID = owner.ID
 	}
-	if err := tx.Commit(); err != nil {
-		return err
+	err := s.Repositories.Universe.Update(universe, ownerID)
+	if err == repositories.ErrStale {
+		return api.ErrPrecondition("Universe was modified since it was last retrieved")
 	}
-	return nil
+	return err
+}
+
+// Usage returns how much of each of its quotas a universe uses
+func (s *Service) Usage(universe *models.Universe) (*models.UniverseUsage, error) {
+	characters, err := s.Repositories.Character.CountByUniverse(universe.ID)
+	if err != nil {
+		return nil, err
+	}
+	collaborators, err := s.Repositories.Collaborator.FindByUniverse(universe.ID)
+	if err != nil {
+		return nil, err
+	}
+	imageBytes, err := s.Repositories.Image.SizeByUniverse(universe.ID)
+	if err != nil {
+		return nil, err
+	}
+	groups, fields := universe.GuideSize()
+	return &models.UniverseUsage{
+		Characters:    models.QuotaUsage{Used: characters, Limit: s.Config.MaxCharactersPerUniverse},
+		Collaborators: models.QuotaUsage{Used: len(collaborators), Limit: s.Config.MaxCollaboratorsPerUniverse},
+		GuideGroups:   models.QuotaUsage{Used: groups, Limit: s.Config.MaxGuideGroups},
+		GuideFields:   models.QuotaUsage{Used: fields, Limit: s.Config.MaxGuideFields},
+		ImageBytes:    models.QuotaUsage{Used: int(imageBytes), Limit: s.Config.MaxImageBytesPerUniverse},
+	}, nil
 }
 
 // FindCollaborators returns a list of collaborators pertaining to a universe
 func (s *Service) FindCollaborators(universe *models.Universe) (*[]models.Collaborator, error) {
-	collaborators := make([]models.Collaborator, 0)
-	if err := s.Providers.DB.Select(
-		&collaborators,
-		`SELECT users.id "user.id", users.display_name "user.display_name", users.email "user.email",
-		collaborators.role FROM collaborators JOIN users ON users.id = collaborators.user_id WHERE universe_id = $1`,
-		universe.ID,
-	); err != nil {
-		fmt.Println(err)
+	collaborators, err := s.Repositories.Collaborator.FindByUniverse(universe.ID)
+	if err != nil {
 		return nil, err
 	}
 	return &collaborators, nil
 }
 
-// CreateCollaborator adds a collaborator to a universe
+// CreateCollaborator adds a collaborator to a universe, within its collaborator quota
 func (s *Service) CreateCollaborator(
 	universe *models.Universe,
 	user *models.User,
 	role models.CollaboratorRole,
 ) (*models.Collaborator, error) {
-	var c models.Collaborator
-	if err := s.Providers.DB.Get(
-		&c,
-		`INSERT INTO collaborators (universe_id, user_id, role) VALUES ($1, $2, $3) RETURNING universe_id, role`,
-		universe.ID,
-		user.ID,
-		role,
+	collaborators, err := s.Repositories.Collaborator.FindByUniverse(universe.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := api.CheckQuota(
+		len(collaborators),
+		1,
+		s.Config.MaxCollaboratorsPerUniverse,
+		fmt.Sprintf("Universes can have up to %d collaborators", s.Config.MaxCollaboratorsPerUniverse),
 	); err != nil {
 		return nil, err
 	}
+	c := &models.Collaborator{UniverseID: universe.ID, UserID: user.ID, Role: role}
+	if err := s.Repositories.Collaborator.Create(c); err != nil {
+		return nil, err
+	}
 	c.User = user
-	return &c, nil
+	return c, nil
 }
 
 // UpdateCollaborator updates an existing collaborator
@@ -259,27 +297,85 @@ func (s *Service) UpdateCollaborator(
 	universe *models.Universe,
 	collaborator *models.Collaborator,
 ) (*models.Collaborator, error) {
-	var c models.Collaborator
-	if err := s.Providers.DB.Get(
-		&c,
-		`UPDATE collaborators SET role = $1 WHERE universe_id = $2 AND user_id = $3 RETURNING universe_id, role`,
-		collaborator.Role,
-		universe.ID,
-		collaborator.UserID,
-	); err != nil {
+	c := &models.Collaborator{UniverseID: universe.ID, UserID: collaborator.UserID, Role: collaborator.Role}
+	if err := s.Repositories.Collaborator.Update(c); err != nil {
 		return nil, err
 	}
-	return &c, nil
+	return c, nil
 }
 
 // RemoveCollaborator deletes an existing collaborator
 func (s *Service) RemoveCollaborator(universe *models.Universe, collaborator *models.Collaborator) error {
-	if _, err := s.Providers.DB.Exec(
-		"DELETE FROM collaborators WHERE universe_id = $1 AND user_id = $2",
-		universe.ID,
-		collaborator.UserID,
-	); err != nil {
+	return s.Repositories.Collaborator.Delete(universe.ID, collaborator.UserID)
+}
+
+// TransferOwnership makes a user the owner of a universe within their universe quota, demoting the previous owner
+// to an admin. Users who are not yet collaborators are added within the collaborator quota of the universe. Every
+// role change is recorded in the audit log without an actor, since ownership is only transferred by operators
+func (s *Service) TransferOwnership(universe *models.Universe, owner *models.User) error {
+	collaborators, err := s.Repositories.Collaborator.FindByUniverse(universe.ID)
+	if err != nil {
 		return err
 	}
+	previous := make(map[string]models.CollaboratorRole)
+	for _, c := range collaborators {
+		if c.Role == models.CollaboratorOwner || c.UserID == owner.ID {
+			previous[c.UserID] = c.Role
+		}
+	}
+	role, isCollaborator := previous[owner.ID]
+	if isCollaborator && role == models.CollaboratorOwner {
+		return nil
+	}
+	if err := s.checkOwnedQuota(owner); err != nil {
+		return err
+	}
+	if !isCollaborator {
+		if err := api.CheckQuota(
+			len(collaborators),
+			1,
+			s.Config.MaxCollaboratorsPerUniverse,
+			fmt.Sprintf("Universes can have up to %d collaborators", s.Config.MaxCollaboratorsPerUniverse),
+		); err != nil {
+			return err
+		}
+	}
+	if err := s.Repositories.Collaborator.TransferOwnership(universe.ID, owner.ID); err != nil {
+		return err
+	}
+
+	if !isCollaborator {
+		s.recordTransfer(universe, models.AuditCollaboratorAdd, owner.ID, nil, models.CollaboratorOwner)
+	}
+	for userID, role := range previous {
+		after := models.CollaboratorAdmin
+		if userID == owner.ID {
+			after = models.CollaboratorOwner
+		}
+		s.recordTransfer(universe, models.AuditCollaboratorUpdate, userID, models.AuditSummary{"role": role}, after)
+	}
 	return nil
 }
+
+// recordTransfer appends a role change made by an ownership transfer to the audit log of a universe. Failures are
+// logged rather than returned since ownership has already been transferred
+func (s *Service) recordTransfer(
+	universe *models.Universe,
+	action models.AuditAction,
+	userID string,
+	before models.AuditSummary,
+	role models.CollaboratorRole,
+) {
+	event := &models.AuditEvent{
+		ID:         s.Providers.ShortID.MustGenerate(),
+		UniverseID: universe.ID,
+		Action:     action,
+		TargetType: models.AuditTargetCollaborator,
+		TargetID:   userID,
+		Before:     before,
+		After:      models.AuditSummary{"role": role},
+	}
+	if err := s.Repositories.Audit.Create(event); err != nil {
+		s.Providers.Logger.Error("Failed to record audit event", "action", action, "universe", universe.ID, "error", err)
+	}
+}
@@ -0,0 +1,182 @@
+package universes
+
+import "cbs/models"
+
+// biography represents the biography field shared by every template
+var biography = models.UniverseGuideField{
+	Name:        "Biography",
+	Description: "A short summary about this character",
+	Required:    true,
+	Type:        models.GuideFieldDescription,
+	Meta:        models.UniverseGuideMetaDescription{MinLength: 1, MaxLength: 4000, Markdown: true},
+}
+
+// attribute creates a number field for a tabletop attribute score
+func attribute(name string) models.UniverseGuideField {
+	return models.UniverseGuideField{
+		Name:     name,
+		Required: true,
+		Type:     models.GuideFieldNumber,
+		Meta:     models.UniverseGuideMetaNumber{Min: 1, Max: 20, Tick: 1},
+	}
+}
+
+// skill creates a progress field for a skill rated out of ten
+func skill(name string, color models.ProgressBarColor) models.UniverseGuideField {
+	return models.UniverseGuideField{
+		Name: name,
+		Type: models.GuideFieldProgress,
+		Meta: models.UniverseGuideMetaProgress{Bar: true, Color: color, Min: 0, Max: 10, Tick: 1},
+	}
+}
+
+// text creates an optional single line text field
+func text(name string, description string) models.UniverseGuideField {
+	return models.UniverseGuideField{
+		Name:        name,
+		Description: description,
+		Type:        models.GuideFieldText,
+		Meta:        models.UniverseGuideMetaText{MaxLength: 200},
+	}
+}
+
+// options creates a required field that takes one of a set of options
+func options(name string, opts ...string) models.UniverseGuideField {
+	return models.UniverseGuideField{
+		Name:     name,
+		Required: true,
+		Type:     models.GuideFieldOptions,
+		Meta:     models.UniverseGuideMetaOptions{Options: opts},
+	}
+}
+
+// list creates an optional list field
+func list(name string, description string, max int) models.UniverseGuideField {
+	return models.UniverseGuideField{
+		Name:        name,
+		Description: description,
+		Type:        models.GuideFieldList,
+		Meta:        models.UniverseGuideMetaList{MaxElements: max},
+	}
+}
+
+// group creates a guide group
+func group(name string, required bool, fields ...models.UniverseGuideField) models.UniverseGuideGroup {
+	return models.UniverseGuideGroup{Name: name, Required: required, Fields: &fields}
+}
+
+// guide creates a universe guide
+func guide(groups ...models.UniverseGuideGroup) *models.UniverseGuide {
+	return &models.UniverseGuide{Groups: &groups}
+}
+
+// Templates represents the curated library of built-in universe templates, selectable by name
+var Templates = []models.UniverseTemplate{
+	{
+		Name:        "fantasy-rpg",
+		Title:       "Fantasy RPG",
+		Description: "Adventurers of a tabletop fantasy campaign, with classes, ancestries and attribute scores",
+		Guide: guide(
+			group("General", true, biography),
+			group(
+				"Adventurer",
+				true,
+				options("Class", "Barbarian", "Bard", "Cleric", "Druid", "Fighter", "Monk", "Paladin", "Ranger",
+					"Rogue", "Sorcerer", "Warlock", "Wizard"),
+				options("Ancestry", "Dwarf", "Elf", "Gnome", "Halfling", "Human", "Orc", "Other"),
+				models.UniverseGuideField{
+					Name:     "Level",
+					Required: true,
+					Type:     models.GuideFieldNumber,
+					Meta:     models.UniverseGuideMetaNumber{Min: 1, Max: 20, Tick: 1},
+				},
+				text("Alignment", "The moral and ethical outlook of this character"),
+			),
+			group(
+				"Attributes",
+				true,
+				attribute("Strength"),
+				attribute("Dexterity"),
+				attribute("Constitution"),
+				attribute("Intelligence"),
+				attribute("Wisdom"),
+				attribute("Charisma"),
+			),
+			group("Inventory", false, list("Equipment", "Weapons, armor and gear carried", 50)),
+		),
+		Settings: &models.UniverseSettings{
+			TitleField:                   "Name",
+			AllowAvatars:                 true,
+			AllowLexicographicalOrdering: true,
+			RelationshipTypes:            DefaultRelationshipTypes,
+		},
+	},
+	{
+		Name:        "sci-fi-crew",
+		Title:       "Sci-fi crew",
+		Description: "The crew of a starship, with ranks, stations and skill ratings",
+		Guide: guide(
+			group("General", true, biography, text("Species", "")),
+			group(
+				"Service",
+				true,
+				options("Rank", "Captain", "Commander", "Lieutenant", "Ensign", "Chief", "Crewman", "Civilian"),
+				options("Station", "Command", "Helm", "Engineering", "Science", "Medical", "Security",
+					"Communications"),
+				models.UniverseGuideField{
+					Name:        "Active duty",
+					Description: "Whether this character currently serves aboard the ship",
+					Type:        models.GuideFieldToggle,
+					Meta:        models.UniverseGuideMetaToggle{},
+				},
+			),
+			group(
+				"Skills",
+				false,
+				skill("Piloting", models.BarColorBlue),
+				skill("Engineering", models.BarColorYellow),
+				skill("Combat", models.BarColorRed),
+				skill("Diplomacy", models.BarColorGreen),
+			),
+		),
+		Settings: &models.UniverseSettings{
+			TitleField:                   "Name",
+			AllowAvatars:                 true,
+			AllowLexicographicalOrdering: true,
+			RelationshipTypes:            DefaultRelationshipTypes,
+		},
+	},
+	{
+		Name:        "modern-drama",
+		Title:       "Modern drama",
+		Description: "Contemporary characters with their occupations, relationships and personalities",
+		Guide: guide(
+			group("General", true, biography),
+			group(
+				"Personal",
+				false,
+				models.UniverseGuideField{
+					Name: "Age",
+					Type: models.GuideFieldNumber,
+					Meta: models.UniverseGuideMetaNumber{Min: 0, Max: 150, Tick: 1},
+				},
+				text("Occupation", ""),
+				text("Hometown", ""),
+				list("Relationships", "Family, friends and rivals of this character", 20),
+			),
+			group(
+				"Personality",
+				false,
+				list("Traits", "", 10),
+				list("Goals", "", 10),
+				list("Secrets", "", 10),
+			),
+		),
+		Settings: &models.UniverseSettings{
+			TitleField:                   "Name",
+			AllowAvatars:                 true,
+			AllowLexicographicalOrdering: true,
+			RelationshipTypes:            DefaultRelationshipTypes,
+		},
+	},
+}
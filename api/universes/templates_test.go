@@ -0,0 +1,43 @@
+package universes
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"testing"
+
+	"github.com/teris-io/shortid"
+)
+
+func TestTemplates(t *testing.T) {
+	sid, err := shortid.New(0, shortid.DefaultABC, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{Providers: &api.Providers{ShortID: sid}}
+	names := make(map[string]bool)
+	for _, template := range Templates {
+		t.Run(template.Name, func(t *testing.T) {
+			if names[template.Name] {
+				t.Fatalf("template name %v is not unique", template.Name)
+			}
+			names[template.Name] = true
+
+			// Templates must pass the same validation as edited universes once copied into one
+			universe, err := s.Copy(dtos.ReqCreateUniverse{Name: template.Title}, &models.Universe{
+				Guide:    template.Guide,
+				Settings: template.Settings,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			valError, err := api.ValidateDTO(&dtos.ReqEditUniverse{Universe: universe})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valError != nil {
+				t.Errorf("template failed validation: %v", valError.Issues)
+			}
+		})
+	}
+}
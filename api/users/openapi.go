@@ -0,0 +1,32 @@
+package users
+
+import (
+	"cbs/api/openapi"
+	"cbs/dtos"
+	"net/http"
+)
+
+// Operations describes the routes of the "users" resource for the OpenAPI document
+var Operations = []openapi.Operation{
+	{
+		Method:  http.MethodPost,
+		Path:    "/",
+		Summary: "Register a user",
+		Body:    []openapi.Content{{Body: dtos.ReqCreateUser{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusCreated,
+			Description: "The registered user",
+			Content:     []openapi.Content{{Body: dtos.ResGetUser{}}},
+		}},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/{userID}",
+		Summary: "Get a user",
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The user",
+			Content:     []openapi.Content{{Body: dtos.ResGetUser{}}},
+		}},
+	},
+}
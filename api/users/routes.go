@@ -3,7 +3,6 @@ package users
 import (
 	"cbs/api"
 	"cbs/dtos"
-	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi"
@@ -26,12 +25,11 @@ func NewRouter(server *api.Server) *Router {
 func (m *Router) CreateUser(w http.ResponseWriter, r *http.Request) error {
 	var payload dtos.ReqCreateUser
 	if err := api.ReadAndValidateBody(r.Body, &payload); err != nil {
-		fmt.Println(err)
 		return err
 	}
 	user := m.Services.User.New(payload)
 	if err := m.Services.User.Create(user); err != nil {
-		fmt.Println(err)
+		api.Logger(r).Error("Failed to register user", "error", err)
 		return api.ErrInternal("Failed to register user")
 	}
 	api.SendResponse(w, dtos.ResGetUser{User: user}, http.StatusCreated)
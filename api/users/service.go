@@ -21,8 +21,8 @@ func (s *Service) New(data dtos.ReqCreateUser) *models.User {
 
 // Find returns all Users
 func (s *Service) Find() (*[]models.User, error) {
-	var users []models.User
-	if err := s.Providers.DB.Select(&users, "SELECT id, email, display_name FROM users"); err != nil {
+	users, err := s.Repositories.User.FindAll()
+	if err != nil {
 		return nil, err
 	}
 	return &users, nil
@@ -30,60 +30,38 @@ func (s *Service) Find() (*[]models.User, error) {
 
 // FindByID returns a User by their ID
 func (s *Service) FindByID(id string) (*models.User, error) {
-	var user models.User
-	if err := s.Providers.DB.Get(&user, "SELECT id, email, display_name FROM users WHERE id = $1", id); err != nil {
-		return nil, err
-	}
-	return &user, nil
+	return s.Repositories.User.FindByID(id)
 }
 
 // FindByEmail returns a User by their email address
 func (s *Service) FindByEmail(email string) (*models.User, error) {
-	var user models.User
-	if err := s.Providers.DB.Get(
-		&user,
-		"SELECT id, email, display_name FROM users WHERE email = $1",
-		email,
-	); err != nil {
-		return nil, err
-	}
-	return &user, nil
+	return s.Repositories.User.FindByEmail(email)
 }
 
 // Create inserts a user into the database
 func (s *Service) Create(user *models.User) error {
-	rows, err := s.Providers.DB.NamedQuery(
-		`INSERT INTO users (id, display_name, email, password_hash) VALUES (:id,:display_name,:email,:password_hash)
-		RETURNING id, display_name, email, password_hash`,
-		user,
-	)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-	for rows.Next() {
-		if err := rows.StructScan(&user); err != nil {
-			return err
-		}
-	}
-	return nil
+	return s.Repositories.User.Create(user)
 }
 
 // Update updates an existing user in the database
 func (s *Service) Update(user *models.User) error {
-	rows, err := s.Providers.DB.NamedQuery(
-		`UPDATE users SET id = :id, display_name = :display_name, email = :email, password_hash = :password_hash)
-		RETURNING id, display_name, email, password_hash`,
-		user,
-	)
+	return s.Repositories.User.Update(user)
+}
+
+// Search returns the users whose email address or display name contains the query, including disabled users
+func (s *Service) Search(query string) (*[]models.User, error) {
+	users, err := s.Repositories.User.Search(query)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		if err := rows.StructScan(&user); err != nil {
-			return err
-		}
+	return &users, nil
+}
+
+// SetDisabled prevents or allows a user from logging in
+func (s *Service) SetDisabled(user *models.User, disabled bool) error {
+	if err := s.Repositories.User.SetDisabled(user.ID, disabled); err != nil {
+		return err
 	}
+	user.Disabled = disabled
 	return nil
 }
@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"cbs/api"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+)
+
+// deliveryClient represents the HTTP client deliveries are sent with. It only connects to public addresses so
+// that webhooks cannot reach the server's own network
+var deliveryClient = newClient(isPublic)
+
+// lookupIP resolves the addresses of a webhook's host when it is registered
+var lookupIP = net.DefaultResolver.LookupIPAddr
+
+// isPublic reports whether an address is reachable from the internet rather than only from the server's network,
+// such as loopback, private and link-local addresses (including cloud metadata services)
+func isPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified())
+}
+
+// newClient creates an HTTP client that only connects to the addresses allowed by allow, checked once the host
+// is resolved so that DNS cannot be used to point a webhook elsewhere after it is registered. Redirects are not
+// followed, and are reported as the receiver's response
+func newClient(allow func(ip net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: DeliveryTimeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allow(ip) {
+				return fmt.Errorf("address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: DeliveryTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: DeliveryTimeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkURL ensures a webhook URL is an http or https URL whose host only resolves to public addresses
+func checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return api.ErrBadBody("Webhook URLs must be http or https URLs")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DeliveryTimeout)
+	defer cancel()
+	addrs, err := lookupIP(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return api.ErrBadBody(fmt.Sprintf("Host '%s' could not be resolved", u.Hostname()))
+	}
+	for _, addr := range addrs {
+		if !isPublic(addr.IP) {
+			return api.ErrBadBody(fmt.Sprintf("Host '%s' resolves to an address that is not public", u.Hostname()))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package webhooks
+
+import (
+	"cbs/api/openapi"
+	"cbs/dtos"
+	"net/http"
+)
+
+// Operations describes the routes of the "webhooks" resource for the OpenAPI document
+var Operations = []openapi.Operation{
+	{
+		Method:  http.MethodGet,
+		Path:    "/",
+		Summary: "List the webhooks of a universe",
+		Session: true,
+		Role:    openapi.RoleOwner,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The webhooks, without their secrets",
+			Content:     []openapi.Content{{Body: dtos.ResGetWebhooks{}}},
+		}},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/",
+		Summary: "Register a webhook",
+		Description: "The URL must be an http or https URL whose host resolves to public addresses. Deliveries are " +
+			"signed with the secret, which is only sent in this response",
+		Session: true,
+		Role:    openapi.RoleOwner,
+		Body:    []openapi.Content{{Body: dtos.ReqCreateWebhook{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusCreated,
+			Description: "The registered webhook along with its secret",
+			Content:     []openapi.Content{{Body: dtos.ResCreateWebhook{}}},
+		}},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/{webhookID}",
+		Summary: "Get a webhook",
+		Session: true,
+		Role:    openapi.RoleOwner,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The webhook, without its secret",
+			Content:     []openapi.Content{{Body: dtos.ResGetWebhook{}}},
+		}},
+	},
+	{
+		Method:      http.MethodPatch,
+		Path:        "/{webhookID}",
+		Summary:     "Edit a webhook",
+		Description: "The webhook is only enabled or disabled when active is set",
+		Session:     true,
+		Role:        openapi.RoleOwner,
+		Body:        []openapi.Content{{Body: dtos.ReqEditWebhook{}}},
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The edited webhook",
+			Content:     []openapi.Content{{Body: dtos.ResGetWebhook{}}},
+		}},
+	},
+	{
+		Method:      http.MethodDelete,
+		Path:        "/{webhookID}",
+		Summary:     "Remove a webhook",
+		Description: "Removing a webhook removes its deliveries",
+		Session:     true,
+		Role:        openapi.RoleOwner,
+		Responses:   []openapi.Response{{Status: http.StatusNoContent, Description: "The webhook was removed"}},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/{webhookID}/secret",
+		Summary:     "Rotate the secret of a webhook",
+		Description: "Deliveries are signed with the new secret from then on, including retries",
+		Session:     true,
+		Role:        openapi.RoleOwner,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The webhook along with its new secret",
+			Content:     []openapi.Content{{Body: dtos.ResCreateWebhook{}}},
+		}},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/{webhookID}/deliveries",
+		Summary: "List the most recent deliveries of a webhook",
+		Session: true,
+		Role:    openapi.RoleOwner,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The deliveries, most recent first",
+			Content:     []openapi.Content{{Body: dtos.ResGetWebhookDeliveries{}}},
+		}},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/{webhookID}/deliveries/{deliveryID}",
+		Summary: "Get a delivery of a webhook",
+		Session: true,
+		Role:    openapi.RoleOwner,
+		Responses: []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: "The delivery, including its payload",
+			Content:     []openapi.Content{{Body: dtos.ResGetWebhookDelivery{}}},
+		}},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/{webhookID}/deliveries/{deliveryID}/redeliver",
+		Summary:     "Send a delivery again",
+		Description: "The payload is queued as a new delivery, leaving the original one as it was",
+		Session:     true,
+		Role:        openapi.RoleOwner,
+		Responses: []openapi.Response{{
+			Status:      http.StatusAccepted,
+			Description: "The queued delivery",
+			Content:     []openapi.Content{{Body: dtos.ResGetWebhookDelivery{}}},
+		}},
+	},
+}
@@ -0,0 +1,149 @@
+package webhooks
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// Router represents a router for the "webhooks" resource
+type Router api.Router
+
+// NewRouter creates a new router assigned to the "webhooks" resource
+func NewRouter(server *api.Server) *Router {
+	router := &Router{
+		Mux:    chi.NewMux(),
+		Server: server}
+	router.Use(
+		server.Middlewares.UserSession,
+		server.Middlewares.Universe,
+		server.Middlewares.Collaborator(models.CollaboratorOwner),
+	)
+	router.Get("/", api.Handler(router.GetWebhooks).ServeHTTP)
+	router.Post("/", api.Handler(router.CreateWebhook).ServeHTTP)
+	router.Route("/{webhookID}", func(r chi.Router) {
+		r.Use(server.Middlewares.Webhook)
+		r.Get("/", api.Handler(router.GetWebhook).ServeHTTP)
+		r.Patch("/", api.Handler(router.EditWebhook).ServeHTTP)
+		r.Delete("/", api.Handler(router.DeleteWebhook).ServeHTTP)
+		r.Post("/secret", api.Handler(router.RotateSecret).ServeHTTP)
+		r.Get("/deliveries", api.Handler(router.GetDeliveries).ServeHTTP)
+		r.Get("/deliveries/{deliveryID}", api.Handler(router.GetDelivery).ServeHTTP)
+		r.Post("/deliveries/{deliveryID}/redeliver", api.Handler(router.Redeliver).ServeHTTP)
+	})
+	return router
+}
+
+// GetWebhooks represents a route that returns every webhook registered to a universe
+func (m *Router) GetWebhooks(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	webhooks, err := m.Services.Webhook.FindByUniverse(universe)
+	if err != nil {
+		return api.ErrInternal("Failed to get webhooks")
+	}
+	api.SendResponse(w, dtos.ResGetWebhooks{Webhooks: webhooks}, http.StatusOK)
+	return nil
+}
+
+// CreateWebhook represents a route that registers a new webhook to a universe
+func (m *Router) CreateWebhook(w http.ResponseWriter, r *http.Request) error {
+	universe, _ := r.Context().Value(api.UniverseContextKey).(*models.Universe)
+	var payload dtos.ReqCreateWebhook
+	if err := api.ReadAndValidateBody(r.Body, &payload); err != nil {
+		return err
+	}
+	webhook, err := m.Services.Webhook.New(universe, payload)
+	if err != nil {
+		return err
+	}
+	if err := m.Services.Webhook.Create(webhook); err != nil {
+		return api.ErrInternal("Failed to create webhook")
+	}
+	api.SendResponse(w, dtos.ResCreateWebhook{Webhook: webhook, Secret: webhook.Secret}, http.StatusCreated)
+	return nil
+}
+
+// GetWebhook represents a route that returns a webhook based on its ID
+func (m *Router) GetWebhook(w http.ResponseWriter, r *http.Request) error {
+	webhook, _ := r.Context().Value(api.WebhookContextKey).(*models.Webhook)
+	api.SendResponse(w, dtos.ResGetWebhook{Webhook: webhook}, http.StatusOK)
+	return nil
+}
+
+// EditWebhook represents a route that modifies a webhook based on its ID
+func (m *Router) EditWebhook(w http.ResponseWriter, r *http.Request) error {
+	webhook, _ := r.Context().Value(api.WebhookContextKey).(*models.Webhook)
+	var payload dtos.ReqEditWebhook
+	if err := api.ReadAndValidateBody(r.Body, &payload); err != nil {
+		return err
+	}
+	if err := m.Services.Webhook.Update(webhook, payload); err != nil {
+		if apierr, ok := err.(api.Error); ok {
+			return apierr
+		}
+		return api.ErrInternal("Failed to edit webhook")
+	}
+	api.SendResponse(w, dtos.ResGetWebhook{Webhook: webhook}, http.StatusOK)
+	return nil
+}
+
+// RotateSecret represents a route that replaces the signing secret of a webhook, returning the new secret
+func (m *Router) RotateSecret(w http.ResponseWriter, r *http.Request) error {
+	webhook, _ := r.Context().Value(api.WebhookContextKey).(*models.Webhook)
+	if err := m.Services.Webhook.RotateSecret(webhook); err != nil {
+		return api.ErrInternal("Failed to rotate webhook secret")
+	}
+	api.SendResponse(w, dtos.ResCreateWebhook{Webhook: webhook, Secret: webhook.Secret}, http.StatusOK)
+	return nil
+}
+
+// DeleteWebhook represents a route that removes a webhook and its delivery log
+func (m *Router) DeleteWebhook(w http.ResponseWriter, r *http.Request) error {
+	webhook, _ := r.Context().Value(api.WebhookContextKey).(*models.Webhook)
+	if err := m.Services.Webhook.Delete(webhook); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	w.Write([]byte(""))
+	return nil
+}
+
+// GetDeliveries represents a route that returns the most recent deliveries of a webhook
+func (m *Router) GetDeliveries(w http.ResponseWriter, r *http.Request) error {
+	webhook, _ := r.Context().Value(api.WebhookContextKey).(*models.Webhook)
+	deliveries, err := m.Services.Webhook.FindDeliveries(webhook)
+	if err != nil {
+		return api.ErrInternal("Failed to get deliveries")
+	}
+	api.SendResponse(w, dtos.ResGetWebhookDeliveries{Deliveries: deliveries}, http.StatusOK)
+	return nil
+}
+
+// GetDelivery represents a route that returns a single delivery of a webhook, including its payload
+func (m *Router) GetDelivery(w http.ResponseWriter, r *http.Request) error {
+	webhook, _ := r.Context().Value(api.WebhookContextKey).(*models.Webhook)
+	delivery, err := m.Services.Webhook.FindDeliveryByID(webhook, chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		return err
+	}
+	api.SendResponse(w, dtos.ResGetWebhookDelivery{WebhookDelivery: delivery}, http.StatusOK)
+	return nil
+}
+
+// Redeliver represents a route that queues a delivery to be sent again
+func (m *Router) Redeliver(w http.ResponseWriter, r *http.Request) error {
+	webhook, _ := r.Context().Value(api.WebhookContextKey).(*models.Webhook)
+	delivery, err := m.Services.Webhook.FindDeliveryByID(webhook, chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		return err
+	}
+	redelivery, err := m.Services.Webhook.Redeliver(delivery)
+	if err != nil {
+		return api.ErrInternal("Failed to queue redelivery")
+	}
+	api.SendResponse(w, dtos.ResGetWebhookDelivery{WebhookDelivery: redelivery}, http.StatusAccepted)
+	return nil
+}
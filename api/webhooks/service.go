@@ -0,0 +1,333 @@
+package webhooks
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"cbs/repositories"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// MaxDeliveryAttempts represents the number of times a delivery is attempted before it is marked as failed
+const MaxDeliveryAttempts = 8
+
+// DeliveryBackoff represents the delay before the first retry of a failed delivery, doubled for every retry after
+const DeliveryBackoff = 15 * time.Second
+
+// DeliveryTimeout represents how long a receiver has to respond to a delivery
+const DeliveryTimeout = 10 * time.Second
+
+// DeliveryPollInterval represents how often the delivery queue is checked for due deliveries
+const DeliveryPollInterval = time.Second
+
+// DeliveryLogLimit represents the number of most recent deliveries kept visible in a webhook's delivery log
+const DeliveryLogLimit = 50
+
+// deliveryBatchSize represents the maximum number of due deliveries claimed per poll
+const deliveryBatchSize = 20
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-CharacterBase-Event"
+	HeaderDelivery  = "X-CharacterBase-Delivery"
+	HeaderSignature = "X-CharacterBase-Signature"
+)
+
+// Service represents a service implementation for the "webhooks" resource
+type Service api.Service
+
+// Sign computes the signature of a delivery payload, sent in HeaderSignature so receivers can verify its origin
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newSecret generates a random secret for signing deliveries
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validateEvents ensures every subscribed event type exists
+func validateEvents(events models.WebhookEvents) error {
+	for _, e := range events {
+		known := false
+		for _, t := range models.UniverseEventTypes {
+			known = known || e == t
+		}
+		if !known {
+			return api.ErrBadBody(fmt.Sprintf("Unknown event type '%s'", e))
+		}
+	}
+	return nil
+}
+
+// New creates a new webhook with a freshly generated signing secret
+func (s *Service) New(universe *models.Universe, data dtos.ReqCreateWebhook) (*models.Webhook, error) {
+	if err := validateEvents(data.Events); err != nil {
+		return nil, err
+	}
+	if err := checkURL(data.URL); err != nil {
+		return nil, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	return &models.Webhook{
+		ID:         s.Providers.ShortID.MustGenerate(),
+		UniverseID: universe.ID,
+		URL:        data.URL,
+		Secret:     secret,
+		Events:     data.Events,
+		Active:     true,
+	}, nil
+}
+
+// FindByUniverse returns every webhook registered to a universe
+func (s *Service) FindByUniverse(universe *models.Universe) (*[]models.Webhook, error) {
+	webhooks, err := s.Repositories.Webhook.FindByUniverse(universe.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &webhooks, nil
+}
+
+// FindByID returns a webhook registered to a universe by its ID
+func (s *Service) FindByID(universe *models.Universe, id string) (*models.Webhook, error) {
+	webhook, err := s.Repositories.Webhook.FindByID(id)
+	if err == repositories.ErrNotFound || (err == nil && webhook.UniverseID != universe.ID) {
+		return nil, api.ErrNotFound("Webhook not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// Create stores a new webhook
+func (s *Service) Create(webhook *models.Webhook) error {
+	return s.Repositories.Webhook.Create(webhook)
+}
+
+// Update modifies the URL, event types and state of an existing webhook, keeping its state unless it is set
+func (s *Service) Update(webhook *models.Webhook, data dtos.ReqEditWebhook) error {
+	if err := validateEvents(data.Events); err != nil {
+		return err
+	}
+	if err := checkURL(data.URL); err != nil {
+		return err
+	}
+	webhook.URL = data.URL
+	webhook.Events = data.Events
+	if data.Active != nil {
+		webhook.Active = *data.Active
+	}
+	return s.Repositories.Webhook.Update(webhook)
+}
+
+// RotateSecret replaces the signing secret of a webhook with a freshly generated one. Deliveries sent from then on
+// are signed with the new secret
+func (s *Service) RotateSecret(webhook *models.Webhook) error {
+	secret, err := newSecret()
+	if err != nil {
+		return err
+	}
+	webhook.Secret = secret
+	return s.Repositories.Webhook.Update(webhook)
+}
+
+// Delete removes a webhook and its delivery log
+func (s *Service) Delete(webhook *models.Webhook) error {
+	return s.Repositories.Webhook.Delete(webhook.ID)
+}
+
+// FindDeliveries returns the most recent deliveries of a webhook
+func (s *Service) FindDeliveries(webhook *models.Webhook) (*[]models.WebhookDelivery, error) {
+	deliveries, err := s.Repositories.Webhook.FindDeliveries(webhook.ID, DeliveryLogLimit)
+	if err != nil {
+		return nil, err
+	}
+	return &deliveries, nil
+}
+
+// FindDeliveryByID returns a delivery of a webhook by its ID
+func (s *Service) FindDeliveryByID(webhook *models.Webhook, id string) (*models.WebhookDelivery, error) {
+	delivery, err := s.Repositories.Webhook.FindDeliveryByID(id)
+	if err == repositories.ErrNotFound || (err == nil && delivery.WebhookID != webhook.ID) {
+		return nil, api.ErrNotFound("Delivery not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Dispatch queues a delivery of a universe event for every active webhook subscribed to it
+func (s *Service) Dispatch(event *models.UniverseEvent) error {
+	webhooks, err := s.Repositories.Webhook.FindByUniverse(event.UniverseID)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	for _, webhook := range webhooks {
+		if !webhook.Active || !webhook.Subscribes(event.Type) {
+			continue
+		}
+		if _, err := s.queue(webhook.ID, event.ID, event.Type, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redeliver queues a new delivery with the same payload as an earlier delivery
+func (s *Service) Redeliver(delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	return s.queue(delivery.WebhookID, delivery.EventID, delivery.EventType, delivery.Payload)
+}
+
+// queue records a pending delivery and schedules it to be sent immediately
+func (s *Service) queue(
+	webhookID string,
+	eventID string,
+	eventType models.UniverseEventType,
+	payload []byte,
+) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		ID:            s.Providers.ShortID.MustGenerate(),
+		WebhookID:     webhookID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := s.Repositories.Webhook.CreateDelivery(delivery); err != nil {
+		return nil, err
+	}
+	if err := s.Repositories.WebhookQueue.Schedule(delivery.ID, now); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Work sends due deliveries from the queue until ctx is cancelled. Any number of server instances may
+// run a worker since every delivery is claimed by exactly one of them
+func (s *Service) Work(ctx context.Context) {
+	ticker := time.NewTicker(DeliveryPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Deliveries claimed before the queue failed are still sent, since they are no longer queued
+			ids, err := s.Repositories.WebhookQueue.Claim(time.Now(), deliveryBatchSize)
+			if err != nil {
+				s.Providers.Logger.Error("Failed to read the webhook delivery queue", "error", err)
+			}
+			for _, id := range ids {
+				if err := s.attempt(id); err != nil {
+					// The delivery is no longer queued, so it is put back rather than left pending forever. Its
+					// webhook may receive it twice when only recording the outcome failed
+					s.Providers.Logger.Error("Failed to process webhook delivery", "delivery", id, "error", err)
+					if err := s.Repositories.WebhookQueue.Schedule(id, time.Now().Add(DeliveryBackoff)); err != nil {
+						s.Providers.Logger.Error("Failed to requeue webhook delivery", "delivery", id, "error", err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// attempt sends a queued delivery, scheduling a retry with exponential backoff when it fails
+func (s *Service) attempt(id string) error {
+	// Deliveries of webhooks deleted since they were queued are dropped
+	delivery, err := s.Repositories.Webhook.FindDeliveryByID(id)
+	if err == repositories.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	webhook, err := s.Repositories.Webhook.FindByID(delivery.WebhookID)
+	if err == repositories.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	delivery.Attempts++
+	status, err := send(webhook, delivery)
+	delivery.ResponseStatus = status
+	delivery.NextAttemptAt = nil
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.Error = ""
+	case delivery.Attempts >= MaxDeliveryAttempts || !webhook.Active:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.Error = err.Error()
+	default:
+		next := time.Now().Add(backoff(delivery.Attempts))
+		delivery.Status = models.WebhookDeliveryPending
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = &next
+	}
+	if err := s.Repositories.Webhook.UpdateDelivery(delivery); err != nil {
+		return err
+	}
+	if delivery.NextAttemptAt != nil {
+		return s.Repositories.WebhookQueue.Schedule(delivery.ID, *delivery.NextAttemptAt)
+	}
+	return nil
+}
+
+// backoff returns the delay before the next attempt of a delivery that has failed the given number of times
+func backoff(attempts int) time.Duration {
+	return DeliveryBackoff * time.Duration(1<<uint(attempts-1))
+}
+
+// send posts a delivery's signed payload to its webhook and returns the receiver's response status.
+// Any response outside of the 2xx range, including redirects, is reported as an error
+func send(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CharacterBase-Webhooks")
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, delivery.Payload))
+	res, err := deliveryClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("receiver responded with status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
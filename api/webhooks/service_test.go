@@ -0,0 +1,137 @@
+package webhooks
+
+import (
+	"cbs/models"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// allowLoopback lets deliveries reach test receivers, which listen on loopback addresses, until the test ends
+func allowLoopback(t *testing.T) {
+	client := deliveryClient
+	deliveryClient = newClient(func(ip net.IP) bool { return true })
+	t.Cleanup(func() { deliveryClient = client })
+}
+
+func TestSend(t *testing.T) {
+	allowLoopback(t)
+	payload := []byte(`{"type":"character.created"}`)
+	tests := []struct {
+		name       string
+		status     int
+		wantstatus int
+		wanterr    bool
+	}{
+		{name: "accepted", status: http.StatusNoContent, wantstatus: http.StatusNoContent},
+		{name: "rejected", status: http.StatusInternalServerError, wantstatus: http.StatusInternalServerError, wanterr: true},
+		{name: "redirected", status: http.StatusFound, wantstatus: http.StatusFound, wanterr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received *http.Request
+			var body []byte
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				body, _ = ioutil.ReadAll(r.Body)
+				if tt.status == http.StatusFound {
+					w.Header().Set("Location", "http://169.254.169.254/")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			webhook := &models.Webhook{URL: receiver.URL, Secret: "secret"}
+			delivery := &models.WebhookDelivery{ID: "d1", EventType: models.EventCharacterCreated, Payload: payload}
+			status, err := send(webhook, delivery)
+			if (err != nil) != tt.wanterr {
+				t.Fatalf("got error %v; want error %v", err, tt.wanterr)
+			}
+			if status != tt.wantstatus {
+				t.Errorf("got status %d; want %d", status, tt.wantstatus)
+			}
+			if string(body) != string(payload) {
+				t.Errorf("got body %s; want %s", body, payload)
+			}
+			if got := received.Header.Get(HeaderSignature); got != Sign("secret", body) {
+				t.Errorf("got signature %v; want %v", got, Sign("secret", body))
+			}
+			if got := received.Header.Get(HeaderEvent); got != string(models.EventCharacterCreated) {
+				t.Errorf("got event %v; want %v", got, models.EventCharacterCreated)
+			}
+			if got := received.Header.Get(HeaderDelivery); got != "d1" {
+				t.Errorf("got delivery %v; want d1", got)
+			}
+		})
+	}
+}
+
+func TestSend_PrivateAddress(t *testing.T) {
+	received := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer receiver.Close()
+
+	webhook := &models.Webhook{URL: receiver.URL, Secret: "secret"}
+	delivery := &models.WebhookDelivery{ID: "d1", EventType: models.EventCharacterCreated, Payload: []byte("{}")}
+	if _, err := send(webhook, delivery); err == nil || received {
+		t.Errorf("got error %v and received %v; want the loopback receiver to be refused", err, received)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	lookup := lookupIP
+	lookupIP = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		if host == "internal.example.com" {
+			return []net.IPAddr{{IP: net.ParseIP("10.0.0.5")}}, nil
+		}
+		return net.DefaultResolver.LookupIPAddr(ctx, host)
+	}
+	defer func() { lookupIP = lookup }()
+	tests := []struct {
+		url     string
+		wanterr bool
+	}{
+		{url: "https://93.184.216.34/hooks"},
+		{url: "ftp://93.184.216.34/hooks", wanterr: true},
+		{url: "http://127.0.0.1:8080/hooks", wanterr: true},
+		{url: "http://169.254.169.254/latest/meta-data", wanterr: true},
+		{url: "http://192.168.1.10/hooks", wanterr: true},
+		{url: "http://[::1]/hooks", wanterr: true},
+		{url: "https://internal.example.com/hooks", wanterr: true},
+	}
+	for _, tt := range tests {
+		if err := checkURL(tt.url); (err != nil) != tt.wanterr {
+			t.Errorf("checkURL(%s) = %v; want error %v", tt.url, err, tt.wanterr)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: DeliveryBackoff, 2: 2 * DeliveryBackoff, 4: 8 * DeliveryBackoff} {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v; want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestValidateEvents(t *testing.T) {
+	tests := []struct {
+		events  models.WebhookEvents
+		wanterr bool
+	}{
+		{events: models.WebhookEvents{models.EventCharacterCreated, models.EventCommentCreated}},
+		{events: models.WebhookEvents{"character.renamed"}, wanterr: true},
+		{events: models.WebhookEvents{models.EventUniverseDeleted}, wanterr: true},
+	}
+	for _, tt := range tests {
+		if err := validateEvents(tt.events); (err != nil) != tt.wanterr {
+			t.Errorf("validateEvents(%v) = %v; want error %v", tt.events, err, tt.wanterr)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package dtos
+
+import (
+	"cbs/models"
+	"time"
+)
+
+// AuditQuery represents a context for querying a universe's audit log. Empty filters match every event
+type AuditQuery struct {
+	Page       int
+	ActorID    string
+	Action     models.AuditAction
+	TargetType models.AuditTargetType
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+}
+
+// ResGetAuditEvents represents a response DTO containing a page of audit events
+type ResGetAuditEvents struct {
+	Events *[]models.AuditEvent `json:"events"`
+	Page   int                  `json:"page"`
+	Total  int                  `json:"total"`
+}
@@ -69,3 +69,50 @@ type ResGetCharacters struct {
 	Page       int                          `json:"page"`
 	Total      int                          `json:"total"`
 }
+
+// CharacterImportFormat represents the file format of a bulk character import
+type CharacterImportFormat string
+
+var (
+	// CharacterImportFormatCSV expects a CSV file with a header row of character and guide field columns
+	CharacterImportFormatCSV CharacterImportFormat = "csv"
+
+	// CharacterImportFormatJSON expects a JSON array of character creation requests
+	CharacterImportFormatJSON CharacterImportFormat = "json"
+)
+
+// CharacterImportOptions represents settings for processing a bulk character import
+type CharacterImportOptions struct {
+	Atomic bool
+	DryRun bool
+}
+
+// CharacterExportFormat represents the file format of a character export
+type CharacterExportFormat string
+
+var (
+	// CharacterExportFormatCSV produces a CSV file with one column per guide field, in guide order
+	CharacterExportFormatCSV CharacterExportFormat = "csv"
+
+	// CharacterExportFormatJSON produces the characters as they are returned by the API
+	CharacterExportFormatJSON CharacterExportFormat = "json"
+
+	// CharacterExportFormatMarkdown produces a Markdown document with guide groups as headings
+	CharacterExportFormatMarkdown CharacterExportFormat = "md"
+)
+
+// CharacterPatchType represents the media type of a partial character update
+type CharacterPatchType string
+
+var (
+	// CharacterPatchMerge expects a JSON Merge Patch (RFC 7396) document
+	CharacterPatchMerge CharacterPatchType = "application/merge-patch+json"
+
+	// CharacterPatchJSON expects a JSON Patch (RFC 6902) document
+	CharacterPatchJSON CharacterPatchType = "application/json-patch+json"
+)
+
+// ResGetCharacterImport represents a response DTO containing a character import's progress and results
+type ResGetCharacterImport struct {
+	*models.CharacterImport
+}
@@ -0,0 +1,27 @@
+package dtos
+
+import (
+	"cbs/models"
+)
+
+// ReqCreateComment represents a request DTO for commenting on a character. Comments replying to another comment
+// are added to its thread, and collaborators are mentioned by their display names with "@name" in the body
+type ReqCreateComment struct {
+	ParentID string `json:"parentId"`
+	Body     string `json:"body" validate:"required,max=10000"`
+}
+
+// ReqEditComment represents a request DTO for modifying the body of an existing comment
+type ReqEditComment struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+// ResGetComment represents a response DTO containing comment data
+type ResGetComment struct {
+	*models.Comment
+}
+
+// ResGetComments represents a response DTO containing the comment threads of a character
+type ResGetComments struct {
+	Threads *[]models.CommentThread `json:"threads"`
+}
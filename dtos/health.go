@@ -0,0 +1,26 @@
+package dtos
+
+// ResGetHealth represents a response DTO describing whether the process is alive
+type ResGetHealth struct {
+	Status string `json:"status"`
+}
+
+// DependencyStatus represents the result of checking whether an external dependency is reachable
+type DependencyStatus struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+}
+
+// ResGetReadiness represents a response DTO describing whether every dependency of the API is reachable
+type ResGetReadiness struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks"`
+}
+
+// ResGetVersion represents a response DTO containing build metadata
+type ResGetVersion struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
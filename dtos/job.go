@@ -0,0 +1,8 @@
+package dtos
+
+import "cbs/models"
+
+// ResGetJob represents a response DTO containing the status of a background job
+type ResGetJob struct {
+	*models.Job
+}
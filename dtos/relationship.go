@@ -0,0 +1,38 @@
+package dtos
+
+import (
+	"cbs/models"
+)
+
+// ReqCreateRelationship represents a request DTO for relating two characters of a universe. The type must be one
+// of the relationship types of the universe settings
+type ReqCreateRelationship struct {
+	SourceID      string `json:"sourceId" validate:"required"`
+	TargetID      string `json:"targetId" validate:"required,nefield=SourceID"`
+	Type          string `json:"type" validate:"required"`
+	Bidirectional bool   `json:"bidirectional"`
+	Description   string `json:"description"`
+}
+
+// ReqEditRelationship represents a request DTO for modifying the type, direction and description of an existing
+// relationship
+type ReqEditRelationship struct {
+	Type          string `json:"type" validate:"required"`
+	Bidirectional bool   `json:"bidirectional"`
+	Description   string `json:"description"`
+}
+
+// ResGetRelationship represents a response DTO containing relationship data
+type ResGetRelationship struct {
+	*models.Relationship
+}
+
+// ResGetRelationships represents a response DTO containing a collection of relationships
+type ResGetRelationships struct {
+	Relationships *[]models.Relationship `json:"relationships"`
+}
+
+// ResGetRelationshipGraph represents a response DTO containing the neighbourhood of a character
+type ResGetRelationshipGraph struct {
+	*models.RelationshipGraph
+}
@@ -0,0 +1,33 @@
+package dtos
+
+import (
+	"cbs/models"
+	"time"
+)
+
+// ReqCreateShare represents a request DTO for creating a public share link to a universe or one of its characters
+type ReqCreateShare struct {
+	// CharacterID represents the character to share, sharing the whole universe when empty
+	CharacterID string `json:"characterId,omitempty"`
+
+	// ExpiresAt represents when the link stops working, never when absent
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// ResGetShare represents a response DTO containing share link data
+type ResGetShare struct {
+	*models.Share
+}
+
+// ResGetShares represents a response DTO containing a collection of share links
+type ResGetShares struct {
+	Shares *[]models.Share `json:"shares"`
+}
+
+// ResGetShared represents a response DTO containing what a share link exposes: the universe, along with the
+// character when only a character is shared
+type ResGetShared struct {
+	Universe  *models.Universe  `json:"universe"`
+	Character *models.Character `json:"character,omitempty"`
+	ExpiresAt *time.Time        `json:"expiresAt"`
+}
@@ -8,6 +8,15 @@ import (
 type ReqCreateUniverse struct {
 	Name        string `json:"name" validate:"required"`
 	Description string `json:"description" validate:""`
+
+	// Source represents the ID of a universe to copy the guide and settings of
+	Source string `json:"source,omitempty"`
+
+	// IncludeCharacters represents whether the characters of the source universe are copied too
+	IncludeCharacters bool `json:"includeCharacters,omitempty"`
+
+	// Template represents the name of a built-in template to take the guide and settings from
+	Template string `json:"template,omitempty"`
 }
 
 // ReqEditUniverse represents a request DTO for modifying an existing universe
@@ -43,6 +52,16 @@ type ResGetUniverses struct {
 	References *[]models.UniverseReference `json:"universes"`
 }
 
+// ResGetUniverseTemplates represents a response DTO containing the built-in universe templates
+type ResGetUniverseTemplates struct {
+	Templates []models.UniverseTemplate `json:"templates"`
+}
+
+// ResGetUniverseUsage represents a response DTO containing the usage of a universe's quotas
+type ResGetUniverseUsage struct {
+	*models.UniverseUsage
+}
+
 // ResGetCollaborator represents a response DTO containing collaborator data
 type ResGetCollaborator struct {
 	*models.Collaborator
@@ -0,0 +1,46 @@
+package dtos
+
+import (
+	"cbs/models"
+)
+
+// ReqCreateWebhook represents a request DTO for registering a new webhook
+type ReqCreateWebhook struct {
+	URL    string               `json:"url" validate:"required,url"`
+	Events models.WebhookEvents `json:"events" validate:"required,min=1"`
+}
+
+// ReqEditWebhook represents a request DTO for modifying an existing webhook. The webhook is only enabled or
+// disabled when active is set
+type ReqEditWebhook struct {
+	URL    string               `json:"url" validate:"required,url"`
+	Events models.WebhookEvents `json:"events" validate:"required,min=1"`
+	Active *bool                `json:"active"`
+}
+
+// ResGetWebhook represents a response DTO containing webhook data
+type ResGetWebhook struct {
+	*models.Webhook
+}
+
+// ResCreateWebhook represents a response DTO containing webhook data along with its signing secret, sent when the
+// webhook is created or its secret is rotated
+type ResCreateWebhook struct {
+	*models.Webhook
+	Secret string `json:"secret"`
+}
+
+// ResGetWebhooks represents a response DTO containing a collection of webhooks
+type ResGetWebhooks struct {
+	Webhooks *[]models.Webhook `json:"webhooks"`
+}
+
+// ResGetWebhookDelivery represents a response DTO containing webhook delivery data
+type ResGetWebhookDelivery struct {
+	*models.WebhookDelivery
+}
+
+// ResGetWebhookDeliveries represents a response DTO containing a webhook's most recent deliveries
+type ResGetWebhookDeliveries struct {
+	Deliveries *[]models.WebhookDelivery `json:"deliveries"`
+}
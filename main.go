@@ -2,109 +2,268 @@ package main
 
 import (
 	"cbs/api"
+	"cbs/api/audit"
 	"cbs/api/auth"
 	"cbs/api/characters"
+	"cbs/api/comments"
+	"cbs/api/events"
+	"cbs/api/health"
+	"cbs/api/jobs"
+	"cbs/api/relationships"
+	"cbs/api/shares"
 	"cbs/api/universes"
 	"cbs/api/users"
+	"cbs/api/webhooks"
+	"cbs/models"
+	"cbs/repositories"
+	"cbs/repositories/postgres"
+	"cbs/repositories/redisstore"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi"
 	"github.com/go-chi/cors"
 	"github.com/go-redis/redis"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 	"github.com/jmoiron/sqlx"
 	"github.com/teris-io/shortid"
 	"gopkg.in/Masterminds/squirrel.v1"
-	yaml "gopkg.in/yaml.v2"
 )
 
 var (
 	configPath = flag.String("c", "config.yaml", "Path to the configuration file")
 )
 
-// loadConfig loads configuration from a YAML configuration file
-func loadConfig(path string) (*api.Config, error) {
-	var config *api.Config
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, errors.New("failed to read configuration file")
-	}
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, errors.New("failed to parse configuration file")
+// usage describes the command-line interface
+const usage = `usage: %s [-c config.yaml] [command]
+
+Starts the API server when no command is given.
+
+Every configuration key can be overridden through the environment as CBS_<KEY>
+(e.g. CBS_DATABASE_URL), or read from a file given as CBS_<KEY>_FILE.
+
+commands:
+  migrate     manage the database schema (see "migrate help")
+  user        create, search, disable and reset the passwords of users
+  universe    transfer the ownership of universes
+  images      reconcile stored image files with the images of characters
+  sessions    clear user sessions
+  jobs        inspect and retry dead background jobs
+
+Run a command without arguments for its usage.
+`
+
+// configFile returns the configuration file to load. The default file is optional so the API can be configured
+// through the environment alone, but a file given with -c must exist
+func configFile() string {
+	explicit := false
+	flag.Visit(func(f *flag.Flag) {
+		explicit = explicit || f.Name == "c"
+	})
+	if _, err := os.Stat(*configPath); !explicit && errors.Is(err, os.ErrNotExist) {
+		return ""
 	}
-	return config, nil
+	return *configPath
 }
 
-func newServices(providers *api.Providers, config *api.Config) *api.Services {
+func newServices(
+	providers *api.Providers,
+	repositories *repositories.Repositories,
+	config *api.Config,
+) *api.Services {
 	return &api.Services{
-		Auth:      &auth.Service{Providers: providers, Config: config},
-		User:      &users.Service{Providers: providers, Config: config},
-		Universe:  &universes.Service{Providers: providers, Config: config},
-		Character: &characters.Service{Providers: providers, Config: config},
+		Auth:      &auth.Service{Providers: providers, Repositories: repositories, Config: config},
+		User:      &users.Service{Providers: providers, Repositories: repositories, Config: config},
+		Universe:  &universes.Service{Providers: providers, Repositories: repositories, Config: config},
+		Character: &characters.Service{Providers: providers, Repositories: repositories, Config: config},
+		Event:     &events.Service{Providers: providers, Repositories: repositories, Config: config},
+		Webhook:   &webhooks.Service{Providers: providers, Repositories: repositories, Config: config},
+		Audit:     &audit.Service{Providers: providers, Repositories: repositories, Config: config},
+		Share:     &shares.Service{Providers: providers, Repositories: repositories, Config: config},
+		Job:       &jobs.Service{Providers: providers, Repositories: repositories, Config: config},
+		Relationship: &relationships.Service{
+			Providers:    providers,
+			Repositories: repositories,
+			Config:       config,
+		},
+		Comment: &comments.Service{Providers: providers, Repositories: repositories, Config: config},
+	}
+}
+
+// newRepositories creates the repositories backed by Postgres, Redis and AWS S3
+func newRepositories(providers *api.Providers) *repositories.Repositories {
+	repos := postgres.New(providers.DB, *providers.SQLBuilder, providers.Storage)
+	repos.Session = &redisstore.Sessions{Redis: providers.Redis}
+	repos.Import = &redisstore.Imports{Redis: providers.Redis}
+	repos.Job = &redisstore.Jobs{Redis: providers.Redis}
+	repos.WebhookQueue = &redisstore.WebhookQueue{Redis: providers.Redis}
+	return repos
+}
+
+// mountOperational mounts the health and build information endpoints on a router, along with the metrics
+// endpoint when metrics is set
+func mountOperational(r chi.Router, server *api.Server, metrics bool) {
+	healthRouter := health.NewRouter(server)
+	for _, path := range health.Paths {
+		r.Handle(path, healthRouter)
+	}
+	if metrics {
+		r.Handle("/metrics", api.MetricsHandler())
 	}
 }
 
 func newServer(config api.Config, providers *api.Providers) *api.Server {
-	services := newServices(providers, &config)
+	services := newServices(providers, newRepositories(providers), &config)
 	server := api.NewServer(config, providers, services)
 
 	// Configure CORS
 	corsM := cors.New(cors.Options{
-		AllowedOrigins:   config.AllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedOrigins: config.AllowedOrigins,
+		AllowedMethods: []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{
+			"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match", api.RequestIDHeader,
+		},
+		ExposedHeaders:   []string{"Link", "ETag", api.RequestIDHeader, api.JobHeader},
 		AllowCredentials: true,
 		MaxAge:           300,
 	})
 
 	// Mount the API middleware
-	server.Use(middleware.Logger)
+	server.Use(api.MwRequestID)
+	server.Use(api.MwMetrics)
+	server.Use(api.MwRequestLogger(providers.Logger))
 	server.Use(corsM.Handler)
 
 	// Mount the API routers
 	server.Mount("/", auth.NewRouter(server))
+	if config.AdminPort == 0 {
+		mountOperational(server.Mux, server, config.PublicMetrics)
+	}
 	server.Mount("/users", users.NewRouter(server))
 	server.Mount("/universes", universes.NewRouter(server))
 	server.Mount("/universes/{universeID}/characters", characters.NewRouter(server))
+	server.Mount("/universes/{universeID}/events", events.NewRouter(server))
+	server.Mount("/universes/{universeID}/webhooks", webhooks.NewRouter(server))
+	server.Mount("/universes/{universeID}/audit", audit.NewRouter(server))
+	server.Mount("/universes/{universeID}/shares", shares.NewRouter(server))
+	server.Mount("/universes/{universeID}/jobs", jobs.NewRouter(server))
+	server.Mount("/universes/{universeID}/relationships", relationships.NewRouter(server))
+	server.Mount("/universes/{universeID}/characters/{characterID}/comments", comments.NewRouter(server))
+	server.Mount("/shared/{token}", shares.NewPublicRouter(server))
 
 	return server
 }
 
+// shutdown ends event streams, stops accepting requests and waits for in-flight requests and background work
+// to finish within the grace period
+func shutdown(server *api.Server, httpServers []*http.Server, gracePeriod time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	server.Stop()
+	var firstErr error
+	for _, httpServer := range httpServers {
+		if err := httpServer.Shutdown(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := server.Wait(ctx); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// redactURL hides the password of a connection URL so it can be logged. Connection strings that are not URLs are
+// hidden entirely, since they may hold a password in any form
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "[redacted]"
+	}
+	return u.Redacted()
+}
+
+// fatal logs an error that prevents the API from running and exits
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
+
 func main() {
 	// Parse the command-line flags
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), usage, os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
 	// Load API configuration
-	config, err := loadConfig(*configPath)
+	config, err := api.LoadConfig(configFile(), os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Create the structured logger, which also receives the output of the standard library logger
+	logger, err := api.NewLogger(os.Stderr, config.LogLevel, config.LogFormat)
 	if err != nil {
 		panic(err)
 	}
+	slog.SetDefault(logger)
+	for _, key := range config.UnknownKeys() {
+		logger.Warn("Ignoring unknown configuration key", "key", key)
+	}
 
 	// Connect to the database
-	log.Printf("Connecting to database... (url: %v)\n", config.DatabaseURL)
+	logger.Info("Connecting to database...", "url", redactURL(config.DatabaseURL))
 	db, err := sqlx.Connect("postgres", config.DatabaseURL)
 	if err != nil {
-		panic(err)
+		fatal(logger, "Failed to connect to the database", err)
+	}
+	logger.Info("Database connection OK")
+
+	// Run the requested command instead of the server
+	command := flag.Arg(0)
+	switch {
+	case command == "" || isAdminCommand(command):
+	case command == "migrate":
+		if err := runMigrate(db, flag.Args()[1:]); err != nil {
+			fatal(logger, "Failed to migrate the database", err)
+		}
+		return
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Bring the database schema up to date
+	if config.MigrateOnStart {
+		logger.Info("Applying database migrations...")
+		if err := migrateOnStart(db, logger); err != nil {
+			fatal(logger, "Failed to migrate the database", err)
+		}
+		logger.Info("Database migrations OK")
 	}
-	log.Printf("Database connection OK\n")
 
 	// Connect to the Redis store
-	log.Printf("Connecting to Redis... (url: %v)\n", config.RedisURL)
+	logger.Info("Connecting to Redis...", "url", config.RedisURL)
 	redisdb := redis.NewClient(&redis.Options{Addr: config.RedisURL})
 	_, err = redisdb.Ping().Result()
 	if err != nil {
-		panic(err)
+		fatal(logger, "Failed to connect to Redis", err)
 	}
-	log.Printf("Redis connection OK\n")
+	logger.Info("Redis connection OK")
 
 	// Connect to AWS S3
-	log.Printf("Connecting to AWS S3... (bucket: %v)\n", config.S3Bucket)
+	logger.Info("Connecting to AWS S3...", "bucket", config.S3Bucket)
 	storage, err := api.NewStorage(api.StorageConfig{
 		AccessKey:    config.S3AccessKey,
 		AccessSecret: config.S3AccessSecret,
@@ -112,14 +271,14 @@ func main() {
 		Bucket:       config.S3Bucket,
 	})
 	if err != nil {
-		panic(err)
+		fatal(logger, "Failed to connect to AWS S3", err)
 	}
 
 	// Instantiate the ShortID generator
-	log.Printf("Initialising the ShortID generator... (worker: %v; seed: %v)", 0, config.ModelIDSeed)
+	logger.Info("Initialising the ShortID generator...", "worker", 0, "seed", config.ModelIDSeed)
 	sid, err := shortid.New(0, shortid.DefaultABC, config.ModelIDSeed)
 	if err != nil {
-		panic(err)
+		fatal(logger, "Failed to initialise the ShortID generator", err)
 	}
 
 	// Create the query builder
@@ -132,15 +291,90 @@ func main() {
 		Storage:    storage,
 		ShortID:    sid,
 		SQLBuilder: &builder,
+		Logger:     logger,
 	}
 
+	// Run the requested operator command instead of the server
+	if isAdminCommand(command) {
+		if err := runAdmin(newServices(providers, newRepositories(providers), config), flag.Args()); err != nil {
+			fatal(logger, "Command failed", err)
+		}
+		return
+	}
+
+	// Expose the provider metrics
+	api.RegisterProviderMetrics(providers)
+
 	// Create the API server
 	server := newServer(*config, providers)
+	if err := mountOpenAPI(server); err != nil {
+		fatal(logger, "Failed to generate the OpenAPI document", err)
+	}
 
-	// Start the API server
+	// Stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start delivering webhooks from the queue
+	server.Go(func() {
+		server.Services.Webhook.Work(ctx)
+	})
+
+	// Start the background job workers
+	handlers := characters.JobHandlers(server)
+	for i := 0; i < config.JobWorkers; i++ {
+		server.Go(func() {
+			server.Services.Job.Work(ctx, handlers)
+		})
+	}
+	if config.ImageReconcileInterval > 0 {
+		server.Go(func() {
+			server.Services.Job.Recur(ctx, models.JobImagesReconcile, config.ImageReconcileInterval)
+		})
+	}
+
+	// Start the HTTP servers. A server that fails to serve stops the API the same way a signal does
+	failed := make(chan error, 2)
+	serve := func(name string, httpServer *http.Server) {
+		logger.Info(name+" is now listening", "address", httpServer.Addr)
+		go func() {
+			if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+				failed <- fmt.Errorf("%s failed: %w", name, err)
+			}
+		}()
+	}
 	address := fmt.Sprintf("%v:%d", config.Host, config.Port)
-	log.Printf("CharacterBase API is now listening on %v...\n", address)
-	if err := http.ListenAndServe(address, server); err != nil {
-		log.Fatalln(err)
+	httpServers := []*http.Server{api.NewHTTPServer(config, address, server)}
+	serve("CharacterBase API", httpServers[0])
+
+	// Start the admin server for the health endpoints when they are bound to their own port
+	if config.AdminPort != 0 {
+		adminAddress := fmt.Sprintf("%v:%d", config.Host, config.AdminPort)
+		admin := chi.NewMux()
+		mountOperational(admin, server, true)
+		httpServers = append(httpServers, api.NewHTTPServer(config, adminAddress, admin))
+		serve("Health and metrics endpoints", httpServers[1])
+	}
+
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutting down...")
+	case err := <-failed:
+		logger.Error("Shutting down after a server failure", "error", err)
+		exitCode = 1
+	}
+	stop()
+	if err := shutdown(server, httpServers, config.ShutdownGracePeriod()); err != nil {
+		logger.Error("Failed to shut down gracefully", "error", err)
+		exitCode = 1
+	}
+	if err := redisdb.Close(); err != nil {
+		logger.Error("Failed to close the Redis connection", "error", err)
+	}
+	if err := db.Close(); err != nil {
+		logger.Error("Failed to close the database connection", "error", err)
 	}
+	logger.Info("Shutdown complete")
+	os.Exit(exitCode)
 }
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "postgres://cbs:hunter2@db:5432/cbs?sslmode=disable", want: "postgres://cbs:xxxxx@db:5432/cbs?sslmode=disable"},
+		{raw: "postgres://localhost:5432/cbs", want: "postgres://localhost:5432/cbs"},
+		{raw: "host=db user=cbs password=hunter2", want: "[redacted]"},
+	}
+	for _, tt := range tests {
+		if got := redactURL(tt.raw); got != tt.want {
+			t.Errorf("redactURL(%q) = %q; want %q", tt.raw, got, tt.want)
+		}
+	}
+}
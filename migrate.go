@@ -0,0 +1,120 @@
+package main
+
+import (
+	"cbs/migrations"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// migrateUsage describes the arguments accepted by the migrate subcommand
+const migrateUsage = `usage: migrate <command>
+
+commands:
+  up          apply every pending migration
+  down [N]    revert the N most recently applied migrations (default 1)
+  to VERSION  apply or revert migrations until the database is at VERSION
+  status      list every migration and whether it has been applied
+  baseline VERSION
+              record migrations up to VERSION as applied without running them
+
+Databases created before migrations were tracked have no schema_migrations table, so "up" would try to
+create their existing tables again. Adopt them once with "baseline" set to the last migration their schema
+already matches, for example "baseline 5", before running "up" or enabling migrate_on_start.`
+
+// runMigrate runs the migrate subcommand against the database
+func runMigrate(db *sqlx.DB, args []string) error {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	var changed []migrations.Migration
+	switch args[0] {
+	case "up":
+		changed, err = migrator.Up()
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return errors.New("down expects a positive number of migrations")
+			}
+		}
+		changed, err = migrator.Down(n)
+	case "to":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return errors.New("to expects a version number")
+		}
+		changed, err = migrator.To(version)
+	case "status":
+		return printMigrationStatus(migrator)
+	case "baseline":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return errors.New("baseline expects a version number")
+		}
+		recorded, err := migrator.Baseline(version)
+		for _, m := range recorded {
+			fmt.Printf("Marked %d_%s as applied\n", m.Version, m.Name)
+		}
+		return err
+	default:
+		return errors.New(migrateUsage)
+	}
+	for _, m := range changed {
+		fmt.Printf("Migrated %d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		fmt.Println("Database schema is already up to date")
+	}
+	return nil
+}
+
+// printMigrationStatus writes a table of every migration and when it was applied to stdout
+func printMigrationStatus(migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+	return w.Flush()
+}
+
+// migrateOnStart applies every pending migration before the server starts
+func migrateOnStart(db *sqlx.DB, logger *slog.Logger) error {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	changed, err := migrator.Up()
+	for _, m := range changed {
+		logger.Info("Migrated", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
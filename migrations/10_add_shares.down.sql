@@ -0,0 +1 @@
+DROP TABLE shares;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE shares (
+    id text PRIMARY KEY,
+    token text UNIQUE NOT NULL,
+    universe_id text REFERENCES universes(id) ON DELETE CASCADE,
+    character_id text REFERENCES characters(id) ON DELETE CASCADE,
+    creator_id text REFERENCES users(id) ON DELETE CASCADE,
+    expires_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE INDEX share_universe_idx ON shares(universe_id);
@@ -0,0 +1 @@
+ALTER TABLE character_images DROP COLUMN size;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- Images saved before this migration are recorded with a size of 0 until images are reconciled, which sizes them
+-- from their stored files
+ALTER TABLE character_images ADD COLUMN size bigint DEFAULT 0 NOT NULL;
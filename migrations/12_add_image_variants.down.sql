@@ -0,0 +1,10 @@
+-- Only the widest variant of each image is kept, and its file is not moved back to the unversioned path
+DELETE FROM character_images a USING character_images b
+    WHERE a.character_id = b.character_id AND a.key = b.key AND a.width < b.width;
+
+ALTER TABLE character_images
+    DROP CONSTRAINT character_images_pkey,
+    DROP COLUMN width,
+    DROP COLUMN content_type,
+    DROP COLUMN path,
+    ADD PRIMARY KEY (character_id, key);
@@ -0,0 +1,13 @@
+ALTER TABLE character_images
+    ADD COLUMN width integer DEFAULT 512 NOT NULL,
+    ADD COLUMN content_type text DEFAULT 'image/jpeg' NOT NULL,
+    ADD COLUMN path text;
+
+UPDATE character_images SET path = character_id || '_' || key;
+
+ALTER TABLE character_images
+    ALTER COLUMN width DROP DEFAULT,
+    ALTER COLUMN content_type DROP DEFAULT,
+    ALTER COLUMN path SET NOT NULL,
+    DROP CONSTRAINT character_images_pkey,
+    ADD PRIMARY KEY (character_id, key, width);
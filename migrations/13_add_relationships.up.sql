@@ -0,0 +1,16 @@
+CREATE TABLE relationships (
+    id text PRIMARY KEY,
+    universe_id text NOT NULL REFERENCES universes(id) ON DELETE CASCADE,
+    source_id text NOT NULL REFERENCES characters(id) ON DELETE CASCADE,
+    target_id text NOT NULL REFERENCES characters(id) ON DELETE CASCADE,
+    type text NOT NULL,
+    bidirectional boolean DEFAULT false NOT NULL,
+    description text DEFAULT '' NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CHECK (source_id <> target_id)
+);
+
+CREATE INDEX relationship_universe_idx ON relationships(universe_id);
+CREATE INDEX relationship_source_idx ON relationships(source_id);
+CREATE INDEX relationship_target_idx ON relationships(target_id);
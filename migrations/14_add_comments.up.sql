@@ -0,0 +1,16 @@
+CREATE TABLE comments (
+    id text PRIMARY KEY,
+    universe_id text NOT NULL REFERENCES universes(id) ON DELETE CASCADE,
+    character_id text NOT NULL REFERENCES characters(id) ON DELETE CASCADE,
+    parent_id text REFERENCES comments(id) ON DELETE CASCADE,
+    author_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body text NOT NULL,
+    mentions jsonb DEFAULT '[]' NOT NULL,
+    resolved_by text REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE INDEX comment_character_idx ON comments(character_id);
+CREATE INDEX comment_parent_idx ON comments(parent_id);
@@ -0,0 +1,5 @@
+DROP TRIGGER audit_events_no_delete ON audit_events;
+DROP FUNCTION audit_events_undeletable();
+
+ALTER TABLE audit_events ADD CONSTRAINT audit_events_actor_id_fkey FOREIGN KEY (actor_id)
+    REFERENCES users(id) ON DELETE SET NULL NOT VALID;
@@ -0,0 +1,17 @@
+-- Actors are kept as plain user IDs. Clearing them when a user is deleted would update audit events, which the
+-- immutability trigger rejects, so deleting anyone who ever acted in a universe would fail
+ALTER TABLE audit_events DROP CONSTRAINT audit_events_actor_id_fkey;
+
+-- Audit events are only deleted along with their universe, through the cascade of its foreign key, which runs
+-- the trigger one level deeper than a direct DELETE
+CREATE FUNCTION audit_events_undeletable() RETURNS trigger AS $$
+BEGIN
+    IF pg_trigger_depth() > 1 THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'audit events cannot be deleted';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+    FOR EACH ROW EXECUTE PROCEDURE audit_events_undeletable();
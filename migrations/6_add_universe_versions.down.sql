@@ -0,0 +1 @@
+ALTER TABLE universes DROP COLUMN version;
\ No newline at end of file
@@ -0,0 +1,28 @@
+CREATE TABLE webhooks (
+    id text PRIMARY KEY,
+    universe_id text REFERENCES universes(id) ON DELETE CASCADE,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events jsonb NOT NULL,
+    active boolean DEFAULT true NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+    id text PRIMARY KEY,
+    webhook_id text REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id text NOT NULL,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    response_status integer DEFAULT 0 NOT NULL,
+    error text DEFAULT '' NOT NULL,
+    next_attempt_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE INDEX webhook_universe_idx ON webhooks(universe_id);
+CREATE INDEX webhook_delivery_idx ON webhook_deliveries(webhook_id, created_at);
\ No newline at end of file
@@ -0,0 +1,24 @@
+CREATE TABLE audit_events (
+    id text PRIMARY KEY,
+    universe_id text REFERENCES universes(id) ON DELETE CASCADE,
+    actor_id text REFERENCES users(id) ON DELETE SET NULL,
+    action text NOT NULL,
+    target_type text NOT NULL,
+    target_id text NOT NULL,
+    before jsonb,
+    after jsonb,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE INDEX audit_universe_idx ON audit_events(universe_id, created_at);
+CREATE INDEX audit_target_idx ON audit_events(universe_id, target_type, target_id);
+
+-- Audit events are append-only; rows only disappear along with their universe
+CREATE FUNCTION audit_events_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit events cannot be modified';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+    FOR EACH ROW EXECUTE PROCEDURE audit_events_immutable();
\ No newline at end of file
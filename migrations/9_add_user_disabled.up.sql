@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN disabled boolean DEFAULT false NOT NULL;
\ No newline at end of file
@@ -0,0 +1,278 @@
+// Package migrations embeds the numbered SQL migrations of the database schema and applies them,
+// tracking the applied versions in the schema_migrations table
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockID represents the advisory lock held while migrating so that concurrently starting servers take turns
+const lockID = 727274
+
+// filenamePattern matches migration files in the form of "<version>_<name>.<up|down>.sql"
+var filenamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration represents a single numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status represents whether a migration has been applied to the database
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// Migrator represents a runner applying the embedded migrations to a database
+type Migrator struct {
+	DB         *sqlx.DB
+	Migrations []Migration
+}
+
+// Load reads the embedded migrations, ordered by version
+func Load() ([]Migration, error) {
+	entries, err := files.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := filenamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := files.ReadFile(path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// New creates a migrator for the embedded migrations
+func New(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations}, nil
+}
+
+// Latest returns the version of the newest migration
+func (m *Migrator) Latest() int {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up() ([]Migration, error) {
+	return m.To(m.Latest())
+}
+
+// Down reverts the n most recently applied migrations
+func (m *Migrator) Down(n int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(func(conn *sqlx.Conn, applied map[int]time.Time) error {
+		for i := len(m.Migrations) - 1; i >= 0 && len(reverted) < n; i-- {
+			migration := m.Migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := revert(conn, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// To applies or reverts migrations until the database is at the given version. Migrations newer than the
+// version are reverted and pending migrations up to and including it are applied
+func (m *Migrator) To(version int) ([]Migration, error) {
+	if version < 0 || version > m.Latest() {
+		return nil, fmt.Errorf("version %d does not exist (latest: %d)", version, m.Latest())
+	}
+	var changed []Migration
+	err := m.locked(func(conn *sqlx.Conn, applied map[int]time.Time) error {
+		for i := len(m.Migrations) - 1; i >= 0; i-- {
+			migration := m.Migrations[i]
+			if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+				continue
+			}
+			if err := revert(conn, migration); err != nil {
+				return err
+			}
+			changed = append(changed, migration)
+		}
+		for _, migration := range m.Migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if err := apply(conn, migration); err != nil {
+				return err
+			}
+			changed = append(changed, migration)
+		}
+		return nil
+	})
+	return changed, err
+}
+
+// Baseline records every migration up to and including the given version as applied without running it. It adopts
+// a database whose schema was created before migrations were tracked, so that only newer migrations are applied
+func (m *Migrator) Baseline(version int) ([]Migration, error) {
+	if version < 1 || version > m.Latest() {
+		return nil, fmt.Errorf("version %d does not exist (latest: %d)", version, m.Latest())
+	}
+	var recorded []Migration
+	err := m.locked(func(conn *sqlx.Conn, applied map[int]time.Time) error {
+		for _, migration := range m.Migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if _, err := conn.ExecContext(
+				context.Background(),
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+				migration.Version,
+				migration.Name,
+			); err != nil {
+				return err
+			}
+			recorded = append(recorded, migration)
+		}
+		return nil
+	})
+	return recorded, err
+}
+
+// Status reports which of the embedded migrations have been applied
+func (m *Migrator) Status() ([]Status, error) {
+	var statuses []Status
+	err := m.locked(func(conn *sqlx.Conn, applied map[int]time.Time) error {
+		for _, migration := range m.Migrations {
+			status := Status{Migration: migration}
+			if at, ok := applied[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// locked runs fn on a single connection while holding the migration lock, passing it the applied versions
+func (m *Migrator) locked(fn func(conn *sqlx.Conn, applied map[int]time.Time) error) error {
+	ctx := context.Background()
+	conn, err := m.DB.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockID)
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version integer PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamp with time zone DEFAULT now() NOT NULL
+	)`); err != nil {
+		return err
+	}
+	rows := []struct {
+		Version   int       `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}{}
+	if err := conn.SelectContext(ctx, &rows, "SELECT version, applied_at FROM schema_migrations"); err != nil {
+		return err
+	}
+	applied := make(map[int]time.Time)
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return fn(conn, applied)
+}
+
+// apply runs a migration's up script and records its version in a single transaction
+func apply(conn *sqlx.Conn, migration Migration) error {
+	return inTx(conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(migration.Up); err != nil {
+			return fmt.Errorf("failed to apply migration %d_%s: %v", migration.Version, migration.Name, err)
+		}
+		_, err := tx.Exec(
+			"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+			migration.Version,
+			migration.Name,
+		)
+		return err
+	})
+}
+
+// revert runs a migration's down script and removes its version in a single transaction
+func revert(conn *sqlx.Conn, migration Migration) error {
+	return inTx(conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(migration.Down); err != nil {
+			return fmt.Errorf("failed to revert migration %d_%s: %v", migration.Version, migration.Name, err)
+		}
+		_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		return err
+	})
+}
+
+func inTx(conn *sqlx.Conn, fn func(tx *sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditAction represents a kind of mutating action recorded in a universe's audit log
+type AuditAction string
+
+// AuditTargetType represents the kind of resource an audited action was performed on
+type AuditTargetType string
+
+// All the available audit actions
+var (
+	AuditCharacterCreate     AuditAction = "character.create"
+	AuditCharacterUpdate     AuditAction = "character.update"
+	AuditCharacterDelete     AuditAction = "character.delete"
+	AuditCharactersDeleteAll AuditAction = "characters.delete_all"
+	AuditCharactersImport    AuditAction = "characters.import"
+	AuditImageSet            AuditAction = "character.image.set"
+	AuditImageDelete         AuditAction = "character.image.delete"
+	AuditUniverseCreate      AuditAction = "universe.create"
+	AuditUniverseUpdate      AuditAction = "universe.update"
+	AuditCollaboratorAdd     AuditAction = "collaborator.add"
+	AuditCollaboratorUpdate  AuditAction = "collaborator.update"
+	AuditCollaboratorRemove  AuditAction = "collaborator.remove"
+	AuditShareCreate         AuditAction = "share.create"
+	AuditShareDelete         AuditAction = "share.delete"
+	AuditRelationshipCreate  AuditAction = "relationship.create"
+	AuditRelationshipUpdate  AuditAction = "relationship.update"
+	AuditRelationshipDelete  AuditAction = "relationship.delete"
+)
+
+// All the available audit target types
+var (
+	AuditTargetCharacter    AuditTargetType = "character"
+	AuditTargetUniverse     AuditTargetType = "universe"
+	AuditTargetCollaborator AuditTargetType = "collaborator"
+	AuditTargetShare        AuditTargetType = "share"
+	AuditTargetRelationship AuditTargetType = "relationship"
+)
+
+// AuditSummary represents a flat summary of the state of an audited resource, keyed by property path
+type AuditSummary map[string]interface{}
+
+// AuditEvent represents a single entry in a universe's audit log
+type AuditEvent struct {
+	ID         string          `json:"id" db:"id"`
+	UniverseID string          `json:"universeId" db:"universe_id"`
+	ActorID    *string         `json:"actorId" db:"actor_id"`
+	Action     AuditAction     `json:"action" db:"action"`
+	TargetType AuditTargetType `json:"targetType" db:"target_type"`
+	TargetID   string          `json:"targetId" db:"target_id"`
+	Before     AuditSummary    `json:"before" db:"before"`
+	After      AuditSummary    `json:"after" db:"after"`
+	CreatedAt  time.Time       `json:"createdAt" db:"created_at"`
+}
+
+// AuditSummary summarizes the character's name, visibility and field values
+func (c *Character) AuditSummary() AuditSummary {
+	summary := AuditSummary{"name": c.Name, "tag": c.Tag}
+	if c.Meta != nil {
+		summary["meta.hidden"] = c.Meta.Hidden
+		summary["meta.nameHidden"] = c.Meta.NameHidden
+		if c.Meta.Name != nil {
+			summary["meta.name"] = *c.Meta.Name
+		}
+	}
+	if c.Fields != nil {
+		for gName, group := range c.Fields.Groups {
+			if group == nil {
+				continue
+			}
+			for fName, field := range group.Fields {
+				if field != nil {
+					summary[fmt.Sprintf("fields.%s.%s", gName, fName)] = field.Value
+				}
+			}
+		}
+	}
+	return summary
+}
+
+// AuditSummary summarizes the universe's details, settings and guide field definitions
+func (u *Universe) AuditSummary() AuditSummary {
+	summary := AuditSummary{"name": u.Name, "description": u.Description}
+	if u.Settings != nil {
+		summary["settings.titleField"] = u.Settings.TitleField
+		summary["settings.allowAvatars"] = u.Settings.AllowAvatars
+		summary["settings.allowLexicographicalOrdering"] = u.Settings.AllowLexicographicalOrdering
+	}
+	if u.Guide != nil && u.Guide.Groups != nil {
+		// The ordered list of fields captures additions, removals and reordering in a single entry
+		order := make([]string, 0)
+		for _, group := range *u.Guide.Groups {
+			summary[fmt.Sprintf("guide.%s.required", group.Name)] = group.Required
+			if group.Fields == nil {
+				continue
+			}
+			for _, field := range *group.Fields {
+				// Definitions are serialized straight away since decoding a request can modify their meta in place
+				key := fmt.Sprintf("%s.%s", group.Name, field.Name)
+				definition, _ := json.Marshal(field)
+				summary["guide."+key] = json.RawMessage(definition)
+				order = append(order, key)
+			}
+		}
+		summary["guide.fields"] = order
+	}
+	return summary
+}
+
+// AuditSummary summarizes the collaborator's role
+func (c *Collaborator) AuditSummary() AuditSummary {
+	return AuditSummary{"userId": c.UserID, "role": c.Role}
+}
+
+// Value serializes the audit summary, storing absent summaries as NULL
+func (as AuditSummary) Value() (driver.Value, error) {
+	if as == nil {
+		return nil, nil
+	}
+	return json.Marshal(as)
+}
+
+// Scan deserializes the serialized representation of the audit summary
+func (as *AuditSummary) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case nil:
+		*as = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, as)
+	case string:
+		return json.Unmarshal([]byte(v), as)
+	default:
+		return fmt.Errorf("Unsupported type: %T", v)
+	}
+}
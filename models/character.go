@@ -4,11 +4,83 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 )
 
-// CharacterImages represents a map of field keys to image URLs associated with the character
-type CharacterImages map[string]string
+// CharacterImage represents an image associated with a character, stored in a variant for each of several widths.
+// Variants maps each width in pixels to the URL of its variant, and URL points to the widest variant
+type CharacterImage struct {
+	URL         string            `json:"url"`
+	ContentType string            `json:"contentType"`
+	Variants    map[string]string `json:"variants"`
+}
+
+// CharacterImages represents a map of field keys to images associated with the character
+type CharacterImages map[string]CharacterImage
+
+// AddVariant adds a variant to the image under a key, pointing the image to it when it is the widest variant
+func (c CharacterImages) AddVariant(key string, width int, url string, contentType string) {
+	image, ok := c[key]
+	if !ok {
+		image = CharacterImage{Variants: make(map[string]string)}
+	}
+	if widest, _ := image.width(false); width >= widest {
+		image.URL = url
+	}
+	image.ContentType = contentType
+	image.Variants[strconv.Itoa(width)] = url
+	c[key] = image
+}
+
+// NarrowestURL returns the URL of the narrowest variant of an image, which suits lists of characters
+func (i CharacterImage) NarrowestURL() string {
+	_, url := i.width(true)
+	return url
+}
+
+// width returns the width and URL of the narrowest or widest variant of an image
+func (i CharacterImage) width(narrowest bool) (int, string) {
+	found, url := -1, ""
+	for w, u := range i.Variants {
+		n, err := strconv.Atoi(w)
+		if err != nil {
+			continue
+		}
+		if found < 0 || (narrowest && n < found) || (!narrowest && n > found) {
+			found, url = n, u
+		}
+	}
+	return found, url
+}
+
+// ImageVariant represents an encoded variant of a character image, fitting a square of Width pixels
+type ImageVariant struct {
+	Width       int
+	ContentType string
+	Data        []byte
+}
+
+// ImageRecord represents the record of a stored variant of a character image
+type ImageRecord struct {
+	CharacterID string `json:"characterId" db:"character_id"`
+	Key         string `json:"key" db:"key"`
+	Width       int    `json:"width" db:"width"`
+	Path        string `json:"path" db:"path"`
+	Size        int64  `json:"size,omitempty" db:"size"`
+}
+
+// ImageReconciliation represents the differences found between the stored image files and the records of
+// character images. Orphaned files belong to no character, missing files are recorded against a character but no
+// longer stored, unsized files were recorded before image sizes were, along with their stored size, and expired
+// uploads were never processed
+type ImageReconciliation struct {
+	OrphanedFiles  []string      `json:"orphanedFiles"`
+	MissingFiles   []ImageRecord `json:"missingFiles"`
+	UnsizedFiles   []ImageRecord `json:"unsizedFiles"`
+	ExpiredUploads []string      `json:"expiredUploads"`
+	DryRun         bool          `json:"dryRun"`
+}
 
 // Character represents a CharacterBase character
 type Character struct {
@@ -74,13 +146,6 @@ type CharacterField struct {
 	Hidden bool           `json:"hidden"`
 }
 
-// CharacterImage represents an image associated with a character
-type CharacterImage struct {
-	Character Character
-	Field     string
-	PublicURL string
-}
-
 // Value returns a serialized representation of this character meta
 func (m *CharacterMeta) Value() (driver.Value, error) {
 	return json.Marshal(m)
@@ -138,6 +203,11 @@ func (f *CharacterFields) Scan(val interface{}) error {
 	}
 }
 
+// ETag returns an entity tag identifying the current version of the character
+func (c *Character) ETag() string {
+	return fmt.Sprintf("\"%s-%d\"", c.ID, c.UpdatedAt.UnixNano())
+}
+
 // HideHiddenFields obscures values in the character's fields that are marked as hidden
 func (c *Character) HideHiddenFields() {
 	if c.Meta.NameHidden {
@@ -166,3 +236,41 @@ func (c *CharacterReference) HideHiddenFields() {
 		c.ParsedName = nil
 	}
 }
+
+// CharacterImportStatus represents the processing state of a character import
+type CharacterImportStatus string
+
+// All the available character import statuses
+var (
+	CharacterImportPending    CharacterImportStatus = "pending"
+	CharacterImportProcessing CharacterImportStatus = "processing"
+	CharacterImportCompleted  CharacterImportStatus = "completed"
+	CharacterImportFailed     CharacterImportStatus = "failed"
+)
+
+// CharacterImport represents a bulk character import and its progress
+type CharacterImport struct {
+	ID         string                 `json:"id"`
+	UniverseID string                 `json:"universeId"`
+	OwnerID    string                 `json:"ownerId"`
+	Status     CharacterImportStatus  `json:"status"`
+	Atomic     bool                   `json:"atomic"`
+	DryRun     bool                   `json:"dryRun"`
+	Async      bool                   `json:"async"`
+	Total      int                    `json:"total"`
+	Processed  int                    `json:"processed"`
+	Created    int                    `json:"created"`
+	Failed     int                    `json:"failed"`
+	Errors     []CharacterImportError `json:"errors"`
+	Preview    []*Character           `json:"preview,omitempty"`
+	CreatedAt  time.Time              `json:"createdAt"`
+	UpdatedAt  time.Time              `json:"updatedAt"`
+}
+
+// CharacterImportError represents a failure to import a single row of a character import
+type CharacterImportError struct {
+	Row     int               `json:"row"`
+	Name    string            `json:"name"`
+	Message string            `json:"message"`
+	Issues  []ValidationIssue `json:"issues,omitempty"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CommentMentions represents the IDs of the collaborators mentioned in a comment
+type CommentMentions []string
+
+// Comment represents a markdown comment on a character. Comments without a parent start a thread the other
+// comments reply to, and only threads are resolved
+type Comment struct {
+	ID          string          `json:"id" db:"id"`
+	UniverseID  string          `json:"universeId" db:"universe_id"`
+	CharacterID string          `json:"characterId" db:"character_id"`
+	ParentID    *string         `json:"parentId" db:"parent_id"`
+	Author      *User           `json:"author" db:"author"`
+	Body        string          `json:"body" db:"body"`
+	Mentions    CommentMentions `json:"mentions" db:"mentions"`
+	ResolvedBy  *string         `json:"resolvedBy" db:"resolved_by"`
+	ResolvedAt  *time.Time      `json:"resolvedAt" db:"resolved_at"`
+	CreatedAt   time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updatedAt" db:"updated_at"`
+}
+
+// CommentThread represents a comment that starts a thread along with its replies, oldest first
+type CommentThread struct {
+	Comment
+	Replies []Comment `json:"replies"`
+}
+
+// Resolved reports whether a comment has been resolved
+func (c *Comment) Resolved() bool {
+	return c.ResolvedAt != nil
+}
+
+// Value serializes the mentions of a comment
+func (cm CommentMentions) Value() (driver.Value, error) {
+	return json.Marshal(cm)
+}
+
+// Scan deserializes the serialized representation of the mentions of a comment
+func (cm *CommentMentions) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, &cm)
+	case string:
+		return json.Unmarshal([]byte(v), &cm)
+	default:
+		return fmt.Errorf("Unsupported type: %T", v)
+	}
+}
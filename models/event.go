@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// UniverseEventType represents the kind of change a universe event describes
+type UniverseEventType string
+
+// All the available universe event types
+var (
+	EventCharacterCreated    UniverseEventType = "character.created"
+	EventCharacterUpdated    UniverseEventType = "character.updated"
+	EventCharacterDeleted    UniverseEventType = "character.deleted"
+	EventCharactersImported  UniverseEventType = "characters.imported"
+	EventCharactersCleared   UniverseEventType = "characters.cleared"
+	EventGuideChanged        UniverseEventType = "guide.changed"
+	EventUniverseDeleted     UniverseEventType = "universe.deleted"
+	EventCollaboratorAdded   UniverseEventType = "collaborator.added"
+	EventCollaboratorUpdated UniverseEventType = "collaborator.updated"
+	EventCollaboratorRemoved UniverseEventType = "collaborator.removed"
+	EventCommentCreated      UniverseEventType = "comment.created"
+	EventCommentUpdated      UniverseEventType = "comment.updated"
+	EventCommentDeleted      UniverseEventType = "comment.deleted"
+	EventCommentResolved     UniverseEventType = "comment.resolved"
+	EventCommentUnresolved   UniverseEventType = "comment.unresolved"
+)
+
+// UniverseEvent represents a change made to a universe or its resources
+type UniverseEvent struct {
+	ID           string            `json:"id"`
+	Type         UniverseEventType `json:"type"`
+	UniverseID   string            `json:"universeId"`
+	ActorID      string            `json:"actorId"`
+	Character    *Character        `json:"character,omitempty"`
+	Import       *CharacterImport  `json:"import,omitempty"`
+	Universe     *Universe         `json:"universe,omitempty"`
+	Collaborator *Collaborator     `json:"collaborator,omitempty"`
+	Comment      *Comment          `json:"comment,omitempty"`
+	CreatedAt    time.Time         `json:"createdAt"`
+}
+
+// UniverseEventTypes represents every universe event type webhooks can subscribe to. Universe deletions are left
+// out since the webhooks of a universe are deleted along with it, so they only reach event streams
+var UniverseEventTypes = []UniverseEventType{
+	EventCharacterCreated,
+	EventCharacterUpdated,
+	EventCharacterDeleted,
+	EventCharactersImported,
+	EventCharactersCleared,
+	EventGuideChanged,
+	EventCollaboratorAdded,
+	EventCollaboratorUpdated,
+	EventCollaboratorRemoved,
+	EventCommentCreated,
+	EventCommentUpdated,
+	EventCommentDeleted,
+	EventCommentResolved,
+	EventCommentUnresolved,
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobType represents the kind of work a background job performs
+type JobType string
+
+// All the available job types
+var (
+	JobImageProcess     JobType = "image.process"
+	JobFilesDelete      JobType = "files.delete"
+	JobCharactersExport JobType = "characters.export"
+	JobImagesReconcile  JobType = "images.reconcile"
+)
+
+// JobStatus represents the state of a background job
+type JobStatus string
+
+// All the available job statuses. Dead jobs failed every attempt, or failed in a way retrying cannot fix, and wait
+// in the dead-letter queue until they are retried by an operator
+var (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobDead      JobStatus = "dead"
+)
+
+// Job represents a unit of work queued to run in the background, along with its progress. The payload is read
+// by the handler of the job type and is not exposed through the API
+type Job struct {
+	ID         string          `json:"id"`
+	Type       JobType         `json:"type"`
+	UniverseID string          `json:"universeId,omitempty"`
+	CreatorID  string          `json:"creatorId,omitempty"`
+	Status     JobStatus       `json:"status"`
+	Attempts   int             `json:"attempts"`
+	Error      string          `json:"error,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	HasResult  bool            `json:"hasResult"`
+	RunAt      *time.Time      `json:"runAt"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+}
+
+// JobResult represents a file produced by a background job, such as an export
+type JobResult struct {
+	ContentType string `json:"contentType"`
+	Filename    string `json:"filename"`
+	Data        []byte `json:"data"`
+}
+
+// JobFiles represents the payload of a job deleting stored files by their keys
+type JobFiles struct {
+	Keys []string `json:"keys"`
+}
+
+// NewJob creates a pending job of a universe with its payload serialized as JSON
+func NewJob(id string, jobType JobType, universeID string, creatorID string, payload interface{}) (*Job, error) {
+	serialized, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &Job{
+		ID:         id,
+		Type:       jobType,
+		UniverseID: universeID,
+		CreatorID:  creatorID,
+		Status:     JobPending,
+		Payload:    serialized,
+		RunAt:      &now,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}, nil
+}
+
+// Finished reports whether a job will not run again unless it is retried
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobDead
+}
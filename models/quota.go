@@ -0,0 +1,29 @@
+package models
+
+// QuotaUsage represents how much of a limited resource is in use, along with its limit. A limit of 0 is unlimited
+type QuotaUsage struct {
+	Used  int `json:"used"`
+	Limit int `json:"limit"`
+}
+
+// UniverseUsage represents the usage of a universe's quotas
+type UniverseUsage struct {
+	Characters    QuotaUsage `json:"characters"`
+	Collaborators QuotaUsage `json:"collaborators"`
+	GuideGroups   QuotaUsage `json:"guideGroups"`
+	GuideFields   QuotaUsage `json:"guideFields"`
+	ImageBytes    QuotaUsage `json:"imageBytes"`
+}
+
+// GuideSize returns the number of groups of the universe's guide and the number of fields across them
+func (u *Universe) GuideSize() (groups int, fields int) {
+	if u.Guide == nil || u.Guide.Groups == nil {
+		return 0, 0
+	}
+	for _, group := range *u.Guide.Groups {
+		if group.Fields != nil {
+			fields += len(*group.Fields)
+		}
+	}
+	return len(*u.Guide.Groups), fields
+}
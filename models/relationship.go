@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// Relationship represents a typed relationship between two characters of a universe, such as family or rivals.
+// Relationships point from the source to the target character unless they are bidirectional
+type Relationship struct {
+	ID            string    `json:"id" db:"id"`
+	UniverseID    string    `json:"universeId" db:"universe_id"`
+	SourceID      string    `json:"sourceId" db:"source_id"`
+	TargetID      string    `json:"targetId" db:"target_id"`
+	Type          string    `json:"type" db:"type"`
+	Bidirectional bool      `json:"bidirectional" db:"bidirectional"`
+	Description   string    `json:"description" db:"description"`
+	CreatedAt     time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// Connects reports whether a relationship is between two characters, in either direction
+func (r *Relationship) Connects(a string, b string) bool {
+	return (r.SourceID == a && r.TargetID == b) || (r.SourceID == b && r.TargetID == a)
+}
+
+// AuditSummary summarizes the characters a relationship connects and how
+func (r *Relationship) AuditSummary() AuditSummary {
+	return AuditSummary{
+		"sourceId":      r.SourceID,
+		"targetId":      r.TargetID,
+		"type":          r.Type,
+		"bidirectional": r.Bidirectional,
+		"description":   r.Description,
+	}
+}
+
+// RelationshipGraph represents the neighbourhood of a character, made of the characters it is related to within
+// a number of hops as nodes and the relationships between them as edges
+type RelationshipGraph struct {
+	Nodes []CharacterReference `json:"nodes"`
+	Edges []Relationship       `json:"edges"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Share represents a public read-only link to a universe or to a single character of it, opened through an
+// unguessable token
+type Share struct {
+	ID          string     `json:"id" db:"id"`
+	Token       string     `json:"token" db:"token"`
+	UniverseID  string     `json:"universeId" db:"universe_id"`
+	CharacterID *string    `json:"characterId" db:"character_id"`
+	CreatorID   string     `json:"creatorId" db:"creator_id"`
+	ExpiresAt   *time.Time `json:"expiresAt" db:"expires_at"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// Expired reports whether the share is past its expiry
+func (s *Share) Expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+// AuditSummary summarizes what the share exposes and until when, leaving out its token
+func (s *Share) AuditSummary() AuditSummary {
+	return AuditSummary{"characterId": s.CharacterID, "expiresAt": s.ExpiresAt}
+}
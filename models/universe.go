@@ -57,6 +57,7 @@ type Universe struct {
 	Description string            `json:"description" db:"description"`
 	Guide       *UniverseGuide    `json:"guide" db:"guide"`
 	Settings    *UniverseSettings `json:"settings" db:"settings"`
+	Version     int               `json:"version" db:"version"`
 }
 
 // UniverseReference represents a stripped version of Universe,
@@ -68,11 +69,32 @@ type UniverseReference struct {
 	Role CollaboratorRole `json:"role"`
 }
 
-// UniverseSettings represents settings for a universe
+// UniverseTemplate represents a built-in guide and settings that new universes can start from
+type UniverseTemplate struct {
+	Name        string            `json:"name"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Guide       *UniverseGuide    `json:"guide"`
+	Settings    *UniverseSettings `json:"settings"`
+}
+
+// UniverseSettings represents settings for a universe. RelationshipTypes lists the types relationships between
+// the characters of the universe can have
 type UniverseSettings struct {
-	TitleField                   string `json:"titleField" validate:"required"`
-	AllowAvatars                 bool   `json:"allowAvatars"`
-	AllowLexicographicalOrdering bool   `json:"allowLexicographicalOrdering"`
+	TitleField                   string   `json:"titleField" validate:"required"`
+	AllowAvatars                 bool     `json:"allowAvatars"`
+	AllowLexicographicalOrdering bool     `json:"allowLexicographicalOrdering"`
+	RelationshipTypes            []string `json:"relationshipTypes" validate:"unique,dive,required"`
+}
+
+// HasRelationshipType reports whether relationships between the characters of the universe can have a type
+func (us *UniverseSettings) HasRelationshipType(relationshipType string) bool {
+	for _, t := range us.RelationshipTypes {
+		if t == relationshipType {
+			return true
+		}
+	}
+	return false
 }
 
 // UniverseGuide represents a universe guide
@@ -152,6 +174,17 @@ type Collaborator struct {
 	Role       CollaboratorRole `json:"role" db:"role"`
 }
 
+// CanViewCharacter reports whether the collaborator is allowed to view a character. Hidden characters are only
+// visible to their owners, admins and the owner of the universe
+func (c *Collaborator) CanViewCharacter(hidden bool, ownerID string) bool {
+	return !hidden || c.Role != CollaboratorMember || c.UserID == ownerID
+}
+
+// ETag returns an entity tag identifying the current version of the universe
+func (u *Universe) ETag() string {
+	return fmt.Sprintf("\"%s-%d\"", u.ID, u.Version)
+}
+
 // Value returns a serialized representation of this guide
 func (ug *UniverseGuide) Value() (driver.Value, error) {
 	return json.Marshal(ug)
@@ -202,7 +235,6 @@ func (ug *UniverseGuide) SetFieldMeta() error {
 			case GuideFieldText:
 				m := UniverseGuideMetaText{}
 				err = mapstructure.Decode(f.Meta, &m)
-				fmt.Println(m)
 				(*g.Fields)[j].Meta = m
 			case GuideFieldDescription:
 				m := UniverseGuideMetaDescription{}
@@ -10,6 +10,7 @@ type User struct {
 	DisplayName  string `json:"displayName" db:"display_name"`
 	Email        string `json:"email" db:"email"`
 	PasswordHash string `json:"-" db:"password_hash"`
+	Disabled     bool   `json:"-" db:"disabled"`
 }
 
 // SetPassword sets the user's password
@@ -0,0 +1,24 @@
+package models
+
+// ValidationRule represents a machine-readable code for a failed validation rule
+type ValidationRule string
+
+// All the available validation rules
+var (
+	ValidationRuleRequired ValidationRule = "required"
+	ValidationRuleRange    ValidationRule = "range"
+	ValidationRulePattern  ValidationRule = "pattern"
+	ValidationRuleType     ValidationRule = "type"
+	ValidationRuleOptions  ValidationRule = "options"
+	ValidationRuleStep     ValidationRule = "step"
+	ValidationRuleUnknown  ValidationRule = "unknown"
+)
+
+// ValidationIssue represents a single failed validation rule, located by a dot-separated
+// path into the validated document (e.g. "fields.groups.General.fields.Biography")
+type ValidationIssue struct {
+	Path     string         `json:"path"`
+	Rule     ValidationRule `json:"rule"`
+	Expected interface{}    `json:"expected,omitempty"`
+	Message  string         `json:"message"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WebhookDeliveryStatus represents the state of a single webhook delivery
+type WebhookDeliveryStatus string
+
+// All the available webhook delivery statuses
+var (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookEvents represents the universe event types a webhook is subscribed to
+type WebhookEvents []UniverseEventType
+
+// Webhook represents a URL that receives the events of a universe. Its signing secret is only ever sent when it is
+// generated
+type Webhook struct {
+	ID         string        `json:"id" db:"id"`
+	UniverseID string        `json:"universeId" db:"universe_id"`
+	URL        string        `json:"url" db:"url"`
+	Secret     string        `json:"-" db:"secret"`
+	Events     WebhookEvents `json:"events" db:"events"`
+	Active     bool          `json:"active" db:"active"`
+	CreatedAt  time.Time     `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time     `json:"updatedAt" db:"updated_at"`
+}
+
+// WebhookDelivery represents a single universe event sent, or waiting to be sent, to a webhook
+type WebhookDelivery struct {
+	ID             string                `json:"id" db:"id"`
+	WebhookID      string                `json:"webhookId" db:"webhook_id"`
+	EventID        string                `json:"eventId" db:"event_id"`
+	EventType      UniverseEventType     `json:"eventType" db:"event_type"`
+	Payload        json.RawMessage       `json:"payload" db:"payload"`
+	Status         WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts       int                   `json:"attempts" db:"attempts"`
+	ResponseStatus int                   `json:"responseStatus" db:"response_status"`
+	Error          string                `json:"error" db:"error"`
+	NextAttemptAt  *time.Time            `json:"nextAttemptAt" db:"next_attempt_at"`
+	CreatedAt      time.Time             `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time             `json:"updatedAt" db:"updated_at"`
+}
+
+// Subscribes reports whether a webhook should receive events of the given type
+func (w *Webhook) Subscribes(eventType UniverseEventType) bool {
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Value serializes the webhook's event types
+func (we WebhookEvents) Value() (driver.Value, error) {
+	return json.Marshal(we)
+}
+
+// Scan deserializes the serialized representation of the webhook's event types
+func (we *WebhookEvents) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, &we)
+	case string:
+		return json.Unmarshal([]byte(v), &we)
+	default:
+		return fmt.Errorf("Unsupported type: %T", v)
+	}
+}
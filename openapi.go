@@ -0,0 +1,113 @@
+package main
+
+import (
+	"cbs/api"
+	"cbs/api/audit"
+	"cbs/api/auth"
+	"cbs/api/characters"
+	"cbs/api/comments"
+	"cbs/api/events"
+	"cbs/api/jobs"
+	"cbs/api/openapi"
+	"cbs/api/relationships"
+	"cbs/api/shares"
+	"cbs/api/universes"
+	"cbs/api/users"
+	"cbs/api/webhooks"
+)
+
+// apiInfo describes the API in its OpenAPI document
+var apiInfo = openapi.Info{
+	Title:       "CharacterBase API",
+	Description: "Sessions are kept in the user_session cookie set by /login",
+	Version:     "1.0",
+}
+
+// newOpenAPI generates the OpenAPI document of the auth, users, universes, characters, comments,
+// relationships, shares, jobs, events, webhooks and audit routers
+func newOpenAPI(server *api.Server) (*openapi.Document, error) {
+	return openapi.Build(
+		apiInfo,
+		openapi.Mount{Tag: "auth", Router: auth.NewRouter(server).Mux, Operations: auth.Operations},
+		openapi.Mount{
+			Prefix:     "/users",
+			Tag:        "users",
+			Router:     users.NewRouter(server).Mux,
+			Operations: users.Operations,
+		},
+		openapi.Mount{
+			Prefix:     "/universes",
+			Tag:        "universes",
+			Router:     universes.NewRouter(server).Mux,
+			Operations: universes.Operations,
+		},
+		openapi.Mount{
+			Prefix:     "/universes/{universeID}/characters",
+			Tag:        "characters",
+			Router:     characters.NewRouter(server).Mux,
+			Operations: characters.Operations,
+		},
+		openapi.Mount{
+			Prefix:     "/universes/{universeID}/characters/{characterID}/comments",
+			Tag:        "comments",
+			Router:     comments.NewRouter(server).Mux,
+			Operations: comments.Operations,
+		},
+		openapi.Mount{
+			Prefix:     "/universes/{universeID}/relationships",
+			Tag:        "relationships",
+			Router:     relationships.NewRouter(server).Mux,
+			Operations: relationships.Operations,
+		},
+		openapi.Mount{
+			Prefix:     "/universes/{universeID}/shares",
+			Tag:        "shares",
+			Router:     shares.NewRouter(server).Mux,
+			Operations: shares.Operations,
+		},
+		openapi.Mount{
+			Prefix:     "/shared/{token}",
+			Tag:        "shares",
+			Router:     shares.NewPublicRouter(server).Mux,
+			Operations: shares.PublicOperations,
+		},
+		openapi.Mount{
+			Prefix:     "/universes/{universeID}/jobs",
+			Tag:        "jobs",
+			Router:     jobs.NewRouter(server).Mux,
+			Operations: jobs.Operations,
+		},
+		openapi.Mount{
+			Prefix:     "/universes/{universeID}/events",
+			Tag:        "events",
+			Router:     events.NewRouter(server).Mux,
+			Operations: events.Operations,
+		},
+		openapi.Mount{
+			Prefix:     "/universes/{universeID}/webhooks",
+			Tag:        "webhooks",
+			Router:     webhooks.NewRouter(server).Mux,
+			Operations: webhooks.Operations,
+		},
+		openapi.Mount{
+			Prefix:     "/universes/{universeID}/audit",
+			Tag:        "audit",
+			Router:     audit.NewRouter(server).Mux,
+			Operations: audit.Operations,
+		},
+	)
+}
+
+// mountOpenAPI serves the OpenAPI document at /openapi.json
+func mountOpenAPI(server *api.Server) error {
+	doc, err := newOpenAPI(server)
+	if err != nil {
+		return err
+	}
+	handler, err := openapi.Handler(doc)
+	if err != nil {
+		return err
+	}
+	server.Handle("/openapi.json", handler)
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"cbs/api"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+)
+
+var updateOpenAPI = flag.Bool("update", false, "Rewrite openapi.json from the routes and DTOs")
+
+// TestOpenAPIDocument fails when a route or DTO changes without openapi.json being regenerated with
+// "go test -run TestOpenAPIDocument -update ."
+func TestOpenAPIDocument(t *testing.T) {
+	server := api.NewServer(api.Config{}, &api.Providers{}, &api.Services{})
+	doc, err := newOpenAPI(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+	if *updateOpenAPI {
+		if err := os.WriteFile("openapi.json", got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile("openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("openapi.json is out of date with the routes and DTOs; regenerate it with " +
+			"\"go test -run TestOpenAPIDocument -update .\"")
+	}
+}
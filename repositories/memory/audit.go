@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"cbs/dtos"
+	"cbs/models"
+	"sort"
+)
+
+// Audits represents a repository of the audit logs of universes stored in memory
+type Audits struct {
+	*store
+}
+
+// copyAuditEvent copies an audit event through the serialization of its summaries
+func copyAuditEvent(event models.AuditEvent) (*models.AuditEvent, error) {
+	c := event
+	c.Before, c.After = nil, nil
+	if event.ActorID != nil {
+		actorID := *event.ActorID
+		c.ActorID = &actorID
+	}
+	if err := roundTrip(event.Before, &c.Before); err != nil {
+		return nil, err
+	}
+	if err := roundTrip(event.After, &c.After); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create stores an audit event
+func (r *Audits) Create(event *models.AuditEvent) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, exists := r.auditEvents[event.ID]; exists {
+		return errConstraint
+	}
+	if _, ok := r.universes[event.UniverseID]; !ok {
+		return errConstraint
+	}
+	event.CreatedAt = now()
+	stored, err := copyAuditEvent(*event)
+	if err != nil {
+		return err
+	}
+	r.auditEvents[event.ID] = *stored
+	return nil
+}
+
+// matchesAudit reports whether an audit event matches the filters of a query
+func matchesAudit(event models.AuditEvent, query dtos.AuditQuery) bool {
+	switch {
+	case query.ActorID != "" && (event.ActorID == nil || *event.ActorID != query.ActorID):
+		return false
+	case query.Action != "" && event.Action != query.Action:
+		return false
+	case query.TargetType != "" && event.TargetType != query.TargetType:
+		return false
+	case query.TargetID != "" && event.TargetID != query.TargetID:
+		return false
+	case query.Since != nil && event.CreatedAt.Before(*query.Since):
+		return false
+	case query.Until != nil && !event.CreatedAt.Before(*query.Until):
+		return false
+	}
+	return true
+}
+
+// FindByUniverse returns up to limit of the audit events of a universe matching a query, most recent first and
+// skipping the pages before the query's, along with the number of events matching the query
+func (r *Audits) FindByUniverse(
+	universeID string,
+	query dtos.AuditQuery,
+	limit int,
+) ([]models.AuditEvent, int, error) {
+	r.Lock()
+	defer r.Unlock()
+	matched := make([]models.AuditEvent, 0)
+	for _, event := range r.auditEvents {
+		if event.UniverseID == universeID && matchesAudit(event, query) {
+			matched = append(matched, event)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].ID < matched[j].ID
+		}
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+	events := make([]models.AuditEvent, 0)
+	for i := query.Page * limit; i < len(matched) && len(events) < limit; i++ {
+		event, err := copyAuditEvent(matched[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, *event)
+	}
+	return events, len(matched), nil
+}
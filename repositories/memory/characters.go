@@ -0,0 +1,365 @@
+package memory
+
+import (
+	"cbs/dtos"
+	"cbs/models"
+	"cbs/repositories"
+	"sort"
+	"strings"
+)
+
+// Characters represents a repository of characters stored in memory
+type Characters struct {
+	*store
+}
+
+// copyCharacter copies a character along with its fields and meta, leaving out its owner and images
+func copyCharacter(character models.Character) (models.Character, error) {
+	c := character
+	c.Owner = nil
+	c.Universe = nil
+	c.Images = nil
+	if character.Fields != nil {
+		c.Fields = &models.CharacterFields{}
+		if err := roundTrip(character.Fields, c.Fields); err != nil {
+			return c, err
+		}
+	}
+	if character.Meta != nil {
+		c.Meta = &models.CharacterMeta{}
+		if err := roundTrip(character.Meta, c.Meta); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// matchSearch reports whether a name matches a search query the way ILIKE matches it, with spaces in the query
+// matching any number of characters
+func matchSearch(name string, query string) bool {
+	name = strings.ToLower(name)
+	for _, part := range strings.Split(strings.ToLower(query), " ") {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return true
+}
+
+// visible reports whether a character is listed to the querying collaborator
+func visible(character models.Character, ctx dtos.CharacterQuery) bool {
+	if !character.Meta.Hidden {
+		return true
+	}
+	return ctx.IncludeHidden && (ctx.Collaborator.Role != models.CollaboratorMember ||
+		character.OwnerID == ctx.Collaborator.UserID)
+}
+
+// metaName returns the name of a character's meta, which may be unset
+func metaName(character models.Character) models.CharacterMetaName {
+	if character.Meta.Name == nil {
+		return models.CharacterMetaName{}
+	}
+	return *character.Meta.Name
+}
+
+// less reports whether a character sorts before another, placing characters with hidden names last
+func less(a models.Character, b models.Character, sortOrder dtos.CharacterQuerySort) bool {
+	if sortOrder != dtos.CharacterQuerySortLexicographical {
+		if a.Meta.NameHidden != b.Meta.NameHidden {
+			return b.Meta.NameHidden
+		}
+		return a.Name < b.Name
+	}
+
+	// Characters without a full name sort last, then by preferred or last name, last name and first name
+	an, bn := metaName(a), metaName(b)
+	aLast := an.LastName == "" || an.FirstName == "" || a.Meta.NameHidden
+	bLast := bn.LastName == "" || bn.FirstName == "" || b.Meta.NameHidden
+	if aLast != bLast {
+		return bLast
+	}
+	key := func(n models.CharacterMetaName) []string {
+		preferred := n.LastName
+		if n.PreferredName != "" {
+			preferred = n.PreferredName
+		}
+		return []string{preferred, n.LastName, n.FirstName}
+	}
+	ak, bk := key(an), key(bn)
+	for i := range ak {
+		if ak[i] != bk[i] {
+			return ak[i] < bk[i]
+		}
+	}
+	return false
+}
+
+// query returns the stored characters of a universe matching a query in their sorting order
+func (r *Characters) query(universeID string, ctx dtos.CharacterQuery) []models.Character {
+	characters := make([]models.Character, 0)
+	for _, character := range r.characters {
+		if character.UniverseID == universeID && matchSearch(character.Name, ctx.Query) && visible(character, ctx) {
+			characters = append(characters, character)
+		}
+	}
+	sort.SliceStable(characters, func(i, j int) bool {
+		return less(characters[i], characters[j], ctx.Sort)
+	})
+	return characters
+}
+
+// FindByID returns a character by their ID along with their owner
+func (r *Characters) FindByID(id string) (*models.Character, error) {
+	r.Lock()
+	defer r.Unlock()
+	character, ok := r.characters[id]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	c, err := copyCharacter(character)
+	if err != nil {
+		return nil, err
+	}
+	c.Owner = public(r.users[c.OwnerID])
+	c.OwnerID = ""
+	return &c, nil
+}
+
+// FindByUniverse returns a page of references to the characters of a universe matching the query, along with
+// the number of matching characters
+func (r *Characters) FindByUniverse(
+	universeID string,
+	ctx dtos.CharacterQuery,
+	limit int,
+) ([]models.CharacterReference, int, error) {
+	r.Lock()
+	defer r.Unlock()
+	characters := r.query(universeID, ctx)
+	references := make([]models.CharacterReference, 0)
+	for i := ctx.Page * limit; i < len(characters) && i < (ctx.Page+1)*limit; i++ {
+		references = append(references, r.reference(characters[i]))
+	}
+	return references, len(characters), nil
+}
+
+// reference returns a reference to a character, pointing to the narrowest variant of its avatar. The store must
+// be locked
+func (r *Characters) reference(c models.Character) models.CharacterReference {
+	reference := models.CharacterReference{
+		ID:         c.ID,
+		Name:       c.Name,
+		Tag:        c.Tag,
+		OwnerID:    c.OwnerID,
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
+		Hidden:     c.Meta.Hidden,
+		NameHidden: c.Meta.NameHidden,
+	}
+	if avatar, ok := r.images[c.ID]["avatar"]; ok {
+		url := avatar.NarrowestURL()
+		reference.AvatarURL = &url
+	}
+	if c.Meta.Name != nil {
+		name := *c.Meta.Name
+		reference.ParsedName = &name
+	}
+	return reference
+}
+
+// FindReferences returns references to the characters of a universe among the given IDs, ordered by name
+func (r *Characters) FindReferences(universeID string, ids []string) ([]models.CharacterReference, error) {
+	r.Lock()
+	defer r.Unlock()
+	references := make([]models.CharacterReference, 0)
+	for _, id := range ids {
+		if character, ok := r.characters[id]; ok && character.UniverseID == universeID {
+			references = append(references, r.reference(character))
+		}
+	}
+	sort.SliceStable(references, func(i, j int) bool {
+		return references[i].Name < references[j].Name
+	})
+	return references, nil
+}
+
+// FindAllByUniverse returns every character of a universe matching the query
+func (r *Characters) FindAllByUniverse(universeID string, ctx dtos.CharacterQuery) ([]models.Character, error) {
+	r.Lock()
+	defer r.Unlock()
+	characters := r.query(universeID, ctx)
+	for i, character := range characters {
+		c, err := copyCharacter(character)
+		if err != nil {
+			return nil, err
+		}
+		characters[i] = c
+	}
+	return characters, nil
+}
+
+// FindIDsByUniverse returns the IDs of every character of a universe
+func (r *Characters) FindIDsByUniverse(universeID string) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+	ids := make([]string, 0)
+	for id, character := range r.characters {
+		if character.UniverseID == universeID {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// CountByUniverse returns the number of characters of a universe
+func (r *Characters) CountByUniverse(universeID string) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+	count := 0
+	for _, character := range r.characters {
+		if character.UniverseID == universeID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// conflicts reports whether a character would break the uniqueness of names and tags in a universe
+func (r *Characters) conflicts(character models.Character, pending []models.Character) bool {
+	for _, existing := range r.characters {
+		if existing.ID != character.ID && existing.UniverseID == character.UniverseID &&
+			existing.Name == character.Name && existing.Tag == character.Tag {
+			return true
+		}
+	}
+	for _, p := range pending {
+		if p.ID == character.ID || (p.Name == character.Name && p.Tag == character.Tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Create stores characters all at once, within a limit on the characters of their universe
+func (r *Characters) Create(
+	universeID string,
+	ownerID string,
+	limit int,
+	characters ...*models.Character,
+) ([]models.Character, error) {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.universes[universeID]; !ok {
+		return nil, &repositories.BatchError{Err: errConstraint}
+	}
+	if _, ok := r.users[ownerID]; !ok {
+		return nil, &repositories.BatchError{Err: errConstraint}
+	}
+	if limit > 0 {
+		used := 0
+		for _, c := range r.characters {
+			if c.UniverseID == universeID {
+				used++
+			}
+		}
+		if used+len(characters) > limit {
+			return nil, repositories.ErrQuota
+		}
+	}
+	created := make([]models.Character, 0, len(characters))
+	for i, character := range characters {
+		c, err := copyCharacter(*character)
+		if err != nil {
+			return nil, &repositories.BatchError{Index: i, Err: err}
+		}
+		c.UniverseID = universeID
+		c.OwnerID = ownerID
+		c.CreatedAt = now()
+		c.UpdatedAt = c.CreatedAt
+		if _, exists := r.characters[c.ID]; exists || r.conflicts(c, created) {
+			return nil, &repositories.BatchError{Index: i, Err: errConstraint}
+		}
+		created = append(created, c)
+	}
+	for _, c := range created {
+		r.characters[c.ID] = c
+	}
+	for i, c := range created {
+		copied, err := copyCharacter(c)
+		if err != nil {
+			return nil, err
+		}
+		created[i] = copied
+	}
+	return created, nil
+}
+
+// Update updates a character if it was not modified since it was retrieved
+func (r *Characters) Update(character *models.Character) (*models.Character, error) {
+	r.Lock()
+	defer r.Unlock()
+	stored, ok := r.characters[character.ID]
+	if !ok || !stored.UpdatedAt.Equal(character.UpdatedAt) {
+		return nil, repositories.ErrStale
+	}
+	c, err := copyCharacter(*character)
+	if err != nil {
+		return nil, err
+	}
+	c.UniverseID = stored.UniverseID
+	c.OwnerID = stored.OwnerID
+	c.CreatedAt = stored.CreatedAt
+	c.UpdatedAt = now()
+	if r.conflicts(c, nil) {
+		return nil, errConstraint
+	}
+	r.characters[c.ID] = c
+	updated, err := copyCharacter(c)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Delete removes a character along with their images, share links, relationships and comments
+func (r *Characters) Delete(id string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.characters, id)
+	delete(r.images, id)
+	r.deleteShares(func(share models.Share) bool {
+		return share.CharacterID != nil && *share.CharacterID == id
+	})
+	r.deleteRelationships(func(relationship models.Relationship) bool {
+		return relationship.SourceID == id || relationship.TargetID == id
+	})
+	r.deleteComments(func(comment models.Comment) bool {
+		return comment.CharacterID == id
+	})
+	return nil
+}
+
+// DeleteByUniverse removes every character of a universe along with their images, share links, relationships and
+// comments
+func (r *Characters) DeleteByUniverse(universeID string) error {
+	r.Lock()
+	defer r.Unlock()
+	for id, character := range r.characters {
+		if character.UniverseID == universeID {
+			delete(r.characters, id)
+			delete(r.images, id)
+		}
+	}
+	r.deleteShares(func(share models.Share) bool {
+		return share.UniverseID == universeID && share.CharacterID != nil
+	})
+	r.deleteRelationships(func(relationship models.Relationship) bool {
+		return relationship.UniverseID == universeID
+	})
+	r.deleteComments(func(comment models.Comment) bool {
+		return comment.UniverseID == universeID
+	})
+	return nil
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+)
+
+// Collaborators represents a repository of universe collaborators stored in memory
+type Collaborators struct {
+	*store
+}
+
+// FindByID returns the collaborator relation between a universe and a user
+func (r *Collaborators) FindByID(universeID string, userID string) (*models.Collaborator, error) {
+	r.Lock()
+	defer r.Unlock()
+	role, ok := r.collaborators[universeID][userID]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return &models.Collaborator{UniverseID: universeID, UserID: userID, Role: role}, nil
+}
+
+// FindByUniverse returns the collaborators of a universe along with their users
+func (r *Collaborators) FindByUniverse(universeID string) ([]models.Collaborator, error) {
+	r.Lock()
+	defer r.Unlock()
+	collaborators := make([]models.Collaborator, 0)
+	for userID, role := range r.collaborators[universeID] {
+		collaborators = append(collaborators, models.Collaborator{User: public(r.users[userID]), Role: role})
+	}
+	return collaborators, nil
+}
+
+// Create stores a collaborator
+func (r *Collaborators) Create(collaborator *models.Collaborator) error {
+	r.Lock()
+	defer r.Unlock()
+	collaborators, ok := r.collaborators[collaborator.UniverseID]
+	if _, exists := collaborators[collaborator.UserID]; !ok || exists {
+		return errConstraint
+	}
+	if _, ok := r.users[collaborator.UserID]; !ok {
+		return errConstraint
+	}
+	collaborators[collaborator.UserID] = collaborator.Role
+	return nil
+}
+
+// Update updates the role of an existing collaborator
+func (r *Collaborators) Update(collaborator *models.Collaborator) error {
+	r.Lock()
+	defer r.Unlock()
+	collaborators := r.collaborators[collaborator.UniverseID]
+	if _, ok := collaborators[collaborator.UserID]; !ok {
+		return repositories.ErrNotFound
+	}
+	collaborators[collaborator.UserID] = collaborator.Role
+	return nil
+}
+
+// Delete removes a collaborator from a universe
+func (r *Collaborators) Delete(universeID string, userID string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.collaborators[universeID], userID)
+	return nil
+}
+
+// TransferOwnership makes a user the owner of a universe, demoting the previous owner to an admin
+func (r *Collaborators) TransferOwnership(universeID string, userID string) error {
+	r.Lock()
+	defer r.Unlock()
+	collaborators, ok := r.collaborators[universeID]
+	if _, exists := r.users[userID]; !ok || !exists {
+		return errConstraint
+	}
+	for id, role := range collaborators {
+		if role == models.CollaboratorOwner {
+			collaborators[id] = models.CollaboratorAdmin
+		}
+	}
+	collaborators[userID] = models.CollaboratorOwner
+	return nil
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"sort"
+)
+
+// Comments represents a repository of comments on characters stored in memory
+type Comments struct {
+	*store
+}
+
+// copyComment copies a stored comment along with its mentions and resolution, attaching its author. The store
+// must be locked
+func (s *store) copyComment(comment models.Comment) *models.Comment {
+	c := comment
+	c.Author = public(s.users[comment.Author.ID])
+	c.Mentions = append(models.CommentMentions{}, comment.Mentions...)
+	if comment.ParentID != nil {
+		parentID := *comment.ParentID
+		c.ParentID = &parentID
+	}
+	if comment.ResolvedBy != nil {
+		resolvedBy := *comment.ResolvedBy
+		c.ResolvedBy = &resolvedBy
+	}
+	if comment.ResolvedAt != nil {
+		resolvedAt := *comment.ResolvedAt
+		c.ResolvedAt = &resolvedAt
+	}
+	return &c
+}
+
+// FindByID returns a comment on a character by its ID
+func (r *Comments) FindByID(characterID string, id string) (*models.Comment, error) {
+	r.Lock()
+	defer r.Unlock()
+	comment, ok := r.comments[id]
+	if !ok || comment.CharacterID != characterID {
+		return nil, repositories.ErrNotFound
+	}
+	return r.copyComment(comment), nil
+}
+
+// FindByCharacter returns the comments on a character along with their authors, ordered by creation
+func (r *Comments) FindByCharacter(characterID string) ([]models.Comment, error) {
+	r.Lock()
+	defer r.Unlock()
+	comments := make([]models.Comment, 0)
+	for _, comment := range r.comments {
+		if comment.CharacterID == characterID {
+			comments = append(comments, *r.copyComment(comment))
+		}
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		if comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].ID < comments[j].ID
+		}
+		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+	})
+	return comments, nil
+}
+
+// Create stores a comment
+func (r *Comments) Create(comment *models.Comment) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, exists := r.comments[comment.ID]; exists {
+		return errConstraint
+	}
+	if _, ok := r.universes[comment.UniverseID]; !ok {
+		return errConstraint
+	}
+	if _, ok := r.characters[comment.CharacterID]; !ok {
+		return errConstraint
+	}
+	if _, ok := r.users[comment.Author.ID]; !ok {
+		return errConstraint
+	}
+	if comment.ParentID != nil {
+		if _, ok := r.comments[*comment.ParentID]; !ok {
+			return errConstraint
+		}
+	}
+	comment.CreatedAt = now()
+	comment.UpdatedAt = comment.CreatedAt
+	r.comments[comment.ID] = *r.copyComment(*comment)
+	*comment = *r.copyComment(*comment)
+	return nil
+}
+
+// Update saves the body and mentions of a comment and stamps it
+func (r *Comments) Update(comment *models.Comment) error {
+	r.Lock()
+	defer r.Unlock()
+	stored, ok := r.comments[comment.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	stored.Body = comment.Body
+	stored.Mentions = append(models.CommentMentions{}, comment.Mentions...)
+	stored.UpdatedAt = now()
+	r.comments[comment.ID] = stored
+	*comment = *r.copyComment(stored)
+	return nil
+}
+
+// Resolve saves who resolved a comment and when, clearing both when it is reopened
+func (r *Comments) Resolve(comment *models.Comment) error {
+	r.Lock()
+	defer r.Unlock()
+	stored, ok := r.comments[comment.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	resolved := r.copyComment(*comment)
+	stored.ResolvedBy = resolved.ResolvedBy
+	stored.ResolvedAt = resolved.ResolvedAt
+	r.comments[comment.ID] = stored
+	*comment = *r.copyComment(stored)
+	return nil
+}
+
+// Delete removes a comment along with its replies
+func (r *Comments) Delete(id string) error {
+	r.Lock()
+	defer r.Unlock()
+	r.deleteComments(func(comment models.Comment) bool {
+		return comment.ID == id || (comment.ParentID != nil && *comment.ParentID == id)
+	})
+	return nil
+}
+
+// deleteComments removes the comments matching a predicate. The store must be locked
+func (s *store) deleteComments(match func(comment models.Comment) bool) {
+	for id, comment := range s.comments {
+		if match(comment) {
+			delete(s.comments, id)
+		}
+	}
+}
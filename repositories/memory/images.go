@@ -0,0 +1,280 @@
+package memory
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Images represents a repository of character images stored in memory. Files are discarded, and only
+// their keys, sizes and modification times are kept, except for uploads waiting to be processed
+type Images struct {
+	*store
+}
+
+// imagePath returns the storage key of a variant of a character image
+func imagePath(characterID string, key string, width int) string {
+	return fmt.Sprintf("%s%s_%s_%d", repositories.ImagePrefix, characterID, key, width)
+}
+
+// variantPaths returns the storage keys of every variant of a character image
+func (r *Images) variantPaths(characterID string, key string) []string {
+	paths := make([]string, 0)
+	for width := range r.images[characterID][key].Variants {
+		n, _ := strconv.Atoi(width)
+		paths = append(paths, imagePath(characterID, key, n))
+	}
+	return paths
+}
+
+// Save records the variants of an image against a character along with their sizes
+func (r *Images) Save(characterID string, key string, variants []models.ImageVariant) error {
+	r.Lock()
+	defer r.Unlock()
+	for _, variant := range variants {
+		r.files[imagePath(characterID, key, variant.Width)] = time.Now()
+	}
+	if _, ok := r.characters[characterID]; !ok {
+		return errConstraint
+	}
+	for _, path := range r.variantPaths(characterID, key) {
+		delete(r.imageSizes, path)
+	}
+	if _, ok := r.images[characterID]; !ok {
+		r.images[characterID] = make(models.CharacterImages)
+	}
+	delete(r.images[characterID], key)
+	for _, variant := range variants {
+		path := imagePath(characterID, key, variant.Width)
+		r.imageSizes[path] = int64(len(variant.Data))
+		r.images[characterID].AddVariant(key, variant.Width, "memory://"+path, variant.ContentType)
+	}
+	return nil
+}
+
+// Delete removes the files of every variant of an image and their records
+func (r *Images) Delete(characterID string, key string) error {
+	r.Lock()
+	defer r.Unlock()
+	for _, path := range r.variantPaths(characterID, key) {
+		delete(r.files, path)
+		delete(r.imageSizes, path)
+	}
+	delete(r.images[characterID], key)
+	return nil
+}
+
+// Size returns the size in bytes of every variant of the image under a key of a character, or 0 when there is
+// none
+func (r *Images) Size(characterID string, key string) (int64, error) {
+	r.Lock()
+	defer r.Unlock()
+	var size int64
+	for _, path := range r.variantPaths(characterID, key) {
+		size += r.imageSizes[path]
+	}
+	return size, nil
+}
+
+// SizeByUniverse returns the total size in bytes of the images of every character of a universe
+func (r *Images) SizeByUniverse(universeID string) (int64, error) {
+	r.Lock()
+	defer r.Unlock()
+	var size int64
+	for id, character := range r.characters {
+		if character.UniverseID != universeID {
+			continue
+		}
+		for key := range r.images[id] {
+			for _, path := range r.variantPaths(id, key) {
+				size += r.imageSizes[path]
+			}
+		}
+	}
+	return size, nil
+}
+
+// copyImage copies an image along with its variants
+func copyImage(image models.CharacterImage) models.CharacterImage {
+	variants := make(map[string]string, len(image.Variants))
+	for width, url := range image.Variants {
+		variants[width] = url
+	}
+	image.Variants = variants
+	return image
+}
+
+// FindByCharacter returns the images of a character
+func (r *Images) FindByCharacter(characterID string) (models.CharacterImages, error) {
+	r.Lock()
+	defer r.Unlock()
+	images := make(models.CharacterImages)
+	for key, image := range r.images[characterID] {
+		images[key] = copyImage(image)
+	}
+	return images, nil
+}
+
+// FindByUniverse returns the images of every character of a universe
+func (r *Images) FindByUniverse(universeID string) (map[string]models.CharacterImages, error) {
+	r.Lock()
+	defer r.Unlock()
+	images := make(map[string]models.CharacterImages)
+	for id, character := range r.characters {
+		if character.UniverseID != universeID || len(r.images[id]) == 0 {
+			continue
+		}
+		images[id] = make(models.CharacterImages)
+		for key, image := range r.images[id] {
+			images[id][key] = copyImage(image)
+		}
+	}
+	return images, nil
+}
+
+// FindFiles returns the keys of the stored files of every image of a character
+func (r *Images) FindFiles(characterID string) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+	paths := make([]string, 0)
+	for key := range r.images[characterID] {
+		paths = append(paths, r.variantPaths(characterID, key)...)
+	}
+	return paths, nil
+}
+
+// FindFilesByUniverse returns the keys of the stored files of every image of every character of a universe
+func (r *Images) FindFilesByUniverse(universeID string) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+	paths := make([]string, 0)
+	for id, character := range r.characters {
+		if character.UniverseID != universeID {
+			continue
+		}
+		for key := range r.images[id] {
+			paths = append(paths, r.variantPaths(id, key)...)
+		}
+	}
+	return paths, nil
+}
+
+// FindOrphaned returns the keys of stored files under the image prefix older than minAge that are not recorded
+// against a character
+func (r *Images) FindOrphaned(minAge time.Duration) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+	orphaned := make([]string, 0)
+	for path, modified := range r.files {
+		if !strings.HasPrefix(path, repositories.ImagePrefix) || time.Since(modified) < minAge {
+			continue
+		}
+		inUse := false
+		for id, images := range r.images {
+			for key := range images {
+				for _, p := range r.variantPaths(id, key) {
+					inUse = inUse || p == path
+				}
+			}
+		}
+		if !inUse {
+			orphaned = append(orphaned, path)
+		}
+	}
+	return orphaned, nil
+}
+
+// DeleteFile removes a stored file by its key
+func (r *Images) DeleteFile(key string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.files, key)
+	delete(r.uploads, key)
+	return nil
+}
+
+// FindMissing returns the records of image variants whose files are no longer stored
+func (r *Images) FindMissing() ([]models.ImageRecord, error) {
+	r.Lock()
+	defer r.Unlock()
+	missing := make([]models.ImageRecord, 0)
+	for id, images := range r.images {
+		for key, image := range images {
+			for width := range image.Variants {
+				n, _ := strconv.Atoi(width)
+				path := imagePath(id, key, n)
+				if _, ok := r.files[path]; !ok {
+					missing = append(missing, models.ImageRecord{CharacterID: id, Key: key, Width: n, Path: path})
+				}
+			}
+		}
+	}
+	return missing, nil
+}
+
+// DeleteRecord removes the record of an image variant, dropping the image once it has no variants left
+func (r *Images) DeleteRecord(record models.ImageRecord) error {
+	r.Lock()
+	defer r.Unlock()
+	image, ok := r.images[record.CharacterID][record.Key]
+	if !ok {
+		return nil
+	}
+	delete(r.imageSizes, record.Path)
+	delete(r.images[record.CharacterID], record.Key)
+	for width, url := range image.Variants {
+		if n, _ := strconv.Atoi(width); n != record.Width {
+			r.images[record.CharacterID].AddVariant(record.Key, n, url, image.ContentType)
+		}
+	}
+	return nil
+}
+
+// FindUnsized returns no records, since every variant saved in memory has its size recorded
+func (r *Images) FindUnsized() ([]models.ImageRecord, error) {
+	return make([]models.ImageRecord, 0), nil
+}
+
+// SetSize records the size of an image variant
+func (r *Images) SetSize(record models.ImageRecord) error {
+	r.Lock()
+	defer r.Unlock()
+	r.imageSizes[record.Path] = record.Size
+	return nil
+}
+
+// SaveUpload stores an uploaded image under a key until it is processed
+func (r *Images) SaveUpload(key string, data []byte) error {
+	r.Lock()
+	defer r.Unlock()
+	r.files[key] = time.Now()
+	r.uploads[key] = append([]byte(nil), data...)
+	return nil
+}
+
+// FindUpload reads back an uploaded image stored under a key
+func (r *Images) FindUpload(key string) ([]byte, error) {
+	r.Lock()
+	defer r.Unlock()
+	data, ok := r.uploads[key]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// FindUploads returns the keys of stored uploads older than minAge
+func (r *Images) FindUploads(minAge time.Duration) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+	uploads := make([]string, 0)
+	for key := range r.uploads {
+		if time.Since(r.files[key]) >= minAge {
+			uploads = append(uploads, key)
+		}
+	}
+	return uploads, nil
+}
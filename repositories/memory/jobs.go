@@ -0,0 +1,179 @@
+package memory
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Jobs represents a repository of background jobs and their queues stored in memory
+type Jobs struct {
+	*store
+}
+
+// expiry returns when a record saved now with ttl expires, or the zero time when ttl is 0
+func expiry(ttl time.Duration) time.Time {
+	if ttl == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// expired reports whether a record has expired
+func (e expiring) expired() bool {
+	return !e.expires.IsZero() && time.Now().After(e.expires)
+}
+
+// Save stores a job, expiring it after ttl unless ttl is 0
+func (r *Jobs) Save(job *models.Job, ttl time.Duration) error {
+	serialized, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.jobs[job.ID] = expiring{data: serialized, expires: expiry(ttl)}
+	return nil
+}
+
+// FindByID returns a job by its ID
+func (r *Jobs) FindByID(id string) (*models.Job, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.find(id)
+}
+
+func (r *Jobs) find(id string) (*models.Job, error) {
+	stored, ok := r.jobs[id]
+	if !ok || stored.expired() {
+		return nil, repositories.ErrNotFound
+	}
+	var job models.Job
+	if err := json.Unmarshal(stored.data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Schedule queues a job to be claimed once at is reached
+func (r *Jobs) Schedule(id string, at time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+	r.jobQueue[id] = at
+	return nil
+}
+
+// Claim leases up to limit jobs due by now from the queue, earliest first
+func (r *Jobs) Claim(now time.Time, lease time.Duration, limit int) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+	ids := make([]string, 0)
+	for id, at := range r.jobQueue {
+		if !at.After(now) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return r.jobQueue[ids[i]].Before(r.jobQueue[ids[j]])
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	for _, id := range ids {
+		r.jobQueue[id] = now.Add(lease)
+	}
+	return ids, nil
+}
+
+// Renew extends the lease of a claimed job, leaving jobs that have left the queue out of it
+func (r *Jobs) Renew(id string, until time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.jobQueue[id]; ok {
+		r.jobQueue[id] = until
+	}
+	return nil
+}
+
+// Complete removes a job from the queue
+func (r *Jobs) Complete(id string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.jobQueue, id)
+	return nil
+}
+
+// Bury moves a job to the dead-letter queue
+func (r *Jobs) Bury(id string, at time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.jobQueue, id)
+	r.jobDead[id] = at
+	return nil
+}
+
+// Unbury takes a job out of the dead-letter queue
+func (r *Jobs) Unbury(id string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.jobDead, id)
+	return nil
+}
+
+// FindDead returns the jobs of the dead-letter queue, most recently buried first
+func (r *Jobs) FindDead(limit int) ([]models.Job, error) {
+	r.Lock()
+	defer r.Unlock()
+	ids := make([]string, 0, len(r.jobDead))
+	for id := range r.jobDead {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return r.jobDead[ids[i]].After(r.jobDead[ids[j]])
+	})
+	jobs := make([]models.Job, 0)
+	for _, id := range ids {
+		if len(jobs) == limit {
+			break
+		}
+		job, err := r.find(id)
+		if err == repositories.ErrNotFound {
+			delete(r.jobDead, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// SaveResult stores the file produced by a job, expiring it after ttl
+func (r *Jobs) SaveResult(id string, result *models.JobResult, ttl time.Duration) error {
+	serialized, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.jobResults[id] = expiring{data: serialized, expires: expiry(ttl)}
+	return nil
+}
+
+// FindResult returns the file produced by a job
+func (r *Jobs) FindResult(id string) (*models.JobResult, error) {
+	r.Lock()
+	defer r.Unlock()
+	stored, ok := r.jobResults[id]
+	if !ok || stored.expired() {
+		return nil, repositories.ErrNotFound
+	}
+	var result models.JobResult
+	if err := json.Unmarshal(stored.data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,115 @@
+// Package memory implements the repositories in memory so the API can be exercised without external services.
+// Records are copied in and out of the store, and constraints and cascading deletes follow the database schema
+package memory
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+)
+
+// store represents the records shared by the in-memory repositories
+type store struct {
+	sync.Mutex
+	users         map[string]models.User
+	universes     map[string]models.Universe
+	collaborators map[string]map[string]models.CollaboratorRole
+	characters    map[string]models.Character
+	images        map[string]models.CharacterImages
+	imageSizes    map[string]int64
+	files         map[string]time.Time
+	uploads       map[string][]byte
+	sessions      map[string]expiring
+	imports       map[string]expiring
+	shares        map[string]models.Share
+	jobs          map[string]expiring
+	jobQueue      map[string]time.Time
+	jobDead       map[string]time.Time
+	jobResults    map[string]expiring
+	relationships map[string]models.Relationship
+	comments      map[string]models.Comment
+	webhooks      map[string]models.Webhook
+	deliveries    map[string]models.WebhookDelivery
+	deliveryQueue map[string]time.Time
+	auditEvents   map[string]models.AuditEvent
+}
+
+// expiring represents a serialized record that expires
+type expiring struct {
+	data    []byte
+	expires time.Time
+}
+
+// New creates empty in-memory repositories
+func New() *repositories.Repositories {
+	s := &store{
+		users:         make(map[string]models.User),
+		universes:     make(map[string]models.Universe),
+		collaborators: make(map[string]map[string]models.CollaboratorRole),
+		characters:    make(map[string]models.Character),
+		images:        make(map[string]models.CharacterImages),
+		imageSizes:    make(map[string]int64),
+		files:         make(map[string]time.Time),
+		uploads:       make(map[string][]byte),
+		sessions:      make(map[string]expiring),
+		imports:       make(map[string]expiring),
+		shares:        make(map[string]models.Share),
+		jobs:          make(map[string]expiring),
+		jobQueue:      make(map[string]time.Time),
+		jobDead:       make(map[string]time.Time),
+		jobResults:    make(map[string]expiring),
+		relationships: make(map[string]models.Relationship),
+		comments:      make(map[string]models.Comment),
+		webhooks:      make(map[string]models.Webhook),
+		deliveries:    make(map[string]models.WebhookDelivery),
+		deliveryQueue: make(map[string]time.Time),
+		auditEvents:   make(map[string]models.AuditEvent),
+	}
+	return &repositories.Repositories{
+		User:         &Users{s},
+		Universe:     &Universes{s},
+		Collaborator: &Collaborators{s},
+		Character:    &Characters{s},
+		Image:        &Images{s},
+		Session:      &Sessions{s},
+		Import:       &Imports{s},
+		Share:        &Shares{s},
+		Job:          &Jobs{s},
+		Relationship: &Relationships{s},
+		Comment:      &Comments{s},
+		Webhook:      &Webhooks{s},
+		WebhookQueue: &WebhookQueue{s},
+		Audit:        &Audits{s},
+	}
+}
+
+// now returns the current time at the precision of database timestamps
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// valuer represents a column that serializes itself to the database
+type valuer interface {
+	Value() (driver.Value, error)
+}
+
+// scanner represents a column that deserializes itself from the database
+type scanner interface {
+	Scan(val interface{}) error
+}
+
+// roundTrip copies a column through its database serialization, so stored records never share memory with
+// the callers and read back the way they would from the database
+func roundTrip(src valuer, dst scanner) error {
+	value, err := src.Value()
+	if err != nil {
+		return err
+	}
+	return dst.Scan(value)
+}
+
+// errConstraint is returned when a record violates a constraint of the database schema
+var errConstraint = errors.New("memory: record violates a constraint")
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"sort"
+)
+
+// Relationships represents a repository of relationships between characters stored in memory
+type Relationships struct {
+	*store
+}
+
+// FindByID returns a relationship of a universe by its ID
+func (r *Relationships) FindByID(universeID string, id string) (*models.Relationship, error) {
+	r.Lock()
+	defer r.Unlock()
+	relationship, ok := r.relationships[id]
+	if !ok || relationship.UniverseID != universeID {
+		return nil, repositories.ErrNotFound
+	}
+	return &relationship, nil
+}
+
+// FindByCharacters returns the relationships of a universe involving any of the given characters, or every
+// relationship of the universe when none are given, ordered by creation
+func (r *Relationships) FindByCharacters(universeID string, characterIDs []string) ([]models.Relationship, error) {
+	r.Lock()
+	defer r.Unlock()
+	involved := make(map[string]bool, len(characterIDs))
+	for _, id := range characterIDs {
+		involved[id] = true
+	}
+	relationships := make([]models.Relationship, 0)
+	for _, relationship := range r.relationships {
+		if relationship.UniverseID != universeID {
+			continue
+		}
+		if len(involved) == 0 || involved[relationship.SourceID] || involved[relationship.TargetID] {
+			relationships = append(relationships, relationship)
+		}
+	}
+	sort.SliceStable(relationships, func(i, j int) bool {
+		if relationships[i].CreatedAt.Equal(relationships[j].CreatedAt) {
+			return relationships[i].ID < relationships[j].ID
+		}
+		return relationships[i].CreatedAt.Before(relationships[j].CreatedAt)
+	})
+	return relationships, nil
+}
+
+// Create stores a relationship
+func (r *Relationships) Create(relationship *models.Relationship) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, exists := r.relationships[relationship.ID]; exists {
+		return errConstraint
+	}
+	if _, ok := r.universes[relationship.UniverseID]; !ok {
+		return errConstraint
+	}
+	for _, id := range []string{relationship.SourceID, relationship.TargetID} {
+		if _, ok := r.characters[id]; !ok {
+			return errConstraint
+		}
+	}
+	if relationship.SourceID == relationship.TargetID {
+		return errConstraint
+	}
+	relationship.CreatedAt = now()
+	relationship.UpdatedAt = relationship.CreatedAt
+	r.relationships[relationship.ID] = *relationship
+	return nil
+}
+
+// Update saves the type, direction and description of a relationship and stamps it
+func (r *Relationships) Update(relationship *models.Relationship) error {
+	r.Lock()
+	defer r.Unlock()
+	stored, ok := r.relationships[relationship.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	stored.Type = relationship.Type
+	stored.Bidirectional = relationship.Bidirectional
+	stored.Description = relationship.Description
+	stored.UpdatedAt = now()
+	r.relationships[relationship.ID] = stored
+	*relationship = stored
+	return nil
+}
+
+// Delete removes a relationship
+func (r *Relationships) Delete(id string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.relationships, id)
+	return nil
+}
+
+// deleteRelationships removes the relationships matching a predicate. The store must be locked
+func (s *store) deleteRelationships(match func(relationship models.Relationship) bool) {
+	for id, relationship := range s.relationships {
+		if match(relationship) {
+			delete(s.relationships, id)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"encoding/json"
+	"time"
+)
+
+// Sessions represents a repository of user sessions stored in memory
+type Sessions struct {
+	*store
+}
+
+// Create stores a session of a user
+func (r *Sessions) Create(key string, user *models.User, ttl time.Duration) error {
+	serialized, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.sessions[key] = expiring{data: serialized, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Find returns the user of a session and refreshes its TTL
+func (r *Sessions) Find(key string, ttl time.Duration) (*models.User, error) {
+	r.Lock()
+	defer r.Unlock()
+	session, ok := r.sessions[key]
+	if !ok || time.Now().After(session.expires) {
+		delete(r.sessions, key)
+		return nil, repositories.ErrNotFound
+	}
+	session.expires = time.Now().Add(ttl)
+	r.sessions[key] = session
+	var user models.User
+	if err := json.Unmarshal(session.data, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Clear deletes every session of a user, or every session of every user when userID is empty
+func (r *Sessions) Clear(userID string) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+	cleared := 0
+	for key, session := range r.sessions {
+		var owner models.User
+		if userID != "" && (json.Unmarshal(session.data, &owner) != nil || owner.ID != userID) {
+			continue
+		}
+		delete(r.sessions, key)
+		cleared++
+	}
+	return cleared, nil
+}
+
+// Imports represents a repository of character import progress stored in memory
+type Imports struct {
+	*store
+}
+
+// Save stores the progress of a character import
+func (r *Imports) Save(job *models.CharacterImport, ttl time.Duration) error {
+	serialized, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.imports[job.UniverseID+":"+job.ID] = expiring{data: serialized, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// FindByID returns the progress of a character import of a universe by its ID
+func (r *Imports) FindByID(universeID string, id string) (*models.CharacterImport, error) {
+	r.Lock()
+	defer r.Unlock()
+	stored, ok := r.imports[universeID+":"+id]
+	if !ok || time.Now().After(stored.expires) {
+		return nil, repositories.ErrNotFound
+	}
+	var job models.CharacterImport
+	if err := json.Unmarshal(stored.data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"sort"
+)
+
+// Shares represents a repository of public share links stored in memory
+type Shares struct {
+	*store
+}
+
+// copyShare copies a share link along with its character and expiry
+func copyShare(share models.Share) *models.Share {
+	c := share
+	if share.CharacterID != nil {
+		characterID := *share.CharacterID
+		c.CharacterID = &characterID
+	}
+	if share.ExpiresAt != nil {
+		expiresAt := *share.ExpiresAt
+		c.ExpiresAt = &expiresAt
+	}
+	return &c
+}
+
+// FindByID returns a share link of a universe by its ID
+func (r *Shares) FindByID(universeID string, id string) (*models.Share, error) {
+	r.Lock()
+	defer r.Unlock()
+	share, ok := r.shares[id]
+	if !ok || share.UniverseID != universeID {
+		return nil, repositories.ErrNotFound
+	}
+	return copyShare(share), nil
+}
+
+// FindByToken returns a share link by its token
+func (r *Shares) FindByToken(token string) (*models.Share, error) {
+	r.Lock()
+	defer r.Unlock()
+	for _, share := range r.shares {
+		if share.Token == token {
+			return copyShare(share), nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+// FindByUniverse returns the share links of a universe, or only those created by a user when creatorID is set,
+// ordered by creation
+func (r *Shares) FindByUniverse(universeID string, creatorID string) ([]models.Share, error) {
+	r.Lock()
+	defer r.Unlock()
+	shares := make([]models.Share, 0)
+	for _, share := range r.shares {
+		if share.UniverseID == universeID && (creatorID == "" || share.CreatorID == creatorID) {
+			shares = append(shares, *copyShare(share))
+		}
+	}
+	sort.SliceStable(shares, func(i, j int) bool {
+		return shares[i].CreatedAt.Before(shares[j].CreatedAt)
+	})
+	return shares, nil
+}
+
+// Create stores a share link
+func (r *Shares) Create(share *models.Share) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, exists := r.shares[share.ID]; exists {
+		return errConstraint
+	}
+	if _, ok := r.universes[share.UniverseID]; !ok {
+		return errConstraint
+	}
+	if _, ok := r.users[share.CreatorID]; !ok {
+		return errConstraint
+	}
+	if share.CharacterID != nil {
+		if _, ok := r.characters[*share.CharacterID]; !ok {
+			return errConstraint
+		}
+	}
+	for _, existing := range r.shares {
+		if existing.Token == share.Token {
+			return errConstraint
+		}
+	}
+	share.CreatedAt = now()
+	r.shares[share.ID] = *copyShare(*share)
+	return nil
+}
+
+// Delete removes a share link
+func (r *Shares) Delete(id string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.shares, id)
+	return nil
+}
+
+// deleteShares removes the share links matching a predicate. The store must be locked
+func (s *store) deleteShares(match func(share models.Share) bool) {
+	for id, share := range s.shares {
+		if match(share) {
+			delete(s.shares, id)
+		}
+	}
+}
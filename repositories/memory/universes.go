@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"sort"
+)
+
+// Universes represents a repository of universes stored in memory
+type Universes struct {
+	*store
+}
+
+// copyUniverse copies a universe along with its guide and settings
+func copyUniverse(universe models.Universe) (models.Universe, error) {
+	c := universe
+	if universe.Guide != nil {
+		c.Guide = &models.UniverseGuide{}
+		if err := roundTrip(universe.Guide, c.Guide); err != nil {
+			return c, err
+		}
+	}
+	if universe.Settings != nil {
+		c.Settings = &models.UniverseSettings{}
+		if err := roundTrip(universe.Settings, c.Settings); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// FindAll returns all universes
+func (r *Universes) FindAll() ([]models.Universe, error) {
+	r.Lock()
+	defer r.Unlock()
+	universes := make([]models.Universe, 0, len(r.universes))
+	for _, universe := range r.universes {
+		c, err := copyUniverse(universe)
+		if err != nil {
+			return nil, err
+		}
+		universes = append(universes, c)
+	}
+	return universes, nil
+}
+
+// FindByID returns a universe by its ID
+func (r *Universes) FindByID(id string) (*models.Universe, error) {
+	r.Lock()
+	defer r.Unlock()
+	universe, ok := r.universes[id]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	c, err := copyUniverse(universe)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// FindByCollaborator returns references to the universes a user collaborates in, ordered by name
+func (r *Universes) FindByCollaborator(userID string) ([]models.UniverseReference, error) {
+	r.Lock()
+	defer r.Unlock()
+	universes := make([]models.UniverseReference, 0)
+	for id, collaborators := range r.collaborators {
+		if role, ok := collaborators[userID]; ok {
+			universes = append(universes, models.UniverseReference{ID: id, Name: r.universes[id].Name, Role: role})
+		}
+	}
+	sort.Slice(universes, func(i, j int) bool { return universes[i].Name < universes[j].Name })
+	return universes, nil
+}
+
+// Create stores a universe along with its owner, within a limit on the universes the owner owns
+func (r *Universes) Create(universe *models.Universe, ownerID string, limit int) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.universes[universe.ID]; ok {
+		return errConstraint
+	}
+	if _, ok := r.users[ownerID]; !ok {
+		return errConstraint
+	}
+	if limit > 0 {
+		owned := 0
+		for _, collaborators := range r.collaborators {
+			if collaborators[ownerID] == models.CollaboratorOwner {
+				owned++
+			}
+		}
+		if owned >= limit {
+			return repositories.ErrQuota
+		}
+	}
+	universe.Version = 1
+	c, err := copyUniverse(*universe)
+	if err != nil {
+		return err
+	}
+	r.universes[universe.ID] = c
+	r.collaborators[universe.ID] = map[string]models.CollaboratorRole{ownerID: models.CollaboratorOwner}
+	return nil
+}
+
+// Update updates a universe if it was not modified since it was retrieved, replacing its owner when ownerID is set
+func (r *Universes) Update(universe *models.Universe, ownerID string) error {
+	r.Lock()
+	defer r.Unlock()
+	stored, ok := r.universes[universe.ID]
+	if !ok || stored.Version != universe.Version {
+		return repositories.ErrStale
+	}
+	if ownerID != "" {
+		role, collaborating := r.collaborators[universe.ID][ownerID]
+		if _, ok := r.users[ownerID]; !ok || (collaborating && role != models.CollaboratorOwner) {
+			return errConstraint
+		}
+	}
+	c, err := copyUniverse(*universe)
+	if err != nil {
+		return err
+	}
+	c.Version++
+	r.universes[universe.ID] = c
+	universe.Version = c.Version
+	if ownerID != "" {
+		collaborators := r.collaborators[universe.ID]
+		for userID, role := range collaborators {
+			if role == models.CollaboratorOwner {
+				delete(collaborators, userID)
+			}
+		}
+		collaborators[ownerID] = models.CollaboratorOwner
+	}
+	return nil
+}
+
+// Delete removes a universe along with its collaborators, characters, share links, relationships, comments,
+// webhooks and audit log
+func (r *Universes) Delete(id string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.universes, id)
+	delete(r.collaborators, id)
+	r.deleteShares(func(share models.Share) bool {
+		return share.UniverseID == id
+	})
+	r.deleteRelationships(func(relationship models.Relationship) bool {
+		return relationship.UniverseID == id
+	})
+	r.deleteComments(func(comment models.Comment) bool {
+		return comment.UniverseID == id
+	})
+	r.deleteWebhooks(func(webhook models.Webhook) bool {
+		return webhook.UniverseID == id
+	})
+	for eventID, event := range r.auditEvents {
+		if event.UniverseID == id {
+			delete(r.auditEvents, eventID)
+		}
+	}
+	for characterID, character := range r.characters {
+		if character.UniverseID == id {
+			delete(r.characters, characterID)
+			delete(r.images, characterID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"sort"
+	"strings"
+)
+
+// Users represents a repository of users stored in memory
+type Users struct {
+	*store
+}
+
+// public strips a user of their credentials, as returned by every query except FindCredentials
+func public(user models.User) *models.User {
+	return &models.User{ID: user.ID, DisplayName: user.DisplayName, Email: user.Email}
+}
+
+// FindAll returns all users
+func (r *Users) FindAll() ([]models.User, error) {
+	r.Lock()
+	defer r.Unlock()
+	users := make([]models.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, *public(user))
+	}
+	return users, nil
+}
+
+// FindByID returns a user by their ID along with their disabled state
+func (r *Users) FindByID(id string) (*models.User, error) {
+	r.Lock()
+	defer r.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	found := public(user)
+	found.Disabled = user.Disabled
+	return found, nil
+}
+
+// findByEmail returns a stored user by their email address
+func (r *Users) findByEmail(email string) (models.User, bool) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, true
+		}
+	}
+	return models.User{}, false
+}
+
+// FindByEmail returns a user by their email address
+func (r *Users) FindByEmail(email string) (*models.User, error) {
+	r.Lock()
+	defer r.Unlock()
+	user, ok := r.findByEmail(email)
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return public(user), nil
+}
+
+// FindCredentials returns a user by their email address along with their password hash and disabled state
+func (r *Users) FindCredentials(email string) (*models.User, error) {
+	r.Lock()
+	defer r.Unlock()
+	user, ok := r.findByEmail(email)
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return &user, nil
+}
+
+// Search returns the users whose email address or display name contains the query, including disabled users
+func (r *Users) Search(query string) ([]models.User, error) {
+	r.Lock()
+	defer r.Unlock()
+	users := make([]models.User, 0)
+	query = strings.ToLower(query)
+	for _, user := range r.users {
+		if strings.Contains(strings.ToLower(user.Email), query) ||
+			strings.Contains(strings.ToLower(user.DisplayName), query) {
+			found := public(user)
+			found.Disabled = user.Disabled
+			users = append(users, *found)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return users, nil
+}
+
+// Create stores a user
+func (r *Users) Create(user *models.User) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.users[user.ID]; ok {
+		return errConstraint
+	}
+	if _, ok := r.findByEmail(user.Email); ok {
+		return errConstraint
+	}
+	user.Disabled = false
+	r.users[user.ID] = *user
+	return nil
+}
+
+// Update updates an existing user
+func (r *Users) Update(user *models.User) error {
+	r.Lock()
+	defer r.Unlock()
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	if existing, ok := r.findByEmail(user.Email); ok && existing.ID != user.ID {
+		return errConstraint
+	}
+	stored.DisplayName = user.DisplayName
+	stored.Email = user.Email
+	stored.PasswordHash = user.PasswordHash
+	r.users[user.ID] = stored
+	*user = stored
+	return nil
+}
+
+// SetDisabled prevents or allows a user from logging in
+func (r *Users) SetDisabled(id string, disabled bool) error {
+	r.Lock()
+	defer r.Unlock()
+	if user, ok := r.users[id]; ok {
+		user.Disabled = disabled
+		r.users[id] = user
+	}
+	return nil
+}
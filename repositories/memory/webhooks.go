@@ -0,0 +1,226 @@
+package memory
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Webhooks represents a repository of webhooks and their delivery logs stored in memory
+type Webhooks struct {
+	*store
+}
+
+// copyWebhook copies a webhook along with its event types
+func copyWebhook(webhook models.Webhook) *models.Webhook {
+	c := webhook
+	c.Events = append(models.WebhookEvents{}, webhook.Events...)
+	return &c
+}
+
+// copyDelivery copies a webhook delivery along with its payload and next attempt
+func copyDelivery(delivery models.WebhookDelivery) *models.WebhookDelivery {
+	c := delivery
+	c.Payload = append(json.RawMessage{}, delivery.Payload...)
+	if delivery.NextAttemptAt != nil {
+		next := *delivery.NextAttemptAt
+		c.NextAttemptAt = &next
+	}
+	return &c
+}
+
+// FindByUniverse returns the webhooks of a universe, ordered by creation
+func (r *Webhooks) FindByUniverse(universeID string) ([]models.Webhook, error) {
+	r.Lock()
+	defer r.Unlock()
+	webhooks := make([]models.Webhook, 0)
+	for _, webhook := range r.webhooks {
+		if webhook.UniverseID == universeID {
+			webhooks = append(webhooks, *copyWebhook(webhook))
+		}
+	}
+	sort.SliceStable(webhooks, func(i, j int) bool {
+		if webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].ID < webhooks[j].ID
+		}
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+	return webhooks, nil
+}
+
+// FindByID returns a webhook by its ID
+func (r *Webhooks) FindByID(id string) (*models.Webhook, error) {
+	r.Lock()
+	defer r.Unlock()
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return copyWebhook(webhook), nil
+}
+
+// Create stores a webhook
+func (r *Webhooks) Create(webhook *models.Webhook) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, exists := r.webhooks[webhook.ID]; exists {
+		return errConstraint
+	}
+	if _, ok := r.universes[webhook.UniverseID]; !ok {
+		return errConstraint
+	}
+	webhook.CreatedAt = now()
+	webhook.UpdatedAt = webhook.CreatedAt
+	r.webhooks[webhook.ID] = *copyWebhook(*webhook)
+	return nil
+}
+
+// Update saves the URL, secret, event types and state of a webhook and stamps it
+func (r *Webhooks) Update(webhook *models.Webhook) error {
+	r.Lock()
+	defer r.Unlock()
+	stored, ok := r.webhooks[webhook.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	stored.URL = webhook.URL
+	stored.Secret = webhook.Secret
+	stored.Events = append(models.WebhookEvents{}, webhook.Events...)
+	stored.Active = webhook.Active
+	stored.UpdatedAt = now()
+	r.webhooks[webhook.ID] = stored
+	*webhook = *copyWebhook(stored)
+	return nil
+}
+
+// Delete removes a webhook along with its delivery log
+func (r *Webhooks) Delete(id string) error {
+	r.Lock()
+	defer r.Unlock()
+	r.deleteWebhooks(func(webhook models.Webhook) bool {
+		return webhook.ID == id
+	})
+	return nil
+}
+
+// deleteWebhooks removes the webhooks matching a predicate along with their delivery logs. The store must be
+// locked
+func (s *store) deleteWebhooks(match func(webhook models.Webhook) bool) {
+	for id, webhook := range s.webhooks {
+		if !match(webhook) {
+			continue
+		}
+		delete(s.webhooks, id)
+		for deliveryID, delivery := range s.deliveries {
+			if delivery.WebhookID == id {
+				delete(s.deliveries, deliveryID)
+			}
+		}
+	}
+}
+
+// FindDeliveries returns up to limit of the most recent deliveries of a webhook, most recent first
+func (r *Webhooks) FindDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	r.Lock()
+	defer r.Unlock()
+	deliveries := make([]models.WebhookDelivery, 0)
+	for _, delivery := range r.deliveries {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, *copyDelivery(delivery))
+		}
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		if deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].ID > deliveries[j].ID
+		}
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// FindDeliveryByID returns a webhook delivery by its ID
+func (r *Webhooks) FindDeliveryByID(id string) (*models.WebhookDelivery, error) {
+	r.Lock()
+	defer r.Unlock()
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	return copyDelivery(delivery), nil
+}
+
+// CreateDelivery stores a webhook delivery
+func (r *Webhooks) CreateDelivery(delivery *models.WebhookDelivery) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, exists := r.deliveries[delivery.ID]; exists {
+		return errConstraint
+	}
+	if _, ok := r.webhooks[delivery.WebhookID]; !ok {
+		return errConstraint
+	}
+	delivery.CreatedAt = now()
+	delivery.UpdatedAt = delivery.CreatedAt
+	r.deliveries[delivery.ID] = *copyDelivery(*delivery)
+	return nil
+}
+
+// UpdateDelivery saves the outcome of a delivery attempt and stamps it
+func (r *Webhooks) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	r.Lock()
+	defer r.Unlock()
+	stored, ok := r.deliveries[delivery.ID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	updated := copyDelivery(*delivery)
+	stored.Status = updated.Status
+	stored.Attempts = updated.Attempts
+	stored.ResponseStatus = updated.ResponseStatus
+	stored.Error = updated.Error
+	stored.NextAttemptAt = updated.NextAttemptAt
+	stored.UpdatedAt = now()
+	r.deliveries[delivery.ID] = stored
+	*delivery = *copyDelivery(stored)
+	return nil
+}
+
+// WebhookQueue represents a queue of webhook deliveries stored in memory
+type WebhookQueue struct {
+	*store
+}
+
+// Schedule queues a delivery to be claimed once at is reached
+func (r *WebhookQueue) Schedule(id string, at time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+	r.deliveryQueue[id] = at
+	return nil
+}
+
+// Claim takes up to limit deliveries due by now out of the queue, earliest first
+func (r *WebhookQueue) Claim(now time.Time, limit int) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+	ids := make([]string, 0)
+	for id, at := range r.deliveryQueue {
+		if !at.After(now) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return r.deliveryQueue[ids[i]].Before(r.deliveryQueue[ids[j]])
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	for _, id := range ids {
+		delete(r.deliveryQueue, id)
+	}
+	return ids, nil
+}
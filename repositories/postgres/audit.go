@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"cbs/dtos"
+	"cbs/models"
+
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/Masterminds/squirrel.v1"
+)
+
+// Audits represents a repository of the audit logs of universes stored in Postgres
+type Audits struct {
+	DB      *sqlx.DB
+	Builder squirrel.StatementBuilderType
+}
+
+// Create inserts an audit event
+func (r *Audits) Create(event *models.AuditEvent) error {
+	return r.DB.Get(
+		event,
+		`INSERT INTO audit_events (id, universe_id, actor_id, action, target_type, target_id, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at`,
+		event.ID,
+		event.UniverseID,
+		event.ActorID,
+		event.Action,
+		event.TargetType,
+		event.TargetID,
+		event.Before,
+		event.After,
+	)
+}
+
+// FindByUniverse returns up to limit of the audit events of a universe matching a query, most recent first and
+// skipping the pages before the query's, along with the number of events matching the query
+func (r *Audits) FindByUniverse(
+	universeID string,
+	query dtos.AuditQuery,
+	limit int,
+) ([]models.AuditEvent, int, error) {
+	var (
+		count  = 0
+		events = make([]models.AuditEvent, 0)
+	)
+
+	gensql := filterAudit(r.Builder.Select(`id, universe_id, actor_id, action, target_type, target_id, before,
+	after, created_at`).From(`audit_events`).Where(`universe_id = ?`, universeID), query)
+	querysql, queryargs, err := gensql.OrderBy(`created_at DESC`, `id`).Limit(uint64(limit)).
+		Offset(uint64(query.Page * limit)).ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+	gensql = filterAudit(r.Builder.Select(`COUNT(*)`).From(`audit_events`).Where(`universe_id = ?`, universeID),
+		query)
+	countsql, countargs, err := gensql.ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := r.DB.Select(&events, querysql, queryargs...); err != nil {
+		return nil, 0, err
+	}
+	if err := r.DB.Get(&count, countsql, countargs...); err != nil {
+		return nil, 0, err
+	}
+	return events, count, nil
+}
+
+// filterAudit narrows an audit log query down to the filters set in the query context
+func filterAudit(gensql squirrel.SelectBuilder, query dtos.AuditQuery) squirrel.SelectBuilder {
+	if query.ActorID != "" {
+		gensql = gensql.Where(`actor_id = ?`, query.ActorID)
+	}
+	if query.Action != "" {
+		gensql = gensql.Where(`action = ?`, query.Action)
+	}
+	if query.TargetType != "" {
+		gensql = gensql.Where(`target_type = ?`, query.TargetType)
+	}
+	if query.TargetID != "" {
+		gensql = gensql.Where(`target_id = ?`, query.TargetID)
+	}
+	if query.Since != nil {
+		gensql = gensql.Where(`created_at >= ?`, *query.Since)
+	}
+	if query.Until != nil {
+		gensql = gensql.Where(`created_at < ?`, *query.Until)
+	}
+	return gensql
+}
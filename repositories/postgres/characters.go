@@ -0,0 +1,266 @@
+package postgres
+
+import (
+	"cbs/dtos"
+	"cbs/models"
+	"cbs/repositories"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"gopkg.in/Masterminds/squirrel.v1"
+)
+
+/*
+QueryFindByID represents a database query that returns
+a single character's information via their ID
+
+$1 — Character ID
+*/
+const QueryFindByID = `SELECT characters.id, characters.universe_id, characters.name,
+characters.tag, characters.fields, characters.meta, characters.created_at, characters.updated_at, users.id AS
+"owner.id", users.email AS "owner.email", users.display_name AS "owner.display_name" FROM characters JOIN users ON
+characters.owner_id = users.id WHERE characters.id = $1`
+
+// referenceColumns represents the columns selected when retrieving character references, referencing the
+// narrowest variant of each avatar
+const referenceColumns = `id, name, tag, owner_id, created_at, updated_at, (SELECT url FROM character_images WHERE
+character_id = characters.id AND key = 'avatar' ORDER BY width LIMIT 1) AS avatar_url, (meta->>'hidden')::boolean
+AS hidden, CASE WHEN meta->>'nameHidden' IS NULL THEN false ELSE (meta->>'nameHidden')::boolean END AS name_hidden,
+meta->'name' AS parsed_name`
+
+// Characters represents a repository of characters stored in Postgres
+type Characters struct {
+	DB      *sqlx.DB
+	Builder squirrel.StatementBuilderType
+}
+
+// normalizeSearch converts a character search query into an ILIKE pattern
+func normalizeSearch(query string) string {
+	if query == "" {
+		return "%"
+	}
+	return fmt.Sprintf("%%%v%%", strings.Replace(query, " ", "%", -1))
+}
+
+// filterByQuery restricts a character query to the characters the querying collaborator is allowed to list
+func filterByQuery(gensql squirrel.SelectBuilder, ctx dtos.CharacterQuery) squirrel.SelectBuilder {
+	// Factor whether all characters should be included into the query
+	if ctx.Collaborator.Role != models.CollaboratorMember {
+		// Factor whether hidden characters should be included or not
+		if !ctx.IncludeHidden {
+			gensql = gensql.Where(`(meta->>'hidden')::boolean IS FALSE`)
+		}
+	} else {
+		// Factor whether hidden characters should be included or not
+		if !ctx.IncludeHidden {
+			gensql = gensql.Where(`((meta->>'hidden')::boolean IS FALSE OR (owner_id=? AND (meta->>'hidden')::boolean
+			IS FALSE))`, ctx.Collaborator.UserID)
+		} else {
+			gensql = gensql.Where(`((meta->>'hidden')::boolean IS FALSE OR owner_id=?)`, ctx.Collaborator.UserID)
+		}
+	}
+	return gensql
+}
+
+// orderByQuery sorts a character query according to the requested sorting order
+func orderByQuery(gensql squirrel.SelectBuilder, ctx dtos.CharacterQuery) squirrel.SelectBuilder {
+	// Factor whether characters should be sorted nominally or lexicographically
+	if ctx.Sort == dtos.CharacterQuerySortLexicographical {
+		return gensql.OrderBy(`meta->'name'->>'lastName' = '' OR meta->'name'->>'firstName' = '' OR (meta->>
+		'nameHidden')::boolean IS TRUE, CASE WHEN meta->'name'->>'preferredName' != '' THEN meta->'name'->>
+		'preferredName' ELSE meta->'name'->>'lastName' END, meta->'name'->>'lastName', meta->'name'->>'firstName'`)
+	}
+	return gensql.OrderBy(`(meta->>'nameHidden')::boolean IS TRUE, name`)
+}
+
+// FindByID returns a character by their ID along with their owner
+func (r *Characters) FindByID(id string) (*models.Character, error) {
+	var character models.Character
+	if err := r.DB.Get(&character, QueryFindByID, id); err != nil {
+		return nil, err
+	}
+	return &character, nil
+}
+
+// FindByUniverse returns a page of references to the characters of a universe matching the query, along with
+// the number of matching characters
+func (r *Characters) FindByUniverse(
+	universeID string,
+	ctx dtos.CharacterQuery,
+	limit int,
+) ([]models.CharacterReference, int, error) {
+	var (
+		count      = 0
+		characters = make([]models.CharacterReference, 0)
+		query      = normalizeSearch(ctx.Query)
+	)
+
+	// Create the search query
+	gensql := r.Builder.Select(referenceColumns).From(`characters`).
+		Where(`universe_id = ? AND name ILIKE ?`, universeID, query)
+
+	// Factor whether hidden characters should be included and how they should be sorted
+	gensql = orderByQuery(filterByQuery(gensql, ctx), ctx)
+
+	// Apply the rest of the statements
+	gensql = gensql.Limit(uint64(limit)).Offset(uint64(ctx.Page * limit))
+
+	// Convert to SQL statement
+	querysql, queryargs, err := gensql.ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Create the count query, factoring whether hidden characters should be included in the count
+	gensql = filterByQuery(r.Builder.Select(`COUNT(*)`).From(`characters`).Where(
+		`universe_id = ? AND name ILIKE ?`,
+		universeID,
+		query,
+	), ctx)
+	countsql, countargs, err := gensql.ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Run the queries
+	if err := r.DB.Select(&characters, querysql, queryargs...); err != nil {
+		return nil, 0, err
+	}
+	if err := r.DB.Get(&count, countsql, countargs...); err != nil {
+		return nil, 0, err
+	}
+	return characters, count, nil
+}
+
+// FindReferences returns references to the characters of a universe among the given IDs, ordered by name
+func (r *Characters) FindReferences(universeID string, ids []string) ([]models.CharacterReference, error) {
+	characters := make([]models.CharacterReference, 0)
+	if len(ids) == 0 {
+		return characters, nil
+	}
+	err := r.DB.Select(
+		&characters,
+		`SELECT `+referenceColumns+` FROM characters WHERE universe_id = $1 AND id = ANY($2) ORDER BY name`,
+		universeID,
+		pq.Array(ids),
+	)
+	return characters, err
+}
+
+// FindAllByUniverse returns every character of a universe matching the query
+func (r *Characters) FindAllByUniverse(universeID string, ctx dtos.CharacterQuery) ([]models.Character, error) {
+	characters := make([]models.Character, 0)
+	gensql := r.Builder.Select(`id, universe_id, owner_id, name, tag, fields, meta, created_at,
+	updated_at`).From(`characters`).Where(`universe_id = ? AND name ILIKE ?`, universeID, normalizeSearch(ctx.Query))
+	querysql, queryargs, err := orderByQuery(filterByQuery(gensql, ctx), ctx).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.DB.Select(&characters, querysql, queryargs...); err != nil {
+		return nil, err
+	}
+	return characters, nil
+}
+
+// FindIDsByUniverse returns the IDs of every character of a universe
+func (r *Characters) FindIDsByUniverse(universeID string) ([]string, error) {
+	ids := make([]string, 0)
+	if err := r.DB.Select(&ids, `SELECT id FROM characters WHERE universe_id = $1`, universeID); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// CountByUniverse returns the number of characters of a universe
+func (r *Characters) CountByUniverse(universeID string) (int, error) {
+	var count int
+	err := r.DB.Get(&count, `SELECT COUNT(*) FROM characters WHERE universe_id = $1`, universeID)
+	return count, err
+}
+
+// Create inserts characters inside a single transaction, within a limit on the characters of their universe. The
+// universe is locked while its characters are counted, so that concurrent inserts cannot exceed the limit together
+func (r *Characters) Create(
+	universeID string,
+	ownerID string,
+	limit int,
+	characters ...*models.Character,
+) ([]models.Character, error) {
+	created := make([]models.Character, len(characters))
+	err := inTx(r.DB, func(tx *sqlx.Tx) error {
+		if limit > 0 {
+			if _, err := tx.Exec(`SELECT 1 FROM universes WHERE id = $1 FOR NO KEY UPDATE`, universeID); err != nil {
+				return err
+			}
+			var used int
+			if err := tx.Get(&used, `SELECT COUNT(*) FROM characters WHERE universe_id = $1`, universeID); err != nil {
+				return err
+			}
+			if used+len(characters) > limit {
+				return repositories.ErrQuota
+			}
+		}
+		for i, character := range characters {
+			if err := tx.Get(
+				&created[i],
+				`INSERT INTO characters (id, universe_id, owner_id, name, tag, fields, meta) VALUES
+				($1, $2, $3, $4, $5, $6, $7) RETURNING id, universe_id, owner_id, name, tag, fields, meta,
+				created_at, updated_at`,
+				character.ID,
+				universeID,
+				ownerID,
+				character.Name,
+				character.Tag,
+				character.Fields,
+				character.Meta,
+			); err != nil {
+				return &repositories.BatchError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Update updates a character if it was not modified since it was retrieved
+func (r *Characters) Update(character *models.Character) (*models.Character, error) {
+	var c models.Character
+	err := r.DB.Get(
+		&c,
+		`UPDATE characters SET name = $1, tag = $2, fields = $3, meta = $4, updated_at = $5 WHERE id = $6 AND
+		updated_at = $7 RETURNING id, universe_id, owner_id, name, tag, fields, meta, updated_at, created_at`,
+		character.Name,
+		character.Tag,
+		character.Fields,
+		character.Meta,
+		time.Now(),
+		character.ID,
+		character.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, repositories.ErrStale
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Delete removes a character
+func (r *Characters) Delete(id string) error {
+	_, err := r.DB.Exec(`DELETE FROM characters WHERE id = $1`, id)
+	return err
+}
+
+// DeleteByUniverse removes every character of a universe
+func (r *Characters) DeleteByUniverse(universeID string) error {
+	_, err := r.DB.Exec(`DELETE FROM characters WHERE universe_id = $1`, universeID)
+	return err
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"cbs/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Collaborators represents a repository of universe collaborators stored in Postgres
+type Collaborators struct {
+	DB *sqlx.DB
+}
+
+// FindByID returns the collaborator relation between a universe and a user
+func (r *Collaborators) FindByID(universeID string, userID string) (*models.Collaborator, error) {
+	var collaborator models.Collaborator
+	if err := r.DB.Get(
+		&collaborator,
+		"SELECT universe_id, user_id, role FROM collaborators WHERE universe_id = $1 AND user_id = $2",
+		universeID,
+		userID,
+	); err != nil {
+		return nil, err
+	}
+	return &collaborator, nil
+}
+
+// FindByUniverse returns the collaborators of a universe along with their users
+func (r *Collaborators) FindByUniverse(universeID string) ([]models.Collaborator, error) {
+	collaborators := make([]models.Collaborator, 0)
+	if err := r.DB.Select(
+		&collaborators,
+		`SELECT users.id "user.id", users.display_name "user.display_name", users.email "user.email",
+		collaborators.role FROM collaborators JOIN users ON users.id = collaborators.user_id WHERE universe_id = $1`,
+		universeID,
+	); err != nil {
+		return nil, err
+	}
+	return collaborators, nil
+}
+
+// Create inserts a collaborator
+func (r *Collaborators) Create(collaborator *models.Collaborator) error {
+	_, err := r.DB.Exec(
+		`INSERT INTO collaborators (universe_id, user_id, role) VALUES ($1, $2, $3)`,
+		collaborator.UniverseID,
+		collaborator.UserID,
+		collaborator.Role,
+	)
+	return err
+}
+
+// Update updates the role of an existing collaborator
+func (r *Collaborators) Update(collaborator *models.Collaborator) error {
+	return r.DB.Get(
+		collaborator,
+		`UPDATE collaborators SET role = $1 WHERE universe_id = $2 AND user_id = $3 RETURNING universe_id, user_id,
+		role`,
+		collaborator.Role,
+		collaborator.UniverseID,
+		collaborator.UserID,
+	)
+}
+
+// Delete removes a collaborator from a universe
+func (r *Collaborators) Delete(universeID string, userID string) error {
+	_, err := r.DB.Exec(
+		"DELETE FROM collaborators WHERE universe_id = $1 AND user_id = $2",
+		universeID,
+		userID,
+	)
+	return err
+}
+
+// TransferOwnership makes a user the owner of a universe, demoting the previous owner to an admin
+func (r *Collaborators) TransferOwnership(universeID string, userID string) error {
+	return inTx(r.DB, func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(
+			`UPDATE collaborators SET role = $1 WHERE universe_id = $2 AND role = $3`,
+			models.CollaboratorAdmin,
+			universeID,
+			models.CollaboratorOwner,
+		); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			`INSERT INTO collaborators (universe_id, user_id, role) VALUES ($1, $2, $3)
+			ON CONFLICT (universe_id, user_id) DO UPDATE SET role = excluded.role`,
+			universeID,
+			userID,
+			models.CollaboratorOwner,
+		)
+		return err
+	})
+}
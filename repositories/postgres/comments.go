@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"cbs/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// commentColumns represents the columns selected when retrieving comments along with their authors, from the
+// comments joined with the users table
+const commentColumns = `comments.id, comments.universe_id, comments.character_id, comments.parent_id, comments.body,
+	comments.mentions, comments.resolved_by, comments.resolved_at, comments.created_at, comments.updated_at,
+	users.id "author.id", users.display_name "author.display_name", users.email "author.email"`
+
+// Comments represents a repository of comments on characters stored in Postgres
+type Comments struct {
+	DB *sqlx.DB
+}
+
+// FindByID returns a comment on a character by its ID
+func (r *Comments) FindByID(characterID string, id string) (*models.Comment, error) {
+	var comment models.Comment
+	if err := r.DB.Get(
+		&comment,
+		"SELECT "+commentColumns+` FROM comments JOIN users ON users.id = comments.author_id
+		WHERE comments.character_id = $1 AND comments.id = $2`,
+		characterID,
+		id,
+	); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// FindByCharacter returns the comments on a character along with their authors, ordered by creation
+func (r *Comments) FindByCharacter(characterID string) ([]models.Comment, error) {
+	comments := make([]models.Comment, 0)
+	if err := r.DB.Select(
+		&comments,
+		"SELECT "+commentColumns+` FROM comments JOIN users ON users.id = comments.author_id
+		WHERE comments.character_id = $1 ORDER BY comments.created_at, comments.id`,
+		characterID,
+	); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// write runs a statement returning the written comment row and reads it back into comment along with its author
+func (r *Comments) write(comment *models.Comment, query string, args ...interface{}) error {
+	return r.DB.Get(
+		comment,
+		"WITH written AS ("+query+" RETURNING *) SELECT "+commentColumns+
+			" FROM written comments JOIN users ON users.id = comments.author_id",
+		args...,
+	)
+}
+
+// Create inserts a comment
+func (r *Comments) Create(comment *models.Comment) error {
+	return r.write(
+		comment,
+		`INSERT INTO comments (id, universe_id, character_id, parent_id, author_id, body, mentions) VALUES
+		($1, $2, $3, $4, $5, $6, $7)`,
+		comment.ID,
+		comment.UniverseID,
+		comment.CharacterID,
+		comment.ParentID,
+		comment.Author.ID,
+		comment.Body,
+		comment.Mentions,
+	)
+}
+
+// Update saves the body and mentions of a comment and stamps it
+func (r *Comments) Update(comment *models.Comment) error {
+	return r.write(
+		comment,
+		"UPDATE comments SET body = $2, mentions = $3, updated_at = now() WHERE id = $1",
+		comment.ID,
+		comment.Body,
+		comment.Mentions,
+	)
+}
+
+// Resolve saves who resolved a comment and when, clearing both when it is reopened
+func (r *Comments) Resolve(comment *models.Comment) error {
+	return r.write(
+		comment,
+		"UPDATE comments SET resolved_by = $2, resolved_at = $3 WHERE id = $1",
+		comment.ID,
+		comment.ResolvedBy,
+		comment.ResolvedAt,
+	)
+}
+
+// Delete removes a comment along with its replies
+func (r *Comments) Delete(id string) error {
+	_, err := r.DB.Exec("DELETE FROM comments WHERE id = $1", id)
+	return err
+}
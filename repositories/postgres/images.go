@@ -0,0 +1,329 @@
+package postgres
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/models"
+	"cbs/repositories"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Images represents a repository of character images, recorded in Postgres and stored through the API storage
+type Images struct {
+	DB      *sqlx.DB
+	Storage *api.Storage
+}
+
+// imagePath returns the storage key of a variant of a character image
+func imagePath(characterID string, key string, width int) string {
+	return fmt.Sprintf("%s%s_%s_%d", repositories.ImagePrefix, characterID, key, width)
+}
+
+// Save uploads the variants of an image and records them against a character along with their sizes. Files of
+// replaced variants stored under other keys are left for FindOrphaned
+func (r *Images) Save(characterID string, key string, variants []models.ImageVariant) error {
+	urls := make([]string, len(variants))
+	for i, variant := range variants {
+		url, err := r.Storage.Upload(
+			bytes.NewReader(variant.Data),
+			imagePath(characterID, key, variant.Width),
+			variant.ContentType,
+		)
+		if err != nil {
+			return err
+		}
+		urls[i] = url
+	}
+	return inTx(r.DB, func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(
+			`DELETE FROM character_images WHERE character_id = $1 AND key = $2`,
+			characterID,
+			key,
+		); err != nil {
+			return err
+		}
+		for i, variant := range variants {
+			if _, err := tx.Exec(
+				`INSERT INTO character_images (character_id, key, width, url, content_type, path, size) VALUES
+				($1, $2, $3, $4, $5, $6, $7)`,
+				characterID,
+				key,
+				variant.Width,
+				urls[i],
+				variant.ContentType,
+				imagePath(characterID, key, variant.Width),
+				len(variant.Data),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete removes the files of every variant of an image and their records
+func (r *Images) Delete(characterID string, key string) error {
+	var paths []string
+	if err := r.DB.Select(
+		&paths,
+		`SELECT path FROM character_images WHERE character_id = $1 AND key = $2`,
+		characterID,
+		key,
+	); err != nil {
+		return err
+	}
+	if _, err := r.DB.Exec(
+		`DELETE FROM character_images WHERE character_id = $1 AND key = $2`,
+		characterID,
+		key,
+	); err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := r.Storage.Delete(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindByCharacter returns the images of a character
+func (r *Images) FindByCharacter(characterID string) (models.CharacterImages, error) {
+	images := make(models.CharacterImages)
+	rows, err := r.DB.Queryx(
+		`SELECT key, width, url, content_type FROM character_images WHERE character_id = $1`,
+		characterID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			key, url, contentType string
+			width                 int
+		)
+		if err := rows.Scan(&key, &width, &url, &contentType); err != nil {
+			return nil, err
+		}
+		images.AddVariant(key, width, url, contentType)
+	}
+	return images, rows.Err()
+}
+
+// Size returns the size in bytes of every variant of the image under a key of a character, or 0 when there is none
+func (r *Images) Size(characterID string, key string) (int64, error) {
+	var size int64
+	err := r.DB.Get(
+		&size,
+		`SELECT COALESCE(SUM(size), 0) FROM character_images WHERE character_id = $1 AND key = $2`,
+		characterID,
+		key,
+	)
+	return size, err
+}
+
+// SizeByUniverse returns the total size in bytes of the images of every character of a universe
+func (r *Images) SizeByUniverse(universeID string) (int64, error) {
+	var size int64
+	err := r.DB.Get(
+		&size,
+		`SELECT COALESCE(SUM(size), 0) FROM character_images JOIN characters ON characters.id =
+		character_images.character_id WHERE characters.universe_id = $1`,
+		universeID,
+	)
+	return size, err
+}
+
+// FindByUniverse returns the images of every character of a universe at once rather than per character
+func (r *Images) FindByUniverse(universeID string) (map[string]models.CharacterImages, error) {
+	images := make(map[string]models.CharacterImages)
+	rows, err := r.DB.Queryx(
+		`SELECT character_id, key, width, url, content_type FROM character_images JOIN characters ON
+		characters.id = character_images.character_id WHERE characters.universe_id = $1`,
+		universeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id, key, url, contentType string
+			width                     int
+		)
+		if err := rows.Scan(&id, &key, &width, &url, &contentType); err != nil {
+			return nil, err
+		}
+		if _, ok := images[id]; !ok {
+			images[id] = make(models.CharacterImages)
+		}
+		images[id].AddVariant(key, width, url, contentType)
+	}
+	return images, rows.Err()
+}
+
+// FindFiles returns the keys of the stored files of every image of a character
+func (r *Images) FindFiles(characterID string) ([]string, error) {
+	paths := make([]string, 0)
+	err := r.DB.Select(&paths, `SELECT path FROM character_images WHERE character_id = $1`, characterID)
+	return paths, err
+}
+
+// FindFilesByUniverse returns the keys of the stored files of every image of every character of a universe
+func (r *Images) FindFilesByUniverse(universeID string) ([]string, error) {
+	paths := make([]string, 0)
+	err := r.DB.Select(
+		&paths,
+		`SELECT path FROM character_images JOIN characters ON characters.id = character_images.character_id
+		WHERE characters.universe_id = $1`,
+		universeID,
+	)
+	return paths, err
+}
+
+// FindOrphaned returns the keys of stored files under the image prefix older than minAge that are not recorded
+// against a character. Newer files are skipped since they may belong to a character still being saved, and files
+// outside the prefix are never considered since they do not belong to characters
+func (r *Images) FindOrphaned(minAge time.Duration) ([]string, error) {
+	var referenced []string
+	if err := r.DB.Select(
+		&referenced,
+		`SELECT path FROM character_images`,
+	); err != nil {
+		return nil, err
+	}
+	inUse := make(map[string]bool, len(referenced))
+	for _, key := range referenced {
+		inUse[key] = true
+	}
+	objects, err := r.Storage.List(repositories.ImagePrefix)
+	if err != nil {
+		return nil, err
+	}
+	orphaned := make([]string, 0)
+	for _, o := range objects {
+		if !inUse[o.Key] && time.Since(o.LastModified) >= minAge {
+			orphaned = append(orphaned, o.Key)
+		}
+	}
+	return orphaned, nil
+}
+
+// DeleteFile removes a stored file by its key
+func (r *Images) DeleteFile(key string) error {
+	return r.Storage.Delete(key)
+}
+
+// FindMissing returns the records of image variants under the image prefix whose files are no longer stored.
+// Records are read before the files are listed, since files are stored before they are recorded and recorded
+// files are only deleted after their records. Variants stored before the prefix was used are never considered
+func (r *Images) FindMissing() ([]models.ImageRecord, error) {
+	var records []models.ImageRecord
+	if err := r.DB.Select(
+		&records,
+		`SELECT character_id, key, width, path FROM character_images WHERE path LIKE $1 || '%'`,
+		repositories.ImagePrefix,
+	); err != nil {
+		return nil, err
+	}
+	objects, err := r.Storage.List(repositories.ImagePrefix)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[string]bool, len(objects))
+	for _, o := range objects {
+		stored[o.Key] = true
+	}
+	missing := make([]models.ImageRecord, 0)
+	for _, record := range records {
+		if !stored[record.Path] {
+			missing = append(missing, record)
+		}
+	}
+	return missing, nil
+}
+
+// DeleteRecord removes the record of an image variant
+func (r *Images) DeleteRecord(record models.ImageRecord) error {
+	_, err := r.DB.Exec(
+		`DELETE FROM character_images WHERE character_id = $1 AND key = $2 AND width = $3`,
+		record.CharacterID,
+		record.Key,
+		record.Width,
+	)
+	return err
+}
+
+// FindUnsized returns the records of stored image variants recorded with no size, along with the size of their
+// files. Those are the variants saved before the size column was added, and so before the image prefix was used,
+// so the whole bucket is listed. Nothing outside the records is changed
+func (r *Images) FindUnsized() ([]models.ImageRecord, error) {
+	unsized := make([]models.ImageRecord, 0)
+	var records []models.ImageRecord
+	if err := r.DB.Select(
+		&records,
+		`SELECT character_id, key, width, path FROM character_images WHERE size = 0`,
+	); err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return unsized, nil
+	}
+	objects, err := r.Storage.List("")
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64, len(objects))
+	for _, o := range objects {
+		sizes[o.Key] = o.Size
+	}
+	for _, record := range records {
+		if size := sizes[record.Path]; size > 0 {
+			record.Size = size
+			unsized = append(unsized, record)
+		}
+	}
+	return unsized, nil
+}
+
+// SetSize records the size of an image variant
+func (r *Images) SetSize(record models.ImageRecord) error {
+	_, err := r.DB.Exec(
+		`UPDATE character_images SET size = $4 WHERE character_id = $1 AND key = $2 AND width = $3`,
+		record.CharacterID,
+		record.Key,
+		record.Width,
+		record.Size,
+	)
+	return err
+}
+
+// SaveUpload stores an uploaded image under a key until it is processed
+func (r *Images) SaveUpload(key string, data []byte) error {
+	_, err := r.Storage.Upload(bytes.NewReader(data), key, "application/octet-stream")
+	return err
+}
+
+// FindUpload reads back an uploaded image stored under a key
+func (r *Images) FindUpload(key string) ([]byte, error) {
+	return r.Storage.Download(key)
+}
+
+// FindUploads returns the keys of stored uploads older than minAge
+func (r *Images) FindUploads(minAge time.Duration) ([]string, error) {
+	objects, err := r.Storage.List(repositories.UploadPrefix)
+	if err != nil {
+		return nil, err
+	}
+	uploads := make([]string, 0)
+	for _, o := range objects {
+		if time.Since(o.LastModified) >= minAge {
+			uploads = append(uploads, o.Key)
+		}
+	}
+	return uploads, nil
+}
@@ -0,0 +1,41 @@
+// Package postgres implements the repositories on top of a Postgres database, storing character image files
+// through the API storage
+package postgres
+
+import (
+	"cbs/api"
+	"cbs/repositories"
+
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/Masterminds/squirrel.v1"
+)
+
+// New creates the Postgres repositories. Sessions, imports, jobs and the webhook delivery queue are not kept in
+// Postgres and are left unset
+func New(db *sqlx.DB, builder squirrel.StatementBuilderType, storage *api.Storage) *repositories.Repositories {
+	return &repositories.Repositories{
+		User:         &Users{DB: db},
+		Universe:     &Universes{DB: db},
+		Collaborator: &Collaborators{DB: db},
+		Character:    &Characters{DB: db, Builder: builder},
+		Image:        &Images{DB: db, Storage: storage},
+		Share:        &Shares{DB: db},
+		Relationship: &Relationships{DB: db},
+		Comment:      &Comments{DB: db},
+		Webhook:      &Webhooks{DB: db},
+		Audit:        &Audits{DB: db, Builder: builder},
+	}
+}
+
+// inTx runs fn inside a transaction, committing it when fn succeeds and rolling it back otherwise
+func inTx(db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"cbs/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// relationshipColumns represents the columns selected when retrieving relationships
+const relationshipColumns = "id, universe_id, source_id, target_id, type, bidirectional, description, created_at, " +
+	"updated_at"
+
+// Relationships represents a repository of relationships between characters stored in Postgres
+type Relationships struct {
+	DB *sqlx.DB
+}
+
+// FindByID returns a relationship of a universe by its ID
+func (r *Relationships) FindByID(universeID string, id string) (*models.Relationship, error) {
+	var relationship models.Relationship
+	if err := r.DB.Get(
+		&relationship,
+		"SELECT "+relationshipColumns+" FROM relationships WHERE universe_id = $1 AND id = $2",
+		universeID,
+		id,
+	); err != nil {
+		return nil, err
+	}
+	return &relationship, nil
+}
+
+// FindByCharacters returns the relationships of a universe involving any of the given characters, or every
+// relationship of the universe when none are given, ordered by creation
+func (r *Relationships) FindByCharacters(universeID string, characterIDs []string) ([]models.Relationship, error) {
+	relationships := make([]models.Relationship, 0)
+	if err := r.DB.Select(
+		&relationships,
+		"SELECT "+relationshipColumns+` FROM relationships WHERE universe_id = $1 AND (cardinality($2::text[]) = 0 OR
+		source_id = ANY($2) OR target_id = ANY($2)) ORDER BY created_at, id`,
+		universeID,
+		pq.Array(characterIDs),
+	); err != nil {
+		return nil, err
+	}
+	return relationships, nil
+}
+
+// Create inserts a relationship
+func (r *Relationships) Create(relationship *models.Relationship) error {
+	return r.DB.Get(
+		relationship,
+		`INSERT INTO relationships (id, universe_id, source_id, target_id, type, bidirectional, description) VALUES
+		($1, $2, $3, $4, $5, $6, $7) RETURNING `+relationshipColumns,
+		relationship.ID,
+		relationship.UniverseID,
+		relationship.SourceID,
+		relationship.TargetID,
+		relationship.Type,
+		relationship.Bidirectional,
+		relationship.Description,
+	)
+}
+
+// Update saves the type, direction and description of a relationship and stamps it
+func (r *Relationships) Update(relationship *models.Relationship) error {
+	return r.DB.Get(
+		relationship,
+		`UPDATE relationships SET type = $2, bidirectional = $3, description = $4, updated_at = now() WHERE id = $1
+		RETURNING `+relationshipColumns,
+		relationship.ID,
+		relationship.Type,
+		relationship.Bidirectional,
+		relationship.Description,
+	)
+}
+
+// Delete removes a relationship
+func (r *Relationships) Delete(id string) error {
+	_, err := r.DB.Exec("DELETE FROM relationships WHERE id = $1", id)
+	return err
+}
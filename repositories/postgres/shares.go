@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"cbs/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// shareColumns represents the columns selected when retrieving share links
+const shareColumns = "id, token, universe_id, character_id, creator_id, expires_at, created_at"
+
+// Shares represents a repository of public share links stored in Postgres
+type Shares struct {
+	DB *sqlx.DB
+}
+
+// FindByID returns a share link of a universe by its ID
+func (r *Shares) FindByID(universeID string, id string) (*models.Share, error) {
+	var share models.Share
+	if err := r.DB.Get(
+		&share,
+		"SELECT "+shareColumns+" FROM shares WHERE universe_id = $1 AND id = $2",
+		universeID,
+		id,
+	); err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// FindByToken returns a share link by its token
+func (r *Shares) FindByToken(token string) (*models.Share, error) {
+	var share models.Share
+	if err := r.DB.Get(&share, "SELECT "+shareColumns+" FROM shares WHERE token = $1", token); err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// FindByUniverse returns the share links of a universe, or only those created by a user when creatorID is set,
+// ordered by creation
+func (r *Shares) FindByUniverse(universeID string, creatorID string) ([]models.Share, error) {
+	shares := make([]models.Share, 0)
+	if err := r.DB.Select(
+		&shares,
+		"SELECT "+shareColumns+" FROM shares WHERE universe_id = $1 AND ($2 = '' OR creator_id = $2) ORDER BY created_at",
+		universeID,
+		creatorID,
+	); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// Create inserts a share link
+func (r *Shares) Create(share *models.Share) error {
+	return r.DB.Get(
+		share,
+		`INSERT INTO shares (id, token, universe_id, character_id, creator_id, expires_at) VALUES ($1, $2, $3, $4, $5,
+		$6) RETURNING `+shareColumns,
+		share.ID,
+		share.Token,
+		share.UniverseID,
+		share.CharacterID,
+		share.CreatorID,
+		share.ExpiresAt,
+	)
+}
+
+// Delete removes a share link
+func (r *Shares) Delete(id string) error {
+	_, err := r.DB.Exec("DELETE FROM shares WHERE id = $1", id)
+	return err
+}
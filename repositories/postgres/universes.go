@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Universes represents a repository of universes stored in Postgres
+type Universes struct {
+	DB *sqlx.DB
+}
+
+// FindAll returns all universes
+func (r *Universes) FindAll() ([]models.Universe, error) {
+	universes := make([]models.Universe, 0)
+	if err := r.DB.Select(
+		&universes,
+		"SELECT id, name, description, guide, settings, version FROM universes",
+	); err != nil {
+		return nil, err
+	}
+	return universes, nil
+}
+
+// FindByID returns a universe by its ID
+func (r *Universes) FindByID(id string) (*models.Universe, error) {
+	var universe models.Universe
+	if err := r.DB.Get(
+		&universe,
+		"SELECT id, name, description, guide, settings, version FROM universes WHERE id = $1",
+		id,
+	); err != nil {
+		return nil, err
+	}
+	return &universe, nil
+}
+
+// FindByCollaborator returns references to the universes a user collaborates in, ordered by name
+func (r *Universes) FindByCollaborator(userID string) ([]models.UniverseReference, error) {
+	universes := make([]models.UniverseReference, 0)
+	if err := r.DB.Select(
+		&universes,
+		`SELECT universes.id, universes.name, collaborators.role FROM collaborators JOIN universes
+		ON universes.id = collaborators.universe_id WHERE user_id = $1 ORDER BY universes.name`,
+		userID,
+	); err != nil {
+		return nil, err
+	}
+	return universes, nil
+}
+
+// Create inserts a universe along with its owner, within a limit on the universes the owner owns. The owner is
+// locked while their universes are counted, so that concurrent inserts cannot exceed the limit together
+func (r *Universes) Create(universe *models.Universe, ownerID string, limit int) error {
+	return inTx(r.DB, func(tx *sqlx.Tx) error {
+		if limit > 0 {
+			if _, err := tx.Exec(`SELECT 1 FROM users WHERE id = $1 FOR NO KEY UPDATE`, ownerID); err != nil {
+				return err
+			}
+			var owned int
+			if err := tx.Get(
+				&owned,
+				`SELECT COUNT(*) FROM collaborators WHERE user_id = $1 AND role = $2`,
+				ownerID,
+				models.CollaboratorOwner,
+			); err != nil {
+				return err
+			}
+			if owned >= limit {
+				return repositories.ErrQuota
+			}
+		}
+		if err := tx.Get(
+			universe,
+			`INSERT INTO universes (id, name, description, guide, settings) VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, name, description, guide, settings, version`,
+			universe.ID,
+			universe.Name,
+			universe.Description,
+			universe.Guide,
+			universe.Settings,
+		); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			`INSERT INTO collaborators (universe_id, user_id, role) VALUES ($1, $2, $3)`,
+			universe.ID,
+			ownerID,
+			models.CollaboratorOwner,
+		)
+		return err
+	})
+}
+
+// Update updates a universe if it was not modified since it was retrieved, replacing its owner when ownerID is set
+func (r *Universes) Update(universe *models.Universe, ownerID string) error {
+	return inTx(r.DB, func(tx *sqlx.Tx) error {
+		err := tx.Get(
+			universe,
+			`UPDATE universes SET name = $1, description = $2, guide = $3, settings = $4, version = version + 1
+			WHERE id = $5 AND version = $6 RETURNING id, name, description, guide, settings, version`,
+			universe.Name,
+			universe.Description,
+			universe.Guide,
+			universe.Settings,
+			universe.ID,
+			universe.Version,
+		)
+		if err == sql.ErrNoRows {
+			return repositories.ErrStale
+		}
+		if err != nil || ownerID == "" {
+			return err
+		}
+		if _, err := tx.Exec(
+			`DELETE FROM collaborators WHERE universe_id = $1 AND role = $2`,
+			universe.ID,
+			models.CollaboratorOwner,
+		); err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			`INSERT INTO collaborators (universe_id, user_id, role) VALUES ($1, $2, $3)`,
+			universe.ID,
+			ownerID,
+			models.CollaboratorOwner,
+		)
+		return err
+	})
+}
+
+// Delete removes a universe
+func (r *Universes) Delete(id string) error {
+	_, err := r.DB.Exec(`DELETE FROM universes WHERE id = $1`, id)
+	return err
+}
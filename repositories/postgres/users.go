@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"cbs/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Users represents a repository of users stored in Postgres
+type Users struct {
+	DB *sqlx.DB
+}
+
+// FindAll returns all users
+func (r *Users) FindAll() ([]models.User, error) {
+	users := make([]models.User, 0)
+	if err := r.DB.Select(&users, "SELECT id, email, display_name FROM users"); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FindByID returns a user by their ID along with their disabled state
+func (r *Users) FindByID(id string) (*models.User, error) {
+	var user models.User
+	if err := r.DB.Get(&user, "SELECT id, email, display_name, disabled FROM users WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByEmail returns a user by their email address
+func (r *Users) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := r.DB.Get(&user, "SELECT id, email, display_name FROM users WHERE email = $1", email); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindCredentials returns a user by their email address along with their password hash and disabled state
+func (r *Users) FindCredentials(email string) (*models.User, error) {
+	var user models.User
+	if err := r.DB.Get(
+		&user,
+		"SELECT id, email, display_name, password_hash, disabled FROM users WHERE email = $1",
+		email,
+	); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Search returns the users whose email address or display name contains the query, including disabled users
+func (r *Users) Search(query string) ([]models.User, error) {
+	users := make([]models.User, 0)
+	if err := r.DB.Select(
+		&users,
+		`SELECT id, email, display_name, disabled FROM users WHERE email ILIKE $1 OR display_name ILIKE $1
+		ORDER BY email`,
+		"%"+query+"%",
+	); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Create inserts a user
+func (r *Users) Create(user *models.User) error {
+	return r.DB.Get(
+		user,
+		`INSERT INTO users (id, display_name, email, password_hash) VALUES ($1, $2, $3, $4)
+		RETURNING id, display_name, email, password_hash, disabled`,
+		user.ID,
+		user.DisplayName,
+		user.Email,
+		user.PasswordHash,
+	)
+}
+
+// Update updates an existing user
+func (r *Users) Update(user *models.User) error {
+	return r.DB.Get(
+		user,
+		`UPDATE users SET display_name = $1, email = $2, password_hash = $3 WHERE id = $4
+		RETURNING id, display_name, email, password_hash, disabled`,
+		user.DisplayName,
+		user.Email,
+		user.PasswordHash,
+		user.ID,
+	)
+}
+
+// SetDisabled prevents or allows a user from logging in
+func (r *Users) SetDisabled(id string, disabled bool) error {
+	_, err := r.DB.Exec(`UPDATE users SET disabled = $1 WHERE id = $2`, disabled, id)
+	return err
+}
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"cbs/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// webhookColumns represents the columns selected when retrieving webhooks
+const webhookColumns = "id, universe_id, url, secret, events, active, created_at, updated_at"
+
+// deliveryColumns represents the columns selected when retrieving webhook deliveries
+const deliveryColumns = "id, webhook_id, event_id, event_type, payload, status, attempts, response_status, error, " +
+	"next_attempt_at, created_at, updated_at"
+
+// Webhooks represents a repository of webhooks and their delivery logs stored in Postgres
+type Webhooks struct {
+	DB *sqlx.DB
+}
+
+// FindByUniverse returns the webhooks of a universe, ordered by creation
+func (r *Webhooks) FindByUniverse(universeID string) ([]models.Webhook, error) {
+	webhooks := make([]models.Webhook, 0)
+	if err := r.DB.Select(
+		&webhooks,
+		"SELECT "+webhookColumns+" FROM webhooks WHERE universe_id = $1 ORDER BY created_at, id",
+		universeID,
+	); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// FindByID returns a webhook by its ID
+func (r *Webhooks) FindByID(id string) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.DB.Get(&webhook, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// Create inserts a webhook
+func (r *Webhooks) Create(webhook *models.Webhook) error {
+	return r.DB.Get(
+		webhook,
+		`INSERT INTO webhooks (id, universe_id, url, secret, events, active) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+webhookColumns,
+		webhook.ID,
+		webhook.UniverseID,
+		webhook.URL,
+		webhook.Secret,
+		webhook.Events,
+		webhook.Active,
+	)
+}
+
+// Update saves the URL, secret, event types and state of a webhook and stamps it
+func (r *Webhooks) Update(webhook *models.Webhook) error {
+	return r.DB.Get(
+		webhook,
+		`UPDATE webhooks SET url = $2, secret = $3, events = $4, active = $5, updated_at = now() WHERE id = $1
+		RETURNING `+webhookColumns,
+		webhook.ID,
+		webhook.URL,
+		webhook.Secret,
+		webhook.Events,
+		webhook.Active,
+	)
+}
+
+// Delete removes a webhook, whose delivery log is removed along with it
+func (r *Webhooks) Delete(id string) error {
+	_, err := r.DB.Exec("DELETE FROM webhooks WHERE id = $1", id)
+	return err
+}
+
+// FindDeliveries returns up to limit of the most recent deliveries of a webhook, most recent first
+func (r *Webhooks) FindDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	deliveries := make([]models.WebhookDelivery, 0)
+	if err := r.DB.Select(
+		&deliveries,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC LIMIT $2",
+		webhookID,
+		limit,
+	); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// FindDeliveryByID returns a webhook delivery by its ID
+func (r *Webhooks) FindDeliveryByID(id string) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.DB.Get(
+		&delivery,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = $1",
+		id,
+	); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// CreateDelivery inserts a webhook delivery
+func (r *Webhooks) CreateDelivery(delivery *models.WebhookDelivery) error {
+	return r.DB.Get(
+		delivery,
+		`INSERT INTO webhook_deliveries (id, webhook_id, event_id, event_type, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING `+deliveryColumns,
+		delivery.ID,
+		delivery.WebhookID,
+		delivery.EventID,
+		delivery.EventType,
+		delivery.Payload,
+		delivery.Status,
+		delivery.NextAttemptAt,
+	)
+}
+
+// UpdateDelivery saves the outcome of a delivery attempt and stamps it
+func (r *Webhooks) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return r.DB.Get(
+		delivery,
+		`UPDATE webhook_deliveries SET status = $2, attempts = $3, response_status = $4, error = $5,
+		next_attempt_at = $6, updated_at = now() WHERE id = $1 RETURNING `+deliveryColumns,
+		delivery.ID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.ResponseStatus,
+		delivery.Error,
+		delivery.NextAttemptAt,
+	)
+}
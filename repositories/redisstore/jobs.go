@@ -0,0 +1,169 @@
+package redisstore
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Keys of the Redis sorted sets of queued and dead jobs, scored by the millisecond they are due or were buried
+const (
+	jobQueueKey = "jobs:queue"
+	jobDeadKey  = "jobs:dead"
+)
+
+// claimScript leases due jobs by pushing their score back by the lease, so a job is claimed by a single worker
+// and returns to the queue if the worker does not complete it
+var claimScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[2], id)
+end
+return ids
+`)
+
+// Jobs represents a repository of background jobs and their queues stored in Redis
+type Jobs struct {
+	Redis *redis.Client
+}
+
+func jobKey(id string) string {
+	return fmt.Sprintf("job:%v", id)
+}
+
+func jobResultKey(id string) string {
+	return fmt.Sprintf("job:%v:result", id)
+}
+
+// score returns the sorted set score of a time
+func score(t time.Time) float64 {
+	return float64(t.UnixNano() / int64(time.Millisecond))
+}
+
+// Save stores a job, expiring it after ttl unless ttl is 0
+func (r *Jobs) Save(job *models.Job, ttl time.Duration) error {
+	serialized, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return r.Redis.Set(jobKey(job.ID), serialized, ttl).Err()
+}
+
+// FindByID returns a job by its ID
+func (r *Jobs) FindByID(id string) (*models.Job, error) {
+	var job models.Job
+	serialized, err := r.Redis.Get(jobKey(id)).Result()
+	if err == redis.Nil {
+		return nil, repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(serialized), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Schedule queues a job to be claimed once at is reached
+func (r *Jobs) Schedule(id string, at time.Time) error {
+	return r.Redis.ZAdd(jobQueueKey, redis.Z{Score: score(at), Member: id}).Err()
+}
+
+// Claim leases up to limit jobs due by now from the queue
+func (r *Jobs) Claim(now time.Time, lease time.Duration, limit int) ([]string, error) {
+	result, err := claimScript.Run(
+		r.Redis,
+		[]string{jobQueueKey},
+		score(now),
+		score(now.Add(lease)),
+		limit,
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+	values, _ := result.([]interface{})
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Renew extends the lease of a claimed job, leaving jobs that have left the queue out of it
+func (r *Jobs) Renew(id string, until time.Time) error {
+	return r.Redis.ZAddXX(jobQueueKey, redis.Z{Score: score(until), Member: id}).Err()
+}
+
+// Complete removes a job from the queue
+func (r *Jobs) Complete(id string) error {
+	return r.Redis.ZRem(jobQueueKey, id).Err()
+}
+
+// Bury moves a job to the dead-letter queue
+func (r *Jobs) Bury(id string, at time.Time) error {
+	_, err := r.Redis.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.ZRem(jobQueueKey, id)
+		pipe.ZAdd(jobDeadKey, redis.Z{Score: score(at), Member: id})
+		return nil
+	})
+	return err
+}
+
+// Unbury takes a job out of the dead-letter queue
+func (r *Jobs) Unbury(id string) error {
+	return r.Redis.ZRem(jobDeadKey, id).Err()
+}
+
+// FindDead returns the jobs of the dead-letter queue, most recently buried first. Jobs that have expired since
+// they were buried are dropped from the queue
+func (r *Jobs) FindDead(limit int) ([]models.Job, error) {
+	ids, err := r.Redis.ZRevRange(jobDeadKey, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]models.Job, 0, len(ids))
+	for _, id := range ids {
+		job, err := r.FindByID(id)
+		if err == repositories.ErrNotFound {
+			r.Redis.ZRem(jobDeadKey, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// SaveResult stores the file produced by a job, expiring it after ttl
+func (r *Jobs) SaveResult(id string, result *models.JobResult, ttl time.Duration) error {
+	serialized, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return r.Redis.Set(jobResultKey(id), serialized, ttl).Err()
+}
+
+// FindResult returns the file produced by a job
+func (r *Jobs) FindResult(id string) (*models.JobResult, error) {
+	var result models.JobResult
+	serialized, err := r.Redis.Get(jobResultKey(id)).Result()
+	if err == redis.Nil {
+		return nil, repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(serialized), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,110 @@
+// Package redisstore implements the session, character import, job and webhook queue repositories on top of Redis
+package redisstore
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Sessions represents a repository of user sessions stored in Redis
+type Sessions struct {
+	Redis *redis.Client
+}
+
+func sessionKey(key string) string {
+	return fmt.Sprintf("session:%v", key)
+}
+
+// Create stores a session of a user
+func (r *Sessions) Create(key string, user *models.User, ttl time.Duration) error {
+	serialized, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	return r.Redis.Set(sessionKey(key), serialized, ttl).Err()
+}
+
+// Find returns the user of a session and refreshes its TTL
+func (r *Sessions) Find(key string, ttl time.Duration) (*models.User, error) {
+	var user models.User
+	serialized, err := r.Redis.Get(sessionKey(key)).Result()
+	if err == redis.Nil {
+		return nil, repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = r.Redis.Expire(sessionKey(key), ttl)
+	if err := json.Unmarshal([]byte(serialized), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Clear deletes every session of a user, or every session of every user when userID is empty
+func (r *Sessions) Clear(userID string) (int, error) {
+	cleared := 0
+	var cursor uint64
+	for {
+		keys, next, err := r.Redis.Scan(cursor, sessionKey("*"), 100).Result()
+		if err != nil {
+			return cleared, err
+		}
+		for _, key := range keys {
+			if userID != "" {
+				var owner models.User
+				serialized, err := r.Redis.Get(key).Result()
+				if err != nil || json.Unmarshal([]byte(serialized), &owner) != nil || owner.ID != userID {
+					continue
+				}
+			}
+			if err := r.Redis.Del(key).Err(); err != nil {
+				return cleared, err
+			}
+			cleared++
+		}
+		if next == 0 {
+			return cleared, nil
+		}
+		cursor = next
+	}
+}
+
+// Imports represents a repository of character import progress stored in Redis
+type Imports struct {
+	Redis *redis.Client
+}
+
+func importKey(universeID string, id string) string {
+	return fmt.Sprintf("import:%v:%v", universeID, id)
+}
+
+// Save stores the progress of a character import
+func (r *Imports) Save(job *models.CharacterImport, ttl time.Duration) error {
+	serialized, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return r.Redis.Set(importKey(job.UniverseID, job.ID), serialized, ttl).Err()
+}
+
+// FindByID returns the progress of a character import of a universe by its ID
+func (r *Imports) FindByID(universeID string, id string) (*models.CharacterImport, error) {
+	var job models.CharacterImport
+	serialized, err := r.Redis.Get(importKey(universeID, id)).Result()
+	if err == redis.Nil {
+		return nil, repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(serialized), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
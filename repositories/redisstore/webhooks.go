@@ -0,0 +1,45 @@
+package redisstore
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// webhookQueueKey represents the Redis sorted set of webhook delivery IDs scored by the second they are next due
+const webhookQueueKey = "webhooks:queue"
+
+// WebhookQueue represents a queue of webhook deliveries stored in Redis
+type WebhookQueue struct {
+	Redis *redis.Client
+}
+
+// Schedule queues a delivery to be claimed once at is reached
+func (r *WebhookQueue) Schedule(id string, at time.Time) error {
+	return r.Redis.ZAdd(webhookQueueKey, redis.Z{Score: float64(at.Unix()), Member: id}).Err()
+}
+
+// Claim takes up to limit deliveries due by now out of the queue. Only the caller that removes a delivery from the
+// queue claims it, so any number of workers may claim deliveries at once
+func (r *WebhookQueue) Claim(now time.Time, limit int) ([]string, error) {
+	ids, err := r.Redis.ZRangeByScore(webhookQueueKey, redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.Unix(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	claimed := make([]string, 0, len(ids))
+	for _, id := range ids {
+		removed, err := r.Redis.ZRem(webhookQueueKey, id).Result()
+		if err != nil {
+			return claimed, err
+		}
+		if removed > 0 {
+			claimed = append(claimed, id)
+		}
+	}
+	return claimed, nil
+}
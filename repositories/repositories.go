@@ -0,0 +1,318 @@
+package repositories
+
+import (
+	"cbs/dtos"
+	"cbs/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned when no record matches. It is sql.ErrNoRows so existing checks against it keep working
+var ErrNotFound = sql.ErrNoRows
+
+// ErrStale is returned when a record was modified since it was retrieved
+var ErrStale = errors.New("record was modified since it was retrieved")
+
+// ErrQuota is returned when saving records would exceed a quota. Quotas are checked in the same transaction as the
+// save, so that concurrent saves cannot exceed them together
+var ErrQuota = errors.New("record would exceed a quota")
+
+// ImagePrefix represents the prefix of the storage keys of character images. Only files under it are reconciled,
+// so other files sharing the bucket are never taken for orphans
+const ImagePrefix = "images/"
+
+// UploadPrefix represents the prefix of the storage keys of uploaded images waiting to be processed in the
+// background
+const UploadPrefix = "uploads/"
+
+// BatchError represents a failure to save one record of a batch, in which case none of the batch was saved
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("record %d: %v", e.Index, e.Err)
+}
+
+// Repositories represents the group of repositories the services read and write their records through
+type Repositories struct {
+	User         User
+	Universe     Universe
+	Collaborator Collaborator
+	Character    Character
+	Image        Image
+	Session      Session
+	Import       Import
+	Share        Share
+	Job          Job
+	Relationship Relationship
+	Comment      Comment
+	Webhook      Webhook
+	WebhookQueue WebhookQueue
+	Audit        Audit
+}
+
+// User represents a repository of users
+type User interface {
+	FindAll() ([]models.User, error)
+
+	// FindByID returns a user by their ID along with their disabled state
+	FindByID(id string) (*models.User, error)
+	FindByEmail(email string) (*models.User, error)
+
+	// FindCredentials returns a user by their email address along with their password hash and disabled state
+	FindCredentials(email string) (*models.User, error)
+
+	// Search returns the users whose email address or display name contains the query, ordered by email
+	Search(query string) ([]models.User, error)
+	Create(user *models.User) error
+	Update(user *models.User) error
+	SetDisabled(id string, disabled bool) error
+}
+
+// Universe represents a repository of universes
+type Universe interface {
+	FindAll() ([]models.Universe, error)
+	FindByID(id string) (*models.Universe, error)
+
+	// FindByCollaborator returns the universes a user collaborates in, ordered by name
+	FindByCollaborator(userID string) ([]models.UniverseReference, error)
+
+	// Create saves a new universe along with its owner, returning ErrQuota if the owner would then own more than
+	// limit universes. A limit of 0 is unlimited
+	Create(universe *models.Universe, ownerID string, limit int) error
+
+	// Update saves a universe and increments its version, returning ErrStale if its version no longer matches.
+	// The owner is replaced when ownerID is set
+	Update(universe *models.Universe, ownerID string) error
+	Delete(id string) error
+}
+
+// Collaborator represents a repository of universe collaborators
+type Collaborator interface {
+	FindByID(universeID string, userID string) (*models.Collaborator, error)
+
+	// FindByUniverse returns the collaborators of a universe along with their users
+	FindByUniverse(universeID string) ([]models.Collaborator, error)
+	Create(collaborator *models.Collaborator) error
+	Update(collaborator *models.Collaborator) error
+	Delete(universeID string, userID string) error
+
+	// TransferOwnership makes a user the owner of a universe, demoting the previous owner to an admin
+	TransferOwnership(universeID string, userID string) error
+}
+
+// Character represents a repository of characters. Characters are returned without their images
+type Character interface {
+	// FindByID returns a character along with its owner
+	FindByID(id string) (*models.Character, error)
+
+	// FindByUniverse returns a page of references to the characters of a universe matching the query, along with
+	// the number of matching characters
+	FindByUniverse(universeID string, query dtos.CharacterQuery, limit int) ([]models.CharacterReference, int, error)
+
+	// FindAllByUniverse returns every character of a universe matching the query
+	FindAllByUniverse(universeID string, query dtos.CharacterQuery) ([]models.Character, error)
+
+	// FindReferences returns references to the characters of a universe among the given IDs, ordered by name
+	FindReferences(universeID string, ids []string) ([]models.CharacterReference, error)
+	FindIDsByUniverse(universeID string) ([]string, error)
+	CountByUniverse(universeID string) (int, error)
+
+	// Create saves new characters all at once, returning a BatchError naming the character that failed, or
+	// ErrQuota if the universe would then hold more than limit characters. A limit of 0 is unlimited
+	Create(
+		universeID string,
+		ownerID string,
+		limit int,
+		characters ...*models.Character,
+	) ([]models.Character, error)
+
+	// Update saves a character and stamps it, returning ErrStale if it was modified since it was retrieved
+	Update(character *models.Character) (*models.Character, error)
+	Delete(id string) error
+	DeleteByUniverse(universeID string) error
+}
+
+// Image represents a repository of character images, holding both the files and the characters they belong to
+type Image interface {
+	// Save stores the variants of an image under a key of a character along with their sizes, replacing every
+	// variant of an existing image
+	Save(characterID string, key string, variants []models.ImageVariant) error
+
+	// Delete removes every variant of the image under a key of a character
+	Delete(characterID string, key string) error
+	FindByCharacter(characterID string) (models.CharacterImages, error)
+
+	// Size returns the size in bytes of every variant of the image under a key of a character, or 0 when there is
+	// none
+	Size(characterID string, key string) (int64, error)
+
+	// SizeByUniverse returns the total size in bytes of the images of every character of a universe
+	SizeByUniverse(universeID string) (int64, error)
+
+	// FindByUniverse returns the images of every character of a universe by character ID
+	FindByUniverse(universeID string) (map[string]models.CharacterImages, error)
+
+	// FindFiles and FindFilesByUniverse return the keys of the stored files of every image of a character or of
+	// every character of a universe
+	FindFiles(characterID string) ([]string, error)
+	FindFilesByUniverse(universeID string) ([]string, error)
+
+	// FindOrphaned returns the keys of stored files older than minAge that no longer belong to a character
+	FindOrphaned(minAge time.Duration) ([]string, error)
+	DeleteFile(key string) error
+
+	// FindMissing returns the records of image variants whose files are no longer stored, and DeleteRecord
+	// removes one such record without touching storage
+	FindMissing() ([]models.ImageRecord, error)
+	DeleteRecord(record models.ImageRecord) error
+
+	// FindUnsized returns the records of stored image variants with no recorded size, such as those saved before
+	// sizes were recorded, along with the size of their files. SetSize records the size of one such variant
+	FindUnsized() ([]models.ImageRecord, error)
+	SetSize(record models.ImageRecord) error
+
+	// SaveUpload stores an uploaded image under a key until it is processed, and FindUpload reads it back.
+	// FindUploads returns the keys of uploads older than minAge. Uploads are removed with DeleteFile
+	SaveUpload(key string, data []byte) error
+	FindUpload(key string) ([]byte, error)
+	FindUploads(minAge time.Duration) ([]string, error)
+}
+
+// Session represents a repository of user sessions
+type Session interface {
+	Create(key string, user *models.User, ttl time.Duration) error
+
+	// Find returns the user of a session and extends the session by ttl
+	Find(key string, ttl time.Duration) (*models.User, error)
+
+	// Clear deletes every session of a user, or every session of every user when userID is empty, returning the
+	// number of deleted sessions
+	Clear(userID string) (int, error)
+}
+
+// Import represents a repository of character import progress, kept while imports are polled
+type Import interface {
+	Save(job *models.CharacterImport, ttl time.Duration) error
+	FindByID(universeID string, id string) (*models.CharacterImport, error)
+}
+
+// Job represents a repository of background jobs and the queues they wait in. Queued jobs are claimed by exactly
+// one worker, and return to the queue when the worker does not finish them within the lease
+type Job interface {
+	// Save stores a job, expiring it after ttl unless ttl is 0
+	Save(job *models.Job, ttl time.Duration) error
+	FindByID(id string) (*models.Job, error)
+
+	// Schedule queues a job to be claimed once at is reached, replacing any earlier schedule or lease
+	Schedule(id string, at time.Time) error
+
+	// Claim leases up to limit jobs due by now from the queue, returning their IDs
+	Claim(now time.Time, lease time.Duration, limit int) ([]string, error)
+
+	// Renew extends the lease of a claimed job until the given time, unless the job has left the queue
+	Renew(id string, until time.Time) error
+
+	// Complete removes a job from the queue once its worker is done with it
+	Complete(id string) error
+
+	// Bury moves a job to the dead-letter queue, and Unbury takes it out again
+	Bury(id string, at time.Time) error
+	Unbury(id string) error
+
+	// FindDead returns the jobs of the dead-letter queue, most recently buried first
+	FindDead(limit int) ([]models.Job, error)
+
+	SaveResult(id string, result *models.JobResult, ttl time.Duration) error
+	FindResult(id string) (*models.JobResult, error)
+}
+
+// Relationship represents a repository of relationships between characters
+type Relationship interface {
+	FindByID(universeID string, id string) (*models.Relationship, error)
+
+	// FindByCharacters returns the relationships of a universe involving any of the given characters, or every
+	// relationship of the universe when none are given, ordered by creation
+	FindByCharacters(universeID string, characterIDs []string) ([]models.Relationship, error)
+	Create(relationship *models.Relationship) error
+
+	// Update saves the type, direction and description of a relationship and stamps it
+	Update(relationship *models.Relationship) error
+	Delete(id string) error
+}
+
+// Comment represents a repository of comments on characters
+type Comment interface {
+	FindByID(characterID string, id string) (*models.Comment, error)
+
+	// FindByCharacter returns the comments on a character along with their authors, ordered by creation
+	FindByCharacter(characterID string) ([]models.Comment, error)
+	Create(comment *models.Comment) error
+
+	// Update saves the body and mentions of a comment and stamps it
+	Update(comment *models.Comment) error
+
+	// Resolve saves who resolved a comment and when, clearing both when it is reopened
+	Resolve(comment *models.Comment) error
+
+	// Delete removes a comment along with its replies
+	Delete(id string) error
+}
+
+// Share represents a repository of public share links
+type Share interface {
+	FindByID(universeID string, id string) (*models.Share, error)
+	FindByToken(token string) (*models.Share, error)
+
+	// FindByUniverse returns the share links of a universe, or only those created by a user when creatorID is set,
+	// ordered by creation
+	FindByUniverse(universeID string, creatorID string) ([]models.Share, error)
+	Create(share *models.Share) error
+	Delete(id string) error
+}
+
+// Webhook represents a repository of webhooks and their delivery logs
+type Webhook interface {
+	// FindByUniverse returns the webhooks of a universe, ordered by creation
+	FindByUniverse(universeID string) ([]models.Webhook, error)
+	FindByID(id string) (*models.Webhook, error)
+	Create(webhook *models.Webhook) error
+
+	// Update saves the URL, secret, event types and state of a webhook and stamps it
+	Update(webhook *models.Webhook) error
+
+	// Delete removes a webhook along with its delivery log
+	Delete(id string) error
+
+	// FindDeliveries returns up to limit of the most recent deliveries of a webhook, most recent first
+	FindDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error)
+	FindDeliveryByID(id string) (*models.WebhookDelivery, error)
+	CreateDelivery(delivery *models.WebhookDelivery) error
+
+	// UpdateDelivery saves the outcome of a delivery attempt and stamps it
+	UpdateDelivery(delivery *models.WebhookDelivery) error
+}
+
+// WebhookQueue represents a queue of webhook deliveries waiting to be sent. Queued deliveries are claimed by
+// exactly one worker
+type WebhookQueue interface {
+	// Schedule queues a delivery to be claimed once at is reached, replacing any earlier schedule
+	Schedule(id string, at time.Time) error
+
+	// Claim takes up to limit deliveries due by now out of the queue, returning their IDs
+	Claim(now time.Time, limit int) ([]string, error)
+}
+
+// Audit represents a repository of the audit logs of universes. Audit events are never modified or removed,
+// except along with their universe
+type Audit interface {
+	Create(event *models.AuditEvent) error
+
+	// FindByUniverse returns up to limit of the audit events of a universe matching a query, most recent first and
+	// skipping the pages before the query's, along with the number of events matching the query
+	FindByUniverse(universeID string, query dtos.AuditQuery, limit int) ([]models.AuditEvent, int, error)
+}
@@ -0,0 +1,12 @@
+package services
+
+import (
+	"cbs/dtos"
+	"cbs/models"
+)
+
+// Audit represents the Audit log service layer
+type Audit interface {
+	Record(event *models.AuditEvent) error
+	FindByUniverse(universe *models.Universe, ctx dtos.AuditQuery) (*[]models.AuditEvent, int, error)
+}
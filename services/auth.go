@@ -11,4 +11,5 @@ type Auth interface {
 	Login(user *models.User, w http.ResponseWriter) error
 	Logout(w http.ResponseWriter) error
 	User(req *http.Request) (*models.User, error)
+	ClearSessions(user *models.User) (int, error)
 }
@@ -4,6 +4,7 @@ import (
 	"cbs/dtos"
 	"cbs/models"
 	"io"
+	"time"
 )
 
 // Character represents the Character service layer
@@ -11,13 +12,46 @@ type Character interface {
 	New(data dtos.ReqCreateCharacter) *models.Character
 	FindByID(id string) (*models.Character, error)
 	FindByUniverse(universe *models.Universe, ctx dtos.CharacterQuery) (*[]models.CharacterReference, int, error)
+	FindAllByUniverse(universe *models.Universe, ctx dtos.CharacterQuery) (*[]models.Character, error)
 	Validate(character *models.Character, universe *models.Universe) error
+	QueueImage(character *models.Character, key string, image io.Reader, creator *models.User) (*models.Job, error)
 	SetImage(character *models.Character, key string, image io.Reader) error
+	SetUploadedImage(character *models.Character, key string, upload string) error
 	DeleteImage(character *models.Character, key string) error
+	DeleteFiles(keys []string) error
+	ReconcileImages(minAge time.Duration, dryRun bool) (*models.ImageReconciliation, error)
 	Create(universe *models.Universe, character *models.Character, owner *models.User) (*models.Character, error)
+	CopyAll(source *models.Universe, target *models.Universe, owner *models.User) (int, error)
 	Update(character *models.Character) (*models.Character, error)
+	Patch(character *models.Character, patchType dtos.CharacterPatchType, patch []byte) (*models.Character, error)
 	Delete(character *models.Character) error
 	DeleteAll(universe *models.Universe) error
 	FindCharacterImages(id string) (models.CharacterImages, error)
 	Search(universe *models.Universe, query string, ctx dtos.CharacterQuery) (*[]models.CharacterReference, int, error)
+	NewImport(universe *models.Universe, owner *models.User, opts dtos.CharacterImportOptions) *models.CharacterImport
+	ParseImport(
+		universe *models.Universe,
+		format dtos.CharacterImportFormat,
+		file io.Reader,
+	) ([]dtos.ReqCreateCharacter, error)
+	Import(
+		job *models.CharacterImport,
+		universe *models.Universe,
+		owner *models.User,
+		rows []dtos.ReqCreateCharacter,
+	) error
+	SaveImport(job *models.CharacterImport) error
+	FindImportByID(universe *models.Universe, id string) (*models.CharacterImport, error)
+	Export(
+		universe *models.Universe,
+		characters []models.Character,
+		format dtos.CharacterExportFormat,
+		w io.Writer,
+	) error
+	QueueExport(
+		universe *models.Universe,
+		ctx dtos.CharacterQuery,
+		format dtos.CharacterExportFormat,
+		creator *models.User,
+	) (*models.Job, error)
 }
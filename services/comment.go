@@ -0,0 +1,27 @@
+package services
+
+import (
+	"cbs/dtos"
+	"cbs/models"
+)
+
+// Comment represents the Comment service layer
+type Comment interface {
+	New(
+		universe *models.Universe,
+		character *models.Character,
+		author *models.User,
+		data dtos.ReqCreateComment,
+	) (*models.Comment, error)
+	FindByID(character *models.Character, id string) (*models.Comment, error)
+	FindByCharacter(character *models.Character, resolved *bool) (*[]models.CommentThread, error)
+	Create(comment *models.Comment) error
+	Update(
+		universe *models.Universe,
+		character *models.Character,
+		comment *models.Comment,
+		data dtos.ReqEditComment,
+	) error
+	Resolve(comment *models.Comment, user *models.User, resolved bool) error
+	Delete(comment *models.Comment) error
+}
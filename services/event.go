@@ -0,0 +1,12 @@
+package services
+
+import (
+	"cbs/models"
+)
+
+// Event represents the universe Event service layer
+type Event interface {
+	Publish(event *models.UniverseEvent) error
+	Subscribe(universe *models.Universe) (<-chan *models.UniverseEvent, func() error, error)
+	Filter(event *models.UniverseEvent, collaborator *models.Collaborator) *models.UniverseEvent
+}
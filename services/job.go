@@ -0,0 +1,27 @@
+package services
+
+import (
+	"cbs/models"
+	"context"
+	"time"
+)
+
+// JobHandler represents a function performing the work of a background job, returning the file it produced, if
+// any. Jobs whose handler fails are retried unless the error is a client error, which retrying cannot fix
+type JobHandler func(ctx context.Context, job *models.Job) (*models.JobResult, error)
+
+// Job represents the Job service layer
+type Job interface {
+	Enqueue(job *models.Job) error
+	FindByID(universe *models.Universe, id string) (*models.Job, error)
+	FindResult(job *models.Job) (*models.JobResult, error)
+	FindDead(limit int) (*[]models.Job, error)
+	Retry(id string) (*models.Job, error)
+
+	// RunDue runs the jobs that are due once, returning how many were run
+	RunDue(ctx context.Context, handlers map[models.JobType]JobHandler) int
+	Work(ctx context.Context, handlers map[models.JobType]JobHandler)
+
+	// Recur queues a job of a type once every interval until ctx is cancelled
+	Recur(ctx context.Context, jobType models.JobType, interval time.Duration)
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"cbs/dtos"
+	"cbs/models"
+)
+
+// Relationship represents the Relationship service layer
+type Relationship interface {
+	New(universe *models.Universe, data dtos.ReqCreateRelationship) (*models.Relationship, error)
+	FindByID(universe *models.Universe, id string) (*models.Relationship, error)
+	FindByUniverse(
+		universe *models.Universe,
+		collaborator *models.Collaborator,
+		characterID string,
+	) (*[]models.Relationship, error)
+	Graph(
+		universe *models.Universe,
+		collaborator *models.Collaborator,
+		character *models.Character,
+		depth int,
+	) (*models.RelationshipGraph, error)
+	Create(relationship *models.Relationship) error
+	Update(universe *models.Universe, relationship *models.Relationship, data dtos.ReqEditRelationship) error
+	Delete(relationship *models.Relationship) error
+}
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"cbs/dtos"
+	"cbs/models"
+)
+
+// Share represents the Share service layer
+type Share interface {
+	New(universe *models.Universe, creator *models.User, data dtos.ReqCreateShare) (*models.Share, error)
+	FindByUniverse(universe *models.Universe, creator *models.User) (*[]models.Share, error)
+	FindByID(universe *models.Universe, id string) (*models.Share, error)
+	FindByToken(token string) (*models.Share, error)
+	Create(share *models.Share) error
+	Delete(share *models.Share) error
+}
@@ -8,6 +8,9 @@ import (
 // Universe represents the Universe service layer
 type Universe interface {
 	New(data dtos.ReqCreateUniverse) *models.Universe
+	Copy(data dtos.ReqCreateUniverse, source *models.Universe) (*models.Universe, error)
+	Templates() []models.UniverseTemplate
+	FindTemplate(name string) (*models.UniverseTemplate, error)
 	Find() (*[]models.Universe, error)
 	FindByID(id string) (*models.Universe, error)
 	FindFromUser(user *models.User) (*[]models.UniverseReference, error)
@@ -22,5 +25,7 @@ type Universe interface {
 	Create(universe *models.Universe, owner *models.User) error
 	Update(universe *models.Universe, owner *models.User) error
 	Delete(universe *models.Universe) error
+	Usage(universe *models.Universe) (*models.UniverseUsage, error)
 	RemoveCollaborator(universe *models.Universe, collaborator *models.Collaborator) error
+	TransferOwnership(universe *models.Universe, owner *models.User) error
 }
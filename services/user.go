@@ -13,4 +13,6 @@ type User interface {
 	FindByEmail(email string) (*models.User, error)
 	Create(user *models.User) error
 	Update(user *models.User) error
+	Search(query string) (*[]models.User, error)
+	SetDisabled(user *models.User, disabled bool) error
 }
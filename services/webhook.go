@@ -0,0 +1,23 @@
+package services
+
+import (
+	"cbs/dtos"
+	"cbs/models"
+	"context"
+)
+
+// Webhook represents the Webhook service layer
+type Webhook interface {
+	New(universe *models.Universe, data dtos.ReqCreateWebhook) (*models.Webhook, error)
+	FindByUniverse(universe *models.Universe) (*[]models.Webhook, error)
+	FindByID(universe *models.Universe, id string) (*models.Webhook, error)
+	Create(webhook *models.Webhook) error
+	Update(webhook *models.Webhook, data dtos.ReqEditWebhook) error
+	RotateSecret(webhook *models.Webhook) error
+	Delete(webhook *models.Webhook) error
+	FindDeliveries(webhook *models.Webhook) (*[]models.WebhookDelivery, error)
+	FindDeliveryByID(webhook *models.Webhook, id string) (*models.WebhookDelivery, error)
+	Dispatch(event *models.UniverseEvent) error
+	Redeliver(delivery *models.WebhookDelivery) (*models.WebhookDelivery, error)
+	Work(ctx context.Context)
+}
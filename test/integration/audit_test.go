@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestAuditRouter_GetAuditEvents(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	for _, name := range []string{"Frodo", "Sam"} {
+		if code := postCharacter(t, userA, universe, characterRequest(name, false)); code != http.StatusCreated {
+			t.Fatalf("got response status %v; want %v", code, http.StatusCreated)
+		}
+	}
+	characters, err := repos.Character.FindAllByUniverse(universe.ID, dtos.CharacterQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := "/universes/" + universe.ID + "/audit"
+	tests := []struct {
+		name      string
+		user      *models.User
+		query     string
+		want      interface{}
+		wantstat  int
+		wanttotal int
+	}{
+		{
+			name:      "filter by action",
+			user:      userA,
+			query:     "?action=" + string(models.AuditCharacterCreate),
+			wantstat:  http.StatusOK,
+			wanttotal: 2,
+		},
+		{
+			name:      "filter by target",
+			user:      userA,
+			query:     "?action=" + string(models.AuditCharacterCreate) + "&target=" + characters[0].ID,
+			wantstat:  http.StatusOK,
+			wanttotal: 1,
+		},
+		{
+			name:      "filter by actor",
+			user:      userA,
+			query:     "?actor=" + userB.ID,
+			wantstat:  http.StatusOK,
+			wanttotal: 0,
+		},
+		{
+			name:     "not admin",
+			user:     userB,
+			want:     api.ErrCodeBadAuth,
+			wantstat: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := userRequest(t, tt.user, "GET", route+tt.query, nil, nil)
+			if tt.want != nil {
+				testAPIResponse(t, rr, tt.want, tt.wantstat, false)
+				return
+			}
+			if rr.Code != tt.wantstat {
+				t.Fatalf("got response status %v; want %v", rr.Code, tt.wantstat)
+			}
+			var res dtos.ResGetAuditEvents
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Fatal("failed to unmarshal response")
+			}
+			if res.Total != tt.wanttotal || len(*res.Events) != tt.wanttotal {
+				t.Fatalf("got %v events (total %v); want %v", len(*res.Events), res.Total, tt.wanttotal)
+			}
+			for _, event := range *res.Events {
+				if event.UniverseID != universe.ID || event.ActorID == nil || *event.ActorID != userA.ID {
+					t.Errorf("got event %v by %v in %v; want an event by %v", event.Action, event.ActorID,
+						event.UniverseID, userA.ID)
+				}
+			}
+		})
+	}
+}
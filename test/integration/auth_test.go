@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func findCookie(name string, cookies []*http.Cookie) *http.Cookie {
@@ -69,16 +70,12 @@ func TestAuthRouter_LogIn(t *testing.T) {
 				if sesscookie == nil {
 					t.Fatalf("response did not get session cookie")
 				}
-				sess, err := server.Providers.Redis.Get(fmt.Sprintf("session:%v", sesscookie.Value)).Result()
+				sess, err := repos.Session.Find(sesscookie.Value, time.Hour)
 				if err != nil {
-					t.Fatalf("failed to get session from redis store")
+					t.Fatalf("failed to get session from the session store")
 				}
-				wantsess, err := json.Marshal(tt.want)
-				if err != nil {
-					t.Fatalf("failed to marshal wanted session")
-				}
-				if sess != string(wantsess) {
-					t.Errorf("got session %v; want %v", sess, string(wantsess))
+				if !compareUser(sess, tt.want.(*dtos.ResGetUser).User) {
+					t.Errorf("got session of %v; want %v", sess, tt.want.(*dtos.ResGetUser).User)
 				}
 			}
 		})
@@ -171,3 +168,28 @@ func TestAuthRouter_LogOut(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthRouter_DisabledSession(t *testing.T) {
+	user := server.Services.User.New(dtos.ReqCreateUser{
+		DisplayName: "trillian",
+		Email:       "trillian@heartofgold.com",
+		Password:    userAPassword})
+	if err := server.Services.User.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.NewRequest("GET", "/me", nil)
+	if err != nil {
+		t.Fatalf("failed to create request")
+	}
+	if _, err := loginUser(r, user); err != nil {
+		t.Fatalf("failed to login user")
+	}
+
+	// Sessions created before a user is disabled are rejected from then on
+	if err := server.Services.User.SetDisabled(user, true); err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, r)
+	testAPIResponse(t, rr, api.ErrCodeBadAuth, http.StatusUnauthorized, false)
+}
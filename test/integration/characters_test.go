@@ -0,0 +1,272 @@
+package integration
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/api/characters"
+	"cbs/dtos"
+	"cbs/models"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"testing"
+)
+
+// characterRequest creates a character creation request for the default universe guide
+func characterRequest(name string, hidden bool) dtos.ReqCreateCharacter {
+	return dtos.ReqCreateCharacter{
+		Name: name,
+		Fields: &dtos.ReqCharacterFields{Groups: map[string]dtos.ReqCharacterGroup{
+			"General": {Fields: map[string]dtos.ReqCharacterField{
+				"Biography": {Value: "A character named " + name, Type: models.GuideFieldDescription},
+			}},
+		}},
+		Meta: &dtos.ReqCharacterMeta{Hidden: hidden},
+	}
+}
+
+// multipartBody encodes form fields as a multipart form, returning the body and its content type
+func multipartBody(t *testing.T, fields map[string][]byte) (*bytes.Buffer, http.Header) {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		if err := writer.WriteField(name, string(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return body, http.Header{"Content-Type": {writer.FormDataContentType()}}
+}
+
+// postCharacter creates a character through the API on behalf of a user
+func postCharacter(t *testing.T, user *models.User, universe *models.Universe, payload dtos.ReqCreateCharacter) int {
+	serialized, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal("failed to marshal payload")
+	}
+	body, header := multipartBody(t, map[string][]byte{"data": serialized})
+	return userRequest(t, user, "POST", "/universes/"+universe.ID+"/characters", body, header).Code
+}
+
+func TestCharacterRouter_CreateCharacter(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	missing := characterRequest("Marvin", false)
+	missing.Fields.Groups["General"] = dtos.ReqCharacterGroup{Fields: map[string]dtos.ReqCharacterField{}}
+	tests := []struct {
+		name     string
+		user     *models.User
+		payload  dtos.ReqCreateCharacter
+		wantstat int
+	}{
+		{name: "owner", user: userA, payload: characterRequest("Arthur", false), wantstat: http.StatusCreated},
+		{name: "member", user: userB, payload: characterRequest("Ford", true), wantstat: http.StatusCreated},
+		{
+			name:     "duplicate",
+			user:     userA,
+			payload:  characterRequest("Arthur", false),
+			wantstat: http.StatusInternalServerError,
+		},
+		{name: "missing field", user: userA, payload: missing, wantstat: http.StatusBadRequest},
+		{name: "unauthenticated", payload: characterRequest("Zaphod", false), wantstat: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := postCharacter(t, tt.user, universe, tt.payload); status != tt.wantstat {
+				t.Errorf("got response status %v; want %v", status, tt.wantstat)
+			}
+		})
+	}
+}
+
+func TestCharacterRouter_GetCharacters(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	for _, payload := range []dtos.ReqCreateCharacter{
+		characterRequest("Trillian", false),
+		characterRequest("Slartibartfast", true),
+		characterRequest("Arthur Dent", false),
+	} {
+		if status := postCharacter(t, userA, universe, payload); status != http.StatusCreated {
+			t.Fatalf("failed to create character %v: got response status %v", payload.Name, status)
+		}
+	}
+	tests := []struct {
+		name  string
+		user  *models.User
+		query string
+		want  []string
+	}{
+		{name: "owner", user: userA, want: []string{"Arthur Dent", "Slartibartfast", "Trillian"}},
+		{name: "member", user: userB, want: []string{"Arthur Dent", "Trillian"}},
+		{name: "without hidden", user: userA, query: "?hidden=false", want: []string{"Arthur Dent", "Trillian"}},
+		{name: "search", user: userA, query: "?q=art+dent", want: []string{"Arthur Dent"}},
+		{name: "no match", user: userA, query: "?q=zaphod", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := userRequest(t, tt.user, "GET", "/universes/"+universe.ID+"/characters/"+tt.query, nil, nil)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+			}
+			var res dtos.ResGetCharacters
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Fatal("failed to unmarshal response")
+			}
+			names := make([]string, 0)
+			for _, c := range *res.Characters {
+				names = append(names, c.Name)
+			}
+			if len(names) != len(tt.want) || res.Total != len(tt.want) {
+				t.Fatalf("got characters %v of %v; want %v", names, res.Total, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Errorf("got characters %v; want %v", names, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCharacterRouter_ImportCharacters(t *testing.T) {
+	tests := []struct {
+		name        string
+		rows        []dtos.ReqCreateCharacter
+		mode        string
+		wantcreated int
+		wantstatus  models.CharacterImportStatus
+	}{
+		{
+			name:        "atomic",
+			rows:        []dtos.ReqCreateCharacter{characterRequest("Arthur", false), characterRequest("Ford", false)},
+			wantcreated: 2,
+			wantstatus:  models.CharacterImportCompleted,
+		},
+		{
+			name:        "atomic with duplicates",
+			rows:        []dtos.ReqCreateCharacter{characterRequest("Arthur", false), characterRequest("Arthur", false)},
+			wantcreated: 0,
+			wantstatus:  models.CharacterImportFailed,
+		},
+		{
+			name:        "partial with duplicates",
+			rows:        []dtos.ReqCreateCharacter{characterRequest("Arthur", false), characterRequest("Arthur", false)},
+			mode:        "partial",
+			wantcreated: 1,
+			wantstatus:  models.CharacterImportCompleted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			universe := createUniverse(t, userA)
+			serialized, err := json.Marshal(tt.rows)
+			if err != nil {
+				t.Fatal("failed to marshal rows")
+			}
+			body := new(bytes.Buffer)
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile("file", "characters.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write(serialized)
+			writer.Close()
+			header := http.Header{"Content-Type": {writer.FormDataContentType()}}
+			route := "/universes/" + universe.ID + "/characters/import?mode=" + tt.mode
+			rr := userRequest(t, userA, "POST", route, body, header)
+			var res dtos.ResGetCharacterImport
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Fatalf("failed to unmarshal response %v", rr.Body.String())
+			}
+			if res.CharacterImport.Status != tt.wantstatus || res.CharacterImport.Created != tt.wantcreated {
+				t.Errorf(
+					"got import %v with %v created; want %v with %v created",
+					res.CharacterImport.Status,
+					res.CharacterImport.Created,
+					tt.wantstatus,
+					tt.wantcreated,
+				)
+			}
+			ids, err := repos.Character.FindIDsByUniverse(universe.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != tt.wantcreated {
+				t.Errorf("got %v stored characters; want %v", len(ids), tt.wantcreated)
+			}
+		})
+	}
+}
+
+func TestCharacterRouter_EditCharacter(t *testing.T) {
+	universe := createUniverse(t, userA)
+	if status := postCharacter(t, userA, universe, characterRequest("Arthur", false)); status != http.StatusCreated {
+		t.Fatalf("failed to create character: got response status %v", status)
+	}
+	ids, err := repos.Character.FindIDsByUniverse(universe.ID)
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("failed to find created character: %v", err)
+	}
+	character, err := server.Services.Character.FindByID(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := character.ETag()
+	tests := []struct {
+		name     string
+		ifMatch  string
+		want     interface{}
+		wantstat int
+	}{
+		{name: "current", ifMatch: etag, wantstat: http.StatusOK},
+		{name: "stale", ifMatch: etag, want: api.ErrCodePrecondition, wantstat: http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := "/universes/" + universe.ID + "/characters/" + character.ID
+			header := http.Header{"Content-Type": {"application/merge-patch+json"}, "If-Match": {tt.ifMatch}}
+			rr := userRequest(t, userA, "PATCH", route, bytes.NewReader([]byte(`{"tag":"Earthman"}`)), header)
+			if tt.want != nil {
+				testAPIResponse(t, rr, tt.want, tt.wantstat, false)
+			} else if rr.Code != tt.wantstat {
+				t.Errorf("got response status %v (%v); want %v", rr.Code, rr.Body.String(), tt.wantstat)
+			}
+		})
+	}
+}
+
+func TestCharacterRouter_GetAvatar(t *testing.T) {
+	universe := createUniverse(t, userA)
+	character := createCharacter(t, userA, universe, characterRequest("Arthur", false))
+	for _, size := range []int{8, 16} {
+		if err := server.Services.Character.SetImage(character, "avatar", pngImage(t, size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Replacing the avatar keeps a single variant for each width
+	route := "/universes/" + universe.ID + "/characters/"
+	rr := userRequest(t, userA, "GET", route+character.ID, nil, nil)
+	var res dtos.ResGetCharacter
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	avatar := res.Images["avatar"]
+	if len(avatar.Variants) != len(characters.ImageWidths) || avatar.ContentType != "image/png" {
+		t.Fatalf("got avatar %+v; want a PNG variant for each of %v", avatar, characters.ImageWidths)
+	}
+	if avatar.URL != avatar.Variants["1024"] {
+		t.Errorf("got avatar URL %v; want the widest variant %v", avatar.URL, avatar.Variants["1024"])
+	}
+
+	// Lists reference the narrowest variant
+	rr = userRequest(t, userA, "GET", route, nil, nil)
+	var list dtos.ResGetCharacters
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	if got := (*list.Characters)[0].AvatarURL; got == nil || *got != avatar.Variants["64"] {
+		t.Errorf("got avatar URL %v; want the narrowest variant %v", got, avatar.Variants["64"])
+	}
+}
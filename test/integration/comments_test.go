@@ -0,0 +1,240 @@
+package integration
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// comment comments on a character through the API on behalf of a user
+func comment(
+	t *testing.T,
+	user *models.User,
+	character *models.Character,
+	payload dtos.ReqCreateComment,
+) *models.Comment {
+	serialized, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal("failed to marshal payload")
+	}
+	route := "/universes/" + character.UniverseID + "/characters/" + character.ID + "/comments"
+	rr := userRequest(t, user, "POST", route, bytes.NewReader(serialized), nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("failed to create comment: got response status %v (%v)", rr.Code, rr.Body.String())
+	}
+	var res dtos.ResGetComment
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	return res.Comment
+}
+
+// getThreads returns the comment threads of a character through the API on behalf of a user
+func getThreads(t *testing.T, user *models.User, character *models.Character, query string) []models.CommentThread {
+	route := "/universes/" + character.UniverseID + "/characters/" + character.ID + "/comments" + query
+	rr := userRequest(t, user, "GET", route, nil, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+	}
+	var res dtos.ResGetComments
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	return *res.Threads
+}
+
+func TestCommentRouter_Threads(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	character := createCharacter(t, userA, universe, characterRequest("Arthur", false))
+	webhook := createWebhook(t, userA, universe, dtos.ReqCreateWebhook{
+		URL: webhookURL,
+		Events: models.WebhookEvents{
+			models.EventCommentCreated,
+			models.EventCommentUpdated,
+			models.EventCommentDeleted,
+			models.EventCommentResolved,
+			models.EventCommentUnresolved,
+		},
+	})
+
+	// Mentions are read from the body, leaving out email addresses and names that are not collaborators
+	body := "Needs a *towel*, @Mark. Ask @ford or john@gmail.com"
+	thread := comment(t, userA, character, dtos.ReqCreateComment{Body: body})
+	reply := comment(t, userB, character, dtos.ReqCreateComment{ParentID: thread.ID, Body: "Added one"})
+
+	// Replying to a reply adds to the same thread
+	comment(t, userA, character, dtos.ReqCreateComment{ParentID: reply.ID, Body: "Thanks"})
+	threads := getThreads(t, userB, character, "")
+	if len(threads) != 1 || len(threads[0].Replies) != 2 {
+		t.Fatalf("got threads %+v; want a single thread with two replies", threads)
+	}
+	if threads[0].Author.ID != userA.ID || len(threads[0].Mentions) != 1 || threads[0].Mentions[0] != userB.ID {
+		t.Errorf("got thread %+v; want a thread by %s mentioning %s", threads[0].Comment, userA.ID, userB.ID)
+	}
+
+	route := "/universes/" + universe.ID + "/characters/" + character.ID + "/comments/"
+	rr := userRequest(t, userB, "POST", route+reply.ID+"/resolve", nil, nil)
+	testAPIResponse(t, rr, api.ErrCodeBadBody, http.StatusBadRequest, false)
+	if rr := userRequest(t, userB, "POST", route+thread.ID+"/resolve", nil, nil); rr.Code != http.StatusOK {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+	}
+	if threads := getThreads(t, userA, character, "?resolved=false"); len(threads) != 0 {
+		t.Errorf("got %d open threads; want 0", len(threads))
+	}
+	resolved := getThreads(t, userA, character, "?resolved=true")
+	if len(resolved) != 1 || resolved[0].ResolvedBy == nil || *resolved[0].ResolvedBy != userB.ID {
+		t.Errorf("got resolved threads %+v; want the thread resolved by %s", resolved, userB.ID)
+	}
+	if rr := userRequest(t, userA, "POST", route+thread.ID+"/unresolve", nil, nil); rr.Code != http.StatusOK {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+	}
+	if threads := getThreads(t, userA, character, "?resolved=false"); len(threads) != 1 {
+		t.Errorf("got %d open threads; want 1", len(threads))
+	}
+
+	// Editing a comment reads its mentions again
+	rr = userRequest(t, userA, "PATCH", route+thread.ID, bytes.NewReader([]byte(`{"body":"Found one"}`)), nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+	}
+	var edited dtos.ResGetComment
+	if err := json.Unmarshal(rr.Body.Bytes(), &edited); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	if len(edited.Mentions) != 0 {
+		t.Errorf("got mentions %v; want none", edited.Mentions)
+	}
+
+	// Deleting a thread deletes its replies
+	if rr := userRequest(t, userA, "DELETE", route+thread.ID, nil, nil); rr.Code != http.StatusNoContent {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusNoContent)
+	}
+	rr = userRequest(t, userB, "PATCH", route+reply.ID, bytes.NewReader([]byte(`{"body":"Gone"}`)), nil)
+	testAPIResponse(t, rr, api.ErrCodeNotFound, http.StatusNotFound, false)
+
+	// Every change to a comment is published
+	published := make(map[models.UniverseEventType]int)
+	for _, delivery := range getDeliveries(t, "/universes/"+universe.ID+"/webhooks/"+webhook.ID) {
+		published[delivery.EventType]++
+	}
+	want := map[models.UniverseEventType]int{
+		models.EventCommentCreated:    3,
+		models.EventCommentResolved:   1,
+		models.EventCommentUnresolved: 1,
+		models.EventCommentUpdated:    1,
+		models.EventCommentDeleted:    1,
+	}
+	for eventType, count := range want {
+		if published[eventType] != count {
+			t.Errorf("got %d %s events; want %d", published[eventType], eventType, count)
+		}
+	}
+}
+
+func TestCommentRouter_EditComment(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	character := createCharacter(t, userB, universe, characterRequest("Ford", false))
+	tests := []struct {
+		name     string
+		author   *models.User
+		user     *models.User
+		want     api.ErrorCode
+		wantstat int
+	}{
+		{name: "own comment", author: userB, user: userB, wantstat: http.StatusOK},
+		{
+			name:     "comment of another member",
+			author:   userA,
+			user:     userB,
+			want:     api.ErrCodeBadAuth,
+			wantstat: http.StatusUnauthorized,
+		},
+		{name: "comment of a member as owner", author: userB, user: userA, wantstat: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := comment(t, tt.author, character, dtos.ReqCreateComment{Body: "Mostly harmless"})
+			route := "/universes/" + universe.ID + "/characters/" + character.ID + "/comments/" + created.ID
+			rr := userRequest(t, tt.user, "PATCH", route, bytes.NewReader([]byte(`{"body":"Harmless"}`)), nil)
+			if tt.want != "" {
+				testAPIResponse(t, rr, tt.want, tt.wantstat, false)
+				rr = userRequest(t, tt.user, "DELETE", route, nil, nil)
+				testAPIResponse(t, rr, tt.want, tt.wantstat, false)
+				return
+			}
+			if rr.Code != tt.wantstat {
+				t.Errorf("got response status %v (%v); want %v", rr.Code, rr.Body.String(), tt.wantstat)
+			}
+			if rr := userRequest(t, tt.user, "DELETE", route, nil, nil); rr.Code != http.StatusNoContent {
+				t.Errorf("got response status %v; want %v", rr.Code, http.StatusNoContent)
+			}
+		})
+	}
+}
+
+func TestCommentRouter_Visibility(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	hidden := createCharacter(t, userA, universe, characterRequest("Zaphod", true))
+	elsewhere := createCharacter(t, userA, createUniverse(t, userA), characterRequest("Marvin", false))
+	comment(t, userA, hidden, dtos.ReqCreateComment{Body: "Two heads"})
+	tests := []struct {
+		name      string
+		user      *models.User
+		universe  *models.Universe
+		character *models.Character
+		method    string
+		body      string
+		want      api.ErrorCode
+		wantstat  int
+	}{
+		{
+			name:      "hidden character as member",
+			user:      userB,
+			universe:  universe,
+			character: hidden,
+			method:    "GET",
+			want:      api.ErrCodeBadAuth,
+			wantstat:  http.StatusUnauthorized,
+		},
+		{
+			name:      "comment on a hidden character as member",
+			user:      userB,
+			universe:  universe,
+			character: hidden,
+			method:    "POST",
+			body:      `{"body":"Hello"}`,
+			want:      api.ErrCodeBadAuth,
+			wantstat:  http.StatusUnauthorized,
+		},
+		{
+			name:      "mention a member on a hidden character",
+			user:      userA,
+			universe:  universe,
+			character: hidden,
+			method:    "POST",
+			body:      `{"body":"Hello @mark"}`,
+			want:      api.ErrCodeBadBody,
+			wantstat:  http.StatusBadRequest,
+		},
+		{
+			name:      "character of another universe",
+			user:      userB,
+			universe:  universe,
+			character: elsewhere,
+			method:    "GET",
+			want:      api.ErrCodeNotFound,
+			wantstat:  http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := "/universes/" + tt.universe.ID + "/characters/" + tt.character.ID + "/comments"
+			rr := userRequest(t, tt.user, tt.method, route, bytes.NewReader([]byte(tt.body)), nil)
+			testAPIResponse(t, rr, tt.want, tt.wantstat, false)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package integration
+
+import (
+	"cbs/models"
+	"cbs/repositories"
+	"testing"
+)
+
+// containsFile reports whether a reconciliation found a file either orphaned or missing
+func containsFile(reconciliation *models.ImageReconciliation, path string) bool {
+	for _, key := range reconciliation.OrphanedFiles {
+		if key == path {
+			return true
+		}
+	}
+	for _, record := range reconciliation.MissingFiles {
+		if record.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReconcileImages(t *testing.T) {
+	universe := createUniverse(t, userA)
+	kept := createCharacter(t, userA, universe, characterRequest("Arthur", false))
+	deleted := createCharacter(t, userA, universe, characterRequest("Ford", false))
+	for _, character := range []*models.Character{kept, deleted} {
+		if err := server.Services.Character.SetImage(character, "avatar", pngImage(t, 16)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The files of a deleted character are orphaned until their deletion job runs, and a variant whose file was
+	// lost is missing
+	orphaned, err := repos.Image.FindFiles(deleted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Services.Character.Delete(deleted); err != nil {
+		t.Fatal(err)
+	}
+	lost := repositories.ImagePrefix + kept.ID + "_avatar_64"
+	if err := repos.Image.DeleteFile(lost); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		reconciliation, err := server.Services.Character.ReconcileImages(0, dryRun)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range append([]string{lost}, orphaned...) {
+			if !containsFile(reconciliation, path) {
+				t.Errorf("got reconciliation %+v with dry run %v; want %s reported", reconciliation, dryRun, path)
+			}
+		}
+	}
+
+	// Once reconciled, the avatar keeps its stored variants and nothing is left to reconcile
+	reconciliation, err := server.Services.Character.ReconcileImages(0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reconciliation.OrphanedFiles) != 0 || len(reconciliation.MissingFiles) != 0 {
+		t.Errorf("got reconciliation %+v; want nothing left to reconcile", reconciliation)
+	}
+	character, err := server.Services.Character.FindByID(kept.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	avatar := character.Images["avatar"]
+	if _, ok := avatar.Variants["64"]; ok || len(avatar.Variants) != 2 || avatar.URL != avatar.Variants["1024"] {
+		t.Errorf("got avatar %+v; want the 256 and 1024 pixel variants", avatar)
+	}
+}
@@ -2,10 +2,24 @@ package integration
 
 import (
 	"cbs/api"
+	"cbs/api/audit"
 	"cbs/api/auth"
+	"cbs/api/characters"
+	"cbs/api/comments"
+	"cbs/api/jobs"
+	"cbs/api/relationships"
+	"cbs/api/shares"
+	"cbs/api/universes"
 	"cbs/api/users"
+	"cbs/api/webhooks"
 	"cbs/dtos"
+	"cbs/migrations"
 	"cbs/models"
+	"cbs/repositories"
+	"cbs/repositories/memory"
+	"cbs/repositories/postgres"
+	"cbs/repositories/redisstore"
+	"cbs/services"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -21,14 +35,22 @@ import (
 
 	"github.com/go-redis/redis"
 
-	"github.com/jinzhu/gorm"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/teris-io/shortid"
+	"gopkg.in/Masterminds/squirrel.v1"
 	yaml "gopkg.in/yaml.v2"
 )
 
 var (
-	configPath = flag.String("tc", "config.yaml", "Path to the testing configuration file")
+	configPath = flag.String("tc", "config.yaml", "Path to the testing configuration file, used with -live")
+	live       = flag.Bool("live", false, "Run against the Postgres database and Redis store of the testing configuration")
 
 	server *api.Server
+	repos  *repositories.Repositories
+
+	// serviceConfig represents the configuration of the services, which tests adjust to exercise quotas
+	serviceConfig *api.Config
 
 	// Test references
 	userA         *models.User
@@ -38,8 +60,17 @@ var (
 	userASessKey  string
 )
 
+// eventStub discards universe events, which are streamed through Redis
+type eventStub struct {
+	services.Event
+}
+
+func (eventStub) Publish(event *models.UniverseEvent) error {
+	return nil
+}
+
 func loadConfig(path string) (*api.Config, error) {
-	var config *api.Config
+	config := api.DefaultConfig()
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, errors.New("failed to read configuration file")
@@ -125,8 +156,14 @@ func testAPIResponse(t *testing.T, res *httptest.ResponseRecorder, want interfac
 	}
 }
 
-func migrateDatabase(db *gorm.DB) {
-	db.AutoMigrate(&models.User{})
+// migrateDatabase brings the test database to the latest schema using the same migrations as production
+func migrateDatabase(db *sqlx.DB) error {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up()
+	return err
 }
 
 func generateTestData() error {
@@ -135,53 +172,98 @@ func generateTestData() error {
 		DisplayName: "john",
 		Email:       "john@gmail.com",
 		Password:    userAPassword})
-	if err := server.Services.User.Save(userA); err != nil {
+	if err := server.Services.User.Create(userA); err != nil {
 		return err
 	}
 	userB = server.Services.User.New(dtos.ReqCreateUser{
 		DisplayName: "mark",
 		Email:       "mark@yahoo.com",
 		Password:    userBPassword})
-	if err := server.Services.User.Save(userB); err != nil {
+	if err := server.Services.User.Create(userB); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func setup() error {
-	// Parse the flags
-	flag.Parse()
-
-	// Read the configuration file
-	config, err := loadConfig(*configPath)
-	if err != nil {
-		return err
-	}
-
+// connectLive connects the providers to the Postgres database and Redis store of the testing configuration,
+// returning the repositories backed by them
+func connectLive(config *api.Config, providers *api.Providers) (*repositories.Repositories, error) {
 	// Start the database
-	db, err := gorm.Open("postgres", config.DatabaseURL)
+	db, err := sqlx.Connect("postgres", config.DatabaseURL)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := migrateDatabase(db); err != nil {
+		return nil, err
 	}
-	migrateDatabase(db)
 
 	// Connect to the Redis store
 	redisdb := redis.NewClient(&redis.Options{Addr: config.RedisURL, DB: 1})
 	if _, err := redisdb.Ping().Result(); err != nil {
+		return nil, err
+	}
+
+	providers.DB = db
+	providers.Redis = redisdb
+	live := postgres.New(db, squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar), nil)
+	live.Session = &redisstore.Sessions{Redis: redisdb}
+	live.Import = &redisstore.Imports{Redis: redisdb}
+	live.Job = &redisstore.Jobs{Redis: redisdb}
+	live.WebhookQueue = &redisstore.WebhookQueue{Redis: redisdb}
+	return live, nil
+}
+
+func setup() error {
+	// Parse the flags
+	flag.Parse()
+
+	sid, err := shortid.New(0, shortid.DefaultABC, 1)
+	if err != nil {
 		return err
 	}
+	providers := &api.Providers{ShortID: sid}
+
+	// Use the in-memory repositories unless the tests run against live services
+	config := api.DefaultConfig()
+	repos = memory.New()
+	if *live {
+		if config, err = loadConfig(*configPath); err != nil {
+			return err
+		}
+		if repos, err = connectLive(config, providers); err != nil {
+			return err
+		}
+	}
 
 	// Create the API server
-	providers := &api.Providers{DB: db, Redis: redisdb}
+	serviceConfig = config
 	services := &api.Services{
-		Auth: &auth.Service{Config: config, Providers: providers},
-		User: &users.Service{Config: config, Providers: providers},
+		Auth:         &auth.Service{Providers: providers, Repositories: repos, Config: config},
+		User:         &users.Service{Providers: providers, Repositories: repos, Config: config},
+		Universe:     &universes.Service{Providers: providers, Repositories: repos, Config: config},
+		Character:    &characters.Service{Providers: providers, Repositories: repos, Config: config},
+		Event:        eventStub{},
+		Webhook:      &webhooks.Service{Providers: providers, Repositories: repos, Config: config},
+		Audit:        &audit.Service{Providers: providers, Repositories: repos, Config: config},
+		Share:        &shares.Service{Providers: providers, Repositories: repos, Config: config},
+		Job:          &jobs.Service{Providers: providers, Repositories: repos, Config: config},
+		Relationship: &relationships.Service{Providers: providers, Repositories: repos, Config: config},
+		Comment:      &comments.Service{Providers: providers, Repositories: repos, Config: config},
 	}
 	server = api.NewServer(*config, providers, services)
 
 	// Mount the server routes
 	server.Mount("/users", users.NewRouter(server))
+	server.Mount("/universes", universes.NewRouter(server))
+	server.Mount("/universes/{universeID}/characters", characters.NewRouter(server))
+	server.Mount("/universes/{universeID}/webhooks", webhooks.NewRouter(server))
+	server.Mount("/universes/{universeID}/audit", audit.NewRouter(server))
+	server.Mount("/universes/{universeID}/shares", shares.NewRouter(server))
+	server.Mount("/universes/{universeID}/jobs", jobs.NewRouter(server))
+	server.Mount("/universes/{universeID}/relationships", relationships.NewRouter(server))
+	server.Mount("/universes/{universeID}/characters/{characterID}/comments", comments.NewRouter(server))
+	server.Mount("/shared/{token}", shares.NewPublicRouter(server))
 	server.Mount("/", auth.NewRouter(server))
 
 	// Generate the test data
@@ -193,7 +275,13 @@ func setup() error {
 }
 
 func teardown() error {
-	if err := server.Providers.DB.Delete(&models.User{}).Error; err != nil {
+	if !*live {
+		return nil
+	}
+	if _, err := server.Providers.DB.Exec("DELETE FROM universes"); err != nil {
+		return err
+	}
+	if _, err := server.Providers.DB.Exec("DELETE FROM users"); err != nil {
 		return err
 	}
 
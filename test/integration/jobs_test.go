@@ -0,0 +1,141 @@
+package integration
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/api/characters"
+	"cbs/dtos"
+	"cbs/models"
+	"cbs/repositories"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// runJobs runs the due background jobs the way the workers started by the server do
+func runJobs() {
+	server.Services.Job.RunDue(context.Background(), characters.JobHandlers(server))
+}
+
+// getJob returns the status of a job through the API on behalf of a user
+func getJob(t *testing.T, user *models.User, universe *models.Universe, id string) (*models.Job, int) {
+	rr := userRequest(t, user, "GET", "/universes/"+universe.ID+"/jobs/"+id, nil, nil)
+	if rr.Code != http.StatusOK {
+		return nil, rr.Code
+	}
+	var res dtos.ResGetJob
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	return res.Job, rr.Code
+}
+
+func TestJobRouter_Avatar(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	serialized, err := json.Marshal(characterRequest("Arthur", false))
+	if err != nil {
+		t.Fatal("failed to marshal payload")
+	}
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	if err := writer.WriteField("data", string(serialized)); err != nil {
+		t.Fatal(err)
+	}
+	part, err := writer.CreateFormFile("avatar", "avatar.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(pngImage(t, 16).Bytes())
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{"Content-Type": {writer.FormDataContentType()}}
+
+	// The character is created before its avatar is processed
+	rr := userRequest(t, userA, "POST", "/universes/"+universe.ID+"/characters", body, header)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusCreated)
+	}
+	var created dtos.ResGetCharacter
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	id := rr.Header().Get(api.JobHeader)
+	job, status := getJob(t, userA, universe, id)
+	if status != http.StatusOK || job.Status != models.JobPending || job.Type != models.JobImageProcess {
+		t.Fatalf("got job %+v with status %v; want a pending image job", job, status)
+	}
+	if len(job.Payload) != 0 {
+		t.Errorf("got job payload %s; want it hidden", job.Payload)
+	}
+	stored, err := repos.Job.FindByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(stored.Payload), "data") {
+		t.Errorf("got job payload %s; want only the key of the stored upload", stored.Payload)
+	}
+	upload := repositories.UploadPrefix + id
+	if _, err := repos.Image.FindUpload(upload); err != nil {
+		t.Errorf("got error %v reading the upload; want it stored until processed", err)
+	}
+
+	// Members only follow their own jobs
+	if _, status := getJob(t, userB, universe, id); status != http.StatusNotFound {
+		t.Errorf("got response status %v for the job of another member; want %v", status, http.StatusNotFound)
+	}
+
+	runJobs()
+	if job, _ := getJob(t, userA, universe, id); job.Status != models.JobSucceeded {
+		t.Errorf("got job status %v; want %v", job.Status, models.JobSucceeded)
+	}
+	character, err := server.Services.Character.FindByID(created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(character.Images["avatar"].Variants) != len(characters.ImageWidths) {
+		t.Errorf("got avatar %+v; want a variant for each of %v", character.Images["avatar"], characters.ImageWidths)
+	}
+	if _, err := repos.Image.FindUpload(upload); err != repositories.ErrNotFound {
+		t.Errorf("got error %v reading the processed upload; want %v", err, repositories.ErrNotFound)
+	}
+}
+
+func TestJobRouter_Export(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	createCharacter(t, userA, universe, characterRequest("Arthur", false))
+	createCharacter(t, userA, universe, characterRequest("Zaphod", true))
+	route := "/universes/" + universe.ID + "/"
+
+	rr := userRequest(t, userB, "POST", route+"characters/export?format=pdf", nil, nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got response status %v for an unsupported format; want %v", rr.Code, http.StatusBadRequest)
+	}
+	rr = userRequest(t, userB, "POST", route+"characters/export?format=csv", nil, nil)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusAccepted)
+	}
+	id := rr.Header().Get(api.JobHeader)
+	if rr := userRequest(t, userB, "GET", route+"jobs/"+id+"/result", nil, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("got response status %v before the export ran; want %v", rr.Code, http.StatusNotFound)
+	}
+
+	// The export keeps the visibility of the member who requested it
+	runJobs()
+	rr = userRequest(t, userB, "GET", route+"jobs/"+id+"/result", nil, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+	}
+	if got := rr.Header().Get("Content-Type"); got != characters.ExportContentTypes[dtos.CharacterExportFormatCSV] {
+		t.Errorf("got content type %v; want %v", got, characters.ExportContentTypes[dtos.CharacterExportFormatCSV])
+	}
+	if got := rr.Header().Get("Content-Disposition"); !strings.Contains(got, universe.ID+".csv") {
+		t.Errorf("got content disposition %v; want a download of %s.csv", got, universe.ID)
+	}
+	if !strings.Contains(rr.Body.String(), "Arthur") || strings.Contains(rr.Body.String(), "Zaphod") {
+		t.Errorf("got export %s; want only the visible character", rr.Body.String())
+	}
+}
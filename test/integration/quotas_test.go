@@ -0,0 +1,279 @@
+package integration
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// setQuota changes a quota of the services for the duration of a test
+func setQuota(t *testing.T, quota *int, limit int) {
+	previous := *quota
+	*quota = limit
+	t.Cleanup(func() {
+		*quota = previous
+	})
+}
+
+// pngImage encodes a blank image of the given size as a PNG file
+func pngImage(t *testing.T, size int) *bytes.Buffer {
+	buff := new(bytes.Buffer)
+	if err := png.Encode(buff, image.NewGray(image.Rect(0, 0, size, size))); err != nil {
+		t.Fatal(err)
+	}
+	return buff
+}
+
+func TestQuotas(t *testing.T) {
+	tests := []struct {
+		name    string
+		quota   *int
+		limit   int
+		prepare func(t *testing.T) *models.Universe
+		act     func(t *testing.T, universe *models.Universe) error
+	}{
+		{
+			name:  "universes per user",
+			quota: &serviceConfig.MaxUniversesPerUser,
+			prepare: func(t *testing.T) *models.Universe {
+				universes, err := repos.Universe.FindByCollaborator(userB.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				owned := 0
+				for _, u := range universes {
+					if u.Role == models.CollaboratorOwner {
+						owned++
+					}
+				}
+				setQuota(t, &serviceConfig.MaxUniversesPerUser, owned+1)
+				return createUniverse(t, userB)
+			},
+			act: func(t *testing.T, universe *models.Universe) error {
+				return server.Services.Universe.Create(
+					server.Services.Universe.New(dtos.ReqCreateUniverse{Name: "Magrathea"}),
+					userB,
+				)
+			},
+		},
+		{
+			name:  "transferred universes",
+			quota: &serviceConfig.MaxUniversesPerUser,
+			prepare: func(t *testing.T) *models.Universe {
+				universe := createUniverse(t, userA, userB)
+				createUniverse(t, userB)
+				universes, err := repos.Universe.FindByCollaborator(userB.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				owned := 0
+				for _, u := range universes {
+					if u.Role == models.CollaboratorOwner {
+						owned++
+					}
+				}
+				setQuota(t, &serviceConfig.MaxUniversesPerUser, owned)
+				return universe
+			},
+			act: func(t *testing.T, universe *models.Universe) error {
+				return server.Services.Universe.TransferOwnership(universe, userB)
+			},
+		},
+		{
+			name:  "characters per universe",
+			quota: &serviceConfig.MaxCharactersPerUniverse,
+			limit: 1,
+			prepare: func(t *testing.T) *models.Universe {
+				universe := createUniverse(t, userA)
+				createCharacter(t, userA, universe, characterRequest("Arthur", false))
+				return universe
+			},
+			act: func(t *testing.T, universe *models.Universe) error {
+				character := server.Services.Character.New(characterRequest("Ford", false))
+				_, err := server.Services.Character.Create(universe, character, userA)
+				return err
+			},
+		},
+		{
+			name:  "copied characters",
+			quota: &serviceConfig.MaxCharactersPerUniverse,
+			limit: 1,
+			prepare: func(t *testing.T) *models.Universe {
+				universe := createUniverse(t, userA)
+				createCharacter(t, userA, universe, characterRequest("Arthur", false))
+				createCharacter(t, userA, universe, characterRequest("Ford", false))
+				return universe
+			},
+			act: func(t *testing.T, universe *models.Universe) error {
+				_, err := server.Services.Character.CopyAll(universe, createUniverse(t, userA), userA)
+				return err
+			},
+		},
+		{
+			name:    "collaborators per universe",
+			quota:   &serviceConfig.MaxCollaboratorsPerUniverse,
+			limit:   1,
+			prepare: func(t *testing.T) *models.Universe { return createUniverse(t, userA) },
+			act: func(t *testing.T, universe *models.Universe) error {
+				_, err := server.Services.Universe.CreateCollaborator(universe, userB, models.CollaboratorMember)
+				return err
+			},
+		},
+		{
+			name:    "guide groups",
+			quota:   &serviceConfig.MaxGuideGroups,
+			limit:   1,
+			prepare: func(t *testing.T) *models.Universe { return createUniverse(t, userA) },
+			act: func(t *testing.T, universe *models.Universe) error {
+				universe.Guide = server.Services.Universe.Templates()[0].Guide
+				return server.Services.Universe.Update(universe, nil)
+			},
+		},
+		{
+			name:    "guide fields",
+			quota:   &serviceConfig.MaxGuideFields,
+			limit:   1,
+			prepare: func(t *testing.T) *models.Universe { return nil },
+			act: func(t *testing.T, universe *models.Universe) error {
+				template, err := server.Services.Universe.FindTemplate("sci-fi-crew")
+				if err != nil {
+					t.Fatal(err)
+				}
+				copied, err := server.Services.Universe.Copy(
+					dtos.ReqCreateUniverse{Name: "Heart of Gold"},
+					&models.Universe{Guide: template.Guide, Settings: template.Settings},
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return server.Services.Universe.Create(copied, userA)
+			},
+		},
+		{
+			name:  "image bytes per universe",
+			quota: &serviceConfig.MaxImageBytesPerUniverse,
+			limit: 1,
+			prepare: func(t *testing.T) *models.Universe {
+				universe := createUniverse(t, userA)
+				createCharacter(t, userA, universe, characterRequest("Arthur", false))
+				return universe
+			},
+			act: func(t *testing.T, universe *models.Universe) error {
+				ids, err := repos.Character.FindIDsByUniverse(universe.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				character, err := server.Services.Character.FindByID(ids[0])
+				if err != nil {
+					t.Fatal(err)
+				}
+				return server.Services.Character.SetImage(character, "avatar", pngImage(t, 8))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			universe := tt.prepare(t)
+			if tt.limit > 0 {
+				setQuota(t, tt.quota, tt.limit)
+			}
+			err := tt.act(t, universe)
+			apierr, ok := err.(api.Error)
+			if !ok || apierr.Code != api.ErrCodeQuota {
+				t.Fatalf("got error %v; want a %v error", err, api.ErrCodeQuota)
+			}
+
+			// Lifting the quota allows the action again
+			setQuota(t, tt.quota, 0)
+			if err := tt.act(t, universe); err != nil {
+				t.Errorf("got error %v without a quota; want none", err)
+			}
+		})
+	}
+}
+
+func TestQuotas_Concurrent(t *testing.T) {
+	setQuota(t, &serviceConfig.MaxCharactersPerUniverse, 3)
+	universe := createUniverse(t, userA)
+
+	// Characters created at the same time cannot exceed the quota together
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			character := server.Services.Character.New(characterRequest(fmt.Sprintf("Arthur %d", i), false))
+			server.Services.Character.Create(universe, character, userA)
+		}(i)
+	}
+	wg.Wait()
+	used, err := repos.Character.CountByUniverse(universe.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used != 3 {
+		t.Errorf("got %v characters; want 3", used)
+	}
+}
+
+func TestQuotas_ImportCharacters(t *testing.T) {
+	setQuota(t, &serviceConfig.MaxCharactersPerUniverse, 2)
+	created := createUniverse(t, userA)
+	createCharacter(t, userA, created, characterRequest("Arthur", false))
+
+	// Characters are validated against the guide as it is stored
+	universe, err := server.Services.Universe.FindByID(created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []dtos.ReqCreateCharacter{
+		characterRequest("Ford", false),
+		characterRequest("Trillian", false),
+		characterRequest("Zaphod", false),
+	}
+	for _, atomic := range []bool{true, false} {
+		job := server.Services.Character.NewImport(universe, userA, dtos.CharacterImportOptions{Atomic: atomic})
+		if err := server.Services.Character.Import(job, universe, userA, rows); err != nil {
+			t.Fatal(err)
+		}
+		wantcreated := 0
+		if !atomic {
+			wantcreated = 1
+		}
+		if job.Created != wantcreated || len(job.Errors) == 0 || job.Errors[0].Row != 2 {
+			t.Errorf("atomic %v: got %v created with errors %+v; want %v created and row 2 over the quota",
+				atomic, job.Created, job.Errors, wantcreated)
+		}
+	}
+}
+
+func TestUniverseRouter_GetUsage(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	character := createCharacter(t, userA, universe, characterRequest("Arthur", false))
+	if err := server.Services.Character.SetImage(character, "avatar", pngImage(t, 8)); err != nil {
+		t.Fatal(err)
+	}
+	rr := userRequest(t, userB, "GET", "/universes/"+universe.ID+"/usage", nil, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got response status %v (%v); want %v", rr.Code, rr.Body.String(), http.StatusOK)
+	}
+	var usage models.UniverseUsage
+	if err := json.Unmarshal(rr.Body.Bytes(), &usage); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	if usage.Characters.Used != 1 || usage.Collaborators.Used != 2 || usage.GuideGroups.Used != 1 ||
+		usage.GuideFields.Used != 1 || usage.ImageBytes.Used == 0 {
+		t.Errorf("got usage %+v; want 1 character, 2 collaborators, 1 group, 1 field and some image bytes", usage)
+	}
+	if usage.Characters.Limit != serviceConfig.MaxCharactersPerUniverse {
+		t.Errorf("got character limit %v; want %v", usage.Characters.Limit, serviceConfig.MaxCharactersPerUniverse)
+	}
+}
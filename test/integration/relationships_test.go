@@ -0,0 +1,223 @@
+package integration
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// relate creates a relationship of a type between two characters through the relationship service
+func relate(t *testing.T, universe *models.Universe, source, target *models.Character) *models.Relationship {
+	relationship, err := server.Services.Relationship.New(universe, dtos.ReqCreateRelationship{
+		SourceID: source.ID,
+		TargetID: target.ID,
+		Type:     "ally",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Services.Relationship.Create(relationship); err != nil {
+		t.Fatal(err)
+	}
+	return relationship
+}
+
+func TestRelationshipRouter_CreateRelationship(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	arthur := createCharacter(t, userA, universe, characterRequest("Arthur", false))
+	zaphod := createCharacter(t, userA, universe, characterRequest("Zaphod", true))
+	ford := createCharacter(t, userB, universe, characterRequest("Ford", false))
+	elsewhere := createCharacter(t, userA, createUniverse(t, userA), characterRequest("Marvin", false))
+	relate(t, universe, arthur, ford)
+	tests := []struct {
+		name     string
+		user     *models.User
+		payload  dtos.ReqCreateRelationship
+		want     api.ErrorCode
+		wantstat int
+	}{
+		{
+			name:     "as owner",
+			user:     userA,
+			payload:  dtos.ReqCreateRelationship{SourceID: arthur.ID, TargetID: zaphod.ID, Type: "family"},
+			wantstat: http.StatusCreated,
+		},
+		{
+			name:     "own character as member",
+			user:     userB,
+			payload:  dtos.ReqCreateRelationship{SourceID: ford.ID, TargetID: arthur.ID, Type: "rival"},
+			wantstat: http.StatusCreated,
+		},
+		{
+			name:     "character of another user as member",
+			user:     userB,
+			payload:  dtos.ReqCreateRelationship{SourceID: arthur.ID, TargetID: ford.ID, Type: "rival"},
+			want:     api.ErrCodeBadAuth,
+			wantstat: http.StatusUnauthorized,
+		},
+		{
+			name:     "hidden character as member",
+			user:     userB,
+			payload:  dtos.ReqCreateRelationship{SourceID: ford.ID, TargetID: zaphod.ID, Type: "ally"},
+			want:     api.ErrCodeBadAuth,
+			wantstat: http.StatusUnauthorized,
+		},
+		{
+			name:     "character of another universe",
+			user:     userA,
+			payload:  dtos.ReqCreateRelationship{SourceID: arthur.ID, TargetID: elsewhere.ID, Type: "ally"},
+			want:     api.ErrCodeNotFound,
+			wantstat: http.StatusNotFound,
+		},
+		{
+			name:     "unknown type",
+			user:     userA,
+			payload:  dtos.ReqCreateRelationship{SourceID: arthur.ID, TargetID: zaphod.ID, Type: "nemesis"},
+			want:     api.ErrCodeBadBody,
+			wantstat: http.StatusBadRequest,
+		},
+		{
+			name:     "duplicate in the other direction",
+			user:     userB,
+			payload:  dtos.ReqCreateRelationship{SourceID: ford.ID, TargetID: arthur.ID, Type: "ally"},
+			want:     api.ErrCodeBadBody,
+			wantstat: http.StatusBadRequest,
+		},
+		{
+			name:     "same character",
+			user:     userA,
+			payload:  dtos.ReqCreateRelationship{SourceID: arthur.ID, TargetID: arthur.ID, Type: "ally"},
+			want:     api.ErrCodeBadBody,
+			wantstat: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serialized, err := json.Marshal(tt.payload)
+			if err != nil {
+				t.Fatal("failed to marshal payload")
+			}
+			route := "/universes/" + universe.ID + "/relationships"
+			rr := userRequest(t, tt.user, "POST", route, bytes.NewReader(serialized), nil)
+			if tt.want != "" {
+				testAPIResponse(t, rr, tt.want, tt.wantstat, false)
+				return
+			}
+			if rr.Code != tt.wantstat {
+				t.Fatalf("got response status %v (%v); want %v", rr.Code, rr.Body.String(), tt.wantstat)
+			}
+		})
+	}
+}
+
+func TestRelationshipRouter_EditRelationship(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	arthur := createCharacter(t, userA, universe, characterRequest("Arthur", false))
+	ford := createCharacter(t, userB, universe, characterRequest("Ford", false))
+	relationship := relate(t, universe, arthur, ford)
+	route := "/universes/" + universe.ID + "/relationships/" + relationship.ID
+	body := []byte(`{"type":"rival","bidirectional":true}`)
+
+	// Members can view the relationships of others' characters, but only edit those of their own
+	if rr := userRequest(t, userB, "GET", route, nil, nil); rr.Code != http.StatusOK {
+		t.Errorf("got response status %v; want %v", rr.Code, http.StatusOK)
+	}
+	rr := userRequest(t, userB, "PATCH", route, bytes.NewReader(body), nil)
+	testAPIResponse(t, rr, api.ErrCodeBadAuth, http.StatusUnauthorized, false)
+	rr = userRequest(t, userB, "DELETE", route, nil, nil)
+	testAPIResponse(t, rr, api.ErrCodeBadAuth, http.StatusUnauthorized, false)
+
+	rr = userRequest(t, userA, "PATCH", route, bytes.NewReader(body), nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+	}
+	var edited dtos.ResGetRelationship
+	if err := json.Unmarshal(rr.Body.Bytes(), &edited); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	if edited.Type != "rival" || !edited.Bidirectional {
+		t.Errorf("got relationship %+v; want a bidirectional rival relationship", edited.Relationship)
+	}
+	if rr := userRequest(t, userA, "DELETE", route, nil, nil); rr.Code != http.StatusNoContent {
+		t.Errorf("got response status %v; want %v", rr.Code, http.StatusNoContent)
+	}
+	rr = userRequest(t, userA, "GET", route, nil, nil)
+	testAPIResponse(t, rr, api.ErrCodeNotFound, http.StatusNotFound, false)
+}
+
+func TestRelationshipRouter_GetGraph(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	arthur := createCharacter(t, userA, universe, characterRequest("Arthur", false))
+	ford := createCharacter(t, userA, universe, characterRequest("Ford", false))
+	trillian := createCharacter(t, userA, universe, characterRequest("Trillian", false))
+	zaphod := createCharacter(t, userA, universe, characterRequest("Zaphod", true))
+	marvin := createCharacter(t, userA, universe, characterRequest("Marvin", false))
+
+	// Arthur - Ford - Trillian, while Marvin is only reached through the hidden Zaphod
+	relate(t, universe, arthur, ford)
+	relate(t, universe, ford, trillian)
+	relate(t, universe, arthur, zaphod)
+	relate(t, universe, zaphod, marvin)
+	relate(t, universe, zaphod, ford)
+	tests := []struct {
+		name      string
+		user      *models.User
+		depth     int
+		wantNodes []string
+		wantEdges int
+	}{
+		{name: "one hop", user: userA, depth: 1, wantNodes: []string{"Arthur", "Ford", "Zaphod"}, wantEdges: 3},
+		{
+			name:      "two hops",
+			user:      userA,
+			depth:     2,
+			wantNodes: []string{"Arthur", "Ford", "Marvin", "Trillian", "Zaphod"},
+			wantEdges: 5,
+		},
+		{name: "one hop as member", user: userB, depth: 1, wantNodes: []string{"Arthur", "Ford"}, wantEdges: 1},
+		{
+			name:      "three hops as member",
+			user:      userB,
+			depth:     3,
+			wantNodes: []string{"Arthur", "Ford", "Trillian"},
+			wantEdges: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := "/universes/" + universe.ID + "/relationships/graph/" + arthur.ID
+			rr := userRequest(t, tt.user, "GET", route+"?depth="+strconv.Itoa(tt.depth), nil, nil)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+			}
+			var graph dtos.ResGetRelationshipGraph
+			if err := json.Unmarshal(rr.Body.Bytes(), &graph); err != nil {
+				t.Fatal("failed to unmarshal response")
+			}
+			names := make([]string, 0, len(graph.Nodes))
+			for _, node := range graph.Nodes {
+				names = append(names, node.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.wantNodes) {
+				t.Errorf("got nodes %v; want %v", names, tt.wantNodes)
+			}
+			if len(graph.Edges) != tt.wantEdges {
+				t.Errorf("got %d edges; want %d", len(graph.Edges), tt.wantEdges)
+			}
+		})
+	}
+
+	route := "/universes/" + universe.ID + "/relationships/graph/"
+	rr := userRequest(t, userA, "GET", route+arthur.ID+"?depth=4", nil, nil)
+	testAPIResponse(t, rr, api.ErrCodeBadBody, http.StatusBadRequest, false)
+	rr = userRequest(t, userB, "GET", route+zaphod.ID, nil, nil)
+	testAPIResponse(t, rr, api.ErrCodeBadAuth, http.StatusUnauthorized, false)
+}
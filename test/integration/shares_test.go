@@ -0,0 +1,216 @@
+package integration
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// createCharacter creates a character owned by a user through the character service
+func createCharacter(
+	t *testing.T,
+	user *models.User,
+	universe *models.Universe,
+	payload dtos.ReqCreateCharacter,
+) *models.Character {
+	saved, err := server.Services.Character.Create(universe, server.Services.Character.New(payload), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return saved
+}
+
+// createShare creates a share link through the API
+func createShare(
+	t *testing.T,
+	user *models.User,
+	universe *models.Universe,
+	payload dtos.ReqCreateShare,
+) *models.Share {
+	serialized, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal("failed to marshal payload")
+	}
+	rr := userRequest(t, user, "POST", "/universes/"+universe.ID+"/shares", bytes.NewReader(serialized), nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("failed to create share: got response status %v (%v)", rr.Code, rr.Body.String())
+	}
+	var share models.Share
+	if err := json.Unmarshal(rr.Body.Bytes(), &share); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	return &share
+}
+
+func TestShareRouter_CreateShare(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	characterA := createCharacter(t, userA, universe, characterRequest("Arthur", false))
+	characterB := createCharacter(t, userB, universe, characterRequest("Ford", false))
+	elsewhere := createCharacter(t, userA, createUniverse(t, userA), characterRequest("Zaphod", false))
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name     string
+		user     *models.User
+		payload  dtos.ReqCreateShare
+		want     api.ErrorCode
+		wantstat int
+	}{
+		{name: "universe as owner", user: userA, wantstat: http.StatusCreated},
+		{name: "universe as member", user: userB, want: api.ErrCodeBadAuth, wantstat: http.StatusUnauthorized},
+		{
+			name:     "own character as member",
+			user:     userB,
+			payload:  dtos.ReqCreateShare{CharacterID: characterB.ID},
+			wantstat: http.StatusCreated,
+		},
+		{
+			name:     "character of another user as member",
+			user:     userB,
+			payload:  dtos.ReqCreateShare{CharacterID: characterA.ID},
+			want:     api.ErrCodeBadAuth,
+			wantstat: http.StatusUnauthorized,
+		},
+		{
+			name:     "character of another universe",
+			user:     userA,
+			payload:  dtos.ReqCreateShare{CharacterID: elsewhere.ID},
+			want:     api.ErrCodeNotFound,
+			wantstat: http.StatusNotFound,
+		},
+		{
+			name:     "past expiry",
+			user:     userA,
+			payload:  dtos.ReqCreateShare{ExpiresAt: &past},
+			want:     api.ErrCodeBadBody,
+			wantstat: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serialized, err := json.Marshal(tt.payload)
+			if err != nil {
+				t.Fatal("failed to marshal payload")
+			}
+			rr := userRequest(t, tt.user, "POST", "/universes/"+universe.ID+"/shares", bytes.NewReader(serialized), nil)
+			if tt.want != "" {
+				testAPIResponse(t, rr, tt.want, tt.wantstat, false)
+				return
+			}
+			if rr.Code != tt.wantstat {
+				t.Fatalf("got response status %v (%v); want %v", rr.Code, rr.Body.String(), tt.wantstat)
+			}
+		})
+	}
+
+	// Members only list the share links they created
+	for _, tt := range []struct {
+		user *models.User
+		want int
+	}{{user: userA, want: 2}, {user: userB, want: 1}} {
+		rr := userRequest(t, tt.user, "GET", "/universes/"+universe.ID+"/shares", nil, nil)
+		var res struct {
+			Shares []models.Share `json:"shares"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+			t.Fatal("failed to unmarshal response")
+		}
+		if len(res.Shares) != tt.want {
+			t.Errorf("%v got %v shares; want %v", tt.user.DisplayName, len(res.Shares), tt.want)
+		}
+	}
+}
+
+func TestShareRouter_GetShared(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	secret := characterRequest("Arthur", false)
+	secret.Fields.Groups["General"].Fields["Biography"] = dtos.ReqCharacterField{
+		Value:  "Secretly a sandwich maker",
+		Type:   models.GuideFieldDescription,
+		Hidden: true,
+	}
+	visible := createCharacter(t, userA, universe, secret)
+	hidden := createCharacter(t, userA, universe, characterRequest("Ford", true))
+	universeShare := createShare(t, userA, universe, dtos.ReqCreateShare{})
+	characterShare := createShare(t, userA, universe, dtos.ReqCreateShare{CharacterID: visible.ID})
+	hiddenShare := createShare(t, userA, universe, dtos.ReqCreateShare{CharacterID: hidden.ID})
+	deletedShare := createShare(t, userA, universe, dtos.ReqCreateShare{})
+	rr := userRequest(t, userA, "DELETE", "/universes/"+universe.ID+"/shares/"+deletedShare.ID, nil, nil)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("failed to delete share: got response status %v", rr.Code)
+	}
+	expiredShare := createShare(t, userA, universe, dtos.ReqCreateShare{})
+	expired := time.Now().Add(-time.Minute)
+	expiredShare.ID += "-expired"
+	expiredShare.Token += "-expired"
+	expiredShare.ExpiresAt = &expired
+	if err := repos.Share.Create(expiredShare); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		route     string
+		wantstat  int
+		wantchars int
+	}{
+		{name: "universe", route: "/shared/" + universeShare.Token, wantstat: http.StatusOK},
+		{
+			name:      "universe characters",
+			route:     "/shared/" + universeShare.Token + "/characters",
+			wantstat:  http.StatusOK,
+			wantchars: 1,
+		},
+		{
+			name:     "universe character",
+			route:    "/shared/" + universeShare.Token + "/characters/" + visible.ID,
+			wantstat: http.StatusOK,
+		},
+		{
+			name:     "hidden character",
+			route:    "/shared/" + universeShare.Token + "/characters/" + hidden.ID,
+			wantstat: http.StatusNotFound,
+		},
+		{name: "character", route: "/shared/" + characterShare.Token, wantstat: http.StatusOK},
+		{
+			name:     "character characters",
+			route:    "/shared/" + characterShare.Token + "/characters",
+			wantstat: http.StatusNotFound,
+		},
+		{
+			name:     "other character",
+			route:    "/shared/" + characterShare.Token + "/characters/" + hidden.ID,
+			wantstat: http.StatusNotFound,
+		},
+		{name: "hidden shared character", route: "/shared/" + hiddenShare.Token, wantstat: http.StatusNotFound},
+		{name: "deleted", route: "/shared/" + deletedShare.Token, wantstat: http.StatusNotFound},
+		{name: "expired", route: "/shared/" + expiredShare.Token, wantstat: http.StatusNotFound},
+		{name: "unknown", route: "/shared/unknown", wantstat: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := userRequest(t, nil, "GET", tt.route, nil, nil)
+			if rr.Code != tt.wantstat {
+				t.Fatalf("got response status %v (%v); want %v", rr.Code, rr.Body.String(), tt.wantstat)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			body := rr.Body.Bytes()
+			if bytes.Contains(body, []byte("sandwich")) || bytes.Contains(body, []byte(userA.Email)) {
+				t.Errorf("got hidden field or owner email in response %v", rr.Body.String())
+			}
+			var res dtos.ResGetCharacters
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Fatal("failed to unmarshal response")
+			}
+			if res.Characters != nil && len(*res.Characters) != tt.wantchars {
+				t.Errorf("got %v characters; want %v", len(*res.Characters), tt.wantchars)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package integration
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// userRequest sends a request to the API on behalf of a user, or anonymously when user is nil
+func userRequest(
+	t *testing.T,
+	user *models.User,
+	method, route string,
+	body io.Reader,
+	header http.Header,
+) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(method, route, body)
+	if err != nil {
+		t.Fatal("failed to create request")
+	}
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	if user != nil {
+		if _, err := loginUser(r, user); err != nil {
+			t.Fatal("failed to login user")
+		}
+	}
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, r)
+	return rr
+}
+
+// createUniverse creates a universe owned by a user, along with its collaborators
+func createUniverse(t *testing.T, owner *models.User, members ...*models.User) *models.Universe {
+	universe := server.Services.Universe.New(dtos.ReqCreateUniverse{Name: "Earth"})
+	if err := server.Services.Universe.Create(universe, owner); err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range members {
+		if _, err := server.Services.Universe.CreateCollaborator(
+			universe,
+			member,
+			models.CollaboratorMember,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return universe
+}
+
+func TestUniverseRouter_CreateUniverse(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     *models.User
+		payload  dtos.ReqCreateUniverse
+		want     interface{}
+		wantstat int
+	}{
+		{
+			name:     "create universe",
+			user:     userA,
+			payload:  dtos.ReqCreateUniverse{Name: "Middle-earth", Description: "A fantasy world"},
+			wantstat: http.StatusCreated,
+		},
+		{
+			name:     "no name",
+			user:     userA,
+			payload:  dtos.ReqCreateUniverse{Description: "A fantasy world"},
+			want:     api.ErrCodeBadBody,
+			wantstat: http.StatusBadRequest,
+		},
+		{
+			name:     "unauthenticated",
+			payload:  dtos.ReqCreateUniverse{Name: "Middle-earth"},
+			want:     api.ErrCodeBadAuth,
+			wantstat: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serialized, err := json.Marshal(tt.payload)
+			if err != nil {
+				t.Fatal("failed to marshal payload")
+			}
+			rr := userRequest(t, tt.user, "POST", "/universes", bytes.NewReader(serialized), nil)
+			if tt.want != nil {
+				testAPIResponse(t, rr, tt.want, tt.wantstat, false)
+				return
+			}
+			if rr.Code != tt.wantstat {
+				t.Fatalf("got response status %v; want %v", rr.Code, tt.wantstat)
+			}
+			var created models.Universe
+			if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+				t.Fatal("failed to unmarshal response")
+			}
+			collaborator, err := server.Services.Universe.FindCollaboratorByID(created.ID, tt.user.ID)
+			if err != nil {
+				t.Fatalf("creator is not a collaborator: %v", err)
+			}
+			if collaborator.Role != models.CollaboratorOwner {
+				t.Errorf("got creator role %v; want %v", collaborator.Role, models.CollaboratorOwner)
+			}
+		})
+	}
+}
+
+func TestUniverseRouter_EditUniverse(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	tests := []struct {
+		name     string
+		user     *models.User
+		ifMatch  string
+		version  int
+		want     interface{}
+		wantstat int
+		wantver  int
+	}{
+		{
+			name:     "owner",
+			user:     userA,
+			ifMatch:  universe.ETag(),
+			wantstat: http.StatusOK,
+			wantver:  2,
+		},
+		{
+			name:     "stale",
+			user:     userA,
+			ifMatch:  universe.ETag(),
+			want:     api.ErrCodePrecondition,
+			wantstat: http.StatusPreconditionFailed,
+			wantver:  2,
+		},
+		{
+			name:     "stale version",
+			user:     userA,
+			want:     api.ErrCodePrecondition,
+			wantstat: http.StatusPreconditionFailed,
+			wantver:  2,
+		},
+		{
+			name:     "fabricated version",
+			user:     userA,
+			version:  99,
+			want:     api.ErrCodePrecondition,
+			wantstat: http.StatusPreconditionFailed,
+			wantver:  2,
+		},
+		{
+			name:     "member",
+			user:     userB,
+			want:     api.ErrCodeBadAuth,
+			wantstat: http.StatusUnauthorized,
+			wantver:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited := *universe
+			edited.Description = "Mostly harmless"
+			if tt.version != 0 {
+				edited.Version = tt.version
+			}
+			serialized, err := json.Marshal(edited)
+			if err != nil {
+				t.Fatal("failed to marshal payload")
+			}
+			header := http.Header{}
+			if tt.ifMatch != "" {
+				header.Set("If-Match", tt.ifMatch)
+			}
+			rr := userRequest(t, tt.user, "PATCH", "/universes/"+universe.ID, bytes.NewReader(serialized), header)
+			if tt.want != nil {
+				testAPIResponse(t, rr, tt.want, tt.wantstat, false)
+			} else if rr.Code != tt.wantstat {
+				t.Errorf("got response status %v; want %v", rr.Code, tt.wantstat)
+			}
+			saved, err := server.Services.Universe.FindByID(universe.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved.Version != tt.wantver {
+				t.Errorf("got version %v; want %v", saved.Version, tt.wantver)
+			}
+		})
+	}
+}
+
+func TestUniverseRouter_GetCollaborators(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	rr := userRequest(t, userB, "GET", "/universes/"+universe.ID+"/collaborators", nil, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+	}
+	var res struct {
+		Collaborators []models.Collaborator `json:"collaborators"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	roles := make(map[string]models.CollaboratorRole)
+	for _, c := range res.Collaborators {
+		roles[c.User.ID] = c.Role
+	}
+	want := map[string]models.CollaboratorRole{userA.ID: models.CollaboratorOwner, userB.ID: models.CollaboratorMember}
+	if len(roles) != len(want) || roles[userA.ID] != want[userA.ID] || roles[userB.ID] != want[userB.ID] {
+		t.Errorf("got collaborators %v; want %v", roles, want)
+	}
+}
+
+func TestUniverseRouter_CopyUniverse(t *testing.T) {
+	source := createUniverse(t, userA, userB)
+	source.Guide = server.Services.Universe.Templates()[0].Guide
+	if err := server.Services.Universe.Update(source, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Arthur", "Ford"} {
+		row := characterRequest(name, name == "Ford")
+		row.Fields.Groups = nil
+		character := server.Services.Character.New(row)
+		if _, err := server.Services.Character.Create(source, character, userA); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name      string
+		user      *models.User
+		payload   dtos.ReqCreateUniverse
+		want      interface{}
+		wantstat  int
+		wantchars int
+	}{
+		{
+			name:     "template",
+			user:     userB,
+			payload:  dtos.ReqCreateUniverse{Name: "Copy", Template: "sci-fi-crew"},
+			wantstat: http.StatusCreated,
+		},
+		{
+			name:     "unknown template",
+			user:     userB,
+			payload:  dtos.ReqCreateUniverse{Name: "Copy", Template: "western"},
+			want:     api.ErrCodeBadBody,
+			wantstat: http.StatusBadRequest,
+		},
+		{
+			name:     "guide",
+			user:     userB,
+			payload:  dtos.ReqCreateUniverse{Name: "Copy", Source: source.ID},
+			wantstat: http.StatusCreated,
+		},
+		{
+			name:      "guide and characters",
+			user:      userA,
+			payload:   dtos.ReqCreateUniverse{Name: "Copy", Source: source.ID, IncludeCharacters: true},
+			wantstat:  http.StatusCreated,
+			wantchars: 2,
+		},
+		{
+			name:     "characters as member",
+			user:     userB,
+			payload:  dtos.ReqCreateUniverse{Name: "Copy", Source: source.ID, IncludeCharacters: true},
+			want:     api.ErrCodeBadAuth,
+			wantstat: http.StatusUnauthorized,
+		},
+		{
+			name:     "source and template",
+			user:     userA,
+			payload:  dtos.ReqCreateUniverse{Name: "Copy", Source: source.ID, Template: "sci-fi-crew"},
+			want:     api.ErrCodeBadBody,
+			wantstat: http.StatusBadRequest,
+		},
+		{
+			name:     "not collaborating",
+			user:     userB,
+			payload:  dtos.ReqCreateUniverse{Name: "Copy", Source: createUniverse(t, userA).ID},
+			want:     api.ErrCodeNotFound,
+			wantstat: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serialized, err := json.Marshal(tt.payload)
+			if err != nil {
+				t.Fatal("failed to marshal payload")
+			}
+			rr := userRequest(t, tt.user, "POST", "/universes", bytes.NewReader(serialized), nil)
+			if tt.want != nil {
+				testAPIResponse(t, rr, tt.want, tt.wantstat, false)
+				return
+			}
+			if rr.Code != tt.wantstat {
+				t.Fatalf("got response status %v (%v); want %v", rr.Code, rr.Body.String(), tt.wantstat)
+			}
+			var created models.Universe
+			if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+				t.Fatal("failed to unmarshal response")
+			}
+			wantGuide := source.Guide
+			if tt.payload.Template != "" {
+				template, _ := server.Services.Universe.FindTemplate(tt.payload.Template)
+				wantGuide = template.Guide
+			}
+			if len(*created.Guide.Groups) != len(*wantGuide.Groups) {
+				t.Errorf("got %v guide groups; want %v", len(*created.Guide.Groups), len(*wantGuide.Groups))
+			}
+			ids, err := repos.Character.FindIDsByUniverse(created.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != tt.wantchars {
+				t.Errorf("got %v copied characters; want %v", len(ids), tt.wantchars)
+			}
+		})
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	if err := server.Services.Universe.TransferOwnership(universe, userB); err != nil {
+		t.Fatal(err)
+	}
+	for user, want := range map[*models.User]models.CollaboratorRole{
+		userA: models.CollaboratorAdmin,
+		userB: models.CollaboratorOwner,
+	} {
+		collaborator, err := server.Services.Universe.FindCollaboratorByID(universe.ID, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if collaborator.Role != want {
+			t.Errorf("got role %v for %v; want %v", collaborator.Role, user.ID, want)
+		}
+	}
+
+	// Both role changes are audited, and transferring to the owner changes nothing
+	if err := server.Services.Universe.TransferOwnership(universe, userB); err != nil {
+		t.Fatal(err)
+	}
+	events, total, err := repos.Audit.FindByUniverse(
+		universe.ID,
+		dtos.AuditQuery{Action: models.AuditCollaboratorUpdate},
+		10,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Fatalf("got %v audited role changes; want 2", total)
+	}
+	for _, event := range events {
+		if event.ActorID != nil || event.TargetType != models.AuditTargetCollaborator {
+			t.Errorf("got audit event %+v; want a collaborator update without an actor", event)
+		}
+	}
+}
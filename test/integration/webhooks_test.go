@@ -0,0 +1,196 @@
+package integration
+
+import (
+	"bytes"
+	"cbs/api"
+	"cbs/dtos"
+	"cbs/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// webhookURL represents a public address webhooks are registered with, which is never contacted by these tests
+const webhookURL = "http://93.184.216.34/hooks"
+
+// createWebhook registers a webhook to a universe through the API on behalf of a user
+func createWebhook(
+	t *testing.T,
+	user *models.User,
+	universe *models.Universe,
+	payload dtos.ReqCreateWebhook,
+) dtos.ResCreateWebhook {
+	serialized, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal("failed to marshal payload")
+	}
+	rr := userRequest(t, user, "POST", "/universes/"+universe.ID+"/webhooks", bytes.NewReader(serialized), nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusCreated)
+	}
+	var created dtos.ResCreateWebhook
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	return created
+}
+
+func TestWebhookRouter_CreateWebhook(t *testing.T) {
+	universe := createUniverse(t, userA, userB)
+	tests := []struct {
+		name     string
+		user     *models.User
+		payload  dtos.ReqCreateWebhook
+		want     interface{}
+		wantstat int
+	}{
+		{
+			name: "private address",
+			user: userA,
+			payload: dtos.ReqCreateWebhook{
+				URL:    "http://127.0.0.1/hooks",
+				Events: models.WebhookEvents{models.EventCharacterCreated},
+			},
+			want:     api.ErrCodeBadBody,
+			wantstat: http.StatusBadRequest,
+		},
+		{
+			name: "unsupported scheme",
+			user: userA,
+			payload: dtos.ReqCreateWebhook{
+				URL:    "ftp://93.184.216.34/hooks",
+				Events: models.WebhookEvents{models.EventCharacterCreated},
+			},
+			want:     api.ErrCodeBadBody,
+			wantstat: http.StatusBadRequest,
+		},
+		{
+			name: "not owner",
+			user: userB,
+			payload: dtos.ReqCreateWebhook{
+				URL:    webhookURL,
+				Events: models.WebhookEvents{models.EventCharacterCreated},
+			},
+			want:     api.ErrCodeBadAuth,
+			wantstat: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serialized, err := json.Marshal(tt.payload)
+			if err != nil {
+				t.Fatal("failed to marshal payload")
+			}
+			rr := userRequest(t, tt.user, "POST", "/universes/"+universe.ID+"/webhooks", bytes.NewReader(serialized), nil)
+			testAPIResponse(t, rr, tt.want, tt.wantstat, false)
+		})
+	}
+}
+
+func TestWebhookRouter_Lifecycle(t *testing.T) {
+	universe := createUniverse(t, userA)
+	created := createWebhook(t, userA, universe, dtos.ReqCreateWebhook{
+		URL:    webhookURL,
+		Events: models.WebhookEvents{models.EventCharacterCreated},
+	})
+	if created.Secret == "" {
+		t.Fatal("got no secret on creation")
+	}
+	route := "/universes/" + universe.ID + "/webhooks/" + created.ID
+
+	// The secret is never sent again once the webhook is created
+	rr := userRequest(t, userA, "GET", route, nil, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+	}
+	if strings.Contains(rr.Body.String(), created.Secret) {
+		t.Error("got secret in webhook response")
+	}
+
+	// Rotating the secret replaces it with a new one
+	rr = userRequest(t, userA, "POST", route+"/secret", nil, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+	}
+	var rotated dtos.ResCreateWebhook
+	if err := json.Unmarshal(rr.Body.Bytes(), &rotated); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	if rotated.Secret == "" || rotated.Secret == created.Secret {
+		t.Errorf("got rotated secret %q; want a new secret", rotated.Secret)
+	}
+
+	// Disabled webhooks stay disabled when edited without setting active, and receive no deliveries
+	inactive := false
+	edit := func(payload dtos.ReqEditWebhook) models.Webhook {
+		serialized, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatal("failed to marshal payload")
+		}
+		rr := userRequest(t, userA, "PATCH", route, bytes.NewReader(serialized), nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+		}
+		var edited models.Webhook
+		if err := json.Unmarshal(rr.Body.Bytes(), &edited); err != nil {
+			t.Fatal("failed to unmarshal response")
+		}
+		return edited
+	}
+	events := models.WebhookEvents{models.EventCharacterCreated}
+	edit(dtos.ReqEditWebhook{URL: webhookURL, Events: events, Active: &inactive})
+	if edited := edit(dtos.ReqEditWebhook{URL: webhookURL, Events: events}); edited.Active {
+		t.Error("got webhook enabled by an edit that did not set active")
+	}
+	if code := postCharacter(t, userA, universe, characterRequest("Frodo", false)); code != http.StatusCreated {
+		t.Fatalf("got response status %v; want %v", code, http.StatusCreated)
+	}
+	if deliveries := getDeliveries(t, route); len(deliveries) != 0 {
+		t.Fatalf("got %v deliveries for a disabled webhook; want 0", len(deliveries))
+	}
+
+	// Enabled webhooks receive the events they subscribe to
+	active := true
+	edit(dtos.ReqEditWebhook{URL: webhookURL, Events: events, Active: &active})
+	if code := postCharacter(t, userA, universe, characterRequest("Sam", false)); code != http.StatusCreated {
+		t.Fatalf("got response status %v; want %v", code, http.StatusCreated)
+	}
+	deliveries := getDeliveries(t, route)
+	if len(deliveries) != 1 {
+		t.Fatalf("got %v deliveries; want 1", len(deliveries))
+	}
+	delivery := deliveries[0]
+	if delivery.EventType != models.EventCharacterCreated || delivery.Status != models.WebhookDeliveryPending {
+		t.Errorf("got delivery %v (%v); want pending %v", delivery.EventType, delivery.Status,
+			models.EventCharacterCreated)
+	}
+	rr = userRequest(t, userA, "POST", route+"/deliveries/"+delivery.ID+"/redeliver", nil, nil)
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("got redeliver status %v; want %v", rr.Code, http.StatusAccepted)
+	}
+
+	// Removing a webhook removes its deliveries
+	rr = userRequest(t, userA, "DELETE", route, nil, nil)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusNoContent)
+	}
+	if _, err := repos.Webhook.FindDeliveryByID(delivery.ID); err == nil {
+		t.Error("got delivery of a removed webhook")
+	}
+	rr = userRequest(t, userA, "GET", route, nil, nil)
+	testAPIResponse(t, rr, api.ErrCodeNotFound, http.StatusNotFound, false)
+}
+
+// getDeliveries retrieves the most recent deliveries of a webhook through the API on behalf of its owner
+func getDeliveries(t *testing.T, route string) []models.WebhookDelivery {
+	rr := userRequest(t, userA, "GET", route+"/deliveries", nil, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got response status %v; want %v", rr.Code, http.StatusOK)
+	}
+	var res dtos.ResGetWebhookDeliveries
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatal("failed to unmarshal response")
+	}
+	return *res.Deliveries
+}